@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/eternnoir/cc-history-export/internal/converter"
+)
+
+// runDiff runs the diff subcommand, which compares two JSON exports:
+//
+//	cc-export diff [--json] OLD.json NEW.json
+func runDiff(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "Write the differences as JSON instead of a summary")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cc-export diff [--json] OLD.json NEW.json\n\n")
+		fmt.Fprintf(flags.Output(), "Compare two JSON exports and list the added, removed and changed sessions.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("diff requires two JSON exports")
+	}
+
+	oldProjects, err := readJSONExport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	newProjects, err := readJSONExport(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diff := converter.DiffExports(oldProjects, newProjects)
+	if *jsonOutput {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+	return diff.WriteSummary(stdout)
+}
+
+// readJSONExport reads the projects of a JSON export file
+func readJSONExport(path string) ([]*converter.JSONProject, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer file.Close()
+
+	projects, err := converter.ReadJSONExport(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return projects, nil
+}
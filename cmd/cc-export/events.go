@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// eventOutput is where --events-json writes progress events
+var eventOutput io.Writer = os.Stderr
+
+// event is a machine-readable progress event, written as one JSON line
+type event struct {
+	Event    string `json:"event"`
+	Time     string `json:"time"`
+	Source   string `json:"source,omitempty"`
+	Project  string `json:"project,omitempty"`
+	Sessions int    `json:"sessions,omitempty"`
+	Messages int    `json:"messages,omitempty"`
+	File     string `json:"file,omitempty"`
+	Files    int    `json:"files,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Event types, in the order they are emitted
+const (
+	eventScanStarted    = "scan_started"
+	eventProjectScanned = "project_scanned"
+	eventExportWritten  = "export_written"
+	eventDone           = "done"
+
+	// Emitted at any point for a skipped line or unreadable file
+	eventWarning = "warning"
+)
+
+// eventEmitter writes progress events as NDJSON. A nil emitter discards
+// events, so callers don't need to check whether --events-json is set.
+type eventEmitter struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	files int
+}
+
+// newEventEmitter creates an emitter writing to w
+func newEventEmitter(w io.Writer) *eventEmitter {
+	return &eventEmitter{enc: json.NewEncoder(w)}
+}
+
+// emit writes an event, stamping it with the current time
+func (e *eventEmitter) emit(ev event) {
+	if e == nil {
+		return
+	}
+	ev.Time = time.Now().UTC().Format(time.RFC3339)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if ev.Event == eventExportWritten {
+		e.files++
+	}
+	e.enc.Encode(ev)
+}
+
+// Warnf emits a warning event, so the warnings of readers and scanners stay
+// in the NDJSON stream instead of being written to stderr as text
+func (e *eventEmitter) Warnf(format string, args ...any) {
+	e.emit(event{Event: eventWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// done emits the final event with the number of files written and the
+// error that ended the run, if any
+func (e *eventEmitter) done(err error) {
+	if e == nil {
+		return
+	}
+	ev := event{Event: eventDone, Files: e.files}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	e.emit(ev)
+}
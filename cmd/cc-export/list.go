@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+	"github.com/eternnoir/cc-history-export/internal/reader"
+)
+
+// listTitleLength is the maximum length of session titles in the list table
+const listTitleLength = 50
+
+// listProject is a project in the JSON output of the list subcommand
+type listProject struct {
+	Path         string `json:"path"`
+	Name         string `json:"name"`
+	SessionCount int    `json:"session_count"`
+	MessageCount int    `json:"message_count"`
+	StartTime    string `json:"start_time,omitempty"`
+	EndTime      string `json:"end_time,omitempty"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	TotalTokens  int    `json:"total_tokens"`
+}
+
+// listSession is a session in the JSON output of list --project
+type listSession struct {
+	Project      string `json:"project"`
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	MessageCount int    `json:"message_count"`
+	StartTime    string `json:"start_time,omitempty"`
+	EndTime      string `json:"end_time,omitempty"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	TotalTokens  int    `json:"total_tokens"`
+}
+
+// runList runs the list subcommand, which prints the projects of the source
+// directory, or the sessions of the matching projects, without exporting:
+//
+//	cc-export list [--source DIR] [--project PATH] [--format table|json] [--sort KEY]
+func runList(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	source := flags.String("source", "", "Path to .claude directory or a .tar.gz/.tgz archive of one, or comma-separated paths to merge (defaults to $CLAUDE_CONFIG_DIR, then ~/.claude)")
+	project := flags.String("project", "", "List the sessions of the projects whose path contains this text instead of the projects")
+	format := flags.String("format", "table", "Output format: table or json")
+	sortBy := flags.String("sort", "date", "Order of projects and their sessions: date, date-desc, messages, tokens or name")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: cc-export list [--source DIR] [--project PATH] [--format table|json] [--sort KEY]\n\n")
+		fmt.Fprintf(flags.Output(), "List projects with their session and message counts, date ranges and token totals, or the sessions of a project.\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return fmt.Errorf("unexpected argument: %s", flags.Arg(0))
+	}
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("unsupported list format: %s (use table or json)", *format)
+	}
+	switch models.SortKey(*sortBy) {
+	case models.SortByDate, models.SortByDateDesc, models.SortByMessages, models.SortByTokens, models.SortByName:
+	default:
+		return fmt.Errorf("unsupported sort order: %s (use date, date-desc, messages, tokens or name)", *sortBy)
+	}
+
+	cfg := &config{sourcePath: *source}
+	if cfg.sourcePath == "" {
+		cfg.sourcePath, _ = findSourcePath()
+	}
+	if len(cfg.sourcePaths()) == 0 {
+		return fmt.Errorf("could not determine .claude directory path")
+	}
+	scanOpts := &reader.ScanOptions{}
+	if *project != "" {
+		scanOpts.ProjectPaths = []string{*project}
+	}
+	projects, err := reader.ScanRoots(cfg.sourcePaths(), scanOpts)
+	if err != nil {
+		return fmt.Errorf("failed to scan projects: %w", err)
+	}
+	if *project != "" && len(projects) == 0 {
+		return fmt.Errorf("no project matches %s", *project)
+	}
+	models.SortProjects(projects, models.SortKey(*sortBy))
+
+	if *project != "" {
+		return writeSessionList(stdout, projects, *format == "json")
+	}
+	return writeProjectList(stdout, projects, *format == "json")
+}
+
+// writeProjectList writes a table, or a JSON array, of the projects
+func writeProjectList(w io.Writer, projects []*models.Project, asJSON bool) error {
+	list := make([]*listProject, 0, len(projects))
+	for _, project := range projects {
+		usage := project.GetUsageTotals()
+		start, end := project.GetTimeRange()
+		list = append(list, &listProject{
+			Path:         project.Path,
+			Name:         project.GetProjectName(),
+			SessionCount: project.GetSessionCount(),
+			MessageCount: project.GetTotalMessages(),
+			StartTime:    formatListTime(start, time.RFC3339),
+			EndTime:      formatListTime(end, time.RFC3339),
+			InputTokens:  usage.InputTokens,
+			OutputTokens: usage.OutputTokens,
+			TotalTokens:  usage.Total(),
+		})
+	}
+	if asJSON {
+		return writeListJSON(w, list)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tSESSIONS\tMESSAGES\tFIRST\tLAST\tTOKENS")
+	for i, p := range list {
+		start, end := projects[i].GetTimeRange()
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%d\n", p.Path, p.SessionCount, p.MessageCount,
+			tableCell(formatListTime(start, "2006-01-02")), tableCell(formatListTime(end, "2006-01-02")), p.TotalTokens)
+	}
+	return tw.Flush()
+}
+
+// writeSessionList writes a table, or a JSON array, of the sessions of the
+// projects
+func writeSessionList(w io.Writer, projects []*models.Project, asJSON bool) error {
+	var list []*listSession
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			usage := session.GetUsageTotals()
+			list = append(list, &listSession{
+				Project:      project.Path,
+				ID:           session.ID,
+				Title:        session.GetTitle(),
+				MessageCount: session.GetMessageCount(),
+				StartTime:    formatListTime(session.StartTime, time.RFC3339),
+				EndTime:      formatListTime(session.EndTime, time.RFC3339),
+				InputTokens:  usage.InputTokens,
+				OutputTokens: usage.OutputTokens,
+				TotalTokens:  usage.Total(),
+			})
+		}
+	}
+	if asJSON {
+		if list == nil {
+			list = []*listSession{}
+		}
+		return writeListJSON(w, list)
+	}
+
+	// Sessions are grouped under a line naming their project
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	i := 0
+	for n, project := range projects {
+		if n > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "%s\n", project.Path)
+		fmt.Fprintln(tw, "SESSION\tSTARTED\tMESSAGES\tTOKENS\tTITLE")
+		for _, session := range project.Sessions {
+			s := list[i]
+			i++
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", s.ID, tableCell(formatListTime(session.StartTime, "2006-01-02 15:04")),
+				s.MessageCount, s.TotalTokens, strings.ReplaceAll(session.GetPreview(listTitleLength), "\t", " "))
+		}
+	}
+	return tw.Flush()
+}
+
+// writeListJSON writes v as indented JSON
+func writeListJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// formatListTime formats t with layout, or returns an empty string if t is
+// unknown
+func formatListTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// tableCell returns s, or "-" for an empty table cell
+func tableCell(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"os/user"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,31 +20,99 @@ import (
 	"github.com/eternnoir/cc-history-export/internal/exporter"
 	"github.com/eternnoir/cc-history-export/internal/models"
 	"github.com/eternnoir/cc-history-export/internal/reader"
+	"github.com/eternnoir/cc-history-export/internal/stats"
 )
 
 const version = "1.0.0"
 
+// stdinSource is the --source value that reads a single session's JSONL from
+// stdin instead of scanning a .claude directory
+const stdinSource = "-"
+
 type config struct {
 	// Input options
-	sourcePath   string
-	projectPaths []string
-	startTime    string
-	endTime      string
+	sourcePath         string
+	sourceOrigin       string
+	projectPaths       []string
+	excludePaths       []string
+	projectRegex       string
+	models             []string
+	startTime          string
+	since              string
+	endTime            string
+	filter             string
+	search             string
+	minMessages        int
+	onlyErrors         bool
+	searchTrim         bool
+	includeRegenerated bool
+	includeDiagnostics bool
+	mergeSessions      bool
+	checkPaths         bool
+	strict             bool
 	
 	// Output options
 	outputPath   string
 	format       string
+	formatSource string
+	templateFile string
+	sortBy       string
 	batchExport  bool
+	granularity  string
+	pathStrategy string
+	datePrefix   bool
+	incremental  bool
+	watch        bool
+	watchPeriod  time.Duration
+	fileIndex    bool
+	indexOnly    bool
+	promptsOnly  bool
+	searchOutput bool
+	sessionID    string
+	contextCount int
+	titleLength  int
+	dailyUsage   bool
 	
 	// Format-specific options
-	prettyJSON   bool
-	showThinking bool
-	includeRaw   bool
-	includeTodos bool
+	prettyJSON     bool
+	showThinking   bool
+	thinkingChars  int
+	showToolUse    bool
+	splitReasoning bool
+	keywords       int
+	numberTools    bool
+	collapseLength int
+	resultLines    int
+	linkTools      bool
+	readingWPM     int
+	cumulative     bool
+	subagents      bool
+	relativeTimes  bool
+	timeFormat     string
+	userContent    string
+	showBranches   bool
+	flatten        bool
+	noTools        bool
+	includeRaw     bool
+	includeTodos   bool
+	includeShell   bool
+	includeConfig  bool
+	anonymize      bool
+	includeSource  bool
+	anonymizeRules string
 	
 	// Other options
+	eventsJSON  bool
+	progress    bool
 	maxSessions int
+	maxMessages int
+	concurrency int
+	totals      bool
+	statsOnly   bool
+	tagsFile    string
+	pricingFile string
 	verbose     bool
+	quiet       bool
 	version     bool
 }
 
@@ -64,9 +140,24 @@ func parseDateTime(s string) (time.Time, error) {
 		}
 	}
 	
+	// Relative durations count back from now
+	if t, err := parseRelativeTime(s, time.Now()); err == nil {
+		return t, nil
+	}
+	
 	return time.Time{}, fmt.Errorf("unsupported datetime format")
 }
 
+// parseRelativeTime parses a duration such as 7d, 24h or 2d3h as the time
+// that long before now
+func parseRelativeTime(s string, now time.Time) (time.Time, error) {
+	d, err := reader.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-d), nil
+}
+
 // isDateOnly checks if the input string is in date-only format
 func isDateOnly(s string) bool {
 	_, err := time.Parse("2006-01-02", s)
@@ -74,6 +165,20 @@ func isDateOnly(s string) bool {
 }
 
 func main() {
+	// Subcommands take their own flags
+	if len(os.Args) > 1 && (os.Args[1] == "diff" || os.Args[1] == "list") {
+		subcommand := runDiff
+		if os.Args[1] == "list" {
+			subcommand = runList
+		}
+		err := subcommand(os.Args[2:], os.Stdout)
+		if err != nil && err != flag.ErrHelp {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	
 	cfg := parseFlags()
 	
 	if cfg.version {
@@ -86,7 +191,15 @@ func main() {
 		os.Exit(1)
 	}
 	
-	if err := run(cfg); err != nil {
+	// Cancel on the first Ctrl-C so scanning and exporting stop cleanly; a
+	// second Ctrl-C exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	
+	if err := run(ctx, cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -96,31 +209,96 @@ func parseFlags() *config {
 	cfg := &config{}
 	
 	// Define flags
-	flag.StringVar(&cfg.sourcePath, "source", "", "Path to .claude directory (defaults to ~/.claude)")
-	flag.StringVar(&cfg.outputPath, "output", "", "Output file path (use '-' or leave empty for stdout)")
-	flag.StringVar(&cfg.format, "format", "markdown", "Export format: json, markdown, html")
+	flag.StringVar(&cfg.sourcePath, "source", "", "Path to .claude directory or a .tar.gz/.tgz archive of one, comma-separated paths to merge, or - to read one session's JSONL from stdin (defaults to $CLAUDE_CONFIG_DIR, then ~/.claude)")
+	flag.StringVar(&cfg.outputPath, "output", "", "Output file path (use '-' or leave empty for stdout); with --batch, a directory or a .tar, .tar.gz or .tgz archive")
+	flag.StringVar(&cfg.format, "format", "markdown", "Export format: json, yaml, markdown, html, text (plain prose), jsonl (one message per line), csv (session statistics), template (see --template), or several separated by commas (inferred from the --output extension if not set)")
+	
+	flag.StringVar(&cfg.templateFile, "template", "", "Go text/template file to render the export with (implies --format template)")
+	
+	flag.StringVar(&cfg.sortBy, "sort", "date", "Order of projects and their sessions: date, date-desc, messages, tokens or name")
 	
 	// Filter flags
 	projectsStr := flag.String("projects", "", "Comma-separated project paths to filter")
-	flag.StringVar(&cfg.startTime, "start-time", "", "Start date/time (YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)")
+	excludeStr := flag.String("exclude-projects", "", "Comma-separated project paths to skip, even if they match --projects")
+	flag.StringVar(&cfg.projectRegex, "projects-regex", "", "Only export projects whose path matches this regular expression, e.g. \"^/Users/me/work/\"")
+	modelsStr := flag.String("models", "", "Comma-separated models; only export sessions that used one of them (matches part of the name or family, e.g. opus or claude-3.5-sonnet)")
+	flag.StringVar(&cfg.startTime, "start-time", "", "Start date/time (YYYY-MM-DD, YYYY-MM-DD HH:MM:SS or a duration before now like 7d)")
+	flag.StringVar(&cfg.since, "since", "", "Only export sessions active within this duration before now, e.g. 7d, 24h or 2d3h (same as a relative --start-time)")
 	flag.StringVar(&cfg.endTime, "end-time", "", "End date/time (YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)")
+	flag.StringVar(&cfg.filter, "filter", "", "Filter expression, e.g. \"(project=/work/a OR project=/work/b) AND since=7d\"")
+	flag.StringVar(&cfg.search, "search", "", "Only export sessions with a message containing this text (case-insensitive; thinking is searched with --show-thinking)")
+	flag.BoolVar(&cfg.searchTrim, "search-trim", false, "With --search, drop the messages of matching sessions that do not contain the text")
 	flag.IntVar(&cfg.maxSessions, "max-sessions", 0, "Maximum number of sessions to export (0 = unlimited)")
+	flag.IntVar(&cfg.maxMessages, "max-messages-per-session", 0, "Export only the first this many messages of each session to JSON, YAML, JSONL or Markdown, noting how many were omitted (0 = unlimited)")
+	flag.IntVar(&cfg.minMessages, "min-messages", 0, "Skip sessions with fewer than this many messages (0 = no minimum)")
+	flag.BoolVar(&cfg.onlyErrors, "only-errors", false, "Only export sessions with a tool result that is an error (flagged is_error, a <tool_use_error> or a JSON object with an error field)")
 	
 	// Format options
 	flag.BoolVar(&cfg.prettyJSON, "pretty", true, "Pretty print JSON output")
-	flag.BoolVar(&cfg.showThinking, "show-thinking", false, "Include thinking content in Markdown")
+	flag.BoolVar(&cfg.showThinking, "show-thinking", false, "Include thinking content in Markdown, HTML and text")
+	flag.IntVar(&cfg.thinkingChars, "max-thinking-chars", 0, "Show at most this many characters of each thinking block in Markdown and HTML, noting its full length (0 = no limit)")
+	flag.BoolVar(&cfg.showToolUse, "show-tool-use", false, "Include tool calls with their input in text exports")
+	flag.BoolVar(&cfg.splitReasoning, "split-reasoning", false, "Separate assistant thinking from answers (thinking/answer fields in JSON)")
+	flag.IntVar(&cfg.keywords, "keywords", 0, "Number of keywords to tag each session with (0 = none)")
+	flag.BoolVar(&cfg.numberTools, "number-tools", false, "Number tool calls in Markdown and link each tool result to its call")
+	flag.IntVar(&cfg.collapseLength, "collapse-preamble", 0, "Collapse a first user message longer than this many characters in Markdown, keeping its last paragraph visible (0 = never)")
+	flag.BoolVar(&cfg.linkTools, "link-tool-results", false, "Show each tool result with its tool call: under the call in Markdown, in a tool_calls field in JSON")
+	flag.IntVar(&cfg.resultLines, "tool-result-lines", 0, "Show at most this many lines of each tool result in Markdown (0 = no limit)")
+	flag.StringVar(&cfg.userContent, "user-content", "raw", "How to render Markdown in user messages: raw, escape, quote or fence")
+	flag.BoolVar(&cfg.showBranches, "show-branches", false, "Render Markdown in conversation tree order with edited/regenerated branches indented (implies --include-regenerated)")
+	flag.BoolVar(&cfg.noTools, "no-tools", false, "Leave tool calls and tool results out of JSON, YAML, JSONL and Markdown content; counts still include them")
+	flag.BoolVar(&cfg.flatten, "flatten", false, "Render each project's sessions in Markdown as one chronological conversation, with a heading per day instead of per-session headers")
+	flag.BoolVar(&cfg.relativeTimes, "relative-times", false, "Show message times as offsets from the session start instead of absolute times")
+	flag.StringVar(&cfg.timeFormat, "time-format", "", "Format of all times in JSON, YAML, JSONL, Markdown, HTML and text, and of formatTime without a layout in templates: rfc3339, unix, kitchen or a Go layout like \"2006-01-02 15:04\" (default: each format's own)")
+	flag.BoolVar(&cfg.subagents, "group-subagents", false, "Render each subagent's messages and todos in a collapsible section in Markdown")
+	flag.BoolVar(&cfg.cumulative, "cumulative-tokens", false, "Show a running token total after each assistant message in Markdown")
+	flag.IntVar(&cfg.readingWPM, "reading-wpm", 0, "Show estimated reading time in Markdown session headers at this many words per minute, e.g. 200 (0 = hidden)")
 	flag.BoolVar(&cfg.includeRaw, "include-raw", false, "Include raw message data in JSON")
 	flag.BoolVar(&cfg.includeTodos, "include-todos", true, "Include todo lists")
+	flag.BoolVar(&cfg.includeShell, "include-shell-snapshots", false, "Include the shell snapshots Claude Code takes when it starts, each in the project of the session it was taken for (Markdown and JSON)")
+	flag.BoolVar(&cfg.includeConfig, "include-config", false, "Include CLAUDE.md instructions: the source directory's before Markdown exports and as claude_md in JSON, and each project's own")
+	flag.BoolVar(&cfg.includeSource, "include-source", false, "Name the JSONL file each session was read from (source_file in JSON, a footnote in Markdown)")
+	flag.BoolVar(&cfg.anonymize, "anonymize", false, "Replace the home directory with <HOME> and the user name with <USER> in paths, messages and tool inputs")
+	flag.StringVar(&cfg.anonymizeRules, "anonymize-rules", "", "JSON file mapping text to the placeholder replacing it, added to the --anonymize rules (implies --anonymize)")
+	flag.BoolVar(&cfg.includeRegenerated, "include-regenerated", false, "Include superseded edit/regeneration branches (labeled regenerated)")
+	flag.BoolVar(&cfg.includeDiagnostics, "include-diagnostics", false, "Include diagnostic log entries (lines with a level such as debug)")
+	flag.BoolVar(&cfg.mergeSessions, "merge-sessions", false, "Merge sessions continued in several files of a project (same session ID) into one")
+	flag.BoolVar(&cfg.checkPaths, "check-paths", false, "Flag projects whose directory no longer exists (exists: false)")
+	flag.BoolVar(&cfg.strict, "strict", false, "Fail on the first malformed line, unparsable message or unreadable session file instead of skipping it (--verbose lists skipped files)")
 	
 	// Export options
 	flag.BoolVar(&cfg.batchExport, "batch", false, "Export each project/session to separate files")
+	flag.StringVar(&cfg.granularity, "granularity", "project", "Batch file granularity: project or session (one file per session)")
+	flag.StringVar(&cfg.pathStrategy, "path-strategy", "flat", "Batch file layout: flat, or date (YYYY/MM subdirectories by the start date of each project or session)")
+	flag.BoolVar(&cfg.datePrefix, "date-prefix", false, "Prefix batch filenames with the project's last activity date")
+	flag.BoolVar(&cfg.incremental, "incremental", false, "Skip batch files whose source sessions are unchanged since the last incremental export")
+	flag.BoolVar(&cfg.watch, "watch", false, "After exporting, keep watching the source directory and export each new session until interrupted (requires --batch --granularity session)")
+	flag.DurationVar(&cfg.watchPeriod, "watch-interval", 5*time.Second, "How often --watch re-scans the source directory")
+	flag.BoolVar(&cfg.fileIndex, "file-index", false, "With --batch, also write index.json listing each exported file with its project, session and message counts, date range and size")
+	flag.IntVar(&cfg.concurrency, "concurrency", 0, "Number of files written in parallel in batch mode (0 = serial)")
+	flag.BoolVar(&cfg.indexOnly, "index", false, "Export a session index instead of content (with --batch, also write index file)")
+	flag.BoolVar(&cfg.promptsOnly, "prompts-only", false, "Export only your prompts, as a numbered Markdown list or a JSON array with project, session and timestamp")
+	flag.StringVar(&cfg.sessionID, "session", "", "Export only the session with this ID")
+	flag.BoolVar(&cfg.searchOutput, "search-results", false, "With --search, export only the matching messages with surrounding context")
+	flag.IntVar(&cfg.contextCount, "context-messages", 2, "Messages shown before and after each match with --search-results")
+	flag.BoolVar(&cfg.dailyUsage, "daily", false, "With csv format, export token usage and estimated cost per day and model instead of per session")
+	flag.IntVar(&cfg.titleLength, "title-length", models.DefaultTitleLength, "Maximum length in characters of session titles in the index")
 	
 	// Other flags
+	flag.BoolVar(&cfg.totals, "totals", false, "Print message, token and estimated cost totals without exporting")
+	flag.BoolVar(&cfg.statsOnly, "stats-only", false, "Write a usage summary (totals, busiest day, tokens per model) as text or JSON instead of exporting content")
+	flag.StringVar(&cfg.pricingFile, "pricing-file", "", "JSON file of per-million-token prices by model, adding to or overriding the built-in prices")
+	flag.StringVar(&cfg.tagsFile, "tags-file", "", "JSON file mapping project paths to tags; with --totals, also print totals per tag")
+	flag.BoolVar(&cfg.eventsJSON, "events-json", false, "Write progress events (scan_started, project_scanned, export_written, done) and warnings as NDJSON to stderr")
+	flag.BoolVar(&cfg.progress, "progress", false, "Show a progress line on stderr while scanning and batch exporting (also shown with --verbose); only when stderr is a terminal")
 	flag.BoolVar(&cfg.verbose, "verbose", false, "Verbose output")
+	flag.BoolVar(&cfg.quiet, "quiet", false, "Suppress warnings about skipped lines, unreadable files and inconsistent token usage")
 	flag.BoolVar(&cfg.version, "version", false, "Show version")
 	
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s diff [--json] OLD.json NEW.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s list [--source DIR] [--project PATH] [--format table|json] [--sort KEY]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Claude Code History Export Tool v%s\n\n", version)
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
@@ -131,88 +309,480 @@ func parseFlags() *config {
 		fmt.Fprintf(os.Stderr, "  # Export all data to Markdown file\n")
 		fmt.Fprintf(os.Stderr, "  cc-export --output conversations.md\n\n")
 		fmt.Fprintf(os.Stderr, "  # Export specific project to JSON\n")
-		fmt.Fprintf(os.Stderr, "  cc-export --projects /Users/myproject --format json --output project.json\n\n")
+		fmt.Fprintf(os.Stderr, "  cc-export --projects /Users/myproject --output project.json\n\n")
 		fmt.Fprintf(os.Stderr, "  # Export date range with batch output\n")
 		fmt.Fprintf(os.Stderr, "  cc-export --start-time 2024-01-01 --end-time 2024-12-31 --batch --output exports/\n\n")
+		fmt.Fprintf(os.Stderr, "  # Export recent sessions from either of two projects\n")
+		fmt.Fprintf(os.Stderr, "  cc-export --filter \"(project=/work/a OR project=/work/b) AND since=7d\"\n\n")
 		fmt.Fprintf(os.Stderr, "  # Export with specific time range (use quotes for spaces)\n")
 		fmt.Fprintf(os.Stderr, "  cc-export --start-time \"2024-01-01 09:00:00\" --end-time \"2024-01-31 18:00:00\" --output january.md\n\n")
 	}
 	
 	flag.Parse()
 	
-	// Parse project paths
-	if *projectsStr != "" {
-		cfg.projectPaths = strings.Split(*projectsStr, ",")
-		for i := range cfg.projectPaths {
-			cfg.projectPaths[i] = strings.TrimSpace(cfg.projectPaths[i])
+	// Infer the format from the output filename unless given explicitly
+	formatSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "format" {
+			formatSet = true
+		}
+	})
+	if !formatSet && cfg.templateFile != "" {
+		cfg.format = string(exporter.FormatTemplate)
+	} else if !formatSet {
+		if format, ok := exporter.DetectFormat(cfg.outputPath); ok {
+			cfg.format = string(format)
+			cfg.formatSource = "output filename"
 		}
 	}
 	
+	// Parse project paths, excluded project paths and models
+	cfg.projectPaths = splitList(*projectsStr)
+	cfg.excludePaths = splitList(*excludeStr)
+	cfg.models = splitList(*modelsStr)
+	cfg.format = strings.Join(splitList(cfg.format), ",")
+	cfg.sourcePath = strings.Join(splitList(cfg.sourcePath), ",")
+	
 	// Default source path
 	if cfg.sourcePath == "" {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			cfg.sourcePath = filepath.Join(home, ".claude")
-		}
+		cfg.sourcePath, cfg.sourceOrigin = findSourcePath()
+	} else {
+		cfg.sourceOrigin = "--source"
 	}
 	
 	return cfg
 }
 
+// findSourcePath finds the Claude Code configuration directory and returns
+// it along with where it came from. CLAUDE_CONFIG_DIR takes precedence, then
+// the XDG config directory on Linux if it holds projects, then ~/.claude.
+func findSourcePath() (path, origin string) {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return dir, "CLAUDE_CONFIG_DIR"
+	}
+	
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", ""
+	}
+	
+	if runtime.GOOS == "linux" {
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		dir := filepath.Join(configHome, "claude")
+		if info, err := os.Stat(filepath.Join(dir, "projects")); err == nil && info.IsDir() {
+			return dir, "XDG config directory"
+		}
+	}
+	
+	return filepath.Join(home, ".claude"), "home directory"
+}
+
+// splitList splits a comma-separated flag value, dropping the entries that
+// are empty once trimmed: an empty pattern would match everything
+func splitList(s string) []string {
+	var list []string
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// sourcePaths returns the source directories, which --source may list
+// separated by commas
+func (cfg *config) sourcePaths() []string {
+	return splitList(cfg.sourcePath)
+}
+
+// formats returns the export formats, which --format may list separated by
+// commas
+func (cfg *config) formats() []string {
+	return splitList(cfg.format)
+}
+
 func validateConfig(cfg *config) error {
+	if cfg.templateFile != "" && !slices.Contains(cfg.formats(), "template") {
+		return fmt.Errorf("--template requires --format template")
+	}
+	
+	// Several formats are exported from one scan, each validated on its own
+	if formats := cfg.formats(); len(formats) > 1 {
+		return validateFormats(cfg, formats)
+	}
+	
 	// outputPath can be empty or "-" for stdout
 	if cfg.outputPath == "" || cfg.outputPath == "-" {
-		// batch export requires output directory
-		if cfg.batchExport {
+		// batch export requires output directory (totals mode writes nothing)
+		if cfg.batchExport && !cfg.totals {
 			return fmt.Errorf("batch export requires an output directory")
 		}
 	}
 	
-	if cfg.sourcePath == "" {
+	if len(cfg.sourcePaths()) == 0 {
 		return fmt.Errorf("could not determine .claude directory path")
 	}
 	
-	// Check if source directory exists
-	if _, err := os.Stat(cfg.sourcePath); os.IsNotExist(err) {
-		return fmt.Errorf(".claude directory not found at %s", cfg.sourcePath)
+	// Source paths are not anonymized, as incremental exports read them
+	if cfg.includeSource && (cfg.anonymize || cfg.anonymizeRules != "") {
+		return fmt.Errorf("--include-source cannot be combined with --anonymize (source paths are not anonymized)")
+	}
+	
+	if cfg.quiet && cfg.verbose {
+		return fmt.Errorf("--quiet cannot be combined with --verbose")
+	}
+	
+	// A session piped to stdin is exported on its own
+	if cfg.sourcePath == stdinSource && cfg.batchExport {
+		return fmt.Errorf("--source - cannot be combined with --batch")
+	}
+	
+	// Check if source directories exist
+	for _, sourcePath := range cfg.sourcePaths() {
+		if sourcePath == stdinSource {
+			if cfg.sourcePath != stdinSource {
+				return fmt.Errorf("--source - cannot be combined with other sources")
+			}
+			continue
+		}
+		if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+			return fmt.Errorf(".claude directory not found at %s", sourcePath)
+		}
+	}
+	
+	// Statistics are a single summary in text or JSON
+	if cfg.statsOnly {
+		switch cfg.format {
+		case "json", "markdown", "text":
+		default:
+			return fmt.Errorf("--stats-only writes text or json, not %s", cfg.format)
+		}
+		if cfg.batchExport {
+			return fmt.Errorf("--stats-only cannot be combined with --batch")
+		}
 	}
 	
 	// Validate format
 	switch cfg.format {
-	case "json", "markdown":
+	case "json", "yaml", "markdown":
 		// Valid formats
+	case "text":
+		// Plain text renders sessions and projects, or statistics
+		if cfg.indexOnly || cfg.searchOutput || cfg.promptsOnly {
+			return fmt.Errorf("text format cannot be combined with --index, --search-results or --prompts-only")
+		}
+	case "jsonl":
+		// JSON Lines holds the messages of sessions and projects
+		if cfg.indexOnly || cfg.searchOutput || cfg.promptsOnly {
+			return fmt.Errorf("jsonl format cannot be combined with --index, --search-results or --prompts-only")
+		}
+	case "template":
+		// Templates render sessions and projects; parse errors are reported
+		// before scanning
+		if cfg.templateFile == "" {
+			return fmt.Errorf("template format requires --template")
+		}
+		if cfg.indexOnly || cfg.searchOutput || cfg.promptsOnly {
+			return fmt.Errorf("template format cannot be combined with --index, --search-results or --prompts-only")
+		}
+		if _, err := readTemplate(cfg.templateFile); err != nil {
+			return err
+		}
+	case "csv":
+		// CSV exports session statistics or daily usage tables only
+		if cfg.indexOnly || cfg.searchOutput || cfg.promptsOnly {
+			return fmt.Errorf("csv format cannot be combined with --index, --search-results or --prompts-only")
+		}
 	case "html":
-		return fmt.Errorf("HTML format not yet implemented")
+		// HTML documents render sessions and projects only
+		if cfg.indexOnly || cfg.searchOutput || cfg.promptsOnly {
+			return fmt.Errorf("html format cannot be combined with --index, --search-results or --prompts-only")
+		}
 	default:
+		if cfg.formatSource != "" {
+			return fmt.Errorf("unsupported format: %s (inferred from %s, use --format to override)", cfg.format, cfg.formatSource)
+		}
 		return fmt.Errorf("unsupported format: %s", cfg.format)
 	}
 	
+	// Daily usage is a single CSV table
+	if cfg.dailyUsage {
+		if cfg.format != "csv" {
+			return fmt.Errorf("--daily requires csv format")
+		}
+		if cfg.batchExport {
+			return fmt.Errorf("--daily cannot be combined with --batch")
+		}
+	}
+	
+	if cfg.searchTrim && cfg.search == "" {
+		return fmt.Errorf("--search-trim requires --search")
+	}
+	
+	// Search results are a single document of excerpts
+	if cfg.searchOutput {
+		if cfg.search == "" {
+			return fmt.Errorf("--search-results requires --search")
+		}
+		if cfg.batchExport {
+			return fmt.Errorf("--search-results cannot be combined with --batch")
+		}
+	}
+	
+	// Prompts are a single list across all sessions
+	if cfg.promptsOnly {
+		if cfg.batchExport {
+			return fmt.Errorf("--prompts-only cannot be combined with --batch")
+		}
+		if cfg.indexOnly || cfg.searchOutput || cfg.dailyUsage {
+			return fmt.Errorf("--prompts-only cannot be combined with --index, --search-results or --daily")
+		}
+	}
+	
+	// A single session is exported on its own
+	if cfg.sessionID != "" {
+		if cfg.batchExport {
+			return fmt.Errorf("--session cannot be combined with --batch")
+		}
+		if cfg.indexOnly || cfg.searchOutput || cfg.dailyUsage || cfg.promptsOnly {
+			return fmt.Errorf("--session cannot be combined with --index, --search-results, --daily or --prompts-only")
+		}
+	}
+	
+	// Validate batch granularity
+	switch exporter.Granularity(cfg.granularity) {
+	case "", exporter.GranularityProject, exporter.GranularitySession:
+	default:
+		return fmt.Errorf("unsupported granularity: %s (use project or session)", cfg.granularity)
+	}
+	switch exporter.PathStrategy(cfg.pathStrategy) {
+	case "", exporter.FlatStrategy:
+	case exporter.DateStrategy:
+		if !cfg.batchExport {
+			return fmt.Errorf("--path-strategy date requires --batch")
+		}
+	default:
+		return fmt.Errorf("unsupported path strategy: %s (use flat or date)", cfg.pathStrategy)
+	}
+	if cfg.incremental {
+		if !cfg.batchExport {
+			return fmt.Errorf("--incremental requires --batch")
+		}
+		if exporter.Granularity(cfg.granularity) == exporter.GranularitySession {
+			return fmt.Errorf("--incremental only supports project granularity")
+		}
+	}
+	
+	// Watching exports each new session of one directory to its own file
+	if cfg.watch {
+		if !cfg.batchExport || exporter.Granularity(cfg.granularity) != exporter.GranularitySession {
+			return fmt.Errorf("--watch requires --batch --granularity session")
+		}
+		if len(cfg.sourcePaths()) > 1 || reader.IsArchive(cfg.sourcePath) {
+			return fmt.Errorf("--watch requires a single source directory")
+		}
+		if cfg.indexOnly || cfg.totals || cfg.statsOnly {
+			return fmt.Errorf("--watch cannot be combined with --index, --totals or --stats-only")
+		}
+		if cfg.watchPeriod <= 0 {
+			return fmt.Errorf("--watch-interval must be positive")
+		}
+	}
+	
+	// Validate sort order
+	switch models.SortKey(cfg.sortBy) {
+	case "", models.SortByDate, models.SortByDateDesc, models.SortByMessages, models.SortByTokens, models.SortByName:
+	default:
+		return fmt.Errorf("unsupported sort order: %s (use date, date-desc, messages, tokens or name)", cfg.sortBy)
+	}
+	
+	if cfg.fileIndex {
+		if !cfg.batchExport {
+			return fmt.Errorf("--file-index requires --batch")
+		}
+		if exporter.Granularity(cfg.granularity) == exporter.GranularitySession {
+			return fmt.Errorf("--file-index only supports project granularity")
+		}
+		if cfg.indexOnly && cfg.format == "json" {
+			return fmt.Errorf("--file-index cannot be combined with --index in json format (both write %s)", exporter.DefaultFileIndexName)
+		}
+	}
+	
+	// A batch export to a .tar, .tar.gz or .tgz output writes every project
+	// file into that one archive
+	if isTar, _ := exporter.TarArchiveType(cfg.outputPath); isTar && cfg.batchExport {
+		if exporter.Granularity(cfg.granularity) == exporter.GranularitySession {
+			return fmt.Errorf("--batch to a tar archive only supports project granularity")
+		}
+		if cfg.incremental || cfg.watch || cfg.indexOnly {
+			return fmt.Errorf("--batch to a tar archive cannot be combined with --incremental, --watch or --index")
+		}
+	}
+	
+	// Validate time format
+	if err := converter.ValidateTimeFormat(cfg.timeFormat); err != nil {
+		return err
+	}
+	if cfg.timeFormat != "" && cfg.relativeTimes {
+		return fmt.Errorf("--time-format cannot be combined with --relative-times")
+	}
+	
+	// Validate user content mode
+	switch converter.UserContentMode(cfg.userContent) {
+	case "", converter.UserContentRaw, converter.UserContentEscape, converter.UserContentQuote, converter.UserContentFence:
+	default:
+		return fmt.Errorf("unsupported user content mode: %s (use raw, escape, quote or fence)", cfg.userContent)
+	}
+	
 	// Validate dates
 	if cfg.startTime != "" {
 		if _, err := parseDateTime(cfg.startTime); err != nil {
-			return fmt.Errorf("invalid start time format: %s (use YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)", cfg.startTime)
+			return fmt.Errorf("invalid start time format: %s (use YYYY-MM-DD, YYYY-MM-DD HH:MM:SS or a duration like 7d)", cfg.startTime)
 		}
 	}
 	
 	if cfg.endTime != "" {
 		if _, err := parseDateTime(cfg.endTime); err != nil {
-			return fmt.Errorf("invalid end time format: %s (use YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)", cfg.endTime)
+			return fmt.Errorf("invalid end time format: %s (use YYYY-MM-DD, YYYY-MM-DD HH:MM:SS or a duration like 7d)", cfg.endTime)
+		}
+	}
+	
+	if cfg.since != "" {
+		if cfg.startTime != "" {
+			return fmt.Errorf("--since cannot be combined with --start-time")
+		}
+		if _, err := reader.ParseDuration(cfg.since); err != nil {
+			return fmt.Errorf("invalid --since duration: %s (use e.g. 7d, 24h or 2d3h)", cfg.since)
+		}
+	}
+	
+	// Validate project path regex
+	if cfg.projectRegex != "" {
+		if _, err := regexp.Compile(cfg.projectRegex); err != nil {
+			return fmt.Errorf("invalid --projects-regex: %w", err)
+		}
+	}
+	
+	// Validate filter expression
+	if cfg.filter != "" {
+		if _, err := reader.ParseFilter(cfg.filter); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
 		}
 	}
 	
 	return nil
 }
 
-func run(cfg *config) error {
+// validateFormats validates a config listing several formats
+func validateFormats(cfg *config, formats []string) error {
+	if cfg.outputPath == "" || cfg.outputPath == "-" {
+		return fmt.Errorf("multiple formats require --output")
+	}
+	if cfg.statsOnly || cfg.totals {
+		return fmt.Errorf("multiple formats cannot be combined with --stats-only or --totals")
+	}
+	if cfg.incremental || cfg.fileIndex {
+		return fmt.Errorf("multiple formats cannot be combined with --incremental or --file-index")
+	}
+	if isTar, _ := exporter.TarArchiveType(cfg.outputPath); isTar {
+		return fmt.Errorf("multiple formats cannot be exported to a tar archive")
+	}
+	
+	// Each format is written to files with its extension, so formats sharing
+	// one would overwrite each other
+	seen := make(map[string]bool)
+	extensions := make(map[string]string)
+	for _, format := range formats {
+		if seen[format] {
+			return fmt.Errorf("format %s is listed more than once", format)
+		}
+		seen[format] = true
+		ext := formatExtension(format)
+		if other, ok := extensions[ext]; ok {
+			return fmt.Errorf("formats %s and %s both write %s files and cannot be combined", other, format, ext)
+		}
+		extensions[ext] = format
+		formatCfg := *cfg
+		formatCfg.format = format
+		if err := validateConfig(&formatCfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// showProgress reports whether to draw progress lines, which would garble
+// --events-json output or a redirected stderr
+func (cfg *config) showProgress() bool {
+	return (cfg.progress || cfg.verbose) && !cfg.eventsJSON && isTerminal(os.Stderr)
+}
+
+func run(ctx context.Context, cfg *config) error {
+	var events *eventEmitter
+	if cfg.eventsJSON {
+		events = newEventEmitter(eventOutput)
+	}
+	
+	err := runExport(ctx, cfg, events)
+	events.done(err)
+	return err
+}
+
+// runExport scans the source directory and exports the results, reporting
+// progress to events
+func runExport(ctx context.Context, cfg *config, events *eventEmitter) error {
+	events.emit(event{Event: eventScanStarted, Source: cfg.sourcePath})
+	
 	if cfg.verbose {
-		fmt.Printf("Scanning %s...\n", cfg.sourcePath)
+		if cfg.sourcePath == stdinSource {
+			fmt.Println("Reading session from stdin...")
+		} else if cfg.sourceOrigin != "" {
+			fmt.Printf("Scanning %s (from %s)...\n", cfg.sourcePath, cfg.sourceOrigin)
+		} else {
+			fmt.Printf("Scanning %s...\n", cfg.sourcePath)
+		}
 	}
 	
 	// Create scanner options
 	scanOpts := &reader.ScanOptions{
-		ProjectPaths: cfg.projectPaths,
-		IncludeTodos: cfg.includeTodos,
-		MaxSessions:  cfg.maxSessions,
+		ProjectPaths:          cfg.projectPaths,
+		ExcludePaths:          cfg.excludePaths,
+		ProjectPathRegex:      cfg.projectRegex,
+		Models:                cfg.models,
+		IncludeTodos:          cfg.includeTodos,
+		IncludeShellSnapshots: cfg.includeShell,
+		MaxSessions:           cfg.maxSessions,
+		MinMessages:           cfg.minMessages,
+		OnlyToolErrors:        cfg.onlyErrors,
+		IncludeRegenerated:    cfg.includeRegenerated || cfg.showBranches,
+		IncludeDiagnostics:    cfg.includeDiagnostics,
+		MergeSessions:         cfg.mergeSessions,
+		CheckPaths:            cfg.checkPaths,
+		IncludeConfig:         cfg.includeConfig,
+		Strict:                cfg.strict,
+		Search:                cfg.search,
+		SearchThinking:        cfg.showThinking,
+		SearchTrim:            cfg.searchTrim,
+		OnProject: func(project *models.Project) {
+			events.emit(event{
+				Event:    eventProjectScanned,
+				Project:  project.Path,
+				Sessions: project.GetSessionCount(),
+				Messages: project.GetTotalMessages(),
+			})
+		},
+	}
+	
+	if cfg.showProgress() {
+		scanOpts.Progress = newProgressLine(os.Stderr, "Scanning").update
+	}
+	if cfg.quiet {
+		scanOpts.Logger = reader.DiscardLogger
+	} else if events != nil {
+		scanOpts.Logger = events
 	}
 	
 	// Parse dates
@@ -220,6 +790,10 @@ func run(cfg *config) error {
 		t, _ := parseDateTime(cfg.startTime)
 		scanOpts.StartDate = &t
 	}
+	if cfg.since != "" {
+		t, _ := parseRelativeTime(cfg.since, time.Now())
+		scanOpts.StartDate = &t
+	}
 	if cfg.endTime != "" {
 		t, _ := parseDateTime(cfg.endTime)
 		// For date-only input, add 1 day to include the entire end date
@@ -228,17 +802,75 @@ func run(cfg *config) error {
 		}
 		scanOpts.EndDate = &t
 	}
+	if cfg.filter != "" {
+		scanOpts.Filter, _ = reader.ParseFilter(cfg.filter)
+	}
+	
+	// Custom prices apply to every cost estimate
+	if cfg.pricingFile != "" {
+		pricing, err := reader.LoadPricing(cfg.pricingFile)
+		if err != nil {
+			return err
+		}
+		models.AddPricing(pricing)
+	}
 	
-	// Scan projects
-	scanner := reader.NewScanner(cfg.sourcePath, scanOpts)
-	projects, err := scanner.ScanProjects()
+	// Scan projects, merging projects found in several source directories
+	var projects []*models.Project
+	var result *reader.ScanResult
+	var err error
+	if cfg.sourcePath == stdinSource {
+		projects, err = readStdinSession(scanOpts)
+	} else {
+		result, err = reader.ScanRootsDetailedContext(ctx, cfg.sourcePaths(), scanOpts)
+		if result != nil {
+			projects = result.Projects
+			if cfg.verbose {
+				defer printFileErrors(os.Stderr, result.FileErrors)
+			}
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to scan projects: %w", err)
 	}
+	models.SortProjects(projects, models.SortKey(cfg.sortBy))
+	
+	// Scrub personal paths before anything is rendered
+	var anonymizeRules []models.AnonymizeRule
+	if cfg.anonymize || cfg.anonymizeRules != "" {
+		anonymizeRules, err = loadAnonymizeRules(cfg)
+		if err != nil {
+			return err
+		}
+		models.Anonymize(projects, anonymizeRules)
+	}
+	
+	// Totals mode prints aggregates and skips exporting entirely
+	if cfg.totals {
+		if !cfg.quiet {
+			printUsageWarnings(os.Stderr, projects)
+		}
+		printTotals(os.Stdout, projects)
+		if cfg.tagsFile != "" {
+			tagMap, err := reader.LoadTagMap(cfg.tagsFile)
+			if err != nil {
+				return err
+			}
+			printTagTotals(os.Stdout, models.GroupByTag(projects, tagMap))
+		}
+		return nil
+	}
+	
+	// Stats mode writes a summary instead of content
+	if cfg.statsOnly {
+		return writeStats(stats.Aggregate(projects), cfg)
+	}
 	
 	if len(projects) == 0 {
 		fmt.Println("No projects found matching the criteria")
-		return nil
+		if !cfg.watch {
+			return nil
+		}
 	}
 	
 	if cfg.verbose {
@@ -253,28 +885,153 @@ func run(cfg *config) error {
 		fmt.Printf("Total messages: %d\n", totalMessages)
 	}
 	
-	// Create exporter
+	// Read CLAUDE.md once for all formats
+	var claudeConfig string
+	if cfg.includeConfig {
+		claudeConfig, err = readClaudeConfig(cfg)
+		if err != nil {
+			return err
+		}
+		claudeConfig = models.AnonymizeText(claudeConfig, anonymizeRules)
+	}
+	
+	if len(projects) > 0 {
+		if err := exportFormats(ctx, projects, cfg, claudeConfig, events); err != nil {
+			return err
+		}
+	}
+	if cfg.watch {
+		return watchSessions(ctx, cfg, scanOpts, result, anonymizeRules, claudeConfig, events)
+	}
+	return nil
+}
+
+// exportFormats exports the projects in each format of cfg from the same
+// scan, to a file named after the format when there are several
+func exportFormats(ctx context.Context, projects []*models.Project, cfg *config, claudeConfig string, events *eventEmitter) error {
+	formats := cfg.formats()
+	for _, format := range formats {
+		formatCfg := *cfg
+		formatCfg.format = format
+		if len(formats) > 1 && !cfg.batchExport {
+			formatCfg.outputPath = formatOutputPath(cfg.outputPath, format)
+		}
+		if err := exportFormat(ctx, projects, &formatCfg, claudeConfig, events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchSessions exports each new session of the source directory as it
+// appears, until ctx is done. Sessions created or changed since scanned,
+// the result of the initial scan, are exported first. Stopping with Ctrl-C
+// is not an error.
+func watchSessions(ctx context.Context, cfg *config, scanOpts *reader.ScanOptions, scanned *reader.ScanResult, anonymizeRules []models.AnonymizeRule, claudeConfig string, events *eventEmitter) error {
+	watchOpts := *scanOpts
+	watchOpts.OnProject = nil
+	watchOpts.Progress = nil
+	
+	if cfg.verbose {
+		fmt.Printf("Watching %s for new sessions every %s...\n", cfg.sourcePath, cfg.watchPeriod)
+	}
+	
+	// An export error stops watching
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var exportErr error
+	scanner := reader.NewScanner(cfg.sourcePath, &watchOpts)
+	err := scanner.WatchFrom(watchCtx, cfg.watchPeriod, scanned, func(session *models.Session) {
+		project := scanner.NewProject(session.ProjectID)
+		project.AddSession(session)
+		projects := []*models.Project{project}
+		if len(anonymizeRules) > 0 {
+			models.Anonymize(projects, anonymizeRules)
+		}
+		if err := exportFormats(watchCtx, projects, cfg, claudeConfig, events); err != nil {
+			exportErr = err
+			cancel()
+		}
+	})
+	if exportErr != nil {
+		return exportErr
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("failed to watch %s: %w", cfg.sourcePath, err)
+}
+
+// exportFormat exports the projects in the format of cfg
+func exportFormat(ctx context.Context, projects []*models.Project, cfg *config, claudeConfig string, events *eventEmitter) error {
 	exportOpts := &exporter.ExportOptions{
-		Format:          exporter.Format(cfg.format),
-		IncludeMetadata: true,
-		IncludeStats:    true,
+		Format:                exporter.Format(cfg.format),
+		IncludeMetadata:       true,
+		IncludeStats:          true,
+		IncludeConfig:         cfg.includeConfig,
+		Config:                claudeConfig,
+		SkipToolMessages:      cfg.noTools,
+		MaxMessagesPerSession: cfg.maxMessages,
 	}
 	
 	// Set format-specific options
 	switch cfg.format {
-	case "json":
+	case "json", "yaml", "jsonl":
 		exportOpts.FormatOptions = &converter.JSONOptions{
 			PrettyPrint:        cfg.prettyJSON,
 			IncludeRawMessages: cfg.includeRaw,
 			OmitEmpty:          true,
+			SplitReasoning:     cfg.splitReasoning,
+			KeywordCount:       cfg.keywords,
+			RelativeTimestamps: cfg.relativeTimes,
+			LinkToolResults:    cfg.linkTools,
+			IncludeSourceFile:  cfg.includeSource,
+			TimeFormat:         cfg.timeFormat,
 		}
 	case "markdown":
 		exportOpts.FormatOptions = &converter.MarkdownOptions{
-			ShowTimestamps: true,
-			ShowTokenUsage: true,
-			ShowThinking:   cfg.showThinking,
-			ShowUUIDs:      false,
+			ShowTimestamps:         true,
+			ShowTokenUsage:         true,
+			ShowThinking:           cfg.showThinking,
+			MaxThinkingChars:       cfg.thinkingChars,
+			ShowUUIDs:              false,
+			SplitReasoning:         cfg.splitReasoning,
+			KeywordCount:           cfg.keywords,
+			NumberToolCalls:        cfg.numberTools,
+			CollapsePreambleLength: cfg.collapseLength,
+			MaxToolResultLines:     cfg.resultLines,
+			LinkToolResults:        cfg.linkTools,
+			ReadingWPM:             cfg.readingWPM,
+			CumulativeTokens:       cfg.cumulative,
+			GroupSubagents:         cfg.subagents,
+			RelativeTimestamps:     cfg.relativeTimes,
+			UserContent:            converter.UserContentMode(cfg.userContent),
+			ShowBranches:           cfg.showBranches,
+			MergeSessions:          cfg.flatten,
+			IncludeSourceFile:      cfg.includeSource,
+			TimeFormat:             cfg.timeFormat,
 		}
+	case "html":
+		exportOpts.FormatOptions = &converter.HTMLOptions{
+			ShowTimestamps:   true,
+			ShowTokenUsage:   true,
+			ShowThinking:     cfg.showThinking,
+			MaxThinkingChars: cfg.thinkingChars,
+			TimeFormat:       cfg.timeFormat,
+		}
+	case "text":
+		exportOpts.FormatOptions = &converter.TextOptions{
+			ShowThinking: cfg.showThinking,
+			ShowToolUse:  cfg.showToolUse,
+			TimeFormat:   cfg.timeFormat,
+		}
+	case "template":
+		tmplOpts, err := readTemplate(cfg.templateFile)
+		if err != nil {
+			return err
+		}
+		tmplOpts.TimeFormat = cfg.timeFormat
+		exportOpts.FormatOptions = tmplOpts
 	}
 	
 	fileExporter, err := exporter.NewFileExporter(exportOpts)
@@ -284,13 +1041,174 @@ func run(cfg *config) error {
 	
 	// Export data
 	if cfg.batchExport {
-		return batchExport(fileExporter, projects, cfg)
+		return batchExport(ctx, fileExporter, projects, cfg, events)
 	} else {
-		return singleExport(fileExporter, projects, cfg)
+		return singleExport(ctx, fileExporter, projects, cfg, events)
 	}
 }
 
-func singleExport(exp *exporter.FileExporter, projects []*models.Project, cfg *config) error {
+// printTotals prints the aggregate message count, token breakdown and
+// estimated cost of the scanned projects
+func printTotals(w io.Writer, projects []*models.Project) {
+	var usage models.Usage
+	var stale []string
+	messages := 0
+	thinking := 0
+	cost := 0.0
+	for _, p := range projects {
+		projectUsage := p.GetUsageTotals()
+		usage.Add(&projectUsage)
+		messages += p.GetTotalMessages()
+		thinking += p.GetThinkingTokens()
+		cost += p.GetEstimatedCost()
+		if p.IsStale() {
+			stale = append(stale, p.ResolvePath())
+		}
+	}
+	
+	fmt.Fprintf(w, "Messages: %d | Input tokens: %d | Output tokens: %d | Cache read tokens: %d | Cache write tokens: %d | Estimated cost: $%.4f\n",
+		messages, usage.InputTokens, usage.OutputTokens, usage.CacheReadInputTokens, usage.CacheCreationInputTokens, cost)
+	
+	if thinking > 0 {
+		fmt.Fprintf(w, "Thinking tokens (estimated, included in output): %d\n", thinking)
+	}
+	
+	if len(stale) > 0 {
+		fmt.Fprintf(w, "Stale projects (directory not found): %d\n", len(stale))
+		for _, path := range stale {
+			fmt.Fprintf(w, "  %s\n", path)
+		}
+	}
+}
+
+// loadAnonymizeRules returns the rules replacing the current user's home
+// directory and user name, followed by those of --anonymize-rules
+func loadAnonymizeRules(cfg *config) ([]models.AnonymizeRule, error) {
+	homeDir, _ := os.UserHomeDir()
+	username := ""
+	if u, err := user.Current(); err == nil {
+		// Windows user names are qualified with their domain
+		username = u.Username[strings.LastIndex(u.Username, `\`)+1:]
+	}
+	rules := models.DefaultAnonymizeRules(homeDir, username)
+	
+	if cfg.anonymizeRules != "" {
+		extra, err := reader.LoadAnonymizeRules(cfg.anonymizeRules)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, extra...)
+	}
+	return rules, nil
+}
+
+// readTemplate reads and parses the --template file, named after its base
+// name in errors
+func readTemplate(path string) (*converter.TemplateOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	options := &converter.TemplateOptions{Name: filepath.Base(path), Text: string(data)}
+	if _, err := converter.NewTemplateConverter(options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// readClaudeConfig reads the CLAUDE.md of the first source directory that
+// has one, skipping archives and stdin
+func readClaudeConfig(cfg *config) (string, error) {
+	for _, sourcePath := range cfg.sourcePaths() {
+		if sourcePath == stdinSource || reader.IsArchive(sourcePath) {
+			continue
+		}
+		config, err := reader.NewScanner(sourcePath, nil).ScanClaudeConfig()
+		if err != nil || config != "" {
+			return config, err
+		}
+	}
+	return "", nil
+}
+
+// readStdinSession reads a single session's JSONL from stdin and returns it
+// in a project named after the session's working directory, so totals and
+// statistics treat it like a scanned session
+func readStdinSession(scanOpts *reader.ScanOptions) ([]*models.Project, error) {
+	session, err := reader.ReadSessionStream(os.Stdin, scanOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session from stdin: %w", err)
+	}
+	
+	project := models.NewProject("stdin")
+	for _, msg := range session.Messages {
+		if msg.CWD != "" {
+			project = models.NewProject(strings.ReplaceAll(msg.CWD, "/", "-"))
+			project.Path = msg.CWD
+			break
+		}
+	}
+	project.AddSession(session)
+	scanOpts.OnProject(project)
+	return []*models.Project{project}, nil
+}
+
+// writeStats writes the summary to the output file or stdout, as JSON with
+// the json format and as text otherwise
+func writeStats(summary *stats.Summary, cfg *config) error {
+	w := io.Writer(os.Stdout)
+	if cfg.outputPath != "" && cfg.outputPath != "-" {
+		file, err := os.Create(cfg.outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	
+	if cfg.format != "json" {
+		return summary.WriteText(w)
+	}
+	encoder := json.NewEncoder(w)
+	if cfg.prettyJSON {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(summary)
+}
+
+// printUsageWarnings prints a warning for each session whose token usage
+// looks inconsistent with its content
+func printUsageWarnings(w io.Writer, projects []*models.Project) {
+	for _, p := range projects {
+		for _, session := range p.Sessions {
+			for _, warning := range session.CheckUsage() {
+				fmt.Fprintf(w, "Warning: session %s: %s\n", session.ID, warning)
+			}
+		}
+	}
+}
+
+// printFileErrors prints the session files that were skipped because they
+// could not be read
+func printFileErrors(w io.Writer, fileErrors []reader.FileError) {
+	if len(fileErrors) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Skipped %d unreadable session files:\n", len(fileErrors))
+	for _, fileError := range fileErrors {
+		fmt.Fprintf(w, "  %s\n", fileError.Error())
+	}
+}
+
+// printTagTotals prints one line of totals per tag
+func printTagTotals(w io.Writer, stats []*models.TagStats) {
+	for _, s := range stats {
+		fmt.Fprintf(w, "[%s] Projects: %d | Sessions: %d | Messages: %d | Input tokens: %d | Output tokens: %d | Estimated cost: $%.4f\n",
+			s.Tag, s.Projects, s.Sessions, s.Messages, s.Usage.InputTokens, s.Usage.OutputTokens, s.Cost)
+	}
+}
+
+func singleExport(ctx context.Context, exp *exporter.FileExporter, projects []*models.Project, cfg *config, events *eventEmitter) error {
 	isStdout := cfg.outputPath == "" || cfg.outputPath == "-"
 	
 	if cfg.verbose && !isStdout {
@@ -299,16 +1217,38 @@ func singleExport(exp *exporter.FileExporter, projects []*models.Project, cfg *c
 	
 	// Export based on number of projects
 	var err error
-	if len(projects) == 1 {
-		err = exp.ExportToFile(cfg.outputPath, projects[0], exporter.ExportTypeProject)
+	if cfg.searchOutput {
+		err = exp.ExportToFileContext(ctx, cfg.outputPath, converter.Search(projects, cfg.search, cfg.contextCount), exporter.ExportTypeSearch)
+	} else if cfg.dailyUsage {
+		err = exp.ExportToFileContext(ctx, cfg.outputPath, models.GetDailyUsage(projects), exporter.ExportTypeDaily)
+	} else if cfg.indexOnly {
+		err = exp.ExportToFileContext(ctx, cfg.outputPath, converter.BuildIndex(projects, nil, cfg.titleLength), exporter.ExportTypeIndex)
+	} else if cfg.promptsOnly {
+		err = exp.ExportToFileContext(ctx, cfg.outputPath, converter.ExtractPrompts(projects), exporter.ExportTypePrompts)
+	} else if cfg.sessionID != "" {
+		session, findErr := models.FindSession(projects, cfg.sessionID)
+		if findErr != nil {
+			return findErr
+		}
+		err = exp.ExportToFileContext(ctx, cfg.outputPath, session, exporter.ExportTypeSession)
+	} else if cfg.sourcePath == stdinSource {
+		err = exp.ExportToFileContext(ctx, cfg.outputPath, projects[0].Sessions[0], exporter.ExportTypeSession)
+	} else if len(projects) == 1 {
+		err = exp.ExportToFileContext(ctx, cfg.outputPath, projects[0], exporter.ExportTypeProject)
 	} else {
-		err = exp.ExportToFile(cfg.outputPath, projects, exporter.ExportTypeProjects)
+		err = exp.ExportToFileContext(ctx, cfg.outputPath, projects, exporter.ExportTypeProjects)
 	}
 	
 	if err != nil {
 		return fmt.Errorf("export failed: %w", err)
 	}
 	
+	file := cfg.outputPath
+	if isStdout {
+		file = "-"
+	}
+	events.emit(event{Event: eventExportWritten, File: file})
+	
 	// Only print success message to stderr when outputting to stdout
 	if isStdout {
 		if cfg.verbose {
@@ -320,34 +1260,165 @@ func singleExport(exp *exporter.FileExporter, projects []*models.Project, cfg *c
 	return nil
 }
 
-func batchExport(exp *exporter.FileExporter, projects []*models.Project, cfg *config) error {
+// formatExtension returns the file extension of an export format
+func formatExtension(format string) string {
+	switch format {
+	case "json":
+		return ".json"
+	case "html":
+		return ".html"
+	case "csv":
+		return ".csv"
+	case "yaml":
+		return ".yaml"
+	case "text":
+		return ".txt"
+	case "jsonl":
+		return ".jsonl"
+	case "template":
+		return ".txt"
+	default:
+		return ".md"
+	}
+}
+
+// tarExport batch exports the projects to the tar archive named by --output,
+// with a file index entry. An unfinished archive is removed.
+func tarExport(ctx context.Context, exp *exporter.FileExporter, projects []*models.Project, cfg *config, gzipped bool, events *eventEmitter) error {
+	if dir := filepath.Dir(cfg.outputPath); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	
+	tarExp := exporter.NewTarExporter(exp, "project_%s"+formatExtension(cfg.format))
+	tarExp.Gzip = gzipped
+	tarExp.Concurrency = cfg.concurrency
+	tarExp.DatePrefix = cfg.datePrefix
+	tarExp.PathStrategy = exporter.PathStrategy(cfg.pathStrategy)
+	if cfg.showProgress() {
+		tarExp.Progress = newProgressLine(os.Stderr, "Exporting").update
+	}
+	
+	if cfg.verbose {
+		fmt.Printf("Batch exporting %d projects to %s...\n", len(projects), cfg.outputPath)
+	}
+	
+	file, err := os.Create(cfg.outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	result, err := tarExp.ExportProjectsContext(ctx, file, projects)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(cfg.outputPath)
+		return fmt.Errorf("batch export failed: %w", err)
+	}
+	events.emit(event{Event: eventExportWritten, File: cfg.outputPath})
+	
+	fmt.Println(result.Summary())
+	
+	if result.HasErrors() {
+		fmt.Fprintf(os.Stderr, "\nErrors occurred:\n")
+		for _, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  - %s: %s\n", e.Item, e.Error)
+		}
+	}
+	
+	if cfg.verbose && len(result.Files) > 0 {
+		fmt.Printf("\nArchive entries in %s:\n", cfg.outputPath)
+		for _, f := range append(result.Files, result.IndexFile) {
+			fmt.Printf("  - %s\n", f)
+		}
+	}
+	
+	return nil
+}
+
+// formatOutputPath derives the output file of one of several formats from
+// the --output path, replacing the extension of a known format, so that
+// output.json exports json and markdown to output.json and output.md
+func formatOutputPath(outputPath, format string) string {
+	if _, ok := exporter.DetectFormat(outputPath); ok {
+		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	}
+	return outputPath + formatExtension(format)
+}
+
+func batchExport(ctx context.Context, exp *exporter.FileExporter, projects []*models.Project, cfg *config, events *eventEmitter) error {
+	if isTar, gzipped := exporter.TarArchiveType(cfg.outputPath); isTar {
+		return tarExport(ctx, exp, projects, cfg, gzipped, events)
+	}
+	
 	// Ensure output directory exists
 	if err := os.MkdirAll(cfg.outputPath, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 	
-	// Determine file extension
-	ext := ".md"
-	if cfg.format == "json" {
-		ext = ".json"
-	} else if cfg.format == "html" {
-		ext = ".html"
-	}
+	ext := formatExtension(cfg.format)
 	
 	// Create batch exporter
+	granularity := exporter.Granularity(cfg.granularity)
 	nameFormat := "project_%s" + ext
+	if granularity == exporter.GranularitySession {
+		nameFormat = "%s" + ext
+	}
 	batchExp := exporter.NewBatchExporter(exp, cfg.outputPath, nameFormat)
+	batchExp.Concurrency = cfg.concurrency
+	batchExp.DatePrefix = cfg.datePrefix
+	batchExp.TitleLength = cfg.titleLength
+	batchExp.Granularity = granularity
+	batchExp.PathStrategy = exporter.PathStrategy(cfg.pathStrategy)
+	batchExp.WriteIndex = cfg.fileIndex
+	if cfg.showProgress() {
+		batchExp.Progress = newProgressLine(os.Stderr, "Exporting").update
+	}
 	
 	if cfg.verbose {
 		fmt.Printf("Batch exporting %d projects to %s...\n", len(projects), cfg.outputPath)
 	}
 	
-	// Export projects
-	result, err := batchExp.ExportProjects(projects)
+	// Export projects, or each of their sessions
+	var result *exporter.BatchExportResult
+	var err error
+	if granularity == exporter.GranularitySession {
+		result, err = batchExp.ExportProjectSessionsContext(ctx, projects)
+	} else if cfg.incremental {
+		result, err = batchExp.ExportProjectsIncrementalContext(ctx, projects, filepath.Join(cfg.outputPath, exporter.DefaultManifestName))
+	} else {
+		result, err = batchExp.ExportProjectsContext(ctx, projects)
+	}
 	if err != nil {
+		// Report what was written before cancellation; unfinished files
+		// have been removed
+		if result != nil && ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "Cancelled: %s\n", result.Summary())
+			for _, f := range result.Files {
+				events.emit(event{Event: eventExportWritten, File: f})
+			}
+		}
 		return fmt.Errorf("batch export failed: %w", err)
 	}
 	
+	if result.IndexFile != "" {
+		result.Files = append(result.Files, result.IndexFile)
+	}
+	
+	// Write session index linking to the exported files
+	if cfg.indexOnly {
+		indexFile, err := batchExp.ExportIndex(projects, "index"+ext)
+		if err != nil {
+			return fmt.Errorf("failed to write index: %w", err)
+		}
+		result.Files = append(result.Files, indexFile)
+	}
+	
+	for _, f := range result.Files {
+		events.emit(event{Event: eventExportWritten, File: f})
+	}
+	
 	// Print results
 	fmt.Println(result.Summary())
 	
@@ -365,5 +1436,12 @@ func batchExport(exp *exporter.FileExporter, projects []*models.Project, cfg *co
 		}
 	}
 	
+	if cfg.verbose && len(result.Skipped) > 0 {
+		fmt.Println("\nUnchanged files:")
+		for _, f := range result.Skipped {
+			fmt.Printf("  - %s\n", f)
+		}
+	}
+	
 	return nil
 }
\ No newline at end of file
@@ -1,10 +1,23 @@
 package main
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/converter"
+	"github.com/eternnoir/cc-history-export/internal/models"
+	"github.com/eternnoir/cc-history-export/internal/reader"
+	"github.com/eternnoir/cc-history-export/internal/stats"
 )
 
 func TestCLIIntegration(t *testing.T) {
@@ -39,7 +52,7 @@ func TestCLIIntegration(t *testing.T) {
 	}
 	
 	// Run the export
-	if err := run(cfg); err != nil {
+	if err := run(context.Background(), cfg); err != nil {
 		t.Fatalf("run() error = %v", err)
 	}
 	
@@ -52,7 +65,7 @@ func TestCLIIntegration(t *testing.T) {
 	cfg.outputPath = filepath.Join(tmpDir, "export.md")
 	cfg.format = "markdown"
 	
-	if err := run(cfg); err != nil {
+	if err := run(context.Background(), cfg); err != nil {
 		t.Fatalf("run() with markdown format error = %v", err)
 	}
 	
@@ -60,6 +73,136 @@ func TestCLIIntegration(t *testing.T) {
 	if _, err := os.Stat(cfg.outputPath); os.IsNotExist(err) {
 		t.Error("Expected markdown file does not exist")
 	}
+	
+	// Test exporting a single session by ID
+	cfg.outputPath = filepath.Join(tmpDir, "session.md")
+	cfg.sessionID = "session1"
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() with --session error = %v", err)
+	}
+	data, err := os.ReadFile(cfg.outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read session export: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Session: ") {
+		t.Errorf("Expected a session export, got:\n%s", data)
+	}
+	cfg.sessionID = "missing"
+	if err := run(context.Background(), cfg); err == nil {
+		t.Error("run() should error for an unknown --session")
+	}
+	cfg.sessionID = ""
+	
+	// Several formats are written next to the output base
+	cfg.outputPath = filepath.Join(tmpDir, "multi.json")
+	cfg.format = "json, markdown"
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig() error for multiple formats = %v", err)
+	}
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() with multiple formats error = %v", err)
+	}
+	for _, name := range []string{"multi.json", "multi.md"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); err != nil {
+			t.Errorf("Expected %s to be written: %v", name, err)
+		}
+	}
+}
+
+func TestStdoutDefaultOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello from stdout"}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "session1.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	// Capture stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		buf.ReadFrom(r)
+		output <- buf.String()
+	}()
+
+	// No output path: the rendered result goes to stdout
+	cfg := &config{
+		sourcePath: claudeDir,
+		format:     "markdown",
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig() error without output = %v", err)
+	}
+	runErr := run(context.Background(), cfg)
+	w.Close()
+	os.Stdout = oldStdout
+	if runErr != nil {
+		t.Fatalf("run() error = %v", runErr)
+	}
+
+	got := <-output
+	if !strings.Contains(got, "# Project: project") || !strings.Contains(got, "Hello from stdout") {
+		t.Errorf("stdout missing rendered export, got %q", got)
+	}
+}
+
+func TestStdinSource(t *testing.T) {
+	sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","cwd":"/Users/test/piped","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello from a pipe"}}`
+	
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	oldStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
+	go func() {
+		w.WriteString(sessionContent)
+		w.Close()
+	}()
+	
+	outputPath := filepath.Join(t.TempDir(), "session.md")
+	cfg := &config{
+		sourcePath: "-",
+		outputPath: outputPath,
+		format:     "markdown",
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig() error for stdin source = %v", err)
+	}
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Session: session1") || !strings.Contains(string(data), "Hello from a pipe") {
+		t.Errorf("Expected a session export, got:\n%s", data)
+	}
+	
+	// Stdin holds a single session
+	cfg.batchExport = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for stdin source with --batch")
+	}
+	cfg.batchExport = false
+	cfg.sourcePath = "-," + t.TempDir()
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for stdin combined with other sources")
+	}
 }
 
 func TestValidateConfig(t *testing.T) {
@@ -68,8 +211,8 @@ func TestValidateConfig(t *testing.T) {
 		sourcePath: "/tmp/.claude",
 		outputPath: "/tmp/output.json",
 		format:     "json",
-		startDate:  "2024-01-01",
-		endDate:    "2024-12-31",
+		startTime:  "2024-01-01",
+		endTime:    "2024-12-31",
 	}
 	
 	// Create source directory for validation
@@ -80,25 +223,255 @@ func TestValidateConfig(t *testing.T) {
 		t.Errorf("validateConfig() error for valid config = %v", err)
 	}
 	
-	// Test missing output path
+	// Test batch export without output path
 	cfg.outputPath = ""
+	cfg.batchExport = true
 	if err := validateConfig(cfg); err == nil {
-		t.Error("validateConfig() should error for missing output path")
+		t.Error("validateConfig() should error for batch export without output path")
 	}
+	cfg.batchExport = false
 	
 	// Test invalid date format
 	cfg.outputPath = "/tmp/output.json"
-	cfg.startDate = "01-01-2024"
+	cfg.startTime = "01-01-2024"
 	if err := validateConfig(cfg); err == nil {
 		t.Error("validateConfig() should error for invalid date format")
 	}
 	
 	// Test unsupported format
-	cfg.startDate = "2024-01-01"
+	cfg.startTime = "2024-01-01"
 	cfg.format = "xml"
 	if err := validateConfig(cfg); err == nil {
 		t.Error("validateConfig() should error for unsupported format")
 	}
+	
+	// CSV exports session statistics or daily usage
+	cfg.format = "csv"
+	cfg.dailyUsage = true
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for csv format = %v", err)
+	}
+	cfg.indexOnly = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for csv with --index")
+	}
+	cfg.indexOnly = false
+	cfg.format = "markdown"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --daily without csv format")
+	}
+	cfg.dailyUsage = false
+	
+	// --search-trim only applies to a search
+	cfg.searchTrim = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --search-trim without --search")
+	}
+	cfg.searchTrim = false
+	
+	// Test unsupported user content mode
+	cfg.format = "markdown"
+	cfg.userContent = "html"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for unsupported user content mode")
+	}
+	cfg.userContent = ""
+	
+	// --since takes a relative duration and replaces --start-time
+	cfg.since = "7d"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --since with --start-time")
+	}
+	cfg.startTime = ""
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for --since = %v", err)
+	}
+	cfg.since = "7 days"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for an invalid --since duration")
+	}
+	cfg.since = ""
+	
+	// Project path regexes are compiled before scanning
+	cfg.projectRegex = "^/Users/me/work/"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for --projects-regex = %v", err)
+	}
+	cfg.projectRegex = "^/Users/me/(work"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for an invalid --projects-regex")
+	}
+	cfg.projectRegex = ""
+	
+	// Date-partitioned files are only written by batch exports
+	cfg.pathStrategy = "date"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --path-strategy date without --batch")
+	}
+	cfg.pathStrategy = "year"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for an unsupported path strategy")
+	}
+	cfg.pathStrategy = ""
+	
+	// Watching writes a file per new session of a single directory
+	cfg.watch = true
+	cfg.watchPeriod = time.Second
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --watch without --batch")
+	}
+	cfg.batchExport = true
+	cfg.granularity = "session"
+	cfg.outputPath = "/tmp/output"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for --watch = %v", err)
+	}
+	cfg.sourcePath = "/tmp/.claude,/tmp/.claude"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --watch with several sources")
+	}
+	cfg.sourcePath = "/tmp/.claude"
+	cfg.watchPeriod = 0
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for a zero --watch-interval")
+	}
+	cfg.watch = false
+	cfg.batchExport = false
+	cfg.granularity = ""
+	cfg.outputPath = ""
+	
+	// Warnings are either silenced or shown with the verbose details
+	cfg.quiet = true
+	cfg.verbose = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --quiet with --verbose")
+	}
+	cfg.verbose = false
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for --quiet = %v", err)
+	}
+	cfg.quiet = false
+	
+	// Source paths would survive anonymization
+	cfg.includeSource = true
+	cfg.anonymize = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --include-source with --anonymize")
+	}
+	cfg.anonymize = false
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for --include-source = %v", err)
+	}
+	cfg.includeSource = false
+	
+	// Times use a preset or a Go layout, instead of offsets
+	cfg.timeFormat = "unix"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for --time-format unix = %v", err)
+	}
+	cfg.relativeTimes = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --time-format with --relative-times")
+	}
+	cfg.relativeTimes = false
+	cfg.timeFormat = "YYYY-MM-DD"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for a time format without layout elements")
+	}
+	cfg.timeFormat = ""
+	
+	// Prompts are a single Markdown, JSON or YAML document
+	cfg.promptsOnly = true
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for --prompts-only = %v", err)
+	}
+	cfg.format = "html"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --prompts-only in html format")
+	}
+	cfg.format = "markdown"
+	cfg.indexOnly = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --prompts-only with --index")
+	}
+	cfg.indexOnly = false
+	cfg.promptsOnly = false
+	
+	// A batch export to an archive writes one entry per project
+	cfg.batchExport = true
+	cfg.outputPath = "/tmp/export.tar.gz"
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error for --batch to a tar archive = %v", err)
+	}
+	cfg.granularity = "session"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --batch --granularity session to a tar archive")
+	}
+	cfg.granularity = ""
+	cfg.incremental = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --incremental to a tar archive")
+	}
+	cfg.incremental = false
+	cfg.format = "json,markdown"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for multiple formats to a tar archive")
+	}
+	cfg.format = "markdown"
+	cfg.outputPath = "/tmp/output.json"
+	cfg.batchExport = false
+	
+	// Each of several formats is validated, and they need an output file
+	cfg.format = "json,xml"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for an unsupported format in a list")
+	}
+	cfg.format = "json,markdown"
+	cfg.outputPath = "-"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for multiple formats on stdout")
+	}
+	cfg.outputPath = "/tmp/output.json"
+	cfg.format = "json,json"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for a repeated format")
+	}
+	cfg.format = "text,template"
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), ".txt") {
+		t.Errorf("validateConfig() error = %v, want formats writing .txt files rejected", err)
+	}
+}
+
+func TestParseRelativeTime(t *testing.T) {
+	now := time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"7d", time.Date(2024, 7, 8, 12, 0, 0, 0, time.UTC), false},
+		{"24h", time.Date(2024, 7, 14, 12, 0, 0, 0, time.UTC), false},
+		{"30m", time.Date(2024, 7, 15, 11, 30, 0, 0, time.UTC), false},
+		{"2d3h", time.Date(2024, 7, 13, 9, 0, 0, 0, time.UTC), false},
+		{"2024-07-01", time.Time{}, true},
+		{"7", time.Time{}, true},
+	}
+	
+	for _, tt := range tests {
+		got, err := parseRelativeTime(tt.input, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRelativeTime(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !got.Equal(tt.want) {
+			t.Errorf("parseRelativeTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+	
+	// --start-time accepts durations as well as dates
+	if got, err := parseDateTime("2d3h"); err != nil || time.Since(got) < 51*time.Hour {
+		t.Errorf("parseDateTime(2d3h) = %v, %v, want about 51 hours ago", got, err)
+	}
 }
 
 func TestParseFlags(t *testing.T) {
@@ -135,4 +508,533 @@ func TestParseFlags(t *testing.T) {
 	if len(cfg.projectPaths) != 2 {
 		t.Errorf("projectPaths length = %v, want 2", len(cfg.projectPaths))
 	}
-}
\ No newline at end of file
+	
+	// Format is inferred from the output filename
+	os.Args = []string{"cc-export", "--output", "x.json"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	if cfg := parseFlags(); cfg.format != "json" {
+		t.Errorf("format = %v, want json inferred from x.json", cfg.format)
+	}
+	
+	// An explicit format wins
+	os.Args = []string{"cc-export", "--output", "x.json", "--format", "markdown"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	if cfg := parseFlags(); cfg.format != "markdown" {
+		t.Errorf("format = %v, want explicit markdown", cfg.format)
+	}
+	
+	// --template selects the template format over the output filename
+	os.Args = []string{"cc-export", "--output", "x.json", "--template", "report.tmpl"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	if cfg := parseFlags(); cfg.format != "template" {
+		t.Errorf("format = %v, want template implied by --template", cfg.format)
+	}
+	
+	// Empty entries of comma-separated lists are dropped
+	os.Args = []string{"cc-export", "--exclude-projects", "scratch,", "--models", " , opus", "--format", "json,"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	cfg = parseFlags()
+	if len(cfg.excludePaths) != 1 || cfg.excludePaths[0] != "scratch" {
+		t.Errorf("excludePaths = %q, want [scratch]", cfg.excludePaths)
+	}
+	if len(cfg.models) != 1 || cfg.models[0] != "opus" {
+		t.Errorf("models = %q, want [opus]", cfg.models)
+	}
+	if cfg.format != "json" {
+		t.Errorf("format = %q, want json", cfg.format)
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldPath := filepath.Join(tmpDir, "old.json")
+	newPath := filepath.Join(tmpDir, "new.json")
+	if err := os.WriteFile(oldPath, []byte(`{"projects":[{"id":"-p","path":"/p","sessions":[{"id":"s1","message_count":1,"messages":[{"uuid":"a"}]}]}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+	if err := os.WriteFile(newPath, []byte(`{"projects":[{"id":"-p","path":"/p","sessions":[{"id":"s1","message_count":2,"messages":[{"uuid":"a"},{"uuid":"b"}]}]}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write export: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runDiff([]string{oldPath, newPath}, &out); err != nil {
+		t.Fatalf("runDiff() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "~ /p s1 (1 -> 2 messages, 1 added, 0 removed)") {
+		t.Errorf("runDiff() summary = %q", out.String())
+	}
+
+	out.Reset()
+	if err := runDiff([]string{"--json", oldPath, newPath}, &out); err != nil {
+		t.Fatalf("runDiff() error = %v", err)
+	}
+	var diff converter.ExportDiff
+	if err := json.Unmarshal(out.Bytes(), &diff); err != nil {
+		t.Fatalf("runDiff() wrote invalid JSON: %v", err)
+	}
+	if len(diff.ChangedSessions) != 1 || diff.MessageDelta != 1 {
+		t.Errorf("runDiff() JSON = %+v, want one changed session", diff)
+	}
+
+	if err := runDiff([]string{oldPath}, io.Discard); err == nil {
+		t.Error("runDiff() accepted a single export")
+	}
+}
+
+func TestRunList(t *testing.T) {
+	claudeDir := filepath.Join(t.TempDir(), ".claude")
+	sessions := map[string]string{
+		"-Users-test-alpha": `{"uuid":"a1","sessionId":"alpha-1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Fix the parser"}}
+{"uuid":"a2","parentUuid":"a1","sessionId":"alpha-1","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"Done."}],"usage":{"input_tokens":10,"output_tokens":20}}}`,
+		"-Users-test-beta": `{"uuid":"b1","sessionId":"beta-1","type":"user","userType":"external","timestamp":"2024-02-01T10:00:00Z","message":{"role":"user","content":"Write the README"}}`,
+	}
+	for name, content := range sessions {
+		projectDir := filepath.Join(claudeDir, "projects", name)
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			t.Fatalf("Failed to create project dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create session file: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runList([]string{"--source", claudeDir}, &out); err != nil {
+		t.Fatalf("runList() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "PROJECT") {
+		t.Fatalf("runList() = %q, want a header and 2 projects", out.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "/Users/test/alpha 1 2 2024-01-01 2024-01-01 30" {
+		t.Errorf("Project row = %q", lines[1])
+	}
+
+	out.Reset()
+	if err := runList([]string{"--source", claudeDir, "--format", "json"}, &out); err != nil {
+		t.Fatalf("runList() error = %v", err)
+	}
+	var projects []listProject
+	if err := json.Unmarshal(out.Bytes(), &projects); err != nil {
+		t.Fatalf("runList() wrote invalid JSON: %v", err)
+	}
+	if len(projects) != 2 || projects[1].Path != "/Users/test/beta" || projects[1].StartTime != "2024-02-01T10:00:00Z" {
+		t.Errorf("runList() JSON = %+v", projects)
+	}
+
+	// Drilling into a project lists its sessions
+	out.Reset()
+	if err := runList([]string{"--source", claudeDir, "--project", "alpha", "--format", "json"}, &out); err != nil {
+		t.Fatalf("runList() error = %v", err)
+	}
+	var list []listSession
+	if err := json.Unmarshal(out.Bytes(), &list); err != nil {
+		t.Fatalf("runList() wrote invalid JSON: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != "alpha-1" || list[0].Title != "Fix the parser" || list[0].TotalTokens != 30 {
+		t.Errorf("runList() sessions = %+v", list)
+	}
+	out.Reset()
+	if err := runList([]string{"--source", claudeDir, "--project", "alpha"}, &out); err != nil {
+		t.Fatalf("runList() error = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "/Users/test/alpha\nSESSION") || !strings.Contains(out.String(), "Fix the parser") {
+		t.Errorf("runList() sessions table = %q", out.String())
+	}
+
+	if err := runList([]string{"--source", claudeDir, "--project", "gamma"}, io.Discard); err == nil {
+		t.Error("runList() should error for a project that does not exist")
+	}
+	if err := runList([]string{"--source", claudeDir, "--format", "csv"}, io.Discard); err == nil {
+		t.Error("runList() should error for an unsupported format")
+	}
+
+	// Without a home directory there is no default source
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+	t.Setenv("HOME", "")
+	if err := runList(nil, io.Discard); err == nil {
+		t.Error("runList() should error without a source directory")
+	}
+}
+
+func TestPrintTotals(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+
+	sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"uuid":"msg2","parentUuid":"msg1","sessionId":"session1","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"id":"asst1","type":"message","role":"assistant","model":"claude-3-opus-20240229","content":[{"type":"text","text":"Hi there!"}],"usage":{"input_tokens":1000,"output_tokens":2000,"cache_read_input_tokens":300}}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "session1.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	projects, err := reader.NewScanner(claudeDir, nil).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	printTotals(&buf, projects)
+
+	// 1000*15 + 2000*75 + 300*1.5 per million
+	want := "Messages: 2 | Input tokens: 1000 | Output tokens: 2000 | Cache read tokens: 300 | Cache write tokens: 0 | Estimated cost: $0.1655\n"
+	if buf.String() != want {
+		t.Errorf("printTotals() = %q, want %q", buf.String(), want)
+	}
+
+	// Stale projects are listed when paths were checked
+	projects[0].CheckPathExists()
+	buf.Reset()
+	printTotals(&buf, projects)
+	if !strings.Contains(buf.String(), "Stale projects (directory not found): 1\n  /Users/test/project\n") {
+		t.Errorf("printTotals() missing stale project, got %q", buf.String())
+	}
+
+	// Totals roll up per tag
+	buf.Reset()
+	printTagTotals(&buf, models.GroupByTag(projects, map[string][]string{"/Users/test": {"work"}}))
+	wantTag := "[work] Projects: 1 | Sessions: 1 | Messages: 2 | Input tokens: 1000 | Output tokens: 2000 | Estimated cost: $0.1655\n"
+	if buf.String() != wantTag {
+		t.Errorf("printTagTotals() = %q, want %q", buf.String(), wantTag)
+	}
+
+	// Totals mode does not need an output path, even with --batch
+	cfg := &config{
+		sourcePath:  claudeDir,
+		format:      "markdown",
+		batchExport: true,
+		totals:      true,
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Errorf("validateConfig() error in totals mode = %v", err)
+	}
+}
+
+func TestStatsOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"uuid":"msg2","parentUuid":"msg1","sessionId":"session1","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"id":"asst1","type":"message","role":"assistant","model":"claude-3-opus-20240229","content":[{"type":"text","text":"Hi there!"}],"usage":{"input_tokens":1000,"output_tokens":2000}}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "session1.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	outputPath := filepath.Join(tmpDir, "stats.json")
+	cfg := &config{
+		sourcePath: claudeDir,
+		outputPath: outputPath,
+		format:     "json",
+		statsOnly:  true,
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig() error = %v", err)
+	}
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read stats: %v", err)
+	}
+	var summary stats.Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("Failed to parse stats JSON: %v", err)
+	}
+	if summary.Sessions != 1 || summary.Messages != 2 || summary.TotalTokens != 3000 || len(summary.Models) != 1 {
+		t.Errorf("Summary = %+v, want 1 session, 2 messages, 3000 tokens and 1 model", summary)
+	}
+	if summary.BusiestDay == nil || summary.BusiestDay.Date != "2024-01-01" {
+		t.Errorf("BusiestDay = %+v, want 2024-01-01", summary.BusiestDay)
+	}
+
+	// Text output, e.g. inferred from a .txt file name
+	cfg.format = "text"
+	cfg.outputPath = filepath.Join(tmpDir, "stats.txt")
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig() error for text = %v", err)
+	}
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	data, err = os.ReadFile(cfg.outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read stats: %v", err)
+	}
+	if !strings.Contains(string(data), "Busiest day: 2024-01-01 (2 messages in 1 sessions)") {
+		t.Errorf("Text stats missing busiest day, got %q", data)
+	}
+
+	// Statistics are a single summary
+	cfg.format = "csv"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --stats-only with csv")
+	}
+	cfg.format = "json"
+	cfg.batchExport = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --stats-only with --batch")
+	}
+	cfg.batchExport = false
+	cfg.statsOnly = false
+	cfg.format = "text"
+	cfg.indexOnly = true
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for text format with --index")
+	}
+}
+
+func TestFindSourcePath(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("CLAUDE_CONFIG_DIR", configDir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	path, origin := findSourcePath()
+	if path != configDir || origin != "CLAUDE_CONFIG_DIR" {
+		t.Errorf("findSourcePath() = (%s, %s), want (%s, CLAUDE_CONFIG_DIR)", path, origin, configDir)
+	}
+
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+	home, _ := os.UserHomeDir()
+	path, _ = findSourcePath()
+	if path != filepath.Join(home, ".claude") {
+		t.Errorf("findSourcePath() = %s, want home default", path)
+	}
+}
+
+func TestEventsJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	for _, name := range []string{"-Users-test-alpha", "-Users-test-beta"} {
+		projectDir := filepath.Join(claudeDir, "projects", name)
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			t.Fatalf("Failed to create test directories: %v", err)
+		}
+		content := `{"uuid":"msg1","sessionId":"` + name + `","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}`
+		if err := os.WriteFile(filepath.Join(projectDir, "session.jsonl"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create session file: %v", err)
+		}
+	}
+	// A malformed line is reported as a warning event, not as text
+	malformed := filepath.Join(claudeDir, "projects", "-Users-test-alpha", "session.jsonl")
+	if err := os.WriteFile(malformed, []byte("not json\n{\"uuid\":\"msg1\",\"sessionId\":\"alpha\",\"type\":\"user\",\"userType\":\"external\",\"timestamp\":\"2024-01-01T10:00:00Z\",\"message\":{\"role\":\"user\",\"content\":\"Hello\"}}"), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	oldOutput := eventOutput
+	eventOutput = &buf
+	defer func() { eventOutput = oldOutput }()
+
+	cfg := &config{
+		sourcePath:  claudeDir,
+		outputPath:  filepath.Join(tmpDir, "exports"),
+		format:      "markdown",
+		batchExport: true,
+		eventsJSON:  true,
+	}
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	var types []string
+	var last event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		last = event{}
+		if err := json.Unmarshal([]byte(line), &last); err != nil {
+			t.Fatalf("Invalid event line %q: %v", line, err)
+		}
+		if last.Event == eventWarning && !strings.Contains(last.Message, "malformed JSON") {
+			t.Errorf("warning event = %+v, want the malformed line", last)
+		}
+		types = append(types, last.Event)
+	}
+
+	want := []string{eventScanStarted, eventWarning, eventProjectScanned, eventProjectScanned, eventExportWritten, eventExportWritten, eventDone}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("Event types = %v, want %v", types, want)
+	}
+	if last.Files != 2 || last.Error != "" {
+		t.Errorf("done event = %+v, want 2 files and no error", last)
+	}
+}
+
+func TestFormatOutputPath(t *testing.T) {
+	tests := []struct {
+		outputPath, format, want string
+	}{
+		{"export", "json", "export.json"},
+		{"export.json", "markdown", "export.md"},
+		{"out/export.md", "text", "out/export.txt"},
+		{"export.v2", "html", "export.v2.html"},
+	}
+	for _, tt := range tests {
+		if got := formatOutputPath(tt.outputPath, tt.format); got != tt.want {
+			t.Errorf("formatOutputPath(%q, %q) = %q, want %q", tt.outputPath, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	var buf bytes.Buffer
+	line := newProgressLine(&buf, "Scanning")
+	line.update(1, 4, "alpha")
+	line.update(4, 4, "exports/beta.md")
+	want := "\rScanning [1/4]  25% alpha\033[K\rScanning [4/4] 100% beta.md\033[K\n"
+	if buf.String() != want {
+		t.Errorf("progress output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestAnonymizeExport(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","cwd":"/Users/test/project","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Open /Users/test/project/acme-corp.txt"}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "session1.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+	rulesFile := filepath.Join(tmpDir, "rules.json")
+	if err := os.WriteFile(rulesFile, []byte(`{"acme-corp": "<COMPANY>"}`), 0644); err != nil {
+		t.Fatalf("Failed to create rules file: %v", err)
+	}
+	t.Setenv("HOME", "/Users/test")
+
+	cfg := &config{
+		sourcePath:     claudeDir,
+		outputPath:     filepath.Join(tmpDir, "export.md"),
+		format:         "markdown",
+		anonymizeRules: rulesFile,
+	}
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	data, err := os.ReadFile(cfg.outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if strings.Contains(string(data), "/Users/test") || strings.Contains(string(data), "acme-corp") {
+		t.Errorf("Export not anonymized:\n%s", data)
+	}
+	if !strings.Contains(string(data), "Open <HOME>/project/<COMPANY>.txt") {
+		t.Errorf("Expected placeholders in export, got:\n%s", data)
+	}
+}
+
+func TestTemplateExport(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","cwd":"/Users/test/project","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "session1.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+	templateFile := filepath.Join(tmpDir, "report.tmpl")
+	if err := os.WriteFile(templateFile, []byte(`{{.GetProjectName}}: {{range .Sessions}}{{range .Messages}}{{text .}}{{end}}{{end}}`), 0644); err != nil {
+		t.Fatalf("Failed to create template file: %v", err)
+	}
+
+	cfg := &config{
+		sourcePath:   claudeDir,
+		outputPath:   filepath.Join(tmpDir, "report.txt"),
+		format:       "template",
+		templateFile: templateFile,
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig() error = %v", err)
+	}
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	data, err := os.ReadFile(cfg.outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if string(data) != "project: Hello" {
+		t.Errorf("Template export = %q, want %q", data, "project: Hello")
+	}
+
+	// Parse errors are reported before scanning, with the line
+	if err := os.WriteFile(templateFile, []byte("ok\n{{range .}}"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+	if err := validateConfig(cfg); err == nil || !strings.Contains(err.Error(), "report.tmpl:2") {
+		t.Errorf("validateConfig() error = %v, want a parse error at report.tmpl:2", err)
+	}
+
+	// --template needs the template format, and the format needs a template
+	cfg.format = "markdown"
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for --template with --format markdown")
+	}
+	cfg.format = "template"
+	cfg.templateFile = ""
+	if err := validateConfig(cfg); err == nil {
+		t.Error("validateConfig() should error for the template format without --template")
+	}
+}
+
+func TestBatchExportToTar(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	for _, name := range []string{"-Users-test-alpha", "-Users-test-beta"} {
+		projectDir := filepath.Join(claudeDir, "projects", name)
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			t.Fatalf("Failed to create test directories: %v", err)
+		}
+		sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}`
+		if err := os.WriteFile(filepath.Join(projectDir, "session1.jsonl"), []byte(sessionContent), 0644); err != nil {
+			t.Fatalf("Failed to create session file: %v", err)
+		}
+	}
+
+	cfg := &config{
+		sourcePath:  claudeDir,
+		outputPath:  filepath.Join(tmpDir, "out", "export.tar"),
+		format:      "markdown",
+		batchExport: true,
+	}
+	if err := validateConfig(cfg); err != nil {
+		t.Fatalf("validateConfig() error = %v", err)
+	}
+	if err := run(context.Background(), cfg); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	file, err := os.Open(cfg.outputPath)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+	var names []string
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	want := []string{"index.json", "project_alpha.md", "project_beta.md"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Archive entries = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// progressLine redraws a single "label [current/total] percent name" line,
+// ending it once the last item is done
+type progressLine struct {
+	w     io.Writer
+	label string
+}
+
+// newProgressLine creates a progress line written to w
+func newProgressLine(w io.Writer, label string) *progressLine {
+	return &progressLine{w: w, label: label}
+}
+
+// update redraws the line for the current item, clearing what is left of the
+// previous one
+func (p *progressLine) update(current, total int, name string) {
+	percent := 100
+	if total > 0 {
+		percent = current * 100 / total
+	}
+	fmt.Fprintf(p.w, "\r%s [%d/%d] %3d%% %s\033[K", p.label, current, total, percent, filepath.Base(name))
+	if current >= total {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
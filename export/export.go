@@ -0,0 +1,242 @@
+// Package export scans Claude Code history and exports it in the formats of
+// the cc-export command. It is the stable entry point for Go programs that
+// embed the exporter; its types are aliases of those of the internal
+// packages. The command itself is built on the internal packages, since it
+// needs options this package does not expose.
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/converter"
+	"github.com/eternnoir/cc-history-export/internal/exporter"
+	"github.com/eternnoir/cc-history-export/internal/models"
+	"github.com/eternnoir/cc-history-export/internal/reader"
+)
+
+// Project is a Claude Code project with its sessions
+type Project = models.Project
+
+// Session is a conversation session
+type Session = models.Session
+
+// Message is a single entry of a session
+type Message = models.Message
+
+// ScanOptions provides options for scanning a Claude directory
+type ScanOptions = reader.ScanOptions
+
+// ScanResult holds the scanned projects and the session files that could not
+// be read
+type ScanResult = reader.ScanResult
+
+// FileError is a session file that could not be read
+type FileError = reader.FileError
+
+// Filter is a parsed filter expression for ScanOptions.Filter
+type Filter = reader.Filter
+
+// Logger receives scan warnings (see ScanOptions.Logger)
+type Logger = reader.Logger
+
+// DiscardLogger silences scan warnings
+var DiscardLogger = reader.DiscardLogger
+
+// NewWriterLogger creates a logger that writes scan warnings to w
+func NewWriterLogger(w io.Writer) Logger {
+	return reader.NewWriterLogger(w)
+}
+
+// Format is an export format
+type Format = exporter.Format
+
+// Export formats
+const (
+	FormatJSON     = exporter.FormatJSON
+	FormatMarkdown = exporter.FormatMarkdown
+	FormatHTML     = exporter.FormatHTML
+	FormatCSV      = exporter.FormatCSV
+	FormatYAML     = exporter.FormatYAML
+	FormatText     = exporter.FormatText
+	FormatJSONL    = exporter.FormatJSONL
+	FormatTemplate = exporter.FormatTemplate
+)
+
+// Format-specific options passed to Export. YAML and JSONL use JSONOptions;
+// CSV has no options. FormatTemplate requires TemplateOptions.
+type (
+	JSONOptions     = converter.JSONOptions
+	MarkdownOptions = converter.MarkdownOptions
+	HTMLOptions     = converter.HTMLOptions
+	TextOptions     = converter.TextOptions
+	TemplateOptions = converter.TemplateOptions
+)
+
+// IndexEntry is one session of a session index (see BuildIndex)
+type IndexEntry = converter.IndexEntry
+
+// SearchResults holds the matching messages of a search (see Search)
+type SearchResults = converter.SearchResults
+
+// Prompt is a prompt typed by the user (see ExtractPrompts)
+type Prompt = converter.Prompt
+
+// DailyUsage is the token usage of one model on one day (see GetDailyUsage)
+type DailyUsage = models.DailyUsage
+
+// AnonymizeRule replaces text with a placeholder (see Anonymize)
+type AnonymizeRule = models.AnonymizeRule
+
+// ToolResult is the result of a tool call in a user message
+type ToolResult = models.ToolResult
+
+// ToolErrorDetector reports whether a tool result is an error (see
+// AddToolErrorDetector)
+type ToolErrorDetector = models.ToolErrorDetector
+
+// Scan scans a Claude directory, or a .tar.gz archive of one, and returns its
+// projects
+func Scan(sourcePath string, opts ScanOptions) ([]*Project, error) {
+	return ScanContext(context.Background(), sourcePath, opts)
+}
+
+// ScanContext is like Scan but stops scanning once ctx is done
+func ScanContext(ctx context.Context, sourcePath string, opts ScanOptions) ([]*Project, error) {
+	return ScanRootsContext(ctx, []string{sourcePath}, opts)
+}
+
+// ScanRootsContext scans several Claude directories or archives with the
+// same options and merges projects found in more than one of them
+func ScanRootsContext(ctx context.Context, sourcePaths []string, opts ScanOptions) ([]*Project, error) {
+	return reader.ScanRootsContext(ctx, sourcePaths, &opts)
+}
+
+// ScanRootsDetailedContext is like ScanRootsContext but also reports the
+// session files that could not be read
+func ScanRootsDetailedContext(ctx context.Context, sourcePaths []string, opts ScanOptions) (*ScanResult, error) {
+	return reader.ScanRootsDetailedContext(ctx, sourcePaths, &opts)
+}
+
+// Watch re-scans a Claude directory every interval until ctx is done and
+// calls callback with each session that appears after the first scan.
+// Sessions that only grow are not reported again.
+func Watch(ctx context.Context, sourcePath string, opts ScanOptions, interval time.Duration, callback func(*Session)) error {
+	return reader.NewScanner(sourcePath, &opts).Watch(ctx, interval, callback)
+}
+
+// WatchFrom is like Watch but starts from seen, an earlier scan of the same
+// directory, and first reports the sessions created or changed since
+func WatchFrom(ctx context.Context, sourcePath string, opts ScanOptions, interval time.Duration, seen *ScanResult, callback func(*Session)) error {
+	return reader.NewScanner(sourcePath, &opts).WatchFrom(ctx, interval, seen, callback)
+}
+
+// ParseFilter parses a filter expression such as
+// "(project=/work/a OR project=/work/b) AND since=7d"
+func ParseFilter(expr string) (Filter, error) {
+	return reader.ParseFilter(expr)
+}
+
+// FindSession returns the session with the given ID across all projects, or
+// an error if none or more than one project has it
+func FindSession(projects []*Project, id string) (*Session, error) {
+	return models.FindSession(projects, id)
+}
+
+// BuildIndex returns one index entry per session of the projects, with titles
+// of at most titleLength characters
+func BuildIndex(projects []*Project, titleLength int) []*IndexEntry {
+	return converter.BuildIndex(projects, nil, titleLength)
+}
+
+// Search returns the messages of the projects that contain query, each with
+// up to contextCount messages before and after it
+func Search(projects []*Project, query string, contextCount int) *SearchResults {
+	return converter.Search(projects, query, contextCount)
+}
+
+// ExtractPrompts returns the prompts typed by the user across the projects,
+// without tool results or assistant output
+func ExtractPrompts(projects []*Project) []*Prompt {
+	return converter.ExtractPrompts(projects)
+}
+
+// GetDailyUsage returns the token usage of the projects per day and model
+func GetDailyUsage(projects []*Project) []*DailyUsage {
+	return models.GetDailyUsage(projects)
+}
+
+// NormalizeModel returns the family of a model name, e.g. claude-3.5-sonnet
+// for claude-3-5-sonnet-20241022
+func NormalizeModel(raw string) string {
+	return models.NormalizeModel(raw)
+}
+
+// AddToolErrorDetector adds a detector of tool errors used by
+// Session.HasToolErrors and ScanOptions.OnlyToolErrors
+func AddToolErrorDetector(detector ToolErrorDetector) {
+	models.AddToolErrorDetector(detector)
+}
+
+// DefaultAnonymizeRules returns the rules replacing homeDir with <HOME> and
+// username with <USER>
+func DefaultAnonymizeRules(homeDir, username string) []AnonymizeRule {
+	return models.DefaultAnonymizeRules(homeDir, username)
+}
+
+// Anonymize replaces the text matched by the rules in the paths and content
+// of the projects, in place
+func Anonymize(projects []*Project, rules []AnonymizeRule) {
+	models.Anonymize(projects, rules)
+}
+
+// Export writes data to w in the given format. Data is a *Session, a *Project
+// or a []*Project, which every format supports; the result of BuildIndex,
+// Search or ExtractPrompts, which FormatJSON, FormatYAML and FormatMarkdown
+// support; or the result of GetDailyUsage, which only FormatCSV supports.
+// Other combinations return an error. FormatOpts holds the options of the
+// format, such as *MarkdownOptions, or nil for the defaults.
+func Export(w io.Writer, data interface{}, format Format, formatOpts interface{}) error {
+	return ExportContext(context.Background(), w, data, format, formatOpts)
+}
+
+// ExportContext is like Export but stops writing once ctx is done
+func ExportContext(ctx context.Context, w io.Writer, data interface{}, format Format, formatOpts interface{}) error {
+	exportType, err := exportTypeOf(data)
+	if err != nil {
+		return err
+	}
+	exp, err := exporter.NewFileExporter(&exporter.ExportOptions{
+		Format:          format,
+		IncludeMetadata: true,
+		IncludeStats:    true,
+		FormatOptions:   formatOpts,
+	})
+	if err != nil {
+		return err
+	}
+	return exp.ExportContext(ctx, w, data, exportType)
+}
+
+// exportTypeOf returns the export type matching the type of data
+func exportTypeOf(data interface{}) (exporter.ExportType, error) {
+	switch data.(type) {
+	case *Session:
+		return exporter.ExportTypeSession, nil
+	case *Project:
+		return exporter.ExportTypeProject, nil
+	case []*Project:
+		return exporter.ExportTypeProjects, nil
+	case []*IndexEntry:
+		return exporter.ExportTypeIndex, nil
+	case *SearchResults:
+		return exporter.ExportTypeSearch, nil
+	case []*Prompt:
+		return exporter.ExportTypePrompts, nil
+	case []*DailyUsage:
+		return exporter.ExportTypeDaily, nil
+	}
+	return "", fmt.Errorf("unsupported data type for export: %T", data)
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanAndExport(t *testing.T) {
+	claudeDir := t.TempDir()
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"uuid":"msg2","parentUuid":"msg1","sessionId":"session1","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"role":"assistant","model":"claude-3","content":[{"type":"text","text":"Hi there!"}]}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "session1.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	projects, err := Scan(claudeDir, ScanOptions{})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(projects) != 1 || projects[0].GetSessionCount() != 1 || projects[0].GetTotalMessages() != 2 {
+		t.Fatalf("Scan() = %d projects, want 1 project with 1 session and 2 messages", len(projects))
+	}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, projects, FormatJSON, &JSONOptions{PrettyPrint: true}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	var output map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Export() wrote invalid JSON: %v", err)
+	}
+
+	buf.Reset()
+	if err := Export(&buf, projects[0].Sessions[0], FormatText, nil); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "ASSISTANT: Hi there!") {
+		t.Errorf("Expected session text, got:\n%s", buf.String())
+	}
+
+	if err := Export(&buf, "not exportable", FormatJSON, nil); err == nil {
+		t.Error("Export() should error for unsupported data")
+	}
+	if err := Export(&buf, projects, Format("pdf"), nil); err == nil {
+		t.Error("Export() should error for unsupported format")
+	}
+
+	// Daily usage is only written as CSV
+	if err := Export(&buf, GetDailyUsage(projects), FormatCSV, nil); err != nil {
+		t.Errorf("Export() of daily usage as CSV error = %v", err)
+	}
+	if err := Export(&buf, GetDailyUsage(projects), FormatJSON, nil); err == nil {
+		t.Error("Export() should error for daily usage as JSON")
+	}
+}
@@ -0,0 +1,117 @@
+package converter
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// sessionHeader is the header row of the session statistics CSV
+var sessionHeader = []string{
+	"project", "session_id", "start_time", "end_time", "duration_minutes",
+	"message_count", "user_messages", "assistant_messages", "input_tokens", "output_tokens",
+	"cache_read_tokens", "cache_creation_tokens",
+}
+
+// dailyUsageHeader is the header row of the daily usage CSV
+var dailyUsageHeader = []string{"date", "model", "input_tokens", "cache_read_tokens", "output_tokens", "estimated_cost", "cache_creation_tokens", "model_family"}
+
+// CSVConverter converts usage statistics to CSV format
+type CSVConverter struct{}
+
+// NewCSVConverter creates a new CSV converter
+func NewCSVConverter() *CSVConverter {
+	return &CSVConverter{}
+}
+
+// WriteSessions writes one row of statistics per session of the projects,
+// e.g. for a spreadsheet. Times are formatted as in the JSON export and input
+// tokens include cache reads, as in GetTokenUsage; cache reads and cache
+// creation tokens also have their own columns.
+func (c *CSVConverter) WriteSessions(w io.Writer, projects []*models.Project) error {
+	return c.WriteSessionsContext(context.Background(), w, projects)
+}
+
+// WriteSessionsContext is like WriteSessions but checks ctx before each
+// session, returning ctx's error once it is done. Rows are flushed after
+// each project.
+func (c *CSVConverter) WriteSessionsContext(ctx context.Context, w io.Writer, projects []*models.Project) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(sessionHeader); err != nil {
+		return err
+	}
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			inputTokens, outputTokens := session.GetTokenUsage()
+			usage := session.GetUsageTotals()
+			record := []string{
+				project.GetProjectName(),
+				session.ID,
+				formatCSVTime(session.StartTime),
+				formatCSVTime(session.EndTime),
+				fmt.Sprintf("%.2f", session.GetDuration().Minutes()),
+				strconv.Itoa(session.GetMessageCount()),
+				strconv.Itoa(session.GetUserMessageCount()),
+				strconv.Itoa(session.GetAssistantMessageCount()),
+				strconv.Itoa(inputTokens),
+				strconv.Itoa(outputTokens),
+				strconv.Itoa(usage.CacheReadInputTokens),
+				strconv.Itoa(usage.CacheCreationInputTokens),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatCSVTime formats a time like the JSON export, or empty if unknown
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(jsonTimeLayout)
+}
+
+// WriteDailyUsage writes one row per day and model with its token usage and
+// estimated cost in USD, e.g. for billing reconciliation. The cost includes
+// cache writes, whose tokens have a column of their own after it. The model
+// family (see models.NormalizeModel) lets the rows of several versions be
+// summed.
+func (c *CSVConverter) WriteDailyUsage(w io.Writer, days []*models.DailyUsage) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(dailyUsageHeader); err != nil {
+		return err
+	}
+	for _, day := range days {
+		record := []string{
+			day.Date,
+			day.Model,
+			strconv.Itoa(day.Usage.InputTokens),
+			strconv.Itoa(day.Usage.CacheReadInputTokens),
+			strconv.Itoa(day.Usage.OutputTokens),
+			fmt.Sprintf("%.4f", day.Cost),
+			strconv.Itoa(day.Usage.CacheCreationInputTokens),
+			models.NormalizeModel(day.Model),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,58 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func TestCSVConverterDailyUsage(t *testing.T) {
+	days := []*models.DailyUsage{
+		{Date: "2024-07-01", Model: "claude-3-opus-20240229", Usage: models.Usage{InputTokens: 1000, CacheReadInputTokens: 300, CacheCreationInputTokens: 50, OutputTokens: 2000}, Cost: 0.1655},
+		{Date: "2024-07-02", Model: "unknown", Usage: models.Usage{InputTokens: 5}},
+	}
+
+	var buf bytes.Buffer
+	if err := NewCSVConverter().WriteDailyUsage(&buf, days); err != nil {
+		t.Fatalf("WriteDailyUsage() error = %v", err)
+	}
+
+	want := "date,model,input_tokens,cache_read_tokens,output_tokens,estimated_cost,cache_creation_tokens,model_family\n" +
+		"2024-07-01,claude-3-opus-20240229,1000,300,2000,0.1655,50,claude-3-opus\n" +
+		"2024-07-02,unknown,5,0,0,0.0000,0,unknown\n"
+	if buf.String() != want {
+		t.Errorf("WriteDailyUsage() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCSVConverterSessions(t *testing.T) {
+	project := models.NewProject("-work-api")
+	session := &models.Session{ID: "s1"}
+	for _, msg := range []*models.Message{
+		{Type: models.MessageTypeUser, UserType: "external", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			Message: json.RawMessage(`{"role":"user","content":"Hello"}`)},
+		{Type: models.MessageTypeAssistant, Timestamp: time.Date(2024, 1, 1, 10, 1, 30, 0, time.UTC),
+			Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":10,"cache_read_input_tokens":5,"cache_creation_input_tokens":7,"output_tokens":20}}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project.AddSession(session)
+	other := models.NewProject("-work-web")
+	other.AddSession(&models.Session{ID: "s2"})
+
+	var buf bytes.Buffer
+	if err := NewCSVConverter().WriteSessions(&buf, []*models.Project{project, other}); err != nil {
+		t.Fatalf("WriteSessions() error = %v", err)
+	}
+
+	want := "project,session_id,start_time,end_time,duration_minutes,message_count,user_messages,assistant_messages,input_tokens,output_tokens,cache_read_tokens,cache_creation_tokens\n" +
+		"api,s1,2024-01-01T10:00:00Z,2024-01-01T10:01:30Z,1.50,2,1,1,15,20,5,7\n" +
+		"web,s2,,,0.00,0,0,0,0,0,0,0\n"
+	if buf.String() != want {
+		t.Errorf("WriteSessions() = %q, want %q", buf.String(), want)
+	}
+}
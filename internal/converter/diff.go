@@ -0,0 +1,223 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ExportDiff holds the differences between two JSON exports (see
+// DiffExports). Projects are identified by ID, sessions by project and
+// session ID, and messages by UUID.
+type ExportDiff struct {
+	AddedProjects   []string       `json:"added_projects,omitempty"`
+	RemovedProjects []string       `json:"removed_projects,omitempty"`
+	AddedSessions   []*SessionDiff `json:"added_sessions,omitempty"`
+	RemovedSessions []*SessionDiff `json:"removed_sessions,omitempty"`
+	ChangedSessions []*SessionDiff `json:"changed_sessions,omitempty"`
+	// Change in the total message count
+	MessageDelta int `json:"message_delta"`
+}
+
+// SessionDiff is a session added, removed or changed between two exports
+type SessionDiff struct {
+	ProjectID   string `json:"project_id"`
+	Project     string `json:"project"` // Project path
+	SessionID   string `json:"session_id"`
+	OldMessages int    `json:"old_messages"`
+	NewMessages int    `json:"new_messages"`
+	// Messages of the old and new export missing from the other, by UUID
+	AddedMessages   int `json:"added_messages,omitempty"`
+	RemovedMessages int `json:"removed_messages,omitempty"`
+}
+
+// IsEmpty reports whether the exports have the same projects, sessions and
+// messages
+func (d *ExportDiff) IsEmpty() bool {
+	return len(d.AddedProjects) == 0 && len(d.RemovedProjects) == 0 &&
+		len(d.AddedSessions) == 0 && len(d.RemovedSessions) == 0 && len(d.ChangedSessions) == 0
+}
+
+// ReadJSONExport reads a JSON export of several projects, a single project or
+// a single session, as written by ConvertProjects, ConvertProject and
+// ConvertSession. A single session is returned in a project of its own.
+func ReadJSONExport(r io.Reader) ([]*JSONProject, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON export: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON export: %w", err)
+	}
+
+	switch {
+	case fields["projects"] != nil:
+		var projects []*JSONProject
+		if err := json.Unmarshal(fields["projects"], &projects); err != nil {
+			return nil, fmt.Errorf("failed to parse projects: %w", err)
+		}
+		return projects, nil
+	case fields["sessions"] != nil:
+		var project JSONProject
+		if err := json.Unmarshal(data, &project); err != nil {
+			return nil, fmt.Errorf("failed to parse project: %w", err)
+		}
+		return []*JSONProject{&project}, nil
+	case fields["messages"] != nil:
+		var session JSONSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			return nil, fmt.Errorf("failed to parse session: %w", err)
+		}
+		project := &JSONProject{ID: session.ProjectID, Sessions: []*JSONSession{&session}}
+		return []*JSONProject{project}, nil
+	}
+	return nil, fmt.Errorf("not a JSON export of projects, a project or a session")
+}
+
+// DiffExports compares the projects of two JSON exports. Sessions of added
+// and removed projects are listed as added and removed sessions; a session
+// has changed if its message count or the UUIDs of its messages differ.
+// Projects and sessions are listed in ID order.
+func DiffExports(oldProjects, newProjects []*JSONProject) *ExportDiff {
+	diff := &ExportDiff{}
+	oldByID := projectsByID(oldProjects)
+	newByID := projectsByID(newProjects)
+
+	for _, id := range sortedKeys(newByID) {
+		if _, ok := oldByID[id]; !ok {
+			diff.AddedProjects = append(diff.AddedProjects, id)
+		}
+	}
+	for _, id := range sortedKeys(oldByID) {
+		if _, ok := newByID[id]; !ok {
+			diff.RemovedProjects = append(diff.RemovedProjects, id)
+		}
+	}
+
+	ids := make(map[string]bool, len(oldByID)+len(newByID))
+	for id := range oldByID {
+		ids[id] = true
+	}
+	for id := range newByID {
+		ids[id] = true
+	}
+	for _, id := range sortedKeys(ids) {
+		diff.diffProject(oldByID[id], newByID[id])
+	}
+	return diff
+}
+
+// diffProject adds the session differences of one project, either of which
+// may be nil
+func (d *ExportDiff) diffProject(oldProject, newProject *JSONProject) {
+	oldSessions := sessionsByID(oldProject)
+	newSessions := sessionsByID(newProject)
+	project := newProject
+	if project == nil {
+		project = oldProject
+	}
+
+	ids := make(map[string]bool, len(oldSessions)+len(newSessions))
+	for id := range oldSessions {
+		ids[id] = true
+	}
+	for id := range newSessions {
+		ids[id] = true
+	}
+	for _, id := range sortedKeys(ids) {
+		oldSession, newSession := oldSessions[id], newSessions[id]
+		sessionDiff := &SessionDiff{ProjectID: project.ID, Project: project.Path, SessionID: id}
+		if oldSession != nil {
+			sessionDiff.OldMessages = oldSession.MessageCount
+		}
+		if newSession != nil {
+			sessionDiff.NewMessages = newSession.MessageCount
+		}
+		d.MessageDelta += sessionDiff.NewMessages - sessionDiff.OldMessages
+
+		switch {
+		case oldSession == nil:
+			d.AddedSessions = append(d.AddedSessions, sessionDiff)
+		case newSession == nil:
+			d.RemovedSessions = append(d.RemovedSessions, sessionDiff)
+		default:
+			sessionDiff.AddedMessages = countMissing(newSession.Messages, oldSession.Messages)
+			sessionDiff.RemovedMessages = countMissing(oldSession.Messages, newSession.Messages)
+			if sessionDiff.OldMessages != sessionDiff.NewMessages || sessionDiff.AddedMessages > 0 || sessionDiff.RemovedMessages > 0 {
+				d.ChangedSessions = append(d.ChangedSessions, sessionDiff)
+			}
+		}
+	}
+}
+
+// WriteSummary writes a human-readable summary of the differences, one line
+// per added (+), removed (-) or changed (~) session
+func (d *ExportDiff) WriteSummary(w io.Writer) error {
+	if d.IsEmpty() {
+		_, err := fmt.Fprintln(w, "No differences")
+		return err
+	}
+
+	fmt.Fprintf(w, "Projects: %d added, %d removed\n", len(d.AddedProjects), len(d.RemovedProjects))
+	fmt.Fprintf(w, "Sessions: %d added, %d removed, %d changed\n", len(d.AddedSessions), len(d.RemovedSessions), len(d.ChangedSessions))
+	fmt.Fprintf(w, "Messages: %+d\n", d.MessageDelta)
+
+	for _, s := range d.AddedSessions {
+		fmt.Fprintf(w, "+ %s %s (%d messages)\n", s.Project, s.SessionID, s.NewMessages)
+	}
+	for _, s := range d.RemovedSessions {
+		fmt.Fprintf(w, "- %s %s (%d messages)\n", s.Project, s.SessionID, s.OldMessages)
+	}
+	for _, s := range d.ChangedSessions {
+		fmt.Fprintf(w, "~ %s %s (%d -> %d messages, %d added, %d removed)\n",
+			s.Project, s.SessionID, s.OldMessages, s.NewMessages, s.AddedMessages, s.RemovedMessages)
+	}
+	return nil
+}
+
+// projectsByID maps projects by ID
+func projectsByID(projects []*JSONProject) map[string]*JSONProject {
+	byID := make(map[string]*JSONProject, len(projects))
+	for _, project := range projects {
+		byID[project.ID] = project
+	}
+	return byID
+}
+
+// sessionsByID maps the sessions of a project, which may be nil, by ID
+func sessionsByID(project *JSONProject) map[string]*JSONSession {
+	byID := make(map[string]*JSONSession)
+	if project != nil {
+		for _, session := range project.Sessions {
+			byID[session.ID] = session
+		}
+	}
+	return byID
+}
+
+// countMissing counts the messages with a UUID that is not in others
+func countMissing(messages, others []*JSONMessage) int {
+	uuids := make(map[string]bool, len(others))
+	for _, msg := range others {
+		uuids[msg.UUID] = true
+	}
+	missing := 0
+	for _, msg := range messages {
+		if msg.UUID != "" && !uuids[msg.UUID] {
+			missing++
+		}
+	}
+	return missing
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
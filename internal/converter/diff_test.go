@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadJSONExport(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		projects int
+		sessions int
+	}{
+		{"projects", `{"project_count":1,"projects":[{"id":"p","sessions":[{"id":"s1","messages":[]}]}]}`, 1, 1},
+		{"project", `{"id":"p","sessions":[{"id":"s1","messages":[]},{"id":"s2","messages":[]}]}`, 1, 2},
+		{"session", `{"id":"s1","project_id":"p","message_count":0,"messages":[]}`, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, err := ReadJSONExport(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("ReadJSONExport() error = %v", err)
+			}
+			if len(projects) != tt.projects || len(projects[0].Sessions) != tt.sessions || projects[0].ID != "p" {
+				t.Errorf("ReadJSONExport() = %d projects, want %d with %d sessions", len(projects), tt.projects, tt.sessions)
+			}
+		})
+	}
+
+	if _, err := ReadJSONExport(strings.NewReader(`{"event":"done"}`)); err == nil {
+		t.Error("ReadJSONExport() accepted a document that is not an export")
+	}
+}
+
+func TestDiffExports(t *testing.T) {
+	msgs := func(uuids ...string) []*JSONMessage {
+		var messages []*JSONMessage
+		for _, uuid := range uuids {
+			messages = append(messages, &JSONMessage{UUID: uuid})
+		}
+		return messages
+	}
+	oldProjects := []*JSONProject{
+		{ID: "-work-api", Path: "/work/api", Sessions: []*JSONSession{
+			{ID: "same", MessageCount: 2, Messages: msgs("a", "b")},
+			{ID: "grew", MessageCount: 1, Messages: msgs("c")},
+			{ID: "gone", MessageCount: 3, Messages: msgs("d", "e", "f")},
+		}},
+		{ID: "-work-old", Path: "/work/old", Sessions: []*JSONSession{
+			{ID: "old", MessageCount: 1, Messages: msgs("g")},
+		}},
+	}
+	newProjects := []*JSONProject{
+		{ID: "-work-api", Path: "/work/api", Sessions: []*JSONSession{
+			{ID: "same", MessageCount: 2, Messages: msgs("a", "b")},
+			{ID: "grew", MessageCount: 3, Messages: msgs("c", "h", "i")},
+			{ID: "new", MessageCount: 4, Messages: msgs("j", "k", "l", "m")},
+		}},
+	}
+
+	diff := DiffExports(oldProjects, newProjects)
+	if len(diff.AddedProjects) != 0 || len(diff.RemovedProjects) != 1 || diff.RemovedProjects[0] != "-work-old" {
+		t.Errorf("projects: added %v, removed %v, want -work-old removed", diff.AddedProjects, diff.RemovedProjects)
+	}
+	if len(diff.AddedSessions) != 1 || diff.AddedSessions[0].SessionID != "new" {
+		t.Errorf("added sessions = %+v, want new", diff.AddedSessions)
+	}
+	if len(diff.RemovedSessions) != 2 || diff.RemovedSessions[0].SessionID != "gone" || diff.RemovedSessions[1].SessionID != "old" {
+		t.Errorf("removed sessions = %+v, want gone and old", diff.RemovedSessions)
+	}
+	if len(diff.ChangedSessions) != 1 || diff.ChangedSessions[0].SessionID != "grew" || diff.ChangedSessions[0].AddedMessages != 2 {
+		t.Errorf("changed sessions = %+v, want grew with 2 added messages", diff.ChangedSessions)
+	}
+	if diff.MessageDelta != 2 {
+		t.Errorf("MessageDelta = %d, want 2", diff.MessageDelta)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteSummary(&buf); err != nil {
+		t.Fatalf("WriteSummary() error = %v", err)
+	}
+	for _, want := range []string{
+		"Sessions: 1 added, 2 removed, 1 changed",
+		"Messages: +2",
+		"+ /work/api new (4 messages)",
+		"- /work/old old (1 messages)",
+		"~ /work/api grew (1 -> 3 messages, 2 added, 0 removed)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Summary missing %q. Output:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	DiffExports(newProjects, newProjects).WriteSummary(&buf)
+	if buf.String() != "No differences\n" {
+		t.Errorf("Summary of identical exports = %q", buf.String())
+	}
+}
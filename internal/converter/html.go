@@ -0,0 +1,355 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// htmlStyle is the stylesheet embedded in every HTML document
+const htmlStyle = `body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #24292f; background: #f6f8fa; margin: 0; padding: 2rem; }
+main { max-width: 960px; margin: 0 auto; }
+h1, h2 { border-bottom: 1px solid #d0d7de; padding-bottom: .3rem; }
+.meta { color: #57606a; font-size: .9rem; margin: .2rem 0; }
+.session { margin: 2rem 0; }
+.message { border-radius: 8px; padding: .8rem 1rem; margin: 1rem 0; border: 1px solid #d0d7de; }
+.message.user { background: #ddf4ff; border-color: #54aeff; }
+.message.assistant { background: #ffffff; }
+.message.regenerated { opacity: .6; }
+.role { font-weight: 600; margin-bottom: .4rem; }
+.text { white-space: pre-wrap; word-wrap: break-word; }
+.empty { color: #57606a; font-style: italic; }
+details { margin: .6rem 0; background: #f6f8fa; border-radius: 6px; padding: .4rem .8rem; }
+summary { cursor: pointer; color: #57606a; }
+pre { background: #161b22; color: #c9d1d9; border-radius: 6px; padding: .8rem; overflow-x: auto; font-size: .85rem; }
+.tool { font-weight: 600; margin-top: .6rem; }
+.json-key { color: #79c0ff; }
+.json-string { color: #a5d6ff; }
+.json-number { color: #ffa657; }
+.json-literal { color: #ff7b72; }
+.todos li.completed { text-decoration: line-through; color: #57606a; }`
+
+// HTMLConverter converts sessions and projects to self-contained HTML
+// documents
+type HTMLConverter struct {
+	options HTMLOptions
+}
+
+// HTMLOptions provides options for HTML conversion
+type HTMLOptions struct {
+	// Include timestamps for each message
+	ShowTimestamps bool
+	// Include token usage statistics
+	ShowTokenUsage bool
+	// Include thinking content in collapsible blocks
+	ShowThinking bool
+	// Maximum number of characters shown of each thinking block, followed
+	// by a note of its full length (0 = no limit)
+	MaxThinkingChars int
+	// Layout of rendered times: a Go layout or rfc3339, unix or kitchen (""
+	// = RFC3339 in session headers, 2006-01-02 15:04:05 elsewhere)
+	TimeFormat string
+}
+
+// NewHTMLConverter creates a new HTML converter
+func NewHTMLConverter(options *HTMLOptions) *HTMLConverter {
+	if options == nil {
+		options = &HTMLOptions{
+			ShowTimestamps: true,
+			ShowTokenUsage: true,
+		}
+	}
+	return &HTMLConverter{
+		options: *options,
+	}
+}
+
+// ConvertSession converts a session to an HTML document
+func (c *HTMLConverter) ConvertSession(session *models.Session) string {
+	var sb strings.Builder
+	c.writeSession(&sb, session, "h1")
+	return htmlDocument("Session: "+session.ID, sb.String())
+}
+
+// ConvertProject converts a project to an HTML document
+func (c *HTMLConverter) ConvertProject(project *models.Project) string {
+	document, _ := c.ConvertProjectContext(context.Background(), project)
+	return document
+}
+
+// ConvertProjectContext is like ConvertProject but checks ctx before each
+// session, returning ctx's error once it is done
+func (c *HTMLConverter) ConvertProjectContext(ctx context.Context, project *models.Project) (string, error) {
+	var sb strings.Builder
+	if err := c.writeProject(ctx, &sb, project); err != nil {
+		return "", err
+	}
+	return htmlDocument("Project: "+project.GetProjectName(), sb.String()), nil
+}
+
+// ConvertProjects converts multiple projects to a single HTML document
+func (c *HTMLConverter) ConvertProjects(projects []*models.Project) string {
+	document, _ := c.ConvertProjectsContext(context.Background(), projects)
+	return document
+}
+
+// ConvertProjectsContext is like ConvertProjects but checks ctx before each
+// session, returning ctx's error once it is done
+func (c *HTMLConverter) ConvertProjectsContext(ctx context.Context, projects []*models.Project) (string, error) {
+	var sb strings.Builder
+	for _, project := range projects {
+		if err := c.writeProject(ctx, &sb, project); err != nil {
+			return "", err
+		}
+	}
+	return htmlDocument(fmt.Sprintf("Claude Code History (%d projects)", len(projects)), sb.String()), nil
+}
+
+// htmlDocument wraps body in a complete HTML document with embedded CSS
+func htmlDocument(title, body string) string {
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(title)))
+	sb.WriteString("<style>\n")
+	sb.WriteString(htmlStyle)
+	sb.WriteString("\n</style>\n</head>\n<body>\n<main>\n")
+	sb.WriteString(body)
+	sb.WriteString("</main>\n</body>\n</html>\n")
+	return sb.String()
+}
+
+// writeProject writes a project section with its todo lists and sessions,
+// stopping once ctx is done
+func (c *HTMLConverter) writeProject(ctx context.Context, sb *strings.Builder, project *models.Project) error {
+	sb.WriteString("<section class=\"project\">\n")
+	sb.WriteString(fmt.Sprintf("<h1>Project: %s</h1>\n", html.EscapeString(project.GetProjectName())))
+	sb.WriteString(fmt.Sprintf("<p class=\"meta\">Path: <code>%s</code></p>\n", html.EscapeString(project.Path)))
+	if project.IsStale() {
+		sb.WriteString("<p class=\"meta\">Exists: no (project directory not found)</p>\n")
+	}
+	sb.WriteString(fmt.Sprintf("<p class=\"meta\">Sessions: %d | Messages: %d</p>\n", project.GetSessionCount(), project.GetTotalMessages()))
+
+	if c.options.ShowTokenUsage {
+		inputTokens, outputTokens := project.GetTotalTokenUsage()
+		if inputTokens > 0 || outputTokens > 0 {
+			sb.WriteString(fmt.Sprintf("<p class=\"meta\">Total Token Usage: Input: %d, Output: %d</p>\n", inputTokens, outputTokens))
+		}
+		writeHTMLCacheUsage(sb, "Total Cache Tokens", project.GetUsageTotals())
+	}
+
+	for _, todoList := range project.TodoLists {
+		c.writeTodoList(sb, todoList)
+	}
+
+	for _, session := range project.Sessions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.writeSession(sb, session, "h2")
+	}
+	sb.WriteString("</section>\n")
+	return nil
+}
+
+// writeHTMLCacheUsage writes a meta line with the cache reads and cache
+// creation tokens of usage, unless both are zero
+func writeHTMLCacheUsage(sb *strings.Builder, label string, usage models.Usage) {
+	if usage.CacheReadInputTokens == 0 && usage.CacheCreationInputTokens == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("<p class=\"meta\">%s: Read: %d, Creation: %d</p>\n", label, usage.CacheReadInputTokens, usage.CacheCreationInputTokens))
+}
+
+// writeSession writes a session section with a heading of the given level
+func (c *HTMLConverter) writeSession(sb *strings.Builder, session *models.Session, heading string) {
+	sb.WriteString("<section class=\"session\">\n")
+	sb.WriteString(fmt.Sprintf("<%s>Session: %s</%s>\n", heading, html.EscapeString(session.GetTitle()), heading))
+	sb.WriteString(fmt.Sprintf("<p class=\"meta\">ID: <code>%s</code></p>\n", html.EscapeString(session.ID)))
+	if !session.StartTime.IsZero() {
+		sb.WriteString(fmt.Sprintf("<p class=\"meta\">Started: %s | Duration: %s</p>\n",
+			formatTime(session.StartTime, c.options.TimeFormat, time.RFC3339), session.GetDuration()))
+	}
+	sb.WriteString(fmt.Sprintf("<p class=\"meta\">Messages: %d</p>\n", session.GetMessageCount()))
+
+	if c.options.ShowTokenUsage {
+		inputTokens, outputTokens := session.GetTokenUsage()
+		if inputTokens > 0 || outputTokens > 0 {
+			sb.WriteString(fmt.Sprintf("<p class=\"meta\">Token Usage: Input: %d, Output: %d</p>\n", inputTokens, outputTokens))
+		}
+		writeHTMLCacheUsage(sb, "Cache Tokens", session.GetUsageTotals())
+	}
+
+	for _, msg := range session.Messages {
+		c.writeMessage(sb, msg)
+	}
+	sb.WriteString("</section>\n")
+}
+
+// writeMessage writes a message, styled by its role
+func (c *HTMLConverter) writeMessage(sb *strings.Builder, msg *models.Message) {
+	if summary, ok := msg.Content.(*models.SummaryMessage); ok {
+		sb.WriteString("<details class=\"summary\">\n<summary>📝 Session Summary</summary>\n")
+		writeHTMLText(sb, summary.Summary)
+		sb.WriteString("</details>\n")
+		return
+	}
+
+	class := "message " + html.EscapeString(string(msg.Type))
+	if msg.Regenerated {
+		class += " regenerated"
+	}
+	sb.WriteString(fmt.Sprintf("<article class=\"%s\">\n", class))
+
+	switch msg.Type {
+	case models.MessageTypeUser:
+		sb.WriteString("<div class=\"role\">👤 User</div>\n")
+	case models.MessageTypeAssistant:
+		sb.WriteString("<div class=\"role\">🤖 Assistant</div>\n")
+	default:
+		sb.WriteString(fmt.Sprintf("<div class=\"role\">%s</div>\n", html.EscapeString(string(msg.Type))))
+	}
+	if c.options.ShowTimestamps && !msg.Timestamp.IsZero() {
+		sb.WriteString(fmt.Sprintf("<p class=\"meta\">%s</p>\n", formatTime(msg.Timestamp, c.options.TimeFormat, markdownTimeLayout)))
+	}
+
+	switch content := msg.Content.(type) {
+	case *models.UserMessage:
+		writeHTMLText(sb, content.Content)
+
+	case []models.ToolResult:
+		for _, result := range content {
+			sb.WriteString(fmt.Sprintf("<div class=\"tool\">Tool Result: <code>%s</code></div>\n", html.EscapeString(result.ToolUseID)))
+			sb.WriteString("<pre>")
+			sb.WriteString(toolResultHTML(result.Content))
+			sb.WriteString("</pre>\n")
+		}
+
+	case *models.AssistantMessage:
+		if content.Model != "" {
+			sb.WriteString(fmt.Sprintf("<p class=\"meta\">Model: %s</p>\n", html.EscapeString(content.Model)))
+		}
+		if len(content.Content) == 0 {
+			writeHTMLText(sb, "")
+		}
+		for _, block := range content.Content {
+			switch block.Type {
+			case "text":
+				writeHTMLText(sb, block.Text)
+			case "thinking":
+				if c.options.ShowThinking {
+					sb.WriteString("<details>\n<summary>💭 Thinking</summary>\n")
+					writeHTMLText(sb, truncateThinking(block.Thinking, c.options.MaxThinkingChars))
+					sb.WriteString("</details>\n")
+				}
+			case "tool_use":
+				sb.WriteString(fmt.Sprintf("<div class=\"tool\">🔧 Tool Use: <code>%s</code></div>\n", html.EscapeString(block.Name)))
+				sb.WriteString("<pre>")
+				sb.WriteString(highlightJSON(block.Input))
+				sb.WriteString("</pre>\n")
+			default:
+				if block.Text != "" {
+					writeHTMLText(sb, block.Text)
+				}
+			}
+		}
+		if c.options.ShowTokenUsage && content.Usage != nil {
+			sb.WriteString(fmt.Sprintf("<p class=\"meta\">Tokens - Input: %d, Output: %d</p>\n",
+				content.Usage.InputTokens+content.Usage.CacheReadInputTokens, content.Usage.OutputTokens))
+		}
+	}
+
+	sb.WriteString("</article>\n")
+}
+
+// writeTodoList writes a todo list as a checklist
+func (c *HTMLConverter) writeTodoList(sb *strings.Builder, todoList *models.TodoList) {
+	sb.WriteString("<details class=\"todos\">\n")
+	sb.WriteString(fmt.Sprintf("<summary>Todo List - Session: %s (%.0f%% complete)</summary>\n<ul>\n",
+		html.EscapeString(todoList.SessionID), todoList.GetCompletionRate()))
+	for _, todo := range todoList.SortedTodos() {
+		sb.WriteString(fmt.Sprintf("<li class=\"%s\">%s (%s)</li>\n",
+			html.EscapeString(string(todo.Status)), html.EscapeString(todo.Content), html.EscapeString(string(todo.Priority))))
+	}
+	sb.WriteString("</ul>\n</details>\n")
+}
+
+// writeHTMLText writes escaped text with its line breaks preserved
+func writeHTMLText(sb *strings.Builder, text string) {
+	if text == "" {
+		sb.WriteString("<div class=\"empty\">[empty message]</div>\n")
+		return
+	}
+	sb.WriteString("<div class=\"text\">")
+	sb.WriteString(html.EscapeString(text))
+	sb.WriteString("</div>\n")
+}
+
+// toolResultHTML renders tool result content: plain text for a JSON string,
+// highlighted JSON otherwise
+func toolResultHTML(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return html.EscapeString(text)
+	}
+	return highlightJSON(raw)
+}
+
+// highlightJSON pretty prints JSON and wraps keys, strings, numbers and
+// literals in spans for syntax highlighting. Invalid JSON is escaped as is.
+func highlightJSON(raw json.RawMessage) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return html.EscapeString(string(raw))
+	}
+	src := indented.String()
+
+	var sb strings.Builder
+	span := func(class, token string) {
+		sb.WriteString(fmt.Sprintf("<span class=\"%s\">%s</span>", class, html.EscapeString(token)))
+	}
+	for i := 0; i < len(src); {
+		ch := src[i]
+		switch {
+		case ch == '"':
+			// Find the closing quote, skipping escaped characters
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(src))
+			class := "json-string"
+			if rest := strings.TrimLeft(src[end:], " "); strings.HasPrefix(rest, ":") {
+				class = "json-key"
+			}
+			span(class, src[i:end])
+			i = end
+		case ch == '-' || (ch >= '0' && ch <= '9'):
+			end := i + 1
+			for end < len(src) && strings.IndexByte("0123456789.eE+-", src[end]) >= 0 {
+				end++
+			}
+			span("json-number", src[i:end])
+			i = end
+		case strings.HasPrefix(src[i:], "true"), strings.HasPrefix(src[i:], "null"):
+			span("json-literal", src[i:i+4])
+			i += 4
+		case strings.HasPrefix(src[i:], "false"):
+			span("json-literal", src[i:i+5])
+			i += 5
+		default:
+			sb.WriteString(html.EscapeString(src[i : i+1]))
+			i++
+		}
+	}
+	return sb.String()
+}
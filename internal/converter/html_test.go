@@ -0,0 +1,121 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func createHTMLTestSession() *models.Session {
+	session := &models.Session{ID: "html-session"}
+	for _, msg := range []*models.Message{
+		{
+			UUID:      "msg1",
+			Type:      models.MessageTypeUser,
+			UserType:  "external",
+			Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			Message:   json.RawMessage(`{"role":"user","content":"Why is if a < b && c > d { broken?"}`),
+		},
+		{
+			UUID:      "msg2",
+			Type:      models.MessageTypeAssistant,
+			Timestamp: time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC),
+			Message: json.RawMessage(`{"role":"assistant","model":"claude-3-opus","content":[` +
+				`{"type":"thinking","thinking":"Check <generics>"},` +
+				`{"type":"text","text":"Use <T any> & retry"},` +
+				`{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"echo <x>","timeout":30,"background":false}}` +
+				`],"usage":{"input_tokens":10,"output_tokens":20}}`),
+		},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	return session
+}
+
+func TestHTMLConverterSession(t *testing.T) {
+	document := NewHTMLConverter(&HTMLOptions{ShowThinking: true}).ConvertSession(createHTMLTestSession())
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<style>",
+		`<article class="message user">`,
+		`<article class="message assistant">`,
+		"Why is if a &lt; b &amp;&amp; c &gt; d { broken?",
+		"Use &lt;T any&gt; &amp; retry",
+		"<details>\n<summary>💭 Thinking</summary>",
+		"Check &lt;generics&gt;",
+		`<span class="json-key">&#34;command&#34;</span>: <span class="json-string">&#34;echo &lt;x&gt;&#34;</span>`,
+		`<span class="json-number">30</span>`,
+		`<span class="json-literal">false</span>`,
+		"</html>",
+	} {
+		if !strings.Contains(document, want) {
+			t.Errorf("HTML missing %q. Output:\n%s", want, document)
+		}
+	}
+	if strings.Contains(document, "<T any>") || strings.Contains(document, "echo <x>") {
+		t.Error("Message content should be HTML escaped")
+	}
+
+	// Long thinking is cut to the character budget
+	document = NewHTMLConverter(&HTMLOptions{ShowThinking: true, MaxThinkingChars: 5}).ConvertSession(createHTMLTestSession())
+	if !strings.Contains(document, "Check…(truncated) [16 characters]") || strings.Contains(document, "generics") {
+		t.Errorf("Thinking should be truncated to 5 characters. Output:\n%s", document)
+	}
+
+	// Thinking is hidden unless requested
+	document = NewHTMLConverter(nil).ConvertSession(createHTMLTestSession())
+	if strings.Contains(document, "Thinking") {
+		t.Error("Thinking should be hidden by default")
+	}
+}
+
+func TestHTMLConverterProjects(t *testing.T) {
+	projects := []*models.Project{models.NewProject("-work-api"), models.NewProject("-work-web")}
+	projects[0].AddSession(createHTMLTestSession())
+	projects[1].AddTodoList(&models.TodoList{
+		SessionID: "s2",
+		Todos:     []*models.Todo{{ID: "1", Content: "Fix <div>", Status: models.TodoStatusCompleted, Priority: models.TodoPriorityHigh}},
+	})
+
+	document := NewHTMLConverter(nil).ConvertProjects(projects)
+	if strings.Count(document, "<!DOCTYPE html>") != 1 {
+		t.Error("Projects should be rendered into a single document")
+	}
+	for _, want := range []string{
+		"<h1>Project: api</h1>",
+		"<h1>Project: web</h1>",
+		`<li class="completed">Fix &lt;div&gt; (high)</li>`,
+	} {
+		if !strings.Contains(document, want) {
+			t.Errorf("HTML missing %q. Output:\n%s", want, document)
+		}
+	}
+}
+
+func TestConvertSummaryMessage(t *testing.T) {
+	summary := &models.Message{Type: models.MessageTypeSummary, Summary: "Fix the <login> redirect", LeafUUID: "msg2"}
+	summary.ParseContent()
+	session := createHTMLTestSession()
+	session.Messages = append([]*models.Message{summary}, session.Messages...)
+
+	markdown := NewMarkdownConverter(nil).ConvertSession(session)
+	if !strings.Contains(markdown, "<details>\n<summary>📝 Session Summary</summary>\n\nFix the <login> redirect\n\n</details>") {
+		t.Errorf("Markdown missing summary block. Output:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "### summary") {
+		t.Errorf("Summary should not render as a message. Output:\n%s", markdown)
+	}
+
+	document := NewHTMLConverter(nil).ConvertSession(session)
+	if !strings.Contains(document, "<details class=\"summary\">\n<summary>📝 Session Summary</summary>\n<div class=\"text\">Fix the &lt;login&gt; redirect</div>") {
+		t.Errorf("HTML missing summary block. Output:\n%s", document)
+	}
+	if strings.Contains(document, `<article class="message summary">`) {
+		t.Error("Summary should not render as a message")
+	}
+}
@@ -0,0 +1,99 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// IndexEntry represents a single session in the session index
+type IndexEntry struct {
+	Project      string `json:"project"`
+	ProjectPath  string `json:"project_path"`
+	SessionID    string `json:"session_id"`
+	Title        string `json:"title"`
+	Date         string `json:"date"`
+	MessageCount int    `json:"message_count"`
+	File         string `json:"file,omitempty"`
+}
+
+// LinkFunc returns the file a session was exported to, or an empty string
+type LinkFunc func(project *models.Project, session *models.Session) string
+
+// BuildIndex builds an index entry for every session across all projects.
+// If link is not nil, it is used to fill in the exported file of each session.
+// Titles are truncated to titleLength characters (0 = DefaultTitleLength).
+func BuildIndex(projects []*models.Project, link LinkFunc, titleLength int) []*IndexEntry {
+	if titleLength <= 0 {
+		titleLength = models.DefaultTitleLength
+	}
+
+	var entries []*IndexEntry
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			entry := &IndexEntry{
+				Project:      project.GetProjectName(),
+				ProjectPath:  project.Path,
+				SessionID:    session.ID,
+				Title:        session.GetPreview(titleLength),
+				MessageCount: session.GetMessageCount(),
+			}
+			if !session.StartTime.IsZero() {
+				entry.Date = session.StartTime.Format("2006-01-02")
+			}
+			if link != nil {
+				entry.File = link(project, session)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// ConvertIndex converts a session index to JSON format
+func (c *JSONConverter) ConvertIndex(entries []*IndexEntry) ([]byte, error) {
+	if entries == nil {
+		entries = []*IndexEntry{}
+	}
+
+	result := map[string]interface{}{
+		"sessions":      entries,
+		"session_count": len(entries),
+	}
+
+	return c.marshal(result)
+}
+
+// ConvertIndex converts a session index to Markdown format
+func (c *MarkdownConverter) ConvertIndex(entries []*IndexEntry) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Session Index\n\n")
+	sb.WriteString(fmt.Sprintf("**Sessions:** %d  \n", len(entries)))
+
+	currentProject := ""
+	for i, entry := range entries {
+		if i == 0 || entry.ProjectPath != currentProject {
+			currentProject = entry.ProjectPath
+			sb.WriteString(fmt.Sprintf("\n## %s\n\n", entry.Project))
+			sb.WriteString(fmt.Sprintf("**Path:** `%s`\n\n", entry.ProjectPath))
+			sb.WriteString("| Date | Session | Messages |\n")
+			sb.WriteString("|------|---------|----------|\n")
+		}
+
+		title := escapeTableCell(entry.Title)
+		if entry.File != "" {
+			title = fmt.Sprintf("[%s](%s)", title, entry.File)
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d |\n", entry.Date, title, entry.MessageCount))
+	}
+
+	return sb.String()
+}
+
+// escapeTableCell escapes text so it can be placed in a Markdown table cell
+func escapeTableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
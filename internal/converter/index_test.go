@@ -0,0 +1,125 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func createIndexFixture() []*models.Project {
+	var projects []*models.Project
+
+	fixtures := []struct {
+		encodedPath string
+		sessionID   string
+		prompt      string
+		timestamp   time.Time
+	}{
+		{"-Users-test-alpha", "alpha-1", "Refactor the parser", time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+		{"-Users-test-alpha", "alpha-2", "Add unit tests", time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{"-Users-test-beta", "beta-1", "Write the README", time.Date(2024, 4, 5, 9, 0, 0, 0, time.UTC)},
+	}
+
+	byPath := make(map[string]*models.Project)
+	for _, f := range fixtures {
+		project, ok := byPath[f.encodedPath]
+		if !ok {
+			project = models.NewProject(f.encodedPath)
+			byPath[f.encodedPath] = project
+			projects = append(projects, project)
+		}
+
+		session := &models.Session{ID: f.sessionID}
+		msg := &models.Message{
+			UUID:      f.sessionID + "-msg",
+			Type:      models.MessageTypeUser,
+			UserType:  "external",
+			Timestamp: f.timestamp,
+			Message:   json.RawMessage(`{"role":"user","content":"` + f.prompt + `"}`),
+		}
+		msg.ParseContent()
+		session.AddMessage(msg)
+		project.AddSession(session)
+	}
+
+	return projects
+}
+
+func TestBuildIndex(t *testing.T) {
+	projects := createIndexFixture()
+
+	link := func(project *models.Project, session *models.Session) string {
+		return "project_" + project.GetProjectName() + ".md"
+	}
+	entries := BuildIndex(projects, link, 0)
+
+	if len(entries) != 3 {
+		t.Fatalf("BuildIndex() returned %d entries, want 3", len(entries))
+	}
+
+	if entries[0].Title != "Refactor the parser" {
+		t.Errorf("Title = %v, want Refactor the parser", entries[0].Title)
+	}
+
+	if entries[2].Date != "2024-04-05" {
+		t.Errorf("Date = %v, want 2024-04-05", entries[2].Date)
+	}
+
+	if entries[2].File != "project_beta.md" {
+		t.Errorf("File = %v, want project_beta.md", entries[2].File)
+	}
+}
+
+func TestConvertIndex(t *testing.T) {
+	projects := createIndexFixture()
+	entries := BuildIndex(projects, nil, 0)
+
+	markdown := NewMarkdownConverter(nil).ConvertIndex(entries)
+
+	for _, project := range projects {
+		if !strings.Contains(markdown, "## "+project.GetProjectName()) {
+			t.Errorf("Missing project %s in index", project.GetProjectName())
+		}
+		for _, session := range project.Sessions {
+			row := "| " + session.StartTime.Format("2006-01-02") + " | " + session.GetTitle() + " |"
+			if !strings.Contains(markdown, row) {
+				t.Errorf("Missing index row %q", row)
+			}
+		}
+	}
+
+	data, err := NewJSONConverter(nil).ConvertIndex(entries)
+	if err != nil {
+		t.Fatalf("ConvertIndex() error = %v", err)
+	}
+
+	var result struct {
+		SessionCount int           `json:"session_count"`
+		Sessions     []*IndexEntry `json:"sessions"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal index: %v", err)
+	}
+
+	if result.SessionCount != 3 {
+		t.Errorf("session_count = %v, want 3", result.SessionCount)
+	}
+
+	for i, entry := range result.Sessions {
+		if entry.Title != entries[i].Title || entry.Date != entries[i].Date {
+			t.Errorf("Session %d = (%s, %s), want (%s, %s)", i, entry.Title, entry.Date, entries[i].Title, entries[i].Date)
+		}
+	}
+}
+
+func TestBuildIndexTitleLength(t *testing.T) {
+	projects := createIndexFixture()
+
+	entries := BuildIndex(projects, nil, 8)
+	if entries[0].Title != "Refactor..." {
+		t.Errorf("Title = %v, want Refactor...", entries[0].Title)
+	}
+}
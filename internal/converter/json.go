@@ -1,8 +1,12 @@
 package converter
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/eternnoir/cc-history-export/internal/models"
 )
@@ -20,6 +24,30 @@ type JSONOptions struct {
 	IncludeRawMessages bool
 	// Exclude empty fields
 	OmitEmpty bool
+	// Emit assistant thinking and answer text as separate fields
+	SplitReasoning bool
+	// Number of keywords to extract per session (0 = none)
+	KeywordCount int
+	// Replace absolute times with message offsets in seconds from the
+	// session start
+	RelativeTimestamps bool
+	// List the tool calls of assistant messages with their results in a
+	// tool_calls field (see Session.LinkToolResults)
+	LinkToolResults bool
+	// CLAUDE.md content added as a top-level claude_md field of
+	// multi-project output ("" = omitted)
+	ClaudeMD string
+	// Leave out tool_use blocks and messages holding only tool calls or
+	// tool results; session counts still include them
+	SkipToolMessages bool
+	// Maximum number of messages exported per session, the earliest by
+	// timestamp; longer sessions are marked truncated (0 = no limit)
+	MaxMessages int
+	// Add the JSONL file each session was read from as source_file
+	IncludeSourceFile bool
+	// Layout of rendered times: a Go layout or rfc3339, unix or kitchen (""
+	// = RFC3339, keeping each time's offset)
+	TimeFormat string
 }
 
 // NewJSONConverter creates a new JSON converter
@@ -37,50 +65,111 @@ func NewJSONConverter(options *JSONOptions) *JSONConverter {
 
 // JSONMessage represents a message in the exported JSON format
 type JSONMessage struct {
-	UUID       string      `json:"uuid"`
-	ParentUUID *string     `json:"parent_uuid,omitempty"`
-	SessionID  string      `json:"session_id"`
-	Type       string      `json:"type"`
-	UserType   string      `json:"user_type,omitempty"`
-	Timestamp  string      `json:"timestamp"`
-	CWD        string      `json:"cwd,omitempty"`
-	Content    interface{} `json:"content"`
-	RawMessage interface{} `json:"raw_message,omitempty"`
+	UUID        string          `json:"uuid"`
+	ParentUUID  *string         `json:"parent_uuid,omitempty"`
+	SessionID   string          `json:"session_id"`
+	Type        string          `json:"type"`
+	UserType    string          `json:"user_type,omitempty"`
+	Level       string          `json:"level,omitempty"`
+	Timestamp   string          `json:"timestamp,omitempty"`
+	Offset      *int            `json:"offset_seconds,omitempty"`
+	CWD         string          `json:"cwd,omitempty"`
+	Regenerated bool            `json:"regenerated,omitempty"`
+	Sidechain   bool            `json:"sidechain,omitempty"`
+	AgentID     string          `json:"agent_id,omitempty"`
+	Match       bool            `json:"match,omitempty"`
+	Content     interface{}     `json:"content"`
+	Thinking    string          `json:"thinking,omitempty"`
+	Answer      string          `json:"answer,omitempty"`
+	ToolCalls   []*JSONToolCall `json:"tool_calls,omitempty"`
+	RawMessage  interface{}     `json:"raw_message,omitempty"`
+}
+
+// JSONToolCall represents a tool call with its result in the exported JSON
+// format
+type JSONToolCall struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Input  json.RawMessage `json:"input,omitempty"`
+	Result *JSONToolResult `json:"result,omitempty"`
+}
+
+// JSONToolResult represents the result of a tool call
+type JSONToolResult struct {
+	MessageUUID string          `json:"message_uuid"`
+	Content     json.RawMessage `json:"content,omitempty"`
+	IsError     bool            `json:"is_error,omitempty"`
 }
 
 // JSONSession represents a session in the exported JSON format
 type JSONSession struct {
 	ID               string         `json:"id"`
 	ProjectID        string         `json:"project_id,omitempty"`
-	StartTime        string         `json:"start_time"`
-	EndTime          string         `json:"end_time"`
+	SourceFile       string         `json:"source_file,omitempty"`
+	StartTime        string         `json:"start_time,omitempty"`
+	EndTime          string         `json:"end_time,omitempty"`
 	Duration         string         `json:"duration"`
 	MessageCount     int            `json:"message_count"`
 	UserMessages     int            `json:"user_messages"`
 	AssistantMessages int           `json:"assistant_messages"`
 	TokenUsage       *TokenUsage    `json:"token_usage,omitempty"`
+	ToolUsage        map[string]int `json:"tool_usage,omitempty"`
+	GitBranches      []string       `json:"git_branches,omitempty"`
+	Keywords         []string       `json:"keywords,omitempty"`
+	WordCount        *WordCount     `json:"word_count,omitempty"`
+	Truncated        bool           `json:"truncated,omitempty"`
+	OmittedCount     int            `json:"omitted_count,omitempty"`
 	Messages         []*JSONMessage `json:"messages"`
 }
 
+// WordCount represents the number of words of prose in a session, without
+// tool calls and tool results. Thinking is counted on its own and left out of
+// the total and the reading time.
+type WordCount struct {
+	User               int `json:"user"`
+	Assistant          int `json:"assistant"`
+	Thinking           int `json:"thinking,omitempty"`
+	Total              int `json:"total"`
+	ReadingTimeSeconds int `json:"reading_time_seconds"` // At models.DefaultReadingWPM
+}
+
 // TokenUsage represents token usage statistics
 type TokenUsage struct {
-	Input  int `json:"input"`
-	Output int `json:"output"`
-	Total  int `json:"total"`
+	Input            int     `json:"input"`
+	Output           int     `json:"output"`
+	Total            int     `json:"total"`
+	Thinking         int     `json:"thinking,omitempty"`           // Estimated share of Output
+	CacheRead        int     `json:"cache_read,omitempty"`         // Share of Input read from the cache
+	CacheCreation    int     `json:"cache_creation,omitempty"`     // Written to the cache, not part of Input
+	EstimatedCostUSD float64 `json:"estimated_cost_usd,omitempty"` // Including cache reads and writes
 }
 
 // JSONProject represents a project in the exported JSON format
 type JSONProject struct {
-	ID           string           `json:"id"`
-	Name         string           `json:"name"`
-	Path         string           `json:"path"`
-	EncodedPath  string           `json:"encoded_path"`
-	SessionCount int              `json:"session_count"`
-	MessageCount int              `json:"message_count"`
-	DateRange    *DateRange       `json:"date_range,omitempty"`
-	TokenUsage   *TokenUsage      `json:"token_usage,omitempty"`
-	Sessions     []*JSONSession   `json:"sessions"`
-	TodoLists    []*JSONTodoList  `json:"todo_lists,omitempty"`
+	ID             string               `json:"id"`
+	Name           string               `json:"name"`
+	Path           string               `json:"path"`
+	EncodedPath    string               `json:"encoded_path"`
+	Exists         *bool                `json:"exists,omitempty"`
+	SessionCount   int                  `json:"session_count"`
+	MessageCount   int                  `json:"message_count"`
+	DateRange      *DateRange           `json:"date_range,omitempty"`
+	TokenUsage     *TokenUsage          `json:"token_usage,omitempty"`
+	ToolUsage      map[string]int       `json:"tool_usage,omitempty"`
+	Config         string               `json:"project_config,omitempty"`
+	Sessions       []*JSONSession       `json:"sessions"`
+	TodoSummary    *TodoSummary         `json:"todo_summary,omitempty"`
+	TodoLists      []*JSONTodoList      `json:"todo_lists,omitempty"`
+	ShellSnapshots []*JSONShellSnapshot `json:"shell_snapshots,omitempty"`
+}
+
+// TodoSummary represents the todos of all todo lists of a project
+type TodoSummary struct {
+	Lists          int            `json:"lists"`
+	Total          int            `json:"total"`
+	ByStatus       map[string]int `json:"by_status"`
+	ByPriority     map[string]int `json:"by_priority"`
+	CompletionRate float64        `json:"completion_rate"`
 }
 
 // DateRange represents a date range
@@ -106,6 +195,15 @@ type JSONTodo struct {
 	Priority string `json:"priority"`
 }
 
+// JSONShellSnapshot represents a shell snapshot in the exported JSON format
+type JSONShellSnapshot struct {
+	Name      string `json:"name"`
+	Shell     string `json:"shell,omitempty"`
+	CreatedAt string `json:"created_at"`
+	SessionID string `json:"session_id,omitempty"`
+	Content   string `json:"content"`
+}
+
 // ConvertSession converts a session to JSON format
 func (c *JSONConverter) ConvertSession(session *models.Session) ([]byte, error) {
 	jsonSession := c.sessionToJSON(session)
@@ -129,58 +227,321 @@ func (c *JSONConverter) ConvertProjects(projects []*models.Project) ([]byte, err
 		"projects":      jsonProjects,
 		"project_count": len(projects),
 	}
+	if c.options.ClaudeMD != "" {
+		result["claude_md"] = c.options.ClaudeMD
+	}
 	
 	return c.marshal(result)
 }
 
+// StreamProjects writes multiple projects to w in the same format as
+// ConvertProjects, marshaling one project at a time instead of building the
+// whole document in memory
+func (c *JSONConverter) StreamProjects(w io.Writer, projects []*models.Project) error {
+	return c.StreamProjectsContext(context.Background(), w, projects)
+}
+
+// StreamProjectsContext is like StreamProjects but stops before the next
+// project once ctx is done, returning the context error. The output written
+// so far is left incomplete.
+func (c *JSONConverter) StreamProjectsContext(ctx context.Context, w io.Writer, projects []*models.Project) error {
+	if len(projects) == 0 {
+		data, err := c.ConvertProjects(projects)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	// Keys are written in the order json.Marshal sorts map keys
+	header, separator, footer := `{%s"project_count":%d,"projects":[`, ",", "]}"
+	configFormat := `"claude_md":%s,`
+	indent := ""
+	if c.options.PrettyPrint {
+		header = "{\n%s  \"project_count\": %d,\n  \"projects\": [\n"
+		configFormat = "  \"claude_md\": %s,\n"
+		separator = ",\n"
+		footer = "\n  ]\n}"
+		indent = "    "
+	}
+
+	config := ""
+	if c.options.ClaudeMD != "" {
+		data, err := json.Marshal(c.options.ClaudeMD)
+		if err != nil {
+			return err
+		}
+		config = fmt.Sprintf(configFormat, data)
+	}
+	if _, err := fmt.Fprintf(w, header, config, len(projects)); err != nil {
+		return err
+	}
+
+	for i, project := range projects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+		}
+
+		var data []byte
+		var err error
+		if c.options.PrettyPrint {
+			data, err = json.MarshalIndent(c.projectToJSON(project), indent, "  ")
+		} else {
+			data, err = json.Marshal(c.projectToJSON(project))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to marshal project %s: %w", project.ID, err)
+		}
+
+		if _, err := io.WriteString(w, indent); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, footer)
+	return err
+}
+
+// StreamProject writes a project to w in the same format as ConvertProject,
+// marshaling one message at a time instead of building the whole project in
+// memory
+func (c *JSONConverter) StreamProject(w io.Writer, project *models.Project) error {
+	return c.StreamProjectContext(context.Background(), w, project)
+}
+
+// StreamProjectContext is like StreamProject but stops before the next
+// message once ctx is done, returning the context error. The output written
+// so far is left incomplete.
+func (c *JSONConverter) StreamProjectContext(ctx context.Context, w io.Writer, project *models.Project) error {
+	header := c.projectHeaderToJSON(project)
+	return c.streamArray(ctx, w, header, "sessions", 0, len(project.Sessions), func(depth, i int) error {
+		session := project.Sessions[i]
+		header := c.sessionHeaderToJSON(session)
+		calls := c.toolCalls(session)
+		messages, omitted := c.messages(session)
+		header.Truncated, header.OmittedCount = omitted > 0, omitted
+		return c.streamArray(ctx, w, header, "messages", depth, len(messages), func(depth, j int) error {
+			data, err := c.marshalAt(c.messageToJSON(messages[j], session, calls), depth)
+			if err != nil {
+				return fmt.Errorf("failed to marshal message %s: %w", messages[j].UUID, err)
+			}
+			_, err = w.Write(data)
+			return err
+		})
+	})
+}
+
+// streamArray writes v, marshaled at the given nesting depth, with the
+// elements of its empty array field key written by writeElement in place of
+// the empty array. Elements are written at depth+2, as json.MarshalIndent
+// places them.
+func (c *JSONConverter) streamArray(ctx context.Context, w io.Writer, v interface{}, key string, depth, n int, writeElement func(depth, i int) error) error {
+	data, err := c.marshalAt(v, depth)
+	if err != nil {
+		return err
+	}
+
+	// The field is the first occurrence: strings in the output have their
+	// quotes escaped and nested objects come after it
+	field := `"` + key + `":[]`
+	separator := ","
+	if c.options.PrettyPrint {
+		field = `"` + key + `": []`
+		separator = ",\n" + strings.Repeat("  ", depth+2)
+	}
+	pos := bytes.Index(data, []byte(field))
+	if pos < 0 {
+		return fmt.Errorf("field %s not found in JSON output", key)
+	}
+	closing := pos + len(field) - 1
+
+	if _, err := w.Write(data[:closing]); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+		} else if c.options.PrettyPrint {
+			if _, err := io.WriteString(w, "\n"+strings.Repeat("  ", depth+2)); err != nil {
+				return err
+			}
+		}
+		if err := writeElement(depth+2, i); err != nil {
+			return err
+		}
+	}
+	if n > 0 && c.options.PrettyPrint {
+		if _, err := io.WriteString(w, "\n"+strings.Repeat("  ", depth+1)); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(data[closing:])
+	return err
+}
+
 // sessionToJSON converts a models.Session to JSONSession
 func (c *JSONConverter) sessionToJSON(session *models.Session) *JSONSession {
+	jsonSession := c.sessionHeaderToJSON(session)
+	messages, omitted := c.messages(session)
+	jsonSession.Truncated, jsonSession.OmittedCount = omitted > 0, omitted
+	jsonSession.Messages = make([]*JSONMessage, len(messages))
+	calls := c.toolCalls(session)
+	for i, msg := range messages {
+		jsonSession.Messages[i] = c.messageToJSON(msg, session, calls)
+	}
+	return jsonSession
+}
+
+// sessionHeaderToJSON converts a models.Session to JSONSession without its
+// messages
+func (c *JSONConverter) sessionHeaderToJSON(session *models.Session) *JSONSession {
 	inputTokens, outputTokens := session.GetTokenUsage()
 	
 	jsonSession := &JSONSession{
 		ID:                session.ID,
 		ProjectID:         session.ProjectID,
-		StartTime:         session.StartTime.Format("2006-01-02T15:04:05Z"),
-		EndTime:           session.EndTime.Format("2006-01-02T15:04:05Z"),
 		Duration:          session.GetDuration().String(),
 		MessageCount:      session.GetMessageCount(),
 		UserMessages:      session.GetUserMessageCount(),
 		AssistantMessages: session.GetAssistantMessageCount(),
-		Messages:          make([]*JSONMessage, len(session.Messages)),
+		ToolUsage:         session.GetToolUsageStats(),
+		GitBranches:       session.GetGitBranches(),
+		Messages:          make([]*JSONMessage, 0),
 	}
 	
-	if inputTokens > 0 || outputTokens > 0 {
+	if c.options.IncludeSourceFile {
+		jsonSession.SourceFile = session.SourceFile
+	}
+	
+	if !c.options.RelativeTimestamps {
+		jsonSession.StartTime = formatTime(session.StartTime, c.options.TimeFormat, jsonTimeLayout)
+		jsonSession.EndTime = formatTime(session.EndTime, c.options.TimeFormat, jsonTimeLayout)
+	}
+	
+	if usage := session.GetUsageTotals(); inputTokens > 0 || outputTokens > 0 || usage.CacheCreationInputTokens > 0 {
 		jsonSession.TokenUsage = &TokenUsage{
-			Input:  inputTokens,
-			Output: outputTokens,
-			Total:  inputTokens + outputTokens,
+			Input:            inputTokens,
+			Output:           outputTokens,
+			Total:            inputTokens + outputTokens,
+			Thinking:         session.GetThinkingTokens(),
+			CacheRead:        usage.CacheReadInputTokens,
+			CacheCreation:    usage.CacheCreationInputTokens,
+			EstimatedCostUSD: session.GetEstimatedCost(),
 		}
 	}
 	
-	for i, msg := range session.Messages {
-		jsonSession.Messages[i] = c.messageToJSON(msg)
+	if c.options.KeywordCount > 0 {
+		jsonSession.Keywords = session.GetTopKeywords(c.options.KeywordCount)
+	}
+	
+	userWords, assistantWords := session.GetWordCount()
+	if thinkingWords := session.GetThinkingWordCount(); userWords > 0 || assistantWords > 0 || thinkingWords > 0 {
+		jsonSession.WordCount = &WordCount{
+			User:               userWords,
+			Assistant:          assistantWords,
+			Thinking:           thinkingWords,
+			Total:              userWords + assistantWords,
+			ReadingTimeSeconds: int(session.GetEstimatedReadingTime().Seconds()),
+		}
 	}
 	
 	return jsonSession
 }
 
-// messageToJSON converts a models.Message to JSONMessage
-func (c *JSONConverter) messageToJSON(msg *models.Message) *JSONMessage {
+// messages returns the messages of session to export, without tool-only
+// messages if SkipToolMessages is set and cut to MaxMessages, along with the
+// number of messages cut
+func (c *JSONConverter) messages(session *models.Session) ([]*models.Message, int) {
+	messages := session.Messages
+	if c.options.SkipToolMessages {
+		// Thinking is always exported
+		messages = withoutToolMessages(messages, true)
+	}
+	if c.options.MaxMessages <= 0 {
+		return messages, 0
+	}
+	first := models.FirstMessages(messages, c.options.MaxMessages)
+	return first, len(messages) - len(first)
+}
+
+// toolCalls links the tool calls of the session to their results if
+// LinkToolResults is set and tool messages are not skipped, and returns nil
+// otherwise
+func (c *JSONConverter) toolCalls(session *models.Session) map[string]*models.ToolCall {
+	if !c.options.LinkToolResults || c.options.SkipToolMessages {
+		return nil
+	}
+	return session.LinkToolResults()
+}
+
+// messageToJSON converts a models.Message of session to JSONMessage, listing
+// its tool calls if calls holds them
+func (c *JSONConverter) messageToJSON(msg *models.Message, session *models.Session, calls map[string]*models.ToolCall) *JSONMessage {
 	jsonMsg := &JSONMessage{
-		UUID:      msg.UUID,
-		SessionID: msg.SessionID,
-		Type:      string(msg.Type),
-		UserType:  msg.UserType,
-		CWD:       msg.CWD,
-		Content:   msg.Content,
+		UUID:        msg.UUID,
+		SessionID:   msg.SessionID,
+		Type:        string(msg.Type),
+		UserType:    msg.UserType,
+		Level:       msg.GetLevel(),
+		CWD:         msg.CWD,
+		Content:     msg.Content,
+		Regenerated: msg.Regenerated,
+		Sidechain:   msg.Sidechain,
+		AgentID:     msg.AgentID,
 	}
 	
 	if msg.ParentUUID != nil {
 		jsonMsg.ParentUUID = msg.ParentUUID
 	}
 	
-	if !msg.Timestamp.IsZero() {
-		jsonMsg.Timestamp = msg.Timestamp.Format("2006-01-02T15:04:05Z")
+	if c.options.RelativeTimestamps {
+		offset := int(session.GetMessageOffset(msg).Seconds())
+		jsonMsg.Offset = &offset
+	} else if !msg.Timestamp.IsZero() {
+		jsonMsg.Timestamp = formatTime(msg.Timestamp, c.options.TimeFormat, jsonTimeLayout)
+	}
+	
+	if assistantMsg, ok := msg.Content.(*models.AssistantMessage); ok && c.options.SkipToolMessages {
+		jsonMsg.Content = withoutToolUse(assistantMsg)
+	}
+	
+	if c.options.SplitReasoning {
+		if assistantMsg, ok := msg.Content.(*models.AssistantMessage); ok {
+			jsonMsg.Thinking = assistantMsg.GetThinking()
+			jsonMsg.Answer = assistantMsg.GetText()
+		}
+	}
+	
+	if assistantMsg, ok := msg.Content.(*models.AssistantMessage); ok && calls != nil {
+		for _, block := range assistantMsg.Content {
+			call, ok := calls[block.ID]
+			if block.Type != "tool_use" || !ok || call.Message != msg {
+				continue
+			}
+			jsonCall := &JSONToolCall{ID: block.ID, Name: block.Name, Input: block.Input}
+			if call.Result != nil {
+				jsonCall.Result = &JSONToolResult{
+					MessageUUID: call.ResultMessage.UUID,
+					Content:     call.Result.Content,
+					IsError:     call.Result.IsError,
+				}
+			}
+			jsonMsg.ToolCalls = append(jsonMsg.ToolCalls, jsonCall)
+		}
 	}
 	
 	if c.options.IncludeRawMessages && len(msg.Message) > 0 {
@@ -195,6 +556,17 @@ func (c *JSONConverter) messageToJSON(msg *models.Message) *JSONMessage {
 
 // projectToJSON converts a models.Project to JSONProject
 func (c *JSONConverter) projectToJSON(project *models.Project) *JSONProject {
+	jsonProject := c.projectHeaderToJSON(project)
+	jsonProject.Sessions = make([]*JSONSession, len(project.Sessions))
+	for i, session := range project.Sessions {
+		jsonProject.Sessions[i] = c.sessionToJSON(session)
+	}
+	return jsonProject
+}
+
+// projectHeaderToJSON converts a models.Project to JSONProject without its
+// sessions
+func (c *JSONConverter) projectHeaderToJSON(project *models.Project) *JSONProject {
 	inputTokens, outputTokens := project.GetTotalTokenUsage()
 	
 	jsonProject := &JSONProject{
@@ -202,39 +574,73 @@ func (c *JSONConverter) projectToJSON(project *models.Project) *JSONProject {
 		Name:         project.GetProjectName(),
 		Path:         project.Path,
 		EncodedPath:  project.EncodedPath,
+		Exists:       project.Exists,
 		SessionCount: project.GetSessionCount(),
 		MessageCount: project.GetTotalMessages(),
-		Sessions:     make([]*JSONSession, len(project.Sessions)),
+		ToolUsage:    project.GetToolUsageStats(),
+		Config:       project.Config,
+		Sessions:     make([]*JSONSession, 0),
 		TodoLists:    make([]*JSONTodoList, len(project.TodoLists)),
 	}
 	
-	if inputTokens > 0 || outputTokens > 0 {
+	if usage := project.GetUsageTotals(); inputTokens > 0 || outputTokens > 0 || usage.CacheCreationInputTokens > 0 {
 		jsonProject.TokenUsage = &TokenUsage{
-			Input:  inputTokens,
-			Output: outputTokens,
-			Total:  inputTokens + outputTokens,
+			Input:            inputTokens,
+			Output:           outputTokens,
+			Total:            inputTokens + outputTokens,
+			Thinking:         project.GetThinkingTokens(),
+			CacheRead:        usage.CacheReadInputTokens,
+			CacheCreation:    usage.CacheCreationInputTokens,
+			EstimatedCostUSD: project.GetEstimatedCost(),
 		}
 	}
 	
 	start, end := project.GetTimeRange()
-	if !start.IsZero() {
+	if !start.IsZero() && !c.options.RelativeTimestamps {
 		jsonProject.DateRange = &DateRange{
 			Start: start.Format("2006-01-02"),
 			End:   end.Format("2006-01-02"),
 		}
 	}
 	
-	for i, session := range project.Sessions {
-		jsonProject.Sessions[i] = c.sessionToJSON(session)
-	}
-	
 	for i, todoList := range project.TodoLists {
 		jsonProject.TodoLists[i] = c.todoListToJSON(todoList)
 	}
+	if len(project.TodoLists) > 0 {
+		jsonProject.TodoSummary = todoSummaryToJSON(project.GetAggregateTodoStats())
+	}
+	
+	for _, snapshot := range project.ShellSnapshots {
+		jsonProject.ShellSnapshots = append(jsonProject.ShellSnapshots, &JSONShellSnapshot{
+			Name:      snapshot.Name,
+			Shell:     snapshot.Shell,
+			CreatedAt: formatTime(snapshot.CreatedAt.UTC(), c.options.TimeFormat, jsonTimeLayout),
+			SessionID: snapshot.SessionID,
+			Content:   snapshot.Content,
+		})
+	}
 	
 	return jsonProject
 }
 
+// todoSummaryToJSON converts models.TodoStats to TodoSummary
+func todoSummaryToJSON(stats models.TodoStats) *TodoSummary {
+	summary := &TodoSummary{
+		Lists:          stats.Lists,
+		Total:          stats.Total,
+		ByStatus:       make(map[string]int, len(stats.ByStatus)),
+		ByPriority:     make(map[string]int, len(stats.ByPriority)),
+		CompletionRate: stats.CompletionRate,
+	}
+	for status, count := range stats.ByStatus {
+		summary.ByStatus[string(status)] = count
+	}
+	for priority, count := range stats.ByPriority {
+		summary.ByPriority[string(priority)] = count
+	}
+	return summary
+}
+
 // todoListToJSON converts a models.TodoList to JSONTodoList
 func (c *JSONConverter) todoListToJSON(todoList *models.TodoList) *JSONTodoList {
 	jsonTodoList := &JSONTodoList{
@@ -245,7 +651,8 @@ func (c *JSONConverter) todoListToJSON(todoList *models.TodoList) *JSONTodoList
 		Todos:          make([]*JSONTodo, len(todoList.Todos)),
 	}
 	
-	for i, todo := range todoList.Todos {
+	// In the order of the Markdown todo list
+	for i, todo := range todoList.SortedTodos() {
 		jsonTodoList.Todos[i] = &JSONTodo{
 			ID:       todo.ID,
 			Content:  todo.Content,
@@ -259,8 +666,14 @@ func (c *JSONConverter) todoListToJSON(todoList *models.TodoList) *JSONTodoList
 
 // marshal handles JSON marshaling with options
 func (c *JSONConverter) marshal(v interface{}) ([]byte, error) {
+	return c.marshalAt(v, 0)
+}
+
+// marshalAt is like marshal but indents the output to be nested depth levels
+// deep in a pretty printed document
+func (c *JSONConverter) marshalAt(v interface{}, depth int) ([]byte, error) {
 	if c.options.PrettyPrint {
-		return json.MarshalIndent(v, "", "  ")
+		return json.MarshalIndent(v, strings.Repeat("  ", depth), "  ")
 	}
 	return json.Marshal(v)
 }
@@ -271,6 +684,7 @@ func (c *JSONConverter) ConvertSessionToCompactJSON(session *models.Session) ([]
 	// Create a simplified representation
 	compact := map[string]interface{}{
 		"id":        session.ID,
+		"hash":      session.GetContentHash(),
 		"project":   session.ProjectID,
 		"start":     session.StartTime.Unix(),
 		"end":       session.EndTime.Unix(),
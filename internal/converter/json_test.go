@@ -1,7 +1,12 @@
 package converter
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -163,6 +168,84 @@ func TestJSONConverterProject(t *testing.T) {
 	if result.TodoLists[0].CompletionRate != 50.0 {
 		t.Errorf("CompletionRate = %v, want 50.0", result.TodoLists[0].CompletionRate)
 	}
+	
+	if summary := result.TodoSummary; summary == nil || summary.Total != 2 || summary.CompletionRate != 50.0 || summary.ByStatus["completed"] != 1 || summary.ByPriority["high"] != 1 {
+		t.Errorf("TodoSummary = %+v, want 2 todos, 1 completed and 1 high priority", summary)
+	}
+	
+	if result.ToolUsage != nil || result.Sessions[0].ToolUsage != nil {
+		t.Errorf("ToolUsage = %v, want none without tool calls", result.ToolUsage)
+	}
+	
+	// Todos are in the order of the Markdown todo list
+	sorted := converter.todoListToJSON(&models.TodoList{Todos: []*models.Todo{
+		{ID: "1", Status: models.TodoStatusCompleted, Priority: models.TodoPriorityHigh},
+		{ID: "2", Status: models.TodoStatusPending, Priority: models.TodoPriorityLow},
+		{ID: "3", Status: models.TodoStatusPending, Priority: models.TodoPriorityHigh},
+	}})
+	if sorted.Todos[0].ID != "3" || sorted.Todos[1].ID != "2" || sorted.Todos[2].ID != "1" {
+		t.Errorf("Todos = %s %s %s, want 3 2 1", sorted.Todos[0].ID, sorted.Todos[1].ID, sorted.Todos[2].ID)
+	}
+}
+
+func TestJSONConverterEstimatedCost(t *testing.T) {
+	session := &models.Session{ID: "session1"}
+	msg := &models.Message{
+		UUID:    "msg1",
+		Type:    models.MessageTypeAssistant,
+		Message: json.RawMessage(`{"role":"assistant","model":"claude-3-opus-20240229","content":[],"usage":{"input_tokens":1000,"output_tokens":1000,"cache_read_input_tokens":1000}}`),
+	}
+	msg.ParseContent()
+	session.AddMessage(msg)
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(session)
+	
+	data, err := NewJSONConverter(nil).ConvertProject(project)
+	if err != nil {
+		t.Fatalf("ConvertProject() error = %v", err)
+	}
+	var result JSONProject
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	
+	// 0.015 input + 0.075 output + 0.0015 cache read
+	want := 0.0915
+	if cost := result.TokenUsage.EstimatedCostUSD; math.Abs(cost-want) > 1e-9 {
+		t.Errorf("Project EstimatedCostUSD = %v, want %v", cost, want)
+	}
+	if cost := result.Sessions[0].TokenUsage.EstimatedCostUSD; math.Abs(cost-want) > 1e-9 {
+		t.Errorf("Session EstimatedCostUSD = %v, want %v", cost, want)
+	}
+}
+
+func TestJSONConverterToolUsage(t *testing.T) {
+	session := &models.Session{ID: "session1"}
+	msg := &models.Message{
+		UUID:    "msg1",
+		Type:    models.MessageTypeAssistant,
+		Message: json.RawMessage(`{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Bash","input":{}},{"type":"tool_use","id":"toolu_2","name":"Bash","input":{}}]}`),
+	}
+	msg.ParseContent()
+	session.AddMessage(msg)
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(session)
+	
+	data, err := NewJSONConverter(nil).ConvertProject(project)
+	if err != nil {
+		t.Fatalf("ConvertProject() error = %v", err)
+	}
+	var result JSONProject
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	
+	if result.ToolUsage["Bash"] != 2 {
+		t.Errorf("Project ToolUsage = %v, want map[Bash:2]", result.ToolUsage)
+	}
+	if result.Sessions[0].ToolUsage["Bash"] != 2 {
+		t.Errorf("Session ToolUsage = %v, want map[Bash:2]", result.Sessions[0].ToolUsage)
+	}
 }
 
 func TestJSONConverterMultipleProjects(t *testing.T) {
@@ -286,6 +369,43 @@ func TestJSONConverterCompact(t *testing.T) {
 	}
 }
 
+func TestJSONConverterCompactHash(t *testing.T) {
+	newSession := func(uuids ...string) *models.Session {
+		session := &models.Session{ID: "same-id"}
+		for _, uuid := range uuids {
+			session.AddMessage(&models.Message{UUID: uuid, Type: models.MessageTypeUser})
+		}
+		return session
+	}
+
+	hashOf := func(session *models.Session) string {
+		data, err := NewJSONConverter(nil).ConvertSessionToCompactJSON(session)
+		if err != nil {
+			t.Fatalf("ConvertSessionToCompactJSON() error = %v", err)
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("Failed to unmarshal compact JSON: %v", err)
+		}
+		hash, _ := result["hash"].(string)
+		return hash
+	}
+
+	original := hashOf(newSession("msg1", "msg2"))
+	if len(original) != 16 {
+		t.Errorf("hash = %q, want 16 hex characters", original)
+	}
+	if hashOf(newSession("msg1", "msg2")) != original {
+		t.Error("hash should match for identical content")
+	}
+	if hashOf(newSession("msg1", "msg3")) == original {
+		t.Error("hash should differ for differing content")
+	}
+	if hashOf(newSession("msg2", "msg1")) == original {
+		t.Error("hash should depend on message order")
+	}
+}
+
 func TestJSONConverterValidation(t *testing.T) {
 	converter := NewJSONConverter(nil)
 	
@@ -314,4 +434,381 @@ func TestJSONConverterValidation(t *testing.T) {
 	if err := converter.ValidateJSON(circular); err == nil {
 		t.Error("ValidateJSON() should error for circular reference")
 	}
-}
\ No newline at end of file
+}
+
+func TestJSONConverterStreamProjects(t *testing.T) {
+	createProject := func(encodedPath string) *models.Project {
+		project := models.NewProject(encodedPath)
+		session := &models.Session{ID: encodedPath + "-session"}
+		msg := &models.Message{
+			UUID:      "msg1",
+			Type:      models.MessageTypeUser,
+			UserType:  "external",
+			Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			Message:   json.RawMessage(`{"role":"user","content":"<b>Hello</b> & welcome"}`),
+		}
+		msg.ParseContent()
+		session.AddMessage(msg)
+		project.AddSession(session)
+		return project
+	}
+
+	claudeMD := "# Rules\nUse \"tabs\" & <b>never</b> spaces\n"
+	tests := []struct {
+		name     string
+		projects []*models.Project
+		pretty   bool
+		claudeMD string
+	}{
+		{"pretty", []*models.Project{createProject("-Users-a"), createProject("-Users-b")}, true, ""},
+		{"compact", []*models.Project{createProject("-Users-a"), createProject("-Users-b")}, false, ""},
+		{"empty pretty", []*models.Project{}, true, ""},
+		{"empty compact", nil, false, ""},
+		{"pretty with CLAUDE.md", []*models.Project{createProject("-Users-a"), createProject("-Users-b")}, true, claudeMD},
+		{"compact with CLAUDE.md", []*models.Project{createProject("-Users-a")}, false, claudeMD},
+		{"empty with CLAUDE.md", nil, true, claudeMD},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewJSONConverter(&JSONOptions{PrettyPrint: tt.pretty, ClaudeMD: tt.claudeMD})
+
+			buffered, err := converter.ConvertProjects(tt.projects)
+			if err != nil {
+				t.Fatalf("ConvertProjects() error = %v", err)
+			}
+
+			var streamed bytes.Buffer
+			if err := converter.StreamProjects(&streamed, tt.projects); err != nil {
+				t.Fatalf("StreamProjects() error = %v", err)
+			}
+
+			var want, got interface{}
+			if err := json.Unmarshal(buffered, &want); err != nil {
+				t.Fatalf("Failed to parse buffered output: %v", err)
+			}
+			if err := json.Unmarshal(streamed.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to parse streamed output: %v\n%s", err, streamed.String())
+			}
+
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("Streamed output differs from buffered output:\n%s\nwant:\n%s", streamed.String(), buffered)
+			}
+
+			if streamed.String() != string(buffered) {
+				t.Errorf("Streamed output is not byte-identical:\n%s\nwant:\n%s", streamed.String(), buffered)
+			}
+
+			if config, _ := got.(map[string]interface{})["claude_md"].(string); config != tt.claudeMD {
+				t.Errorf("claude_md = %q, want %q", config, tt.claudeMD)
+			}
+		})
+	}
+}
+
+func TestJSONConverterStreamProject(t *testing.T) {
+	project := models.NewProject("-Users-test-project")
+	project.Config = "Use \"sessions\": [] & <b>tabs</b>\n"
+	for _, id := range []string{"s1", "s2"} {
+		session := &models.Session{ID: id}
+		for i, content := range []string{`"Hello \"messages\": []"`, `[{"type":"text","text":"Hi"}]`} {
+			msgType := models.MessageTypeUser
+			if i == 1 {
+				msgType = models.MessageTypeAssistant
+			}
+			msg := &models.Message{
+				UUID:      fmt.Sprintf("%s-msg%d", id, i),
+				Type:      msgType,
+				UserType:  "external",
+				Timestamp: time.Date(2024, 1, 1, 10, i, 0, 0, time.UTC),
+				Message:   json.RawMessage(`{"role":"` + string(msgType) + `","content":` + content + `}`),
+			}
+			msg.ParseContent()
+			session.AddMessage(msg)
+		}
+		project.AddSession(session)
+	}
+	project.AddSession(&models.Session{ID: "empty"})
+	project.AddTodoList(&models.TodoList{SessionID: "s1", AgentID: "s1", Todos: []*models.Todo{{ID: "1", Content: "Write tests", Status: models.TodoStatusPending, Priority: models.TodoPriorityHigh}}})
+
+	tests := []struct {
+		name    string
+		project *models.Project
+		pretty  bool
+	}{
+		{"pretty", project, true},
+		{"compact", project, false},
+		{"no sessions pretty", models.NewProject("-Users-empty"), true},
+		{"no sessions compact", models.NewProject("-Users-empty"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewJSONConverter(&JSONOptions{PrettyPrint: tt.pretty, OmitEmpty: true})
+
+			buffered, err := converter.ConvertProject(tt.project)
+			if err != nil {
+				t.Fatalf("ConvertProject() error = %v", err)
+			}
+
+			var streamed bytes.Buffer
+			if err := converter.StreamProject(&streamed, tt.project); err != nil {
+				t.Fatalf("StreamProject() error = %v", err)
+			}
+
+			if streamed.String() != string(buffered) {
+				t.Errorf("Streamed output is not byte-identical:\n%s\nwant:\n%s", streamed.String(), buffered)
+			}
+		})
+	}
+}
+
+func TestJSONConverterLinkToolResults(t *testing.T) {
+	session := createToolSession()
+
+	data, err := NewJSONConverter(&JSONOptions{LinkToolResults: true, OmitEmpty: true}).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	read := result.Messages[0].ToolCalls
+	if len(read) != 1 || read[0].ID != "toolu_1" || read[0].Name != "Read" || string(read[0].Input) != `{"file_path":"main.go"}` {
+		t.Fatalf("Unexpected tool calls %+v", read)
+	}
+	if read[0].Result == nil || read[0].Result.MessageUUID != "msg2" || string(read[0].Result.Content) != `"package main"` {
+		t.Errorf("Unexpected Read result %+v", read[0].Result)
+	}
+	bash := result.Messages[2].ToolCalls
+	if len(bash) != 1 || bash[0].Result == nil || !bash[0].Result.IsError {
+		t.Errorf("Expected the Bash call with its error result, got %+v", bash)
+	}
+	if result.Messages[1].ToolCalls != nil {
+		t.Error("User messages should have no tool calls")
+	}
+
+	// Without the option the output is unchanged
+	data, err = NewJSONConverter(nil).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	if strings.Contains(string(data), "tool_calls") {
+		t.Error("tool_calls should only be included with LinkToolResults")
+	}
+}
+
+func TestJSONConverterSplitReasoning(t *testing.T) {
+	session := &models.Session{ID: "reasoning-session"}
+	msg := &models.Message{
+		UUID: "msg1",
+		Type: models.MessageTypeAssistant,
+		Message: json.RawMessage(`{
+			"role": "assistant",
+			"model": "claude-3",
+			"content": [
+				{"type": "thinking", "thinking": "Consider the edge cases first."},
+				{"type": "text", "text": "Use a nil check."}
+			]
+		}`),
+	}
+	msg.ParseContent()
+	session.AddMessage(msg)
+
+	converter := NewJSONConverter(&JSONOptions{SplitReasoning: true})
+	data, err := converter.ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+
+	if result.Messages[0].Thinking != "Consider the edge cases first." {
+		t.Errorf("Thinking = %q, want the thinking block", result.Messages[0].Thinking)
+	}
+	if result.Messages[0].Answer != "Use a nil check." {
+		t.Errorf("Answer = %q, want the text block", result.Messages[0].Answer)
+	}
+
+	// Thinking words are counted apart from the total
+	if wc := result.WordCount; wc == nil || wc.Assistant != 4 || wc.Thinking != 5 || wc.Total != 4 {
+		t.Errorf("WordCount = %+v, want 4 assistant and 5 thinking words", wc)
+	}
+}
+
+func TestJSONConverterSkipToolMessages(t *testing.T) {
+	session := createMixedToolSession()
+
+	data, err := NewJSONConverter(&JSONOptions{SkipToolMessages: true, LinkToolResults: true, OmitEmpty: true}).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	if strings.Contains(string(data), "tool_use") || strings.Contains(string(data), "tool_result") || strings.Contains(string(data), "tool_calls") {
+		t.Errorf("Output should not contain tool calls or results:\n%s", data)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if result.MessageCount != 6 || len(result.Messages) != 3 {
+		t.Errorf("Got message_count %d with %d messages, want 6 with 3", result.MessageCount, len(result.Messages))
+	}
+	if result.Messages[1].UUID != "msg2" || !strings.Contains(string(data), "Let me look.") {
+		t.Errorf("Expected the text of msg2 to be kept, got %+v", result.Messages[1])
+	}
+
+	// Streaming leaves out the same messages
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(session)
+	converter := NewJSONConverter(&JSONOptions{SkipToolMessages: true, OmitEmpty: true})
+	var buf bytes.Buffer
+	if err := converter.StreamProject(&buf, project); err != nil {
+		t.Fatalf("StreamProject() error = %v", err)
+	}
+	converted, err := converter.ConvertProject(project)
+	if err != nil {
+		t.Fatalf("ConvertProject() error = %v", err)
+	}
+	if buf.String() != string(converted) {
+		t.Errorf("StreamProject() = %s, want %s", buf.String(), converted)
+	}
+
+	// The session is not modified
+	if n := len(session.Messages[1].Content.(*models.AssistantMessage).Content); n != 2 {
+		t.Errorf("Assistant message has %d blocks after export, want 2", n)
+	}
+}
+
+func TestJSONConverterMaxMessages(t *testing.T) {
+	session := createMixedToolSession()
+
+	data, err := NewJSONConverter(&JSONOptions{MaxMessages: 2, OmitEmpty: true}).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if !result.Truncated || result.OmittedCount != 4 || result.MessageCount != 6 {
+		t.Errorf("Got truncated %v, omitted_count %d, message_count %d; want true, 4, 6", result.Truncated, result.OmittedCount, result.MessageCount)
+	}
+	if len(result.Messages) != 2 || result.Messages[0].UUID != "msg1" || result.Messages[1].UUID != "msg2" {
+		t.Errorf("Expected msg1 and msg2, got %+v", result.Messages)
+	}
+
+	// Streaming marks the session the same way
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(session)
+	converter := NewJSONConverter(&JSONOptions{MaxMessages: 2, OmitEmpty: true})
+	var buf bytes.Buffer
+	if err := converter.StreamProject(&buf, project); err != nil {
+		t.Fatalf("StreamProject() error = %v", err)
+	}
+	converted, err := converter.ConvertProject(project)
+	if err != nil {
+		t.Fatalf("ConvertProject() error = %v", err)
+	}
+	if buf.String() != string(converted) {
+		t.Errorf("StreamProject() = %s, want %s", buf.String(), converted)
+	}
+
+	// Sessions within the limit are not marked
+	data, err = NewJSONConverter(&JSONOptions{MaxMessages: 6}).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	if strings.Contains(string(data), "truncated") || strings.Contains(string(data), "omitted_count") {
+		t.Errorf("Unexpected truncation fields:\n%s", data)
+	}
+}
+
+func TestJSONConverterGitBranches(t *testing.T) {
+	session := createMixedToolSession()
+	converter := NewJSONConverter(&JSONOptions{OmitEmpty: true})
+
+	data, err := converter.ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	if strings.Contains(string(data), "git_branches") {
+		t.Errorf("git_branches included without gitBranch:\n%s", data)
+	}
+
+	session.Messages[0].GitBranch = "main"
+	session.Messages[3].GitBranch = "feature/x"
+	data, err = converter.ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if !reflect.DeepEqual(result.GitBranches, []string{"main", "feature/x"}) {
+		t.Errorf("GitBranches = %v, want [main feature/x]", result.GitBranches)
+	}
+}
+
+func TestJSONConverterSourceFile(t *testing.T) {
+	session := &models.Session{ID: "s1", SourceFile: "/home/me/.claude/projects/-work-api/s1.jsonl"}
+	
+	data, err := NewJSONConverter(&JSONOptions{IncludeSourceFile: true}).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if result.SourceFile != session.SourceFile {
+		t.Errorf("SourceFile = %q, want %q", result.SourceFile, session.SourceFile)
+	}
+	
+	data, err = NewJSONConverter(nil).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	if strings.Contains(string(data), "source_file") {
+		t.Errorf("ConvertSession() = %s, want no source_file by default", data)
+	}
+}
+
+func TestJSONConverterTimestampOffset(t *testing.T) {
+	// A local time keeps its offset instead of being stamped as UTC
+	var msg models.Message
+	line := `{"uuid":"m1","sessionId":"s1","type":"user","userType":"external","timestamp":"2024-03-01T10:00:00+08:00","message":{"role":"user","content":"Hello"}}`
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		t.Fatalf("Failed to unmarshal message: %v", err)
+	}
+	msg.ParseContent()
+	session := &models.Session{ID: "s1"}
+	session.AddMessage(&msg)
+
+	data, err := NewJSONConverter(nil).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var exported JSONSession
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Failed to unmarshal session: %v", err)
+	}
+	for name, got := range map[string]string{
+		"start_time": exported.StartTime,
+		"end_time":   exported.EndTime,
+		"timestamp":  exported.Messages[0].Timestamp,
+	} {
+		if got != "2024-03-01T10:00:00+08:00" {
+			t.Errorf("%s = %q, want 2024-03-01T10:00:00+08:00", name, got)
+		}
+		parsed, err := time.Parse(time.RFC3339, got)
+		if err != nil || !parsed.Equal(msg.Timestamp) {
+			t.Errorf("%s = %q does not round-trip to %v", name, got, msg.Timestamp)
+		}
+	}
+}
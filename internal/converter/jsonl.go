@@ -0,0 +1,143 @@
+package converter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// JSONLConverter writes sessions and projects as JSON Lines: one compact JSON
+// object per message, in the format of JSONMessage, so exports can be read
+// back line by line
+type JSONLConverter struct {
+	json *JSONConverter
+}
+
+// JSONLProject is the line written before the messages of a project
+type JSONLProject struct {
+	Type         string `json:"type"` // Always "project"
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	EncodedPath  string `json:"encoded_path"`
+	SessionCount int    `json:"session_count"`
+	MessageCount int    `json:"message_count"`
+}
+
+// JSONLMessage is a message line of a project, tagged with the project ID
+type JSONLMessage struct {
+	ProjectID string `json:"project_id"`
+	*JSONMessage
+}
+
+// JSONLTruncation is the line written after the messages of a session cut to
+// MaxMessages
+type JSONLTruncation struct {
+	Type         string `json:"type"` // Always "truncated"
+	ProjectID    string `json:"project_id,omitempty"`
+	SessionID    string `json:"session_id"`
+	OmittedCount int    `json:"omitted_count"`
+}
+
+// NewJSONLConverter creates a new JSON Lines converter. The JSON options apply
+// to each message as for JSON output, except PrettyPrint.
+func NewJSONLConverter(options *JSONOptions) *JSONLConverter {
+	if options == nil {
+		options = &JSONOptions{OmitEmpty: true}
+	}
+	jsonOpts := *options
+	jsonOpts.PrettyPrint = false
+	return &JSONLConverter{
+		json: NewJSONConverter(&jsonOpts),
+	}
+}
+
+// WriteSession writes one line per message of the session, followed by a
+// truncation line if messages were cut
+func (c *JSONLConverter) WriteSession(w io.Writer, session *models.Session) error {
+	encoder := json.NewEncoder(w)
+	calls := c.json.toolCalls(session)
+	messages, omitted := c.json.messages(session)
+	for _, msg := range messages {
+		if err := encoder.Encode(c.json.messageToJSON(msg, session, calls)); err != nil {
+			return err
+		}
+	}
+	return c.writeTruncation(encoder, "", session, omitted)
+}
+
+// WriteProject writes a project line followed by one line per message of
+// each session, tagged with the project ID, and a truncation line after each
+// session whose messages were cut
+func (c *JSONLConverter) WriteProject(w io.Writer, project *models.Project) error {
+	return c.WriteProjectContext(context.Background(), w, project)
+}
+
+// WriteProjectContext is like WriteProject but checks ctx before each
+// session, returning ctx's error once it is done
+func (c *JSONLConverter) WriteProjectContext(ctx context.Context, w io.Writer, project *models.Project) error {
+	encoder := json.NewEncoder(w)
+	header := &JSONLProject{
+		Type:         "project",
+		ID:           project.ID,
+		Name:         project.GetProjectName(),
+		Path:         project.Path,
+		EncodedPath:  project.EncodedPath,
+		SessionCount: project.GetSessionCount(),
+		MessageCount: project.GetTotalMessages(),
+	}
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+
+	for _, session := range project.Sessions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		calls := c.json.toolCalls(session)
+		messages, omitted := c.json.messages(session)
+		for _, msg := range messages {
+			line := &JSONLMessage{
+				ProjectID:   project.ID,
+				JSONMessage: c.json.messageToJSON(msg, session, calls),
+			}
+			if err := encoder.Encode(line); err != nil {
+				return err
+			}
+		}
+		if err := c.writeTruncation(encoder, project.ID, session, omitted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTruncation writes a truncation line if omitted messages of the
+// session were cut
+func (c *JSONLConverter) writeTruncation(encoder *json.Encoder, projectID string, session *models.Session, omitted int) error {
+	if omitted == 0 {
+		return nil
+	}
+	return encoder.Encode(&JSONLTruncation{
+		Type:         "truncated",
+		ProjectID:    projectID,
+		SessionID:    session.ID,
+		OmittedCount: omitted,
+	})
+}
+
+// WriteProjects writes each project as WriteProject does, checking ctx
+// before each session
+func (c *JSONLConverter) WriteProjects(ctx context.Context, w io.Writer, projects []*models.Project) error {
+	for _, project := range projects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := c.WriteProjectContext(ctx, w, project); err != nil {
+			return err
+		}
+	}
+	return nil
+}
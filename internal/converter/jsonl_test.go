@@ -0,0 +1,89 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func TestJSONLConverter(t *testing.T) {
+	session := &models.Session{ID: "session1", ProjectID: "-Users-test-project"}
+	for _, msg := range []*models.Message{
+		{UUID: "msg1", SessionID: "session1", Type: models.MessageTypeUser, UserType: "external", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), Message: json.RawMessage(`{"role":"user","content":"Hello"}`)},
+		{UUID: "msg2", SessionID: "session1", Type: models.MessageTypeAssistant, Timestamp: time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC), Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Hi\nthere"}]}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(session)
+	converter := NewJSONLConverter(&JSONOptions{PrettyPrint: true})
+
+	var buf bytes.Buffer
+	if err := converter.WriteSession(&buf, session); err != nil {
+		t.Fatalf("WriteSession() error = %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 3 || lines[2] != "" {
+		t.Fatalf("Expected 2 lines ending in a newline, got %q", buf.String())
+	}
+	var msg JSONMessage
+	if err := json.Unmarshal([]byte(lines[1]), &msg); err != nil {
+		t.Fatalf("Invalid message line %q: %v", lines[1], err)
+	}
+	if msg.UUID != "msg2" || msg.SessionID != "session1" || msg.Type != "assistant" {
+		t.Errorf("Message line = %+v, want msg2 of session1", msg)
+	}
+
+	buf.Reset()
+	if err := converter.WriteProjects(context.Background(), &buf, []*models.Project{project, project}); err != nil {
+		t.Fatalf("WriteProjects() error = %v", err)
+	}
+	lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected a header and 2 messages per project, got %q", buf.String())
+	}
+	var header JSONLProject
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("Invalid header line %q: %v", lines[0], err)
+	}
+	if header.Type != "project" || header.ID != project.ID || header.MessageCount != 2 {
+		t.Errorf("Header line = %+v", header)
+	}
+	var tagged map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &tagged); err != nil {
+		t.Fatalf("Invalid message line %q: %v", lines[1], err)
+	}
+	if tagged["project_id"] != project.ID || tagged["session_id"] != "session1" || tagged["uuid"] != "msg1" {
+		t.Errorf("Message line = %v, want msg1 tagged with project and session", tagged)
+	}
+}
+
+func TestJSONLConverterMaxMessages(t *testing.T) {
+	session := createMixedToolSession()
+	session.ProjectID = "-Users-test-project"
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(session)
+
+	var buf bytes.Buffer
+	if err := NewJSONLConverter(&JSONOptions{MaxMessages: 2}).WriteProject(&buf, project); err != nil {
+		t.Fatalf("WriteProject() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header, 2 messages and a truncation line, got %q", buf.String())
+	}
+	var truncation JSONLTruncation
+	if err := json.Unmarshal([]byte(lines[3]), &truncation); err != nil {
+		t.Fatalf("Invalid truncation line %q: %v", lines[3], err)
+	}
+	want := JSONLTruncation{Type: "truncated", ProjectID: project.ID, SessionID: "mixed", OmittedCount: 4}
+	if truncation != want {
+		t.Errorf("Truncation line = %+v, want %+v", truncation, want)
+	}
+}
@@ -1,13 +1,35 @@
 package converter
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/eternnoir/cc-history-export/internal/models"
 )
 
+// emptyMessagePlaceholder is rendered for messages with null or missing content
+const emptyMessagePlaceholder = "*[empty message]*"
+
+// UserContentMode controls how Markdown in user messages is rendered
+type UserContentMode string
+
+const (
+	// UserContentRaw renders user messages as Markdown
+	UserContentRaw UserContentMode = "raw"
+	// UserContentEscape escapes Markdown syntax so it is shown literally
+	UserContentEscape UserContentMode = "escape"
+	// UserContentQuote renders user messages in a blockquote
+	UserContentQuote UserContentMode = "quote"
+	// UserContentFence renders user messages in a fenced code block
+	UserContentFence UserContentMode = "fence"
+)
+
 // MarkdownConverter converts sessions and projects to Markdown format
 type MarkdownConverter struct {
 	options MarkdownOptions
@@ -21,8 +43,61 @@ type MarkdownOptions struct {
 	ShowTokenUsage bool
 	// Include thinking/internal content
 	ShowThinking bool
+	// Maximum number of characters shown of each thinking block, followed
+	// by a note of its full length (0 = no limit)
+	MaxThinkingChars int
 	// Include message UUIDs
 	ShowUUIDs bool
+	// Render each assistant turn as separate Reasoning and Answer sections
+	SplitReasoning bool
+	// Number of keywords to show as tags per session (0 = none)
+	KeywordCount int
+	// Number tool calls and link each tool result to its call
+	NumberToolCalls bool
+	// Collapse a first user message longer than this many characters,
+	// keeping its last paragraph visible (0 = never)
+	CollapsePreambleLength int
+	// Show an estimated reading time in session headers, reading at this
+	// many words per minute (0 = hidden)
+	ReadingWPM int
+	// Show the running total of tokens used so far in the session after
+	// each assistant message
+	CumulativeTokens bool
+	// Render each subagent's messages and todos in a collapsible section
+	// instead of interleaved with the main conversation
+	GroupSubagents bool
+	// Show message times as offsets from the session start (+02:15) and
+	// omit absolute dates and times
+	RelativeTimestamps bool
+	// How to render Markdown in user messages so it cannot break the
+	// document structure ("" = UserContentRaw)
+	UserContent UserContentMode
+	// Maximum number of lines shown of each tool result (0 = no limit)
+	MaxToolResultLines int
+	// Show each tool result under its call in a collapsible block instead of
+	// in the following user message (see Session.LinkToolResults)
+	LinkToolResults bool
+	// Render messages in conversation tree order (see Session.BuildTree),
+	// indenting alternative branches as blockquotes; the last reply to a
+	// message continues at its level
+	ShowBranches bool
+	// Render the sessions of a project as one chronological stream of
+	// messages without per-session headers, with a heading whenever the day
+	// changes. ShowBranches and GroupSubagents do not apply.
+	MergeSessions bool
+	// Leave out tool_use blocks and messages holding only tool calls or
+	// tool results; session counts and tool usage still include them
+	SkipToolMessages bool
+	// Maximum number of messages rendered per session, the earliest by
+	// timestamp, followed by a note of how many were left out (0 = no
+	// limit). With MergeSessions it applies to the whole conversation.
+	MaxMessages int
+	// Name the JSONL file each session was read from in a footnote of the
+	// session heading
+	IncludeSourceFile bool
+	// Layout of rendered times: a Go layout or rfc3339, unix or kitchen (""
+	// = RFC3339 in session headers, 2006-01-02 15:04:05 elsewhere)
+	TimeFormat string
 }
 
 // NewMarkdownConverter creates a new Markdown converter
@@ -40,56 +115,402 @@ func NewMarkdownConverter(options *MarkdownOptions) *MarkdownConverter {
 
 // ConvertSession converts a session to Markdown format
 func (c *MarkdownConverter) ConvertSession(session *models.Session) string {
+	return c.convertSession(session, nil)
+}
+
+// convertSession converts a session, attaching the subagent todos found in
+// todoLists to their subagent sections
+func (c *MarkdownConverter) convertSession(session *models.Session, todoLists []*models.TodoList) string {
 	var sb strings.Builder
 
-	// Session header
-	sb.WriteString(fmt.Sprintf("# Session: %s\n\n", session.ID))
+	// Session header, with a footnote naming the source file; labels are
+	// unique across the sessions of a project
+	footnote := ""
+	if c.options.IncludeSourceFile && session.SourceFile != "" {
+		footnote = "[^source-" + session.ID + "]"
+	}
+	sb.WriteString(fmt.Sprintf("# Session: %s%s\n\n", session.ID, footnote))
 	
 	if !session.StartTime.IsZero() {
-		sb.WriteString(fmt.Sprintf("**Started:** %s  \n", session.StartTime.Format(time.RFC3339)))
-		sb.WriteString(fmt.Sprintf("**Ended:** %s  \n", session.EndTime.Format(time.RFC3339)))
+		if !c.options.RelativeTimestamps {
+			sb.WriteString(fmt.Sprintf("**Started:** %s  \n", formatTime(session.StartTime, c.options.TimeFormat, time.RFC3339)))
+			sb.WriteString(fmt.Sprintf("**Ended:** %s  \n", formatTime(session.EndTime, c.options.TimeFormat, time.RFC3339)))
+		}
 		sb.WriteString(fmt.Sprintf("**Duration:** %s  \n", session.GetDuration()))
 	}
 	
+	if branches := session.GetGitBranches(); len(branches) == 1 {
+		sb.WriteString(fmt.Sprintf("**Git Branch:** `%s`  \n", branches[0]))
+	} else if len(branches) > 1 {
+		sb.WriteString(fmt.Sprintf("**Git Branches:** `%s`  \n", strings.Join(branches, "`, `")))
+	}
+	
 	sb.WriteString(fmt.Sprintf("**Messages:** %d  \n", session.GetMessageCount()))
 	
+	// Thinking words count only when the thinking is shown
+	userWords, assistantWords := session.GetWordCount()
+	if c.options.ShowThinking {
+		assistantWords += session.GetThinkingWordCount()
+	}
+	if userWords > 0 || assistantWords > 0 {
+		sb.WriteString(fmt.Sprintf("**Words:** %d (user: %d, assistant: %d)  \n", userWords+assistantWords, userWords, assistantWords))
+	}
+	
+	if c.options.ReadingWPM > 0 {
+		sb.WriteString(fmt.Sprintf("**Reading Time:** %s  \n", formatReadingTime(session.GetReadingTime(c.options.ReadingWPM))))
+	}
+	
+	if keywords := session.GetTopKeywords(c.options.KeywordCount); len(keywords) > 0 {
+		sb.WriteString(fmt.Sprintf("**Tags:** `%s`  \n", strings.Join(keywords, "` `")))
+	}
+	
 	if c.options.ShowTokenUsage {
 		inputTokens, outputTokens := session.GetTokenUsage()
 		if inputTokens > 0 || outputTokens > 0 {
-			sb.WriteString(fmt.Sprintf("**Token Usage:** Input: %d, Output: %d  \n", inputTokens, outputTokens))
+			sb.WriteString(fmt.Sprintf("**Token Usage:** Input: %d, Output: %d", inputTokens, outputTokens))
+			if thinking := session.GetThinkingTokens(); thinking > 0 {
+				sb.WriteString(fmt.Sprintf(" (thinking: ~%d)", thinking))
+			}
+			sb.WriteString("  \n")
 		}
+		writeCacheUsage(&sb, "Cache Tokens", session.GetUsageTotals())
 	}
 	
+	writeToolUsage(&sb, session.GetToolUsageStats())
+	
 	sb.WriteString("\n---\n\n")
 
+	state := &sessionState{session: session}
+	if c.options.NumberToolCalls {
+		state.toolNumbers = session.GetToolCallNumbers()
+	}
+	if c.options.CollapsePreambleLength > 0 {
+		state.preamble = firstUserMessage(session)
+	}
+	if c.options.LinkToolResults {
+		state.toolCalls = session.LinkToolResults()
+	}
+	state.omitted = c.omittedMessages(session.Messages)
+	
+	// Subagent sections are rendered in place of their first message
+	subagents := make(map[*models.Message]*models.Subagent)
+	if c.options.GroupSubagents {
+		for _, subagent := range session.GetSubagents(todoLists) {
+			for _, msg := range subagent.Messages {
+				subagents[msg] = subagent
+			}
+		}
+	}
+	rendered := make(map[*models.Subagent]bool)
+	
 	// Convert each message
-	for i, msg := range session.Messages {
-		if i > 0 {
-			sb.WriteString("\n---\n\n")
+	prevDepth := -1
+	for _, entry := range c.messageOrder(session) {
+		msg := entry.msg
+		subagent := subagents[msg]
+		if rendered[subagent] || c.skipMessage(msg, state) {
+			continue
 		}
-		sb.WriteString(c.ConvertMessage(msg))
+		if prevDepth >= 0 {
+			sb.WriteString(blockquote("\n---\n\n", min(prevDepth, entry.depth)))
+		}
+		prevDepth = entry.depth
+		if subagent != nil {
+			rendered[subagent] = true
+			sb.WriteString(blockquote(c.convertSubagent(subagent, state), entry.depth))
+			continue
+		}
+		sb.WriteString(blockquote(c.convertMessage(msg, state), entry.depth))
+	}
+	sb.WriteString(omissionNote(len(state.omitted)))
+	if footnote != "" {
+		sb.WriteString(fmt.Sprintf("\n%s: Source: `%s`\n", footnote, session.SourceFile))
+	}
+
+	return sb.String()
+}
+
+// branchEntry is a message with the number of branch levels it is indented
+type branchEntry struct {
+	msg   *models.Message
+	depth int
+}
+
+// messageOrder returns the session's messages in file order, or in tree
+// order with branch depths if ShowBranches is set
+func (c *MarkdownConverter) messageOrder(session *models.Session) []branchEntry {
+	entries := make([]branchEntry, 0, len(session.Messages))
+	if !c.options.ShowBranches {
+		for _, msg := range session.Messages {
+			entries = append(entries, branchEntry{msg: msg})
+		}
+		return entries
+	}
+
+	var walk func(node *models.MessageNode, depth int)
+	walk = func(node *models.MessageNode, depth int) {
+		if node.Message != nil {
+			entries = append(entries, branchEntry{msg: node.Message, depth: depth})
+		}
+		for i, child := range node.Children {
+			// Earlier replies are alternative branches; the orphans held by
+			// the synthetic root are unrelated and stay at its level
+			childDepth := depth
+			if node.Message != nil && i < len(node.Children)-1 {
+				childDepth++
+			}
+			walk(child, childDepth)
+		}
+	}
+	for _, root := range session.BuildTree() {
+		walk(root, 0)
+	}
+	return entries
+}
+
+// blockquote nests each line of text in depth levels of blockquote
+func blockquote(text string, depth int) string {
+	if depth == 0 {
+		return text
+	}
+	prefix := strings.Repeat(">", depth)
+	body, trailingNewline := strings.CutSuffix(text, "\n")
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = prefix
+		} else {
+			lines[i] = prefix + " " + line
+		}
+	}
+	quoted := strings.Join(lines, "\n")
+	if trailingNewline {
+		quoted += "\n"
 	}
+	return quoted
+}
 
+// convertSubagent renders the messages and todos of a subagent in a
+// collapsible section
+func (c *MarkdownConverter) convertSubagent(subagent *models.Subagent, state *sessionState) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<details>\n<summary>🤖 Subagent <code>%s</code> (%d messages)</summary>\n\n", subagent.ID, len(subagent.Messages)))
+	first := true
+	for _, msg := range subagent.Messages {
+		if c.skipMessage(msg, state) {
+			continue
+		}
+		if !first {
+			sb.WriteString("\n---\n\n")
+		}
+		first = false
+		sb.WriteString(c.convertMessage(msg, state))
+	}
+	for _, todoList := range subagent.TodoLists {
+		sb.WriteString("\n")
+		sb.WriteString(c.ConvertTodoList(todoList))
+	}
+	sb.WriteString("\n</details>\n")
 	return sb.String()
 }
 
+// writeCacheUsage writes a header line with the cache reads, which are part
+// of the input tokens, and the cache creation tokens of usage, unless both
+// are zero
+func writeCacheUsage(sb *strings.Builder, label string, usage models.Usage) {
+	if usage.CacheReadInputTokens == 0 && usage.CacheCreationInputTokens == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("**%s:** Read: %d, Creation: %d  \n", label, usage.CacheReadInputTokens, usage.CacheCreationInputTokens))
+}
+
+// writeToolUsage writes a "Tools Used" list of tool call counts, most used
+// first. It must be the last line of a header, as a following line would
+// continue the list.
+func writeToolUsage(sb *strings.Builder, stats map[string]int) {
+	if len(stats) == 0 {
+		return
+	}
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if stats[names[i]] != stats[names[j]] {
+			return stats[names[i]] > stats[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	sb.WriteString("**Tools Used:**\n")
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf("- `%s`: %d\n", name, stats[name]))
+	}
+}
+
+// sessionState holds what ConvertSession knows about the whole session when
+// rendering each of its messages
+type sessionState struct {
+	// Session being rendered, if any
+	session *models.Session
+	// Tool call numbers by tool_use ID
+	toolNumbers map[string]int
+	// First user message, whose preamble may be collapsed
+	preamble *models.Message
+	// Messages matching a search, marked in their header
+	matches map[*models.Message]bool
+	// Tokens used by the messages rendered so far
+	tokens int
+	// Tool calls linked to their results, shown together
+	toolCalls map[string]*models.ToolCall
+	// Messages left out by MaxMessages
+	omitted map[*models.Message]bool
+}
+
+// linkedResult returns the result shown under a tool call, if any
+func (s *sessionState) linkedResult(msg *models.Message, toolUseID string) *models.ToolResult {
+	if call, ok := s.toolCalls[toolUseID]; ok && call.Message == msg {
+		return call.Result
+	}
+	return nil
+}
+
+// isShownWithCall checks if a tool result of msg is shown under its call
+func (s *sessionState) isShownWithCall(msg *models.Message, result models.ToolResult) bool {
+	call, ok := s.toolCalls[result.ToolUseID]
+	return ok && call.ResultMessage == msg
+}
+
+// resultsShownWithCalls checks if msg only holds tool results shown under
+// their calls, so it is not rendered itself
+func (s *sessionState) resultsShownWithCalls(msg *models.Message) bool {
+	results, ok := msg.Content.([]models.ToolResult)
+	if !ok || len(results) == 0 {
+		return false
+	}
+	for _, result := range results {
+		if !s.isShownWithCall(msg, result) {
+			return false
+		}
+	}
+	return true
+}
+
+// skipMessage checks if msg is not rendered on its own: it only holds tool
+// results shown under their calls, or tool messages are skipped and it only
+// holds tool calls or results
+func (c *MarkdownConverter) skipMessage(msg *models.Message, state *sessionState) bool {
+	if state.omitted[msg] || c.options.SkipToolMessages && isToolOnly(msg, c.showsThinking()) {
+		return true
+	}
+	return state.resultsShownWithCalls(msg)
+}
+
+// showsThinking checks if the thinking of assistant messages is rendered,
+// inline or in a reasoning section
+func (c *MarkdownConverter) showsThinking() bool {
+	return c.options.ShowThinking || c.options.SplitReasoning
+}
+
+// omittedMessages returns the messages left out by MaxMessages: the rendered
+// messages after the earliest MaxMessages by timestamp
+func (c *MarkdownConverter) omittedMessages(messages []*models.Message) map[*models.Message]bool {
+	if c.options.MaxMessages <= 0 {
+		return nil
+	}
+	if c.options.SkipToolMessages {
+		messages = withoutToolMessages(messages, c.showsThinking())
+	}
+	first := models.FirstMessages(messages, c.options.MaxMessages)
+	if len(first) == len(messages) {
+		return nil
+	}
+	kept := make(map[*models.Message]bool, len(first))
+	for _, msg := range first {
+		kept[msg] = true
+	}
+	omitted := make(map[*models.Message]bool, len(messages)-len(first))
+	for _, msg := range messages {
+		if !kept[msg] {
+			omitted[msg] = true
+		}
+	}
+	return omitted
+}
+
+// omissionNote renders the note closing a session whose messages were cut
+// to MaxMessages, or "" if none were
+func omissionNote(omitted int) string {
+	switch omitted {
+	case 0:
+		return ""
+	case 1:
+		return "\n---\n\n*… 1 more message omitted*\n"
+	}
+	return fmt.Sprintf("\n---\n\n*… %d more messages omitted*\n", omitted)
+}
+
+// convertSummary renders a conversation summary in a collapsible block
+func convertSummary(summary *models.SummaryMessage) string {
+	text := summary.Summary
+	if text == "" {
+		text = emptyMessagePlaceholder
+	}
+	return fmt.Sprintf("<details>\n<summary>📝 Session Summary</summary>\n\n%s\n\n</details>\n", text)
+}
+
+// firstUserMessage returns the first user message with text content
+func firstUserMessage(session *models.Session) *models.Message {
+	for _, msg := range session.Messages {
+		if userMsg, ok := msg.Content.(*models.UserMessage); ok && userMsg.Content != "" {
+			return msg
+		}
+	}
+	return nil
+}
+
 // ConvertMessage converts a single message to Markdown format
 func (c *MarkdownConverter) ConvertMessage(msg *models.Message) string {
+	return c.convertMessage(msg, &sessionState{})
+}
+
+// convertMessage converts a message using the state of its session
+func (c *MarkdownConverter) convertMessage(msg *models.Message, state *sessionState) string {
+	if summary, ok := msg.Content.(*models.SummaryMessage); ok {
+		return convertSummary(summary)
+	}
+
+	toolNumbers := state.toolNumbers
 	var sb strings.Builder
 
 	// Message header
 	switch msg.Type {
 	case models.MessageTypeUser:
-		sb.WriteString("### 👤 User\n\n")
+		sb.WriteString("### 👤 User")
 	case models.MessageTypeAssistant:
-		sb.WriteString("### 🤖 Assistant\n\n")
+		sb.WriteString("### 🤖 Assistant")
 	default:
-		sb.WriteString(fmt.Sprintf("### %s\n\n", msg.Type))
+		sb.WriteString(fmt.Sprintf("### %s", msg.Type))
+	}
+	if msg.Regenerated {
+		sb.WriteString(" (regenerated)")
+	}
+	if state.matches[msg] {
+		sb.WriteString(" 🔎 Match")
 	}
+	sb.WriteString("\n\n")
 
 	// Metadata
 	if c.options.ShowTimestamps && !msg.Timestamp.IsZero() {
-		sb.WriteString(fmt.Sprintf("*%s*  \n", msg.Timestamp.Format("2006-01-02 15:04:05")))
+		if c.options.RelativeTimestamps {
+			var offset time.Duration
+			if state.session != nil {
+				offset = state.session.GetMessageOffset(msg)
+			}
+			sb.WriteString(fmt.Sprintf("*%s*  \n", formatOffset(offset)))
+		} else {
+			sb.WriteString(fmt.Sprintf("*%s*  \n", formatTime(msg.Timestamp, c.options.TimeFormat, markdownTimeLayout)))
+		}
 	}
 	if c.options.ShowUUIDs && msg.UUID != "" {
 		sb.WriteString(fmt.Sprintf("*UUID: %s*  \n", msg.UUID))
@@ -105,14 +526,31 @@ func (c *MarkdownConverter) ConvertMessage(msg *models.Message) string {
 	switch msg.Type {
 	case models.MessageTypeUser:
 		if userMsg, ok := msg.Content.(*models.UserMessage); ok {
-			sb.WriteString(userMsg.Content)
+			if userMsg.Content == "" {
+				sb.WriteString(emptyMessagePlaceholder)
+			} else if msg == state.preamble && len(userMsg.Content) > c.options.CollapsePreambleLength {
+				sb.WriteString(collapsePreamble(userMsg.Content, c.formatUserContent))
+			} else {
+				sb.WriteString(c.formatUserContent(userMsg.Content))
+			}
 			sb.WriteString("\n")
 		} else if toolResults, ok := msg.Content.([]models.ToolResult); ok {
 			sb.WriteString("**Tool Results:**\n\n")
 			for _, result := range toolResults {
-				sb.WriteString(fmt.Sprintf("- Tool: `%s`\n", result.ToolUseID))
-				sb.WriteString(fmt.Sprintf("  - Type: %s\n", result.Type))
-				sb.WriteString(fmt.Sprintf("  - Content: %s\n", string(result.Content)))
+				if state.isShownWithCall(msg, result) {
+					continue
+				}
+				if n, ok := toolNumbers[result.ToolUseID]; ok {
+					sb.WriteString(fmt.Sprintf("- Tool [#%d](#%s): `%s`", n, toolAnchor(result.ToolUseID), result.ToolUseID))
+				} else {
+					sb.WriteString(fmt.Sprintf("- Tool: `%s`", result.ToolUseID))
+				}
+				if result.IsError {
+					sb.WriteString(" ❌ Error")
+				}
+				sb.WriteString("\n\n")
+				sb.WriteString(c.formatToolResult(result))
+				sb.WriteString("\n")
 			}
 		}
 		
@@ -123,6 +561,20 @@ func (c *MarkdownConverter) ConvertMessage(msg *models.Message) string {
 				sb.WriteString(fmt.Sprintf("*Model: %s*\n\n", assistantMsg.Model))
 			}
 			
+			if len(assistantMsg.Content) == 0 {
+				sb.WriteString(emptyMessagePlaceholder)
+				sb.WriteString("\n\n")
+			}
+			
+			if c.options.SplitReasoning {
+				if thinking := assistantMsg.GetThinking(); thinking != "" {
+					sb.WriteString("#### 💭 Reasoning\n\n")
+					sb.WriteString(truncateThinking(thinking, c.options.MaxThinkingChars))
+					sb.WriteString("\n\n")
+				}
+				sb.WriteString("#### 💬 Answer\n\n")
+			}
+			
 			// Content blocks
 			for _, content := range assistantMsg.Content {
 				switch content.Type {
@@ -131,20 +583,37 @@ func (c *MarkdownConverter) ConvertMessage(msg *models.Message) string {
 					sb.WriteString("\n\n")
 					
 				case "thinking":
-					if c.options.ShowThinking {
+					// Already rendered in the reasoning section when split
+					if c.options.ShowThinking && !c.options.SplitReasoning {
 						sb.WriteString("<details>\n<summary>💭 Thinking</summary>\n\n")
-						sb.WriteString(content.Thinking)
+						sb.WriteString(truncateThinking(content.Thinking, c.options.MaxThinkingChars))
 						sb.WriteString("\n\n</details>\n\n")
 					}
 					
 				case "tool_use":
-					sb.WriteString(fmt.Sprintf("**🔧 Tool Use:** `%s`\n\n", content.Name))
+					if c.options.SkipToolMessages {
+						continue
+					}
+					if n, ok := toolNumbers[content.ID]; ok {
+						sb.WriteString(fmt.Sprintf("<a id=\"%s\"></a>**🔧 Tool Use #%d:** `%s`\n\n", toolAnchor(content.ID), n, content.Name))
+					} else {
+						sb.WriteString(fmt.Sprintf("**🔧 Tool Use:** `%s`\n\n", content.Name))
+					}
 					if content.ID != "" {
 						sb.WriteString(fmt.Sprintf("*ID: %s*\n\n", content.ID))
 					}
 					sb.WriteString("```json\n")
 					sb.WriteString(string(content.Input))
 					sb.WriteString("\n```\n\n")
+					if result := state.linkedResult(msg, content.ID); result != nil {
+						summary := "Result"
+						if result.IsError {
+							summary = "❌ Error"
+						}
+						sb.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n", summary))
+						sb.WriteString(c.formatToolResult(*result))
+						sb.WriteString("\n</details>\n\n")
+					}
 					
 				default:
 					sb.WriteString(fmt.Sprintf("**%s:**\n\n", content.Type))
@@ -161,53 +630,356 @@ func (c *MarkdownConverter) ConvertMessage(msg *models.Message) string {
 					assistantMsg.Usage.InputTokens+assistantMsg.Usage.CacheReadInputTokens,
 					assistantMsg.Usage.OutputTokens))
 			}
+			if c.options.CumulativeTokens && assistantMsg.Usage != nil {
+				state.tokens += assistantMsg.Usage.InputTokens + assistantMsg.Usage.CacheReadInputTokens + assistantMsg.Usage.OutputTokens
+				sb.WriteString(fmt.Sprintf("\n*Cumulative: %s tokens*\n", formatCount(state.tokens)))
+			}
 		}
 	}
 
 	return sb.String()
 }
 
+// truncateThinking shortens thinking to limit characters, noting its full
+// length (limit 0 = no limit)
+func truncateThinking(thinking string, limit int) string {
+	if limit <= 0 {
+		return thinking
+	}
+	runes := []rune(thinking)
+	if len(runes) <= limit {
+		return thinking
+	}
+	return string(runes[:limit]) + fmt.Sprintf("…(truncated) [%d characters]", len(runes))
+}
+
+// formatToolResult renders the content of a tool result as a fenced code
+// block: text as is and other content as indented JSON, cut to
+// MaxToolResultLines lines
+func (c *MarkdownConverter) formatToolResult(result models.ToolResult) string {
+	text, ok := result.GetText()
+	lang := "text"
+	if !ok {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, result.Content, "", "  "); err == nil {
+			text = indented.String()
+		} else {
+			text = string(result.Content)
+		}
+		lang = "json"
+	}
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return "*No output*\n"
+	}
+
+	if limit := c.options.MaxToolResultLines; limit > 0 {
+		if lines := strings.Split(text, "\n"); len(lines) > limit {
+			text = strings.Join(lines[:limit], "\n") + fmt.Sprintf("\n… (%d more lines)", len(lines)-limit)
+		}
+	}
+
+	// The fence must be longer than any backtick run in the content
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + text + "\n" + fence + "\n"
+}
+
+// collapsePreamble folds all but the last paragraph of a long message into
+// a collapsible block, so a question following pasted context stays visible.
+// Both parts are rendered with format.
+func collapsePreamble(content string, format func(string) string) string {
+	content = strings.TrimRight(content, "\n")
+	preamble, question := content, ""
+	if i := strings.LastIndex(content, "\n\n"); i >= 0 {
+		preamble, question = content[:i], strings.TrimLeft(content[i:], "\n")
+	}
+	
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<details>\n<summary>📋 Context (%d lines)</summary>\n\n", strings.Count(preamble, "\n")+1))
+	sb.WriteString(format(preamble))
+	sb.WriteString("\n\n</details>\n")
+	if question != "" {
+		sb.WriteString("\n")
+		sb.WriteString(format(question))
+	}
+	return sb.String()
+}
+
+// formatUserContent renders the text of a user message according to the
+// UserContent option
+func (c *MarkdownConverter) formatUserContent(content string) string {
+	switch c.options.UserContent {
+	case UserContentEscape:
+		return escapeMarkdown(content)
+	case UserContentQuote:
+		return blockquote(content, 1)
+	case UserContentFence:
+		// The fence must be longer than any backtick run in the content
+		fence := "```"
+		for strings.Contains(content, fence) {
+			fence += "`"
+		}
+		return fence + "text\n" + content + "\n" + fence
+	}
+	return content
+}
+
+// markdownEscaper escapes characters with meaning anywhere in a line
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// escapeMarkdown escapes Markdown syntax in text, including list, rule and
+// heading markers at the start of lines, so it renders literally
+func escapeMarkdown(text string) string {
+	lines := strings.Split(markdownEscaper.Replace(text), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed != "" && strings.ContainsRune("-+=", rune(trimmed[0])) {
+			indent := len(line) - len(trimmed)
+			lines[i] = line[:indent] + `\` + trimmed
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatReadingTime formats a reading time in whole minutes, e.g. "~5 min read"
+func formatReadingTime(d time.Duration) string {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	return fmt.Sprintf("~%d min read", max(minutes, 1))
+}
+
+// formatOffset formats an offset from the session start as +MM:SS, or
+// +H:MM:SS from one hour on
+func formatOffset(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds < 0 {
+		seconds = 0
+	}
+	if seconds >= 3600 {
+		return fmt.Sprintf("+%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("+%02d:%02d", seconds/60, seconds%60)
+}
+
+// formatCount formats a number with thousands separators, e.g. "12,430"
+func formatCount(n int) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	digits := strconv.Itoa(n)
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+	return sb.String()
+}
+
+// toolAnchor returns the anchor name of a tool call
+func toolAnchor(toolUseID string) string {
+	return "tool-" + toolUseID
+}
+
 // ConvertProject converts an entire project to Markdown format
 func (c *MarkdownConverter) ConvertProject(project *models.Project) string {
+	markdown, _ := c.ConvertProjectContext(context.Background(), project)
+	return markdown
+}
+
+// ConvertProjectContext is like ConvertProject but checks ctx before each
+// session, or each message of merged sessions, returning ctx's error once
+// it is done
+func (c *MarkdownConverter) ConvertProjectContext(ctx context.Context, project *models.Project) (string, error) {
 	var sb strings.Builder
 
 	// Project header
 	sb.WriteString(fmt.Sprintf("# Project: %s\n\n", project.GetProjectName()))
 	sb.WriteString(fmt.Sprintf("**Path:** `%s`  \n", project.Path))
+	if project.IsStale() {
+		sb.WriteString("**Exists:** no (project directory not found)  \n")
+	}
 	sb.WriteString(fmt.Sprintf("**Sessions:** %d  \n", project.GetSessionCount()))
 	sb.WriteString(fmt.Sprintf("**Total Messages:** %d  \n", project.GetTotalMessages()))
 	
 	if c.options.ShowTokenUsage {
 		inputTokens, outputTokens := project.GetTotalTokenUsage()
 		if inputTokens > 0 || outputTokens > 0 {
-			sb.WriteString(fmt.Sprintf("**Total Token Usage:** Input: %d, Output: %d  \n", inputTokens, outputTokens))
+			sb.WriteString(fmt.Sprintf("**Total Token Usage:** Input: %d, Output: %d", inputTokens, outputTokens))
+			if thinking := project.GetThinkingTokens(); thinking > 0 {
+				sb.WriteString(fmt.Sprintf(" (thinking: ~%d)", thinking))
+			}
+			sb.WriteString("  \n")
 		}
+		writeCacheUsage(&sb, "Total Cache Tokens", project.GetUsageTotals())
 	}
 	
 	start, end := project.GetTimeRange()
-	if !start.IsZero() {
+	if !start.IsZero() && !c.options.RelativeTimestamps {
 		sb.WriteString(fmt.Sprintf("**Date Range:** %s to %s  \n", 
 			start.Format("2006-01-02"), 
 			end.Format("2006-01-02")))
 	}
 	
-	// Todo lists summary
-	if len(project.TodoLists) > 0 {
-		sb.WriteString(fmt.Sprintf("\n## Todo Lists (%d)\n\n", len(project.TodoLists)))
-		for _, todoList := range project.TodoLists {
+	if todoStats := project.GetAggregateTodoStats(); todoStats.Total > 0 {
+		sb.WriteString(fmt.Sprintf("**Overall Todo Completion:** %.0f%% (%d of %d todos)  \n",
+			todoStats.CompletionRate, todoStats.ByStatus[models.TodoStatusCompleted], todoStats.Total))
+	}
+	
+	writeToolUsage(&sb, project.GetToolUsageStats())
+	
+	// The project's own CLAUDE.md, if it was read
+	if project.Config != "" {
+		sb.WriteString("\n## CLAUDE.md\n\n")
+		sb.WriteString(strings.TrimSpace(project.Config))
+		sb.WriteString("\n")
+	}
+	
+	// Todo lists summary, without those shown in subagent sections
+	if todoLists := c.projectTodoLists(project); len(todoLists) > 0 {
+		sb.WriteString(fmt.Sprintf("\n## Todo Lists (%d)\n\n", len(todoLists)))
+		for _, todoList := range todoLists {
 			sb.WriteString(c.ConvertTodoList(todoList))
 			sb.WriteString("\n")
 		}
 	}
 	
+	if len(project.ShellSnapshots) > 0 {
+		sb.WriteString(fmt.Sprintf("\n## Shell Snapshots (%d)\n\n", len(project.ShellSnapshots)))
+		for _, snapshot := range project.ShellSnapshots {
+			sb.WriteString(c.ConvertShellSnapshot(snapshot))
+			sb.WriteString("\n")
+		}
+	}
+	
+	if c.options.MergeSessions {
+		flattened, err := c.convertFlattened(ctx, project)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(flattened)
+		return sb.String(), nil
+	}
+	
 	// Sessions
 	sb.WriteString("\n## Sessions\n\n")
 	for i, session := range project.Sessions {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		if i > 0 {
 			sb.WriteString("\n\n---\n\n")
 		}
-		sb.WriteString(c.ConvertSession(session))
+		sb.WriteString(c.convertSession(session, project.TodoLists))
+	}
+
+	return sb.String(), nil
+}
+
+// projectTodoLists returns the todo lists of the project to list in its own
+// section: all of them, except those of subagents when GroupSubagents renders
+// them in the subagent sections of the sessions
+func (c *MarkdownConverter) projectTodoLists(project *models.Project) []*models.TodoList {
+	if !c.options.GroupSubagents || c.options.MergeSessions {
+		return project.TodoLists
+	}
+	inSubagents := make(map[*models.TodoList]bool)
+	for _, session := range project.Sessions {
+		for _, subagent := range session.GetSubagents(project.TodoLists) {
+			for _, todoList := range subagent.TodoLists {
+				inSubagents[todoList] = true
+			}
+		}
 	}
+	todoLists := make([]*models.TodoList, 0, len(project.TodoLists))
+	for _, todoList := range project.TodoLists {
+		if !inSubagents[todoList] {
+			todoLists = append(todoLists, todoList)
+		}
+	}
+	return todoLists
+}
+
+// convertFlattened renders the messages of all sessions of a project in
+// timestamp order, starting a new heading whenever the day changes
+func (c *MarkdownConverter) convertFlattened(ctx context.Context, project *models.Project) (string, error) {
+	var sb strings.Builder
+	flat := models.FlattenSessions(project.Sessions)
+	state := &sessionState{session: flat}
+	if c.options.NumberToolCalls {
+		state.toolNumbers = flat.GetToolCallNumbers()
+	}
+	if c.options.LinkToolResults {
+		state.toolCalls = flat.LinkToolResults()
+	}
+	state.omitted = c.omittedMessages(flat.Messages)
+
+	sb.WriteString("\n## Conversation\n\n")
+	day := ""
+	first := true
+	for _, msg := range flat.Messages {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if c.skipMessage(msg, state) {
+			continue
+		}
+		// Relative times hide dates, so days are not marked either
+		if !msg.Timestamp.IsZero() && !c.options.RelativeTimestamps {
+			if msgDay := msg.Timestamp.Format("2006-01-02"); msgDay != day {
+				day = msgDay
+				if !first {
+					sb.WriteString("\n")
+				}
+				sb.WriteString(fmt.Sprintf("### %s\n\n", day))
+				first = true
+			}
+		}
+		if !first {
+			sb.WriteString("\n---\n\n")
+		}
+		first = false
+		sb.WriteString(c.convertMessage(msg, state))
+	}
+	sb.WriteString(omissionNote(len(state.omitted)))
+	return sb.String(), nil
+}
+
+// ConvertInstructions renders the content of a CLAUDE.md file as a Project
+// Instructions section, to be placed before an export
+func (c *MarkdownConverter) ConvertInstructions(content string) string {
+	return fmt.Sprintf("## Project Instructions\n\n%s\n\n---\n\n", strings.TrimSpace(content))
+}
+
+// ConvertShellSnapshot converts a shell snapshot to Markdown format, with its
+// content in a collapsible code block
+func (c *MarkdownConverter) ConvertShellSnapshot(snapshot *models.ShellSnapshot) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("### %s\n\n", snapshot.Name))
+	if snapshot.SessionID != "" {
+		sb.WriteString(fmt.Sprintf("*Session: %s*  \n", snapshot.SessionID))
+	}
+	if snapshot.Shell != "" {
+		sb.WriteString(fmt.Sprintf("*Shell: %s*  \n", snapshot.Shell))
+	}
+	sb.WriteString(fmt.Sprintf("*Created: %s*\n\n", formatTime(snapshot.CreatedAt, c.options.TimeFormat, markdownTimeLayout)))
+
+	// The fence must be longer than any backtick run in the content
+	content := strings.TrimRight(snapshot.Content, "\n")
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	sb.WriteString("<details>\n<summary>Snapshot</summary>\n\n")
+	sb.WriteString(fence + "sh\n" + content + "\n" + fence + "\n")
+	sb.WriteString("\n</details>\n")
 
 	return sb.String()
 }
@@ -223,12 +995,22 @@ func (c *MarkdownConverter) ConvertTodoList(todoList *models.TodoList) string {
 	}
 	
 	completionRate := todoList.GetCompletionRate()
-	sb.WriteString(fmt.Sprintf("*Completion: %.0f%%*\n\n", completionRate))
+	sb.WriteString(fmt.Sprintf("*Completion: %.0f%%*", completionRate))
+
+	// Only lists whose todos have priorities get the priority counts
+	high := len(todoList.GetTodosByPriority(models.TodoPriorityHigh))
+	medium := len(todoList.GetTodosByPriority(models.TodoPriorityMedium))
+	low := len(todoList.GetTodosByPriority(models.TodoPriorityLow))
+	if high+medium+low > 0 {
+		sb.WriteString(fmt.Sprintf("  \n*Priority: %d high, %d medium, %d low*", high, medium, low))
+	}
+	sb.WriteString("\n\n")
 
-	// Group todos by status
-	pending := todoList.GetTodosByStatus(models.TodoStatusPending)
-	inProgress := todoList.GetTodosByStatus(models.TodoStatusInProgress)
-	completed := todoList.GetTodosByStatus(models.TodoStatusCompleted)
+	// Group todos by status, highest priority first
+	sorted := &models.TodoList{Todos: todoList.SortedTodos()}
+	pending := sorted.GetTodosByStatus(models.TodoStatusPending)
+	inProgress := sorted.GetTodosByStatus(models.TodoStatusInProgress)
+	completed := sorted.GetTodosByStatus(models.TodoStatusCompleted)
 
 	if len(pending) > 0 {
 		sb.WriteString("#### ⏳ Pending\n\n")
@@ -2,6 +2,7 @@ package converter
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -179,6 +180,43 @@ func TestMarkdownConverterProject(t *testing.T) {
 	if !strings.Contains(markdown, "*Completion: 50%*") {
 		t.Error("Missing completion rate")
 	}
+	
+	if !strings.Contains(markdown, "**Overall Todo Completion:** 50% (1 of 2 todos)") {
+		t.Error("Missing overall todo completion")
+	}
+}
+
+func TestMarkdownConverterTodoPriorityOrder(t *testing.T) {
+	converter := NewMarkdownConverter(nil)
+	markdown := converter.ConvertTodoList(&models.TodoList{
+		SessionID: "session1",
+		Todos: []*models.Todo{
+			{ID: "1", Content: "Task 1", Status: models.TodoStatusPending, Priority: models.TodoPriorityLow},
+			{ID: "2", Content: "Task 2", Status: models.TodoStatusCompleted, Priority: models.TodoPriorityMedium},
+			{ID: "3", Content: "Task 3", Status: models.TodoStatusPending, Priority: models.TodoPriorityHigh},
+			{ID: "4", Content: "Task 4", Status: models.TodoStatusCompleted, Priority: models.TodoPriorityHigh},
+		},
+	})
+
+	// Todos of a status are listed highest priority first
+	if !strings.Contains(markdown, "- [ ] Task 3 (high)\n- [ ] Task 1 (low)\n") {
+		t.Errorf("Missing pending todos in priority order:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "- [x] Task 4 (high)\n- [x] Task 2 (medium)\n") {
+		t.Errorf("Missing completed todos in priority order:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "*Priority: 2 high, 1 medium, 1 low*") {
+		t.Errorf("Missing priority counts:\n%s", markdown)
+	}
+
+	// Todos without priorities get no priority counts
+	markdown = converter.ConvertTodoList(&models.TodoList{
+		SessionID: "session2",
+		Todos:     []*models.Todo{{ID: "1", Content: "Task 1", Status: models.TodoStatusPending}},
+	})
+	if strings.Contains(markdown, "Priority") || !strings.Contains(markdown, "*Completion: 0%*\n\n") {
+		t.Errorf("Unexpected priority line:\n%s", markdown)
+	}
 }
 
 func TestMarkdownConverterOptions(t *testing.T) {
@@ -236,4 +274,811 @@ func TestMarkdownConverterToolResults(t *testing.T) {
 	if !strings.Contains(markdown, "Tool: `tool_123`") {
 		t.Error("Missing tool ID")
 	}
-}
\ No newline at end of file
+	
+	// JSON content is indented in a json block
+	if !strings.Contains(markdown, "```json\n{\n  \"result\": \"success\",") {
+		t.Errorf("Expected indented JSON result. Output:\n%s", markdown)
+	}
+}
+
+func TestMarkdownConverterToolResultText(t *testing.T) {
+	msg := &models.Message{
+		UUID:     "msg1",
+		Type:     models.MessageTypeUser,
+		UserType: "external",
+		Message: json.RawMessage(`{"role":"user","content":[
+			{"tool_use_id":"toolu_1","type":"tool_result","content":"     1\tpackage main\n     2\t\n     3\tfunc main() {}\n     4\t"},
+			{"tool_use_id":"toolu_2","type":"tool_result","content":[{"type":"text","text":"File has ` + "```" + ` fences"}]},
+			{"tool_use_id":"toolu_3","type":"tool_result","content":"command not found","is_error":true}
+		]}`),
+	}
+	msg.ParseContent()
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{MaxToolResultLines: 2}).ConvertMessage(msg)
+
+	for _, want := range []string{
+		"- Tool: `toolu_1`\n\n```text\n     1\tpackage main\n     2\t\n… (2 more lines)\n```\n",
+		"````text\nFile has ``` fences\n````\n",
+		"- Tool: `toolu_3` ❌ Error\n\n```text\ncommand not found\n```\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Missing %q. Output:\n%s", want, markdown)
+		}
+	}
+}
+
+// createToolSession creates a session with a Read call answered in the next
+// message and a Bash call answered together with an unrelated result
+func createToolSession() *models.Session {
+	session := &models.Session{ID: "tools"}
+	for _, raw := range []struct {
+		msgType models.MessageType
+		content string
+	}{
+		{models.MessageTypeAssistant, `{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"main.go"}}]}`},
+		{models.MessageTypeUser, `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"package main"}]}`},
+		{models.MessageTypeAssistant, `{"role":"assistant","content":[{"type":"tool_use","id":"toolu_2","name":"Bash","input":{"command":"ls"}}]}`},
+		{models.MessageTypeUser, `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":"ls: not found","is_error":true},{"type":"tool_result","tool_use_id":"toolu_9","content":"orphan"}]}`},
+	} {
+		msg := &models.Message{
+			UUID:     fmt.Sprintf("msg%d", len(session.Messages)+1),
+			Type:     raw.msgType,
+			UserType: "external",
+			Message:  json.RawMessage(raw.content),
+		}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	return session
+}
+
+func TestMarkdownConverterLinkToolResults(t *testing.T) {
+	session := createToolSession()
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{LinkToolResults: true}).ConvertSession(session)
+
+	for _, want := range []string{
+		"**🔧 Tool Use:** `Read`\n\n*ID: toolu_1*\n\n```json\n{\"file_path\":\"main.go\"}\n```\n\n<details>\n<summary>Result</summary>\n\n```text\npackage main\n```\n\n</details>\n",
+		"<details>\n<summary>❌ Error</summary>\n\n```text\nls: not found\n```\n",
+		"- Tool: `toolu_9`\n\n```text\norphan\n```\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Missing %q. Output:\n%s", want, markdown)
+		}
+	}
+
+	// The message holding only the Read result is folded into its call, the
+	// unrelated result is still shown on its own
+	if n := strings.Count(markdown, "### 👤 User"); n != 1 {
+		t.Errorf("Expected 1 user message, got %d. Output:\n%s", n, markdown)
+	}
+	if strings.Contains(markdown, "Tool: `toolu_1`") || strings.Contains(markdown, "Tool: `toolu_2`") {
+		t.Errorf("Linked results should only be shown under their calls. Output:\n%s", markdown)
+	}
+}
+
+func TestMarkdownConverterStringAssistantContent(t *testing.T) {
+	msg := &models.Message{
+		UUID:    "msg1",
+		Type:    models.MessageTypeAssistant,
+		Message: json.RawMessage(`{"role":"assistant","model":"claude-2","content":"Legacy plain answer"}`),
+	}
+	if err := msg.ParseContent(); err != nil {
+		t.Fatalf("ParseContent() error = %v", err)
+	}
+
+	markdown := NewMarkdownConverter(nil).ConvertMessage(msg)
+
+	if !strings.Contains(markdown, "Legacy plain answer") {
+		t.Errorf("Missing plain string assistant content. Output:\n%s", markdown)
+	}
+
+	// The same answer as a content block array renders identically
+	arrayMsg := &models.Message{
+		UUID:    "msg1",
+		Type:    models.MessageTypeAssistant,
+		Message: json.RawMessage(`{"role":"assistant","model":"claude-2","content":[{"type":"text","text":"Legacy plain answer"}]}`),
+	}
+	if err := arrayMsg.ParseContent(); err != nil {
+		t.Fatalf("ParseContent() error = %v", err)
+	}
+	if arrayMarkdown := NewMarkdownConverter(nil).ConvertMessage(arrayMsg); arrayMarkdown != markdown {
+		t.Errorf("String and array content render differently:\n%s\n%s", markdown, arrayMarkdown)
+	}
+}
+
+
+func TestMarkdownConverterEmptyContent(t *testing.T) {
+	session := &models.Session{ID: "empty-content"}
+
+	userMsg := &models.Message{
+		UUID:     "msg1",
+		Type:     models.MessageTypeUser,
+		UserType: "external",
+		Message:  json.RawMessage(`{"role":"user","content":null}`),
+	}
+	userMsg.ParseContent()
+	session.AddMessage(userMsg)
+
+	assistantMsg := &models.Message{
+		UUID:    "msg2",
+		Type:    models.MessageTypeAssistant,
+		Message: json.RawMessage(`{"role":"assistant","model":"claude-3"}`),
+	}
+	assistantMsg.ParseContent()
+	session.AddMessage(assistantMsg)
+
+	markdown := NewMarkdownConverter(nil).ConvertSession(session)
+
+	if count := strings.Count(markdown, "*[empty message]*"); count != 2 {
+		t.Errorf("Placeholder count = %d, want 2. Output:\n%s", count, markdown)
+	}
+
+	if !strings.Contains(markdown, "**Messages:** 2") {
+		t.Error("Empty messages should still be counted")
+	}
+}
+
+
+func TestMarkdownConverterSplitReasoning(t *testing.T) {
+	msg := &models.Message{
+		UUID: "msg1",
+		Type: models.MessageTypeAssistant,
+		Message: json.RawMessage(`{
+			"role": "assistant",
+			"model": "claude-3",
+			"content": [
+				{"type": "thinking", "thinking": "Consider the edge cases first."},
+				{"type": "text", "text": "Use a nil check."}
+			]
+		}`),
+	}
+	msg.ParseContent()
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{SplitReasoning: true}).ConvertMessage(msg)
+
+	reasoning := strings.Index(markdown, "#### 💭 Reasoning")
+	answer := strings.Index(markdown, "#### 💬 Answer")
+	if reasoning < 0 || answer < 0 {
+		t.Fatalf("Missing reasoning or answer section. Output:\n%s", markdown)
+	}
+
+	thinking := strings.Index(markdown, "Consider the edge cases first.")
+	text := strings.Index(markdown, "Use a nil check.")
+	if !(reasoning < thinking && thinking < answer && answer < text) {
+		t.Errorf("Thinking and answer are not in their sections. Output:\n%s", markdown)
+	}
+
+	if strings.Count(markdown, "Consider the edge cases first.") != 1 {
+		t.Error("Thinking should only be rendered once")
+	}
+}
+
+
+func TestMarkdownConverterKeywords(t *testing.T) {
+	session := &models.Session{ID: "keyword-session"}
+	msg := &models.Message{
+		UUID:     "msg1",
+		Type:     models.MessageTypeUser,
+		UserType: "external",
+		Message:  json.RawMessage(`{"role":"user","content":"Migrate the database schema, then migrate the database seed data"}`),
+	}
+	msg.ParseContent()
+	session.AddMessage(msg)
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{KeywordCount: 2}).ConvertSession(session)
+	if !strings.Contains(markdown, "**Tags:** `database` `migrate`") {
+		t.Errorf("Missing keyword tags. Output:\n%s", markdown)
+	}
+
+	data, err := NewJSONConverter(&JSONOptions{KeywordCount: 2}).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if len(result.Keywords) != 2 || result.Keywords[0] != "database" {
+		t.Errorf("Keywords = %v, want [database migrate]", result.Keywords)
+	}
+}
+
+func TestMarkdownConverterReadingTime(t *testing.T) {
+	session := &models.Session{ID: "reading-session"}
+	msg := &models.Message{
+		UUID:     "msg1",
+		Type:     models.MessageTypeUser,
+		UserType: "external",
+		Message:  json.RawMessage(`{"role":"user","content":"` + strings.TrimSpace(strings.Repeat("word ", 450)) + `"}`),
+	}
+	msg.ParseContent()
+	session.AddMessage(msg)
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{ReadingWPM: 200}).ConvertSession(session)
+	if !strings.Contains(markdown, "**Reading Time:** ~3 min read") {
+		t.Errorf("Missing reading time. Output:\n%s", markdown)
+	}
+
+	markdown = NewMarkdownConverter(&MarkdownOptions{}).ConvertSession(session)
+	if strings.Contains(markdown, "Reading Time") {
+		t.Error("Reading time should be hidden by default")
+	}
+	if !strings.Contains(markdown, "**Words:** 450 (user: 450, assistant: 0)") {
+		t.Errorf("Missing word count. Output:\n%s", markdown)
+	}
+}
+
+func TestMarkdownConverterNumberToolCalls(t *testing.T) {
+	session := &models.Session{ID: "tool-session"}
+	for _, m := range []struct {
+		msgType  models.MessageType
+		userType string
+		raw      string
+	}{
+		{models.MessageTypeAssistant, "", `{"role":"assistant","content":[{"type":"tool_use","id":"toolu_a","name":"Read","input":{}},{"type":"tool_use","id":"toolu_b","name":"Bash","input":{}}]}`},
+		{models.MessageTypeUser, "external", `{"role":"user","content":[{"tool_use_id":"toolu_b","type":"tool_result","content":"ok"}]}`},
+	} {
+		msg := &models.Message{Type: m.msgType, UserType: m.userType, Message: json.RawMessage(m.raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{NumberToolCalls: true}).ConvertSession(session)
+
+	if !strings.Contains(markdown, "<a id=\"tool-toolu_b\"></a>**🔧 Tool Use #2:** `Bash`") {
+		t.Errorf("Missing numbered tool use. Output:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "- Tool [#2](#tool-toolu_b): `toolu_b`") {
+		t.Errorf("Tool result should share the number of its call. Output:\n%s", markdown)
+	}
+
+	markdown = NewMarkdownConverter(nil).ConvertSession(session)
+	if strings.Contains(markdown, "#2") {
+		t.Error("Tool calls should not be numbered by default")
+	}
+}
+
+func TestMarkdownConverterToolUsage(t *testing.T) {
+	session := &models.Session{ID: "tool-session"}
+	msg := &models.Message{Type: models.MessageTypeAssistant, Message: json.RawMessage(`{"role":"assistant","content":[{"type":"tool_use","id":"toolu_a","name":"Read","input":{}},{"type":"tool_use","id":"toolu_b","name":"Bash","input":{}},{"type":"tool_use","id":"toolu_c","name":"Read","input":{}}]}`)}
+	msg.ParseContent()
+	session.AddMessage(msg)
+	project := models.NewProject("-Users-test-tools")
+	project.AddSession(session)
+
+	converter := NewMarkdownConverter(nil)
+	want := "**Tools Used:**\n- `Read`: 2\n- `Bash`: 1\n"
+	if markdown := converter.ConvertSession(session); !strings.Contains(markdown, want+"\n---") {
+		t.Errorf("Missing tools used list at the end of the header. Output:\n%s", markdown)
+	}
+	if markdown := converter.ConvertProject(project); !strings.Contains(markdown, want+"\n## Sessions") {
+		t.Errorf("Missing tools used list in the project header. Output:\n%s", markdown)
+	}
+}
+
+func TestMarkdownConverterCollapsePreamble(t *testing.T) {
+	preamble := strings.Repeat("Context line from CLAUDE.md\n", 100)
+	question := "Why does the build fail?"
+
+	session := &models.Session{ID: "preamble-session"}
+	for _, content := range []string{preamble + "\n" + question, "Long follow-up " + strings.Repeat("x", 5000)} {
+		data, _ := json.Marshal(map[string]string{"role": "user", "content": content})
+		msg := &models.Message{Type: models.MessageTypeUser, UserType: "external", Message: data}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{CollapsePreambleLength: 1000}).ConvertSession(session)
+
+	if !strings.Contains(markdown, "<details>\n<summary>📋 Context (100 lines)</summary>") {
+		t.Errorf("Preamble should be collapsed. Output:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "</details>\n\n"+question) {
+		t.Errorf("Question should stay visible after the collapsed preamble. Output:\n%s", markdown)
+	}
+	if strings.Count(markdown, "<details>") != 1 {
+		t.Error("Only the first user message should be collapsed")
+	}
+
+	markdown = NewMarkdownConverter(nil).ConvertSession(session)
+	if strings.Contains(markdown, "<details>") {
+		t.Error("Preamble should not be collapsed by default")
+	}
+}
+
+func TestMarkdownConverterThinkingTokens(t *testing.T) {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"role": "assistant",
+		"content": []map[string]string{
+			{"type": "thinking", "thinking": strings.Repeat("abcd", 300)},
+			{"type": "text", "text": "Answer"},
+		},
+		"usage": map[string]int{"input_tokens": 10, "output_tokens": 400},
+	})
+	msg := &models.Message{Type: models.MessageTypeAssistant, Message: raw}
+	msg.ParseContent()
+	session := &models.Session{ID: "thinking-session"}
+	session.AddMessage(msg)
+
+	markdown := NewMarkdownConverter(nil).ConvertSession(session)
+	if !strings.Contains(markdown, "**Token Usage:** Input: 10, Output: 400 (thinking: ~300)") {
+		t.Errorf("Missing thinking token estimate. Output:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "**Words:** 1 (user: 0, assistant: 1)") {
+		t.Errorf("Thinking should not be counted as words unless shown. Output:\n%s", markdown)
+	}
+	markdown = NewMarkdownConverter(&MarkdownOptions{ShowThinking: true}).ConvertSession(session)
+	if !strings.Contains(markdown, "**Words:** 2 (user: 0, assistant: 2)") {
+		t.Errorf("Shown thinking should be counted as words. Output:\n%s", markdown)
+	}
+
+	data, err := NewJSONConverter(nil).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if result.TokenUsage == nil || result.TokenUsage.Thinking != 300 {
+		t.Errorf("token_usage.thinking = %+v, want 300", result.TokenUsage)
+	}
+	if result.WordCount == nil || result.WordCount.Assistant != 1 || result.WordCount.Total != 1 {
+		t.Errorf("word_count = %+v, want 1 assistant word without thinking", result.WordCount)
+	}
+}
+
+func TestMarkdownConverterShellSnapshots(t *testing.T) {
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(&models.Session{ID: "session1"})
+	project.AddShellSnapshot(&models.ShellSnapshot{
+		Name:      "snapshot-bash-1704103200000-abc123.sh",
+		Shell:     "bash",
+		CreatedAt: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		SessionID: "session1",
+		Content:   "echo ```\n",
+	})
+
+	markdown := NewMarkdownConverter(nil).ConvertProject(project)
+	for _, want := range []string{
+		"## Shell Snapshots (1)",
+		"### snapshot-bash-1704103200000-abc123.sh",
+		"*Session: session1*  \n*Shell: bash*  \n*Created: 2024-01-01 10:00:00*",
+		"````sh\necho ```\n````",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown missing %q. Output:\n%s", want, markdown)
+		}
+	}
+
+	data, err := NewJSONConverter(nil).ConvertProject(project)
+	if err != nil {
+		t.Fatalf("ConvertProject() error = %v", err)
+	}
+	var result JSONProject
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if len(result.ShellSnapshots) != 1 || result.ShellSnapshots[0].CreatedAt != "2024-01-01T10:00:00Z" || result.ShellSnapshots[0].SessionID != "session1" {
+		t.Errorf("shell_snapshots = %+v", result.ShellSnapshots)
+	}
+}
+
+func TestMarkdownConverterMaxThinkingChars(t *testing.T) {
+	msg := &models.Message{Type: models.MessageTypeAssistant,
+		Message: json.RawMessage(`{"role":"assistant","content":[{"type":"thinking","thinking":"héllo wörld, long reasoning"},{"type":"text","text":"Answer"}]}`)}
+	msg.ParseContent()
+	session := &models.Session{ID: "thinking-budget"}
+	session.AddMessage(msg)
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{ShowThinking: true, MaxThinkingChars: 11}).ConvertSession(session)
+	if !strings.Contains(markdown, "héllo wörld…(truncated) [27 characters]") || strings.Contains(markdown, "long reasoning") {
+		t.Errorf("Thinking should be cut to 11 characters. Output:\n%s", markdown)
+	}
+
+	markdown = NewMarkdownConverter(&MarkdownOptions{ShowThinking: true, SplitReasoning: true, MaxThinkingChars: 11}).ConvertSession(session)
+	if !strings.Contains(markdown, "#### 💭 Reasoning\n\nhéllo wörld…(truncated) [27 characters]") {
+		t.Errorf("Split reasoning should be cut to 11 characters. Output:\n%s", markdown)
+	}
+
+	markdown = NewMarkdownConverter(&MarkdownOptions{ShowThinking: true, MaxThinkingChars: 27}).ConvertSession(session)
+	if strings.Contains(markdown, "truncated") || !strings.Contains(markdown, "long reasoning") {
+		t.Errorf("Thinking within the budget should be shown in full. Output:\n%s", markdown)
+	}
+}
+
+func TestMarkdownConverterCacheTokens(t *testing.T) {
+	msg := &models.Message{Type: models.MessageTypeAssistant,
+		Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":10,"cache_read_input_tokens":200,"cache_creation_input_tokens":50,"output_tokens":5}}`)}
+	msg.ParseContent()
+	session := &models.Session{ID: "cache-session"}
+	session.AddMessage(msg)
+
+	markdown := NewMarkdownConverter(nil).ConvertSession(session)
+	if !strings.Contains(markdown, "**Token Usage:** Input: 210, Output: 5") {
+		t.Errorf("Input tokens should include cache reads. Output:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "**Cache Tokens:** Read: 200, Creation: 50") {
+		t.Errorf("Missing cache token line. Output:\n%s", markdown)
+	}
+
+	data, err := NewJSONConverter(nil).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if result.TokenUsage == nil || result.TokenUsage.Input != 210 || result.TokenUsage.CacheRead != 200 || result.TokenUsage.CacheCreation != 50 {
+		t.Errorf("token_usage = %+v, want input 210 with 200 cache reads and 50 cache creation", result.TokenUsage)
+	}
+}
+
+func TestMarkdownConverterCumulativeTokens(t *testing.T) {
+	session := &models.Session{ID: "cumulative-session"}
+	for _, usage := range []string{
+		`{"input_tokens":1000,"output_tokens":200}`,
+		`{"input_tokens":10,"cache_read_input_tokens":5000,"output_tokens":6220}`,
+	} {
+		msg := &models.Message{
+			Type:    models.MessageTypeAssistant,
+			Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Done"}],"usage":` + usage + `}`),
+		}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{CumulativeTokens: true}).ConvertSession(session)
+	first := strings.Index(markdown, "*Cumulative: 1,200 tokens*")
+	second := strings.Index(markdown, "*Cumulative: 12,430 tokens*")
+	if first < 0 || second < first {
+		t.Errorf("Missing or misordered running totals. Output:\n%s", markdown)
+	}
+
+	markdown = NewMarkdownConverter(nil).ConvertSession(session)
+	if strings.Contains(markdown, "Cumulative") {
+		t.Error("Running totals should be hidden by default")
+	}
+}
+
+func TestMarkdownConverterGroupSubagents(t *testing.T) {
+	project := models.NewProject("-test-project")
+	session := &models.Session{ID: "session1"}
+	for _, msg := range []*models.Message{
+		{UUID: "m1", Type: models.MessageTypeUser, UserType: "external", Message: json.RawMessage(`{"role":"user","content":"Review the code"}`)},
+		{UUID: "a1", Type: models.MessageTypeUser, UserType: "external", Sidechain: true, AgentID: "agent-a", Message: json.RawMessage(`{"role":"user","content":"Subagent task"}`)},
+		{UUID: "a2", Type: models.MessageTypeAssistant, Sidechain: true, AgentID: "agent-a", Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Subagent result"}]}`)},
+		{UUID: "m2", Type: models.MessageTypeAssistant, Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Main answer"}]}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project.AddSession(session)
+	project.AddTodoList(&models.TodoList{
+		SessionID: "session1",
+		AgentID:   "agent-a",
+		Todos:     []*models.Todo{{ID: "1", Content: "Subagent todo", Status: models.TodoStatusPending}},
+	})
+	project.AddTodoList(&models.TodoList{
+		SessionID: "session1",
+		Todos:     []*models.Todo{{ID: "1", Content: "Main todo", Status: models.TodoStatusPending}},
+	})
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{GroupSubagents: true}).ConvertProject(project)
+	start := strings.Index(markdown, "<summary>🤖 Subagent <code>agent-a</code> (2 messages)</summary>")
+	if start < 0 {
+		t.Fatalf("Missing subagent section. Output:\n%s", markdown)
+	}
+	end := start + strings.Index(markdown[start:], "</details>")
+	section := markdown[start:end]
+	for _, want := range []string{"Subagent task", "Subagent result", "- [ ] Subagent todo"} {
+		if !strings.Contains(section, want) {
+			t.Errorf("Subagent section missing %q. Section:\n%s", want, section)
+		}
+	}
+	if strings.Contains(section, "Main answer") || !strings.Contains(markdown[end:], "Main answer") {
+		t.Errorf("Main conversation should follow the subagent section. Output:\n%s", markdown)
+	}
+
+	// Each todo list is shown once, subagent lists only in their section
+	for _, todo := range []string{"Subagent todo", "Main todo"} {
+		if n := strings.Count(markdown, todo); n != 1 {
+			t.Errorf("%q shown %d times, want once. Output:\n%s", todo, n, markdown)
+		}
+	}
+	if !strings.Contains(markdown, "## Todo Lists (1)") {
+		t.Errorf("Project todo lists should leave out the subagent list. Output:\n%s", markdown)
+	}
+}
+
+func TestMarkdownConverterRelativeTimestamps(t *testing.T) {
+	session := &models.Session{ID: "relative-session"}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{0, 2*time.Minute + 15*time.Second} {
+		msg := &models.Message{
+			UUID:      fmt.Sprintf("msg%d", i),
+			Type:      models.MessageTypeUser,
+			UserType:  "external",
+			Timestamp: start.Add(offset),
+			Message:   json.RawMessage(`{"role":"user","content":"Hello"}`),
+		}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{ShowTimestamps: true, RelativeTimestamps: true}).ConvertSession(session)
+	first := strings.Index(markdown, "*+00:00*")
+	second := strings.Index(markdown, "*+02:15*")
+	if first < 0 || second < first {
+		t.Errorf("Missing relative timestamps. Output:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "2024-01-01") {
+		t.Errorf("Absolute times should be omitted. Output:\n%s", markdown)
+	}
+
+	data, err := NewJSONConverter(&JSONOptions{RelativeTimestamps: true}).ConvertSession(session)
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	var result JSONSession
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if result.StartTime != "" || result.Messages[0].Timestamp != "" {
+		t.Errorf("Absolute times should be omitted, got start %q and timestamp %q", result.StartTime, result.Messages[0].Timestamp)
+	}
+	for i, want := range []int{0, 135} {
+		if got := result.Messages[i].Offset; got == nil || *got != want {
+			t.Errorf("messages[%d].offset_seconds = %v, want %d", i, got, want)
+		}
+	}
+}
+
+func TestMarkdownConverterUserContent(t *testing.T) {
+	raw, _ := json.Marshal(map[string]string{
+		"role":    "user",
+		"content": "# Not a heading\nRun `make`:\n```sh\nmake test\n```\n---",
+	})
+	msg := &models.Message{Type: models.MessageTypeUser, UserType: "external", Message: raw}
+	msg.ParseContent()
+
+	// Lines of the message body that would change the document structure
+	structural := func(markdown string) []string {
+		var lines []string
+		for _, line := range strings.Split(markdown, "\n") {
+			if strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "### 👤 User") ||
+				strings.HasPrefix(line, "```sh") || line == "---" {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+
+	if lines := structural(NewMarkdownConverter(nil).ConvertMessage(msg)); len(lines) == 0 {
+		t.Error("Raw user content should be rendered as Markdown")
+	}
+
+	escaped := NewMarkdownConverter(&MarkdownOptions{UserContent: UserContentEscape}).ConvertMessage(msg)
+	if lines := structural(escaped); len(lines) > 0 {
+		t.Errorf("Escaped user content has structural lines %q. Output:\n%s", lines, escaped)
+	}
+	if !strings.Contains(escaped, "\\# Not a heading") || !strings.Contains(escaped, "Run \\`make\\`:") {
+		t.Errorf("Markdown syntax not escaped. Output:\n%s", escaped)
+	}
+
+	quoted := NewMarkdownConverter(&MarkdownOptions{UserContent: UserContentQuote}).ConvertMessage(msg)
+	if !strings.Contains(quoted, "> # Not a heading\n> Run `make`:") {
+		t.Errorf("User content not quoted. Output:\n%s", quoted)
+	}
+
+	fenced := NewMarkdownConverter(&MarkdownOptions{UserContent: UserContentFence}).ConvertMessage(msg)
+	if !strings.Contains(fenced, "````text\n# Not a heading") || !strings.HasSuffix(fenced, "---\n````\n") {
+		t.Errorf("User content not fenced with a longer fence. Output:\n%s", fenced)
+	}
+}
+
+func TestMarkdownConverterShowBranches(t *testing.T) {
+	parent := func(uuid string) *string { return &uuid }
+	session := &models.Session{ID: "branched"}
+	for _, msg := range []*models.Message{
+		{UUID: "u1", Type: models.MessageTypeUser, UserType: "external", Message: json.RawMessage(`{"role":"user","content":"First question"}`)},
+		{UUID: "a1", ParentUUID: parent("u1"), Type: models.MessageTypeAssistant, Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"First answer"}]}`)},
+		{UUID: "u2", ParentUUID: parent("a1"), Type: models.MessageTypeUser, UserType: "external", Message: json.RawMessage(`{"role":"user","content":"Original follow-up"}`)},
+		{UUID: "u2-edit", ParentUUID: parent("a1"), Type: models.MessageTypeUser, UserType: "external", Message: json.RawMessage(`{"role":"user","content":"Edited follow-up"}`)},
+		{UUID: "a2", ParentUUID: parent("u2"), Type: models.MessageTypeAssistant, Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Original reply"}]}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{ShowBranches: true}).ConvertSession(session)
+	for _, want := range []string{"> Original follow-up", "> Original reply", "\nEdited follow-up"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown missing %q. Output:\n%s", want, markdown)
+		}
+	}
+	// The original reply follows its question, before the edited branch
+	if strings.Index(markdown, "Original reply") > strings.Index(markdown, "Edited follow-up") {
+		t.Errorf("Branch should be rendered in tree order. Output:\n%s", markdown)
+	}
+
+	flat := NewMarkdownConverter(&MarkdownOptions{}).ConvertSession(session)
+	if strings.Contains(flat, "> Original") {
+		t.Errorf("Branches should not be indented by default. Output:\n%s", flat)
+	}
+}
+
+func TestMarkdownConverterFlatten(t *testing.T) {
+	project := models.NewProject("-Users-test-project")
+	for _, s := range []struct {
+		id    string
+		times []time.Time
+	}{
+		{"later", []time.Time{time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)}},
+		{"earlier", []time.Time{time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC)}},
+	} {
+		session := &models.Session{ID: s.id}
+		for i, ts := range s.times {
+			msg := &models.Message{
+				UUID:      fmt.Sprintf("%s-%d", s.id, i),
+				Type:      models.MessageTypeUser,
+				UserType:  "external",
+				Timestamp: ts,
+				Message:   json.RawMessage(fmt.Sprintf(`{"role":"user","content":"Message %d of %s"}`, i, s.id)),
+			}
+			msg.ParseContent()
+			session.AddMessage(msg)
+		}
+		project.AddSession(session)
+	}
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{MergeSessions: true}).ConvertProject(project)
+	if strings.Contains(markdown, "# Session:") || strings.Contains(markdown, "## Sessions") {
+		t.Errorf("Expected no session headers, got:\n%s", markdown)
+	}
+	var order []int
+	for _, want := range []string{"\n### 2024-01-01\n", "Message 0 of earlier", "\n### 2024-01-02\n", "Message 1 of earlier", "Message 0 of later"} {
+		order = append(order, strings.Index(markdown, want))
+	}
+	for i := range order {
+		if order[i] < 0 || (i > 0 && order[i] < order[i-1]) {
+			t.Fatalf("Expected day headings and messages in time order, got:\n%s", markdown)
+		}
+	}
+	if strings.Contains(markdown, "### 2024-01-02\n\n\n---") {
+		t.Errorf("Expected no separator after a day heading, got:\n%s", markdown)
+	}
+}
+
+// createMixedToolSession returns a prompt, a reply with a tool call, its
+// result, a reply with only a tool call, its result and a final answer
+func createMixedToolSession() *models.Session {
+	session := &models.Session{ID: "mixed"}
+	for _, raw := range []struct {
+		msgType models.MessageType
+		content string
+	}{
+		{models.MessageTypeUser, `{"role":"user","content":"Where is main?"}`},
+		{models.MessageTypeAssistant, `{"role":"assistant","content":[{"type":"text","text":"Let me look."},{"type":"tool_use","id":"toolu_1","name":"Grep","input":{"pattern":"func main"}}]}`},
+		{models.MessageTypeUser, `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"main.go:3"}]}`},
+		{models.MessageTypeAssistant, `{"role":"assistant","content":[{"type":"tool_use","id":"toolu_2","name":"Read","input":{"file_path":"main.go"}}]}`},
+		{models.MessageTypeUser, `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":"package main"}]}`},
+		{models.MessageTypeAssistant, `{"role":"assistant","content":[{"type":"text","text":"It is in main.go."}]}`},
+	} {
+		msg := &models.Message{
+			UUID:     fmt.Sprintf("msg%d", len(session.Messages)+1),
+			Type:     raw.msgType,
+			UserType: "external",
+			Message:  json.RawMessage(raw.content),
+		}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	return session
+}
+
+func TestMarkdownConverterSkipToolMessages(t *testing.T) {
+	session := createMixedToolSession()
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{SkipToolMessages: true, NumberToolCalls: true}).ConvertSession(session)
+
+	for _, unwanted := range []string{"Tool Use", "Tool Results", "toolu_", "package main"} {
+		if strings.Contains(markdown, unwanted) {
+			t.Errorf("Output should not contain %q:\n%s", unwanted, markdown)
+		}
+	}
+	for _, want := range []string{"Where is main?", "Let me look.", "It is in main.go.", "**Messages:** 6", "- `Grep`: 1"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Missing %q. Output:\n%s", want, markdown)
+		}
+	}
+	if n := strings.Count(markdown, "### "); n != 3 {
+		t.Errorf("Rendered %d messages, want 3:\n%s", n, markdown)
+	}
+
+	// The session is not modified
+	if n := len(session.Messages[1].Content.(*models.AssistantMessage).Content); n != 2 {
+		t.Errorf("Assistant message has %d blocks after rendering, want 2", n)
+	}
+}
+
+func TestMarkdownConverterSkipToolMessagesHiddenThinking(t *testing.T) {
+	session := createMixedToolSession()
+	assistantMsg := session.Messages[3].Content.(*models.AssistantMessage)
+	assistantMsg.Content = append([]models.MessageContent{{Type: "thinking", Thinking: "Read the file."}}, assistantMsg.Content...)
+
+	// Hidden thinking leaves nothing to render besides the tool call
+	markdown := NewMarkdownConverter(&MarkdownOptions{SkipToolMessages: true}).ConvertSession(session)
+	if n := strings.Count(markdown, "### "); n != 3 {
+		t.Errorf("Rendered %d messages, want 3:\n%s", n, markdown)
+	}
+
+	markdown = NewMarkdownConverter(&MarkdownOptions{SkipToolMessages: true, ShowThinking: true}).ConvertSession(session)
+	if n := strings.Count(markdown, "### "); n != 4 || !strings.Contains(markdown, "Read the file.") {
+		t.Errorf("Rendered %d messages, want 4 with the thinking:\n%s", n, markdown)
+	}
+}
+
+func TestMarkdownConverterMaxMessages(t *testing.T) {
+	session := createMixedToolSession()
+
+	markdown := NewMarkdownConverter(&MarkdownOptions{MaxMessages: 2}).ConvertSession(session)
+	if n := strings.Count(markdown, "### "); n != 2 {
+		t.Errorf("Rendered %d messages, want 2:\n%s", n, markdown)
+	}
+	if !strings.HasSuffix(markdown, "\n---\n\n*… 4 more messages omitted*\n") {
+		t.Errorf("Missing omission note at the end:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "It is in main.go.") {
+		t.Errorf("Messages after the first 2 should be omitted:\n%s", markdown)
+	}
+
+	// Skipped tool messages do not count
+	markdown = NewMarkdownConverter(&MarkdownOptions{MaxMessages: 2, SkipToolMessages: true}).ConvertSession(session)
+	if !strings.Contains(markdown, "Let me look.") || !strings.HasSuffix(markdown, "*… 1 more message omitted*\n") {
+		t.Errorf("Expected 2 of 3 conversation messages:\n%s", markdown)
+	}
+
+	// No note when the session fits
+	markdown = NewMarkdownConverter(&MarkdownOptions{MaxMessages: 6}).ConvertSession(session)
+	if strings.Contains(markdown, "omitted") {
+		t.Errorf("Unexpected omission note:\n%s", markdown)
+	}
+}
+
+func TestMarkdownConverterGitBranches(t *testing.T) {
+	session := createMixedToolSession()
+	converter := NewMarkdownConverter(nil)
+
+	if markdown := converter.ConvertSession(session); strings.Contains(markdown, "Git Branch") {
+		t.Errorf("Git branch shown without gitBranch:\n%s", markdown)
+	}
+
+	session.Messages[0].GitBranch = "main"
+	if markdown := converter.ConvertSession(session); !strings.Contains(markdown, "**Git Branch:** `main`  \n") {
+		t.Errorf("Missing git branch:\n%s", markdown)
+	}
+
+	session.Messages[3].GitBranch = "feature/x"
+	if markdown := converter.ConvertSession(session); !strings.Contains(markdown, "**Git Branches:** `main`, `feature/x`  \n") {
+		t.Errorf("Missing git branches:\n%s", markdown)
+	}
+}
+
+func TestMarkdownConverterSourceFile(t *testing.T) {
+	session := &models.Session{ID: "s1", SourceFile: "/home/me/.claude/projects/-work-api/s1.jsonl"}
+	
+	markdown := NewMarkdownConverter(&MarkdownOptions{IncludeSourceFile: true}).ConvertSession(session)
+	if !strings.HasPrefix(markdown, "# Session: s1[^source-s1]\n") {
+		t.Errorf("Session heading has no source footnote:\n%s", markdown)
+	}
+	if !strings.HasSuffix(markdown, "\n[^source-s1]: Source: `/home/me/.claude/projects/-work-api/s1.jsonl`\n") {
+		t.Errorf("Missing source footnote:\n%s", markdown)
+	}
+	
+	if markdown := NewMarkdownConverter(nil).ConvertSession(session); strings.Contains(markdown, "[^source") {
+		t.Errorf("Source footnote shown by default:\n%s", markdown)
+	}
+}
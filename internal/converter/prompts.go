@@ -0,0 +1,70 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// Prompt is a prompt typed by the user, as extracted by ExtractPrompts
+type Prompt struct {
+	Project   string `json:"project"` // Project path
+	Session   string `json:"session"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Prompt    string `json:"prompt"`
+}
+
+// ExtractPrompts returns the text of the user's messages across all sessions,
+// in session order. Tool results, assistant output and empty messages are
+// skipped.
+func ExtractPrompts(projects []*models.Project) []*Prompt {
+	prompts := []*Prompt{}
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			for _, msg := range session.Messages {
+				userMsg, ok := msg.Content.(*models.UserMessage)
+				if !ok || msg.UserType != "external" || strings.TrimSpace(userMsg.Content) == "" {
+					continue
+				}
+				prompt := &Prompt{Project: project.Path, Session: session.ID, Prompt: userMsg.Content}
+				if !msg.Timestamp.IsZero() {
+					prompt.Timestamp = msg.Timestamp.Format(jsonTimeLayout)
+				}
+				prompts = append(prompts, prompt)
+			}
+		}
+	}
+	return prompts
+}
+
+// ConvertPrompts converts prompts to a JSON array
+func (c *JSONConverter) ConvertPrompts(prompts []*Prompt) ([]byte, error) {
+	if prompts == nil {
+		prompts = []*Prompt{}
+	}
+	return c.marshal(prompts)
+}
+
+// ConvertPrompts converts prompts to a numbered Markdown list, each prompt
+// followed by its project and time
+func (c *MarkdownConverter) ConvertPrompts(prompts []*Prompt) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Prompts\n\n")
+	sb.WriteString(fmt.Sprintf("**Prompts:** %d  \n", len(prompts)))
+
+	for i, prompt := range prompts {
+		// Continuation lines are indented to stay in the list item
+		text := strings.ReplaceAll(strings.TrimSpace(prompt.Prompt), "\n", "\n   ")
+		sb.WriteString(fmt.Sprintf("\n%d. %s\n", i+1, text))
+
+		source := fmt.Sprintf("`%s`", prompt.Project)
+		if prompt.Timestamp != "" {
+			source += ", " + prompt.Timestamp
+		}
+		sb.WriteString(fmt.Sprintf("\n   *%s*\n", source))
+	}
+
+	return sb.String()
+}
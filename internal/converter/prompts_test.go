@@ -0,0 +1,68 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func TestExtractPrompts(t *testing.T) {
+	projects := createIndexFixture()
+
+	// Tool results and assistant replies are not prompts
+	session := projects[0].Sessions[0]
+	for _, msg := range []*models.Message{
+		{Type: models.MessageTypeAssistant, Timestamp: time.Date(2024, 3, 1, 9, 0, 5, 0, time.UTC),
+			Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Done"}]}`)},
+		{Type: models.MessageTypeUser, UserType: "external", Timestamp: time.Date(2024, 3, 1, 9, 0, 6, 0, time.UTC),
+			Message: json.RawMessage(`{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}`)},
+		{Type: models.MessageTypeUser, UserType: "external",
+			Message: json.RawMessage(`{"role":"user","content":"Now the lexer\nand the tests"}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	prompts := ExtractPrompts(projects)
+	if len(prompts) != 4 {
+		t.Fatalf("ExtractPrompts() returned %d prompts, want 4", len(prompts))
+	}
+	first := prompts[0]
+	if first.Project != projects[0].Path || first.Session != "alpha-1" || first.Timestamp != "2024-03-01T09:00:00Z" || first.Prompt != "Refactor the parser" {
+		t.Errorf("First prompt = %+v", first)
+	}
+	if prompts[1].Prompt != "Now the lexer\nand the tests" || prompts[1].Timestamp != "" {
+		t.Errorf("Second prompt = %+v, want the follow-up without a timestamp", prompts[1])
+	}
+
+	markdown := NewMarkdownConverter(nil).ConvertPrompts(prompts)
+	for _, want := range []string{
+		"**Prompts:** 4  \n",
+		"\n1. Refactor the parser\n\n   *`" + projects[0].Path + "`, 2024-03-01T09:00:00Z*\n",
+		"\n2. Now the lexer\n   and the tests\n",
+		"\n4. Write the README\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("ConvertPrompts() missing %q. Output:\n%s", want, markdown)
+		}
+	}
+
+	data, err := NewJSONConverter(nil).ConvertPrompts(prompts)
+	if err != nil {
+		t.Fatalf("ConvertPrompts() error = %v", err)
+	}
+	var result []map[string]string
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal prompts: %v", err)
+	}
+	if len(result) != 4 || result[3]["project"] != projects[1].Path || result[3]["session"] != "beta-1" || result[3]["prompt"] != "Write the README" {
+		t.Errorf("ConvertPrompts() = %s", data)
+	}
+
+	if data, err := NewJSONConverter(nil).ConvertPrompts(nil); err != nil || strings.TrimSpace(string(data)) != "[]" {
+		t.Errorf("ConvertPrompts(nil) = %s, %v, want an empty array", data, err)
+	}
+}
@@ -0,0 +1,170 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// SearchResults holds the messages matching a query across sessions
+type SearchResults struct {
+	Query    string
+	Sessions []*SessionMatches
+}
+
+// SessionMatches holds the excerpts of one session that match a query
+type SessionMatches struct {
+	Project  *models.Project
+	Session  *models.Session
+	Excerpts []*SearchExcerpt
+}
+
+// SearchExcerpt is a run of consecutive messages around one or more matches
+type SearchExcerpt struct {
+	Messages []*models.Message
+	// Matched is parallel to Messages and marks the matching ones
+	Matched []bool
+}
+
+// MatchCount returns the total number of matching messages
+func (r *SearchResults) MatchCount() int {
+	count := 0
+	for _, session := range r.Sessions {
+		for _, excerpt := range session.Excerpts {
+			for _, matched := range excerpt.Matched {
+				if matched {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// Search finds the messages containing query (ignoring case) across all
+// sessions. Each match is returned with up to contextMessages messages before
+// and after it; overlapping excerpts are merged.
+func Search(projects []*models.Project, query string, contextMessages int) *SearchResults {
+	results := &SearchResults{Query: query}
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			matches := session.FindMatches(query)
+			if len(matches) == 0 {
+				continue
+			}
+			results.Sessions = append(results.Sessions, &SessionMatches{
+				Project:  project,
+				Session:  session,
+				Excerpts: buildExcerpts(session, matches, contextMessages),
+			})
+		}
+	}
+	return results
+}
+
+// buildExcerpts groups matches with their surrounding messages
+func buildExcerpts(session *models.Session, matches []int, contextMessages int) []*SearchExcerpt {
+	matched := make(map[int]bool, len(matches))
+	for _, i := range matches {
+		matched[i] = true
+	}
+
+	var excerpts []*SearchExcerpt
+	end := -1 // index after the last message of the current excerpt
+	for _, i := range matches {
+		start := max(i-contextMessages, 0)
+		stop := min(i+contextMessages+1, len(session.Messages))
+
+		// Extend the previous excerpt if the windows touch
+		if len(excerpts) == 0 || start > end {
+			excerpts = append(excerpts, &SearchExcerpt{})
+		} else {
+			start = end
+		}
+
+		excerpt := excerpts[len(excerpts)-1]
+		for j := start; j < stop; j++ {
+			excerpt.Messages = append(excerpt.Messages, session.Messages[j])
+			excerpt.Matched = append(excerpt.Matched, matched[j])
+		}
+		end = stop
+	}
+	return excerpts
+}
+
+// ConvertSearchResults converts search results to JSON format
+func (c *JSONConverter) ConvertSearchResults(results *SearchResults) ([]byte, error) {
+	type jsonExcerpt struct {
+		Messages []*JSONMessage `json:"messages"`
+	}
+	type jsonSessionMatches struct {
+		Project     string         `json:"project"`
+		ProjectPath string         `json:"project_path"`
+		SessionID   string         `json:"session_id"`
+		Title       string         `json:"title"`
+		Excerpts    []*jsonExcerpt `json:"excerpts"`
+	}
+
+	sessions := make([]*jsonSessionMatches, len(results.Sessions))
+	for i, s := range results.Sessions {
+		sessions[i] = &jsonSessionMatches{
+			Project:     s.Project.GetProjectName(),
+			ProjectPath: s.Project.Path,
+			SessionID:   s.Session.ID,
+			Title:       s.Session.GetTitle(),
+			Excerpts:    make([]*jsonExcerpt, len(s.Excerpts)),
+		}
+		calls := c.toolCalls(s.Session)
+		for j, excerpt := range s.Excerpts {
+			messages := make([]*JSONMessage, len(excerpt.Messages))
+			for k, msg := range excerpt.Messages {
+				messages[k] = c.messageToJSON(msg, s.Session, calls)
+				messages[k].Match = excerpt.Matched[k]
+			}
+			sessions[i].Excerpts[j] = &jsonExcerpt{Messages: messages}
+		}
+	}
+
+	result := map[string]interface{}{
+		"query":         results.Query,
+		"match_count":   results.MatchCount(),
+		"session_count": len(sessions),
+		"sessions":      sessions,
+	}
+
+	return c.marshal(result)
+}
+
+// ConvertSearchResults converts search results to Markdown format, rendering
+// each match with its surrounding messages grouped by session
+func (c *MarkdownConverter) ConvertSearchResults(results *SearchResults) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# Search Results: %s\n\n", results.Query))
+	sb.WriteString(fmt.Sprintf("**Matches:** %d in %d sessions  \n", results.MatchCount(), len(results.Sessions)))
+
+	for _, s := range results.Sessions {
+		sb.WriteString(fmt.Sprintf("\n## %s: %s\n\n", s.Project.GetProjectName(), s.Session.GetTitle()))
+		sb.WriteString(fmt.Sprintf("**Session:** %s  \n", s.Session.ID))
+		if !s.Session.StartTime.IsZero() && !c.options.RelativeTimestamps {
+			sb.WriteString(fmt.Sprintf("**Date:** %s  \n", s.Session.StartTime.Format("2006-01-02")))
+		}
+
+		for i, excerpt := range s.Excerpts {
+			if i > 0 {
+				sb.WriteString("\n*…*\n")
+			}
+			state := &sessionState{session: s.Session, matches: make(map[*models.Message]bool)}
+			for j, msg := range excerpt.Messages {
+				state.matches[msg] = excerpt.Matched[j]
+			}
+			for _, msg := range excerpt.Messages {
+				sb.WriteString("\n---\n\n")
+				sb.WriteString(c.convertMessage(msg, state))
+			}
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,99 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func createSearchFixture(texts ...string) []*models.Project {
+	project := models.NewProject("-Users-test-search")
+	session := &models.Session{ID: "search-session"}
+	for i, text := range texts {
+		data, _ := json.Marshal(map[string]string{"role": "user", "content": text})
+		msg := &models.Message{
+			UUID:      fmt.Sprintf("msg%d", i),
+			Type:      models.MessageTypeUser,
+			UserType:  "external",
+			Timestamp: time.Date(2024, 1, 1, 10, i, 0, 0, time.UTC),
+			Message:   data,
+		}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project.AddSession(session)
+	return []*models.Project{project}
+}
+
+func TestSearch(t *testing.T) {
+	projects := createSearchFixture("zero", "one", "two", "three", "four MATCH", "five", "six", "seven match", "eight", "nine", "ten", "eleven", "twelve match")
+
+	results := Search(projects, "match", 1)
+	if len(results.Sessions) != 1 {
+		t.Fatalf("Search() returned %d sessions, want 1", len(results.Sessions))
+	}
+	if results.MatchCount() != 3 {
+		t.Errorf("MatchCount() = %d, want 3", results.MatchCount())
+	}
+
+	// Windows 3-5 and 6-8 touch and merge; 11-12 stays separate
+	excerpts := results.Sessions[0].Excerpts
+	if len(excerpts) != 2 || len(excerpts[0].Messages) != 6 || len(excerpts[1].Messages) != 2 {
+		t.Fatalf("Excerpts have wrong shape: %d excerpts", len(excerpts))
+	}
+	if excerpts[0].Messages[0].UUID != "msg3" || !excerpts[0].Matched[1] || excerpts[0].Matched[0] {
+		t.Errorf("First excerpt should start at msg3 with msg4 matched")
+	}
+
+	if results := Search(projects, "absent", 2); len(results.Sessions) != 0 {
+		t.Errorf("Search() for absent term returned %d sessions", len(results.Sessions))
+	}
+}
+
+func TestConvertSearchResults(t *testing.T) {
+	projects := createSearchFixture("Set up the project", "What port does the server use?", "It listens on port 8080", "Thanks", "Now add tests", "Done")
+
+	results := Search(projects, "8080", 1)
+	markdown := NewMarkdownConverter(nil).ConvertSearchResults(results)
+
+	for _, want := range []string{
+		"# Search Results: 8080",
+		"**Matches:** 1 in 1 sessions",
+		"## search: Set up the project",
+		"What port does the server use?",
+		"### 👤 User 🔎 Match",
+		"It listens on port 8080",
+		"Thanks",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Missing %q in search results. Output:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "Now add tests") {
+		t.Error("Messages outside the context window should not be rendered")
+	}
+
+	data, err := NewJSONConverter(nil).ConvertSearchResults(results)
+	if err != nil {
+		t.Fatalf("ConvertSearchResults() error = %v", err)
+	}
+	var result struct {
+		MatchCount int `json:"match_count"`
+		Sessions   []struct {
+			Excerpts []struct {
+				Messages []*JSONMessage `json:"messages"`
+			} `json:"excerpts"`
+		} `json:"sessions"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal search results: %v", err)
+	}
+	messages := result.Sessions[0].Excerpts[0].Messages
+	if result.MatchCount != 1 || len(messages) != 3 || !messages[1].Match || messages[0].Match {
+		t.Errorf("JSON search results have wrong matches: %s", data)
+	}
+}
@@ -0,0 +1,187 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// TemplateConverter renders sessions and projects with a user-supplied
+// text/template. The template is executed with the *models.Session,
+// *models.Project or []*models.Project being exported as dot. A template may
+// define "session", "project" and "projects" templates for each export type;
+// the main template is used for types it does not define.
+type TemplateConverter struct {
+	tmpl *template.Template
+}
+
+// TemplateOptions provides the template for template conversion
+type TemplateOptions struct {
+	// Name of the template, shown in errors (e.g. its file name)
+	Name string
+	// Text of the template
+	Text string
+	// Layout of formatTime without a layout argument: a Go layout or
+	// rfc3339, unix or kitchen ("" = 2006-01-02 15:04:05)
+	TimeFormat string
+}
+
+// TemplateFuncs returns the helper functions available to templates:
+//
+//	formatTime TIME [LAYOUT]  formats a time, by default as 2006-01-02 15:04:05
+//	                          or as the TimeFormat of the options
+//	tokenSum VALUE            input plus output tokens of a session, project,
+//	                          []*Project or message
+//	text MESSAGE              text of a user prompt or assistant reply
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"formatTime": templateFormatTime,
+		"tokenSum":   templateTokenSum,
+		"text":       templateText,
+	}
+}
+
+// NewTemplateConverter parses the template. Parse errors name the template
+// and the line of the error.
+func NewTemplateConverter(options *TemplateOptions) (*TemplateConverter, error) {
+	if options == nil || options.Text == "" {
+		return nil, fmt.Errorf("template is empty")
+	}
+	name := options.Name
+	if name == "" {
+		name = "template"
+	}
+	funcs := TemplateFuncs()
+	if timeFormat := options.TimeFormat; timeFormat != "" {
+		funcs["formatTime"] = func(t time.Time, layout ...string) string {
+			if len(layout) > 0 || t.IsZero() {
+				return templateFormatTime(t, layout...)
+			}
+			return formatTime(t, timeFormat, "")
+		}
+	}
+	tmpl, err := template.New(name).Funcs(funcs).Parse(options.Text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return &TemplateConverter{tmpl: tmpl}, nil
+}
+
+// ConvertSession renders a session
+func (c *TemplateConverter) ConvertSession(session *models.Session) ([]byte, error) {
+	return c.ConvertSessionContext(context.Background(), session)
+}
+
+// ConvertSessionContext is like ConvertSession but stops rendering once ctx
+// is done, returning ctx's error
+func (c *TemplateConverter) ConvertSessionContext(ctx context.Context, session *models.Session) ([]byte, error) {
+	return c.execute(ctx, "session", session)
+}
+
+// ConvertProject renders a project
+func (c *TemplateConverter) ConvertProject(project *models.Project) ([]byte, error) {
+	return c.ConvertProjectContext(context.Background(), project)
+}
+
+// ConvertProjectContext is like ConvertProject but stops rendering once ctx
+// is done, returning ctx's error
+func (c *TemplateConverter) ConvertProjectContext(ctx context.Context, project *models.Project) ([]byte, error) {
+	return c.execute(ctx, "project", project)
+}
+
+// ConvertProjects renders multiple projects
+func (c *TemplateConverter) ConvertProjects(projects []*models.Project) ([]byte, error) {
+	return c.ConvertProjectsContext(context.Background(), projects)
+}
+
+// ConvertProjectsContext is like ConvertProjects but stops rendering once
+// ctx is done, returning ctx's error
+func (c *TemplateConverter) ConvertProjectsContext(ctx context.Context, projects []*models.Project) ([]byte, error) {
+	return c.execute(ctx, "projects", projects)
+}
+
+// execute runs the template named after the export type if it is defined,
+// and the main template otherwise. Execution errors name the template and
+// the line and column of the failing action. Execution stops at the first
+// output written after ctx is done.
+func (c *TemplateConverter) execute(ctx context.Context, name string, data interface{}) ([]byte, error) {
+	tmpl := c.tmpl
+	if named := c.tmpl.Lookup(name); named != nil {
+		tmpl = named
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&contextWriter{ctx: ctx, w: &buf}, data); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// contextWriter fails every write once ctx is done
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+// Write implements io.Writer
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
+// templateFormatTime formats t with layout, or as 2006-01-02 15:04:05
+func templateFormatTime(t time.Time, layout ...string) string {
+	if t.IsZero() {
+		return ""
+	}
+	if len(layout) > 0 {
+		return t.Format(layout[0])
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// templateTokenSum returns the input plus output tokens of v
+func templateTokenSum(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case *models.Session:
+		input, output := v.GetTokenUsage()
+		return input + output, nil
+	case *models.Project:
+		input, output := v.GetTotalTokenUsage()
+		return input + output, nil
+	case []*models.Project:
+		total := 0
+		for _, project := range v {
+			input, output := project.GetTotalTokenUsage()
+			total += input + output
+		}
+		return total, nil
+	case *models.Message:
+		if assistantMsg, ok := v.Content.(*models.AssistantMessage); ok && assistantMsg.Usage != nil {
+			return assistantMsg.Usage.InputTokens + assistantMsg.Usage.CacheReadInputTokens + assistantMsg.Usage.OutputTokens, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("tokenSum: unsupported value of type %T", v)
+}
+
+// templateText returns the text of a user prompt or assistant reply, or ""
+// for other messages
+func templateText(msg *models.Message) string {
+	switch content := msg.Content.(type) {
+	case *models.UserMessage:
+		return content.Content
+	case *models.AssistantMessage:
+		return content.GetText()
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func templateTestProject() *models.Project {
+	session := &models.Session{ID: "session1", ProjectID: "-Users-test-project"}
+	for _, msg := range []*models.Message{
+		{UUID: "msg1", SessionID: "session1", Type: models.MessageTypeUser, UserType: "external", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), Message: json.RawMessage(`{"role":"user","content":"Hello"}`)},
+		{UUID: "msg2", SessionID: "session1", Type: models.MessageTypeAssistant, Timestamp: time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC), Message: json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Hi there"}],"usage":{"input_tokens":10,"output_tokens":5}}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project := models.NewProject("-Users-test-project")
+	project.Path = "/Users/test/project"
+	project.AddSession(session)
+	return project
+}
+
+func TestTemplateConverter(t *testing.T) {
+	project := templateTestProject()
+	converter, err := NewTemplateConverter(&TemplateOptions{
+		Name: "chat.tmpl",
+		Text: `{{range .Messages}}{{formatTime .Timestamp "15:04"}} {{.Type}}: {{text .}}
+{{end}}tokens={{tokenSum .}}`,
+	})
+	if err != nil {
+		t.Fatalf("NewTemplateConverter() error = %v", err)
+	}
+
+	output, err := converter.ConvertSession(project.Sessions[0])
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	want := "10:00 user: Hello\n10:00 assistant: Hi there\ntokens=15"
+	if string(output) != want {
+		t.Errorf("ConvertSession() = %q, want %q", output, want)
+	}
+}
+
+func TestTemplateConverterTimeFormat(t *testing.T) {
+	project := templateTestProject()
+	converter, err := NewTemplateConverter(&TemplateOptions{
+		Text:       `{{formatTime .StartTime}} {{formatTime .StartTime "15:04"}}`,
+		TimeFormat: "rfc3339",
+	})
+	if err != nil {
+		t.Fatalf("NewTemplateConverter() error = %v", err)
+	}
+
+	// The time format is the default layout, an explicit layout still wins
+	output, err := converter.ConvertSession(project.Sessions[0])
+	if err != nil {
+		t.Fatalf("ConvertSession() error = %v", err)
+	}
+	if want := "2024-01-01T10:00:00Z 10:00"; string(output) != want {
+		t.Errorf("ConvertSession() = %q, want %q", output, want)
+	}
+}
+
+func TestTemplateConverterNamedTemplates(t *testing.T) {
+	project := templateTestProject()
+	converter, err := NewTemplateConverter(&TemplateOptions{
+		Text: `{{define "project"}}project {{.GetProjectName}} ({{tokenSum .}}){{end}}` +
+			`{{define "projects"}}{{range .}}{{template "project" .}};{{end}} total {{tokenSum .}}{{end}}` +
+			`session {{.ID}} at {{formatTime .StartTime}}`,
+	})
+	if err != nil {
+		t.Fatalf("NewTemplateConverter() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		convert func() ([]byte, error)
+		want    string
+	}{
+		{"session", func() ([]byte, error) { return converter.ConvertSession(project.Sessions[0]) }, "session session1 at 2024-01-01 10:00:00"},
+		{"project", func() ([]byte, error) { return converter.ConvertProject(project) }, "project project (15)"},
+		{"projects", func() ([]byte, error) { return converter.ConvertProjects([]*models.Project{project, project}) }, "project project (15);project project (15); total 30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := tt.convert()
+			if err != nil {
+				t.Fatalf("convert error = %v", err)
+			}
+			if string(output) != tt.want {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateConverterErrors(t *testing.T) {
+	if _, err := NewTemplateConverter(&TemplateOptions{Name: "bad.tmpl", Text: "line one\n{{if .ID}}"}); err == nil {
+		t.Error("NewTemplateConverter() accepted an unclosed action")
+	} else if !strings.Contains(err.Error(), "bad.tmpl:2") {
+		t.Errorf("Parse error %q does not name the template and line", err)
+	}
+
+	if _, err := NewTemplateConverter(nil); err == nil {
+		t.Error("NewTemplateConverter(nil) accepted a missing template")
+	}
+
+	converter, err := NewTemplateConverter(&TemplateOptions{Name: "exec.tmpl", Text: "ok\n\n{{.NoSuchField}}"})
+	if err != nil {
+		t.Fatalf("NewTemplateConverter() error = %v", err)
+	}
+	_, err = converter.ConvertProject(templateTestProject())
+	if err == nil {
+		t.Fatal("ConvertProject() did not fail on a missing field")
+	}
+	if !strings.Contains(err.Error(), "exec.tmpl:3") {
+		t.Errorf("Execution error %q does not name the template and line", err)
+	}
+
+	converter, err = NewTemplateConverter(&TemplateOptions{Text: "{{tokenSum 3}}"})
+	if err != nil {
+		t.Fatalf("NewTemplateConverter() error = %v", err)
+	}
+	if _, err := converter.ConvertSession(&models.Session{}); err == nil || !strings.Contains(err.Error(), "tokenSum") {
+		t.Errorf("ConvertSession() error = %v, want a tokenSum error", err)
+	}
+}
@@ -0,0 +1,230 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// TextConverter converts sessions and projects to plain text without any
+// markup, one prefixed paragraph per message
+type TextConverter struct {
+	options TextOptions
+}
+
+// TextOptions provides options for plain text conversion
+type TextOptions struct {
+	// Include thinking content of assistant messages
+	ShowThinking bool
+	// Include tool calls of assistant messages with their input
+	ShowToolUse bool
+	// Layout of rendered times: a Go layout or rfc3339, unix or kitchen (""
+	// = 2006-01-02 15:04:05)
+	TimeFormat string
+}
+
+// NewTextConverter creates a new plain text converter
+func NewTextConverter(options *TextOptions) *TextConverter {
+	if options == nil {
+		options = &TextOptions{}
+	}
+	return &TextConverter{
+		options: *options,
+	}
+}
+
+// ConvertSession converts a session to plain text
+func (c *TextConverter) ConvertSession(session *models.Session) string {
+	var sb strings.Builder
+	c.writeSession(&sb, session)
+	return sb.String()
+}
+
+// ConvertProject converts a project to plain text
+func (c *TextConverter) ConvertProject(project *models.Project) string {
+	text, _ := c.ConvertProjectContext(context.Background(), project)
+	return text
+}
+
+// ConvertProjectContext is like ConvertProject but checks ctx before each
+// session, returning ctx's error once it is done
+func (c *TextConverter) ConvertProjectContext(ctx context.Context, project *models.Project) (string, error) {
+	var sb strings.Builder
+	if err := c.writeProject(ctx, &sb, project); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// ConvertProjects converts multiple projects to plain text
+func (c *TextConverter) ConvertProjects(projects []*models.Project) string {
+	text, _ := c.ConvertProjectsContext(context.Background(), projects)
+	return text
+}
+
+// ConvertProjectsContext is like ConvertProjects but checks ctx before each
+// session, returning ctx's error once it is done
+func (c *TextConverter) ConvertProjectsContext(ctx context.Context, projects []*models.Project) (string, error) {
+	var sb strings.Builder
+	for i, project := range projects {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if err := c.writeProject(ctx, &sb, project); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+// writeProject writes a project heading line followed by its sessions,
+// stopping once ctx is done
+func (c *TextConverter) writeProject(ctx context.Context, sb *strings.Builder, project *models.Project) error {
+	sb.WriteString(fmt.Sprintf("Project: %s\n\n", project.GetProjectName()))
+	for i, session := range project.Sessions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		c.writeSession(sb, session)
+	}
+	return nil
+}
+
+// writeSession writes a session heading line followed by its messages. Messages
+// without text, such as tool results, are left out.
+func (c *TextConverter) writeSession(sb *strings.Builder, session *models.Session) {
+	sb.WriteString(fmt.Sprintf("Session: %s\n", session.GetTitle()))
+	if !session.StartTime.IsZero() {
+		sb.WriteString(fmt.Sprintf("Started: %s\n", formatTime(session.StartTime, c.options.TimeFormat, markdownTimeLayout)))
+	}
+
+	for _, msg := range session.Messages {
+		text := c.messageText(msg)
+		if text == "" {
+			continue
+		}
+		sb.WriteString("\n")
+		sb.WriteString(strings.ToUpper(string(msg.Type)))
+		sb.WriteString(": ")
+		sb.WriteString(text)
+		sb.WriteString("\n")
+	}
+}
+
+// messageText returns the text of a user or assistant message, with the
+// paragraphs of an assistant message separated by blank lines and Markdown
+// stripped from prompts, replies and thinking
+func (c *TextConverter) messageText(msg *models.Message) string {
+	switch content := msg.Content.(type) {
+	case *models.UserMessage:
+		return strings.TrimSpace(stripMarkdown(content.Content))
+
+	case *models.AssistantMessage:
+		var paragraphs []string
+		for _, block := range content.Content {
+			var paragraph string
+			switch block.Type {
+			case "text":
+				paragraph = stripMarkdown(block.Text)
+			case "thinking":
+				if c.options.ShowThinking && strings.TrimSpace(block.Thinking) != "" {
+					paragraph = "[thinking] " + stripMarkdown(block.Thinking)
+				}
+			case "tool_use":
+				if c.options.ShowToolUse {
+					paragraph = fmt.Sprintf("[tool: %s] %s", block.Name, compactJSON(block.Input))
+				}
+			}
+			if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+				paragraphs = append(paragraphs, paragraph)
+			}
+		}
+		return strings.Join(paragraphs, "\n\n")
+	}
+	return ""
+}
+
+// Markdown markup removed by stripMarkdown. Emphasis must start and end next
+// to a non-space character, and underscores only count outside of words, so
+// 2 * 3 * 4 and snake_case_names are left alone.
+var (
+	markdownFence     = regexp.MustCompile("^\\s*(```|~~~)")
+	markdownHeading   = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	markdownImage     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	markdownAutolink  = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	markdownStrong    = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	markdownStrike    = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	markdownEmphasis  = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	markdownUnderline = regexp.MustCompile(`(^|\W)_(\S(?:[^_]*?\S)?)_(\W|$)`)
+)
+
+// stripMarkdown removes the Markdown markup of text: code fences, heading
+// markers, emphasis and the backticks of inline code. Links keep their text
+// followed by the URL in parentheses, and images their alt text. Code is left
+// as is.
+func stripMarkdown(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	inFence := false
+	for _, line := range lines {
+		if markdownFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			line = stripInlineMarkdown(markdownHeading.ReplaceAllString(line, ""))
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// stripInlineMarkdown removes the inline markup of a line, leaving the text
+// of code spans untouched
+func stripInlineMarkdown(line string) string {
+	spans := strings.Split(line, "`")
+	if len(spans)%2 == 0 {
+		// An unmatched backtick is not a code span
+		return stripSpanMarkdown(line)
+	}
+	for i := 0; i < len(spans); i += 2 {
+		spans[i] = stripSpanMarkdown(spans[i])
+	}
+	return strings.Join(spans, "")
+}
+
+// stripSpanMarkdown removes links, images and emphasis from text outside of
+// code spans
+func stripSpanMarkdown(text string) string {
+	text = markdownImage.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllStringFunc(text, func(link string) string {
+		match := markdownLink.FindStringSubmatch(link)
+		if match[1] == match[2] {
+			return match[2]
+		}
+		return match[1] + " (" + match[2] + ")"
+	})
+	text = markdownAutolink.ReplaceAllString(text, "$1")
+	text = markdownStrong.ReplaceAllString(text, "$1$2")
+	text = markdownStrike.ReplaceAllString(text, "$1")
+	text = markdownEmphasis.ReplaceAllString(text, "$1")
+	return markdownUnderline.ReplaceAllString(text, "$1$2$3")
+}
+
+// compactJSON returns raw JSON on a single line, or as is if it is not valid
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
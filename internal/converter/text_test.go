@@ -0,0 +1,77 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func TestTextConverter(t *testing.T) {
+	session := &models.Session{
+		ID:        "test-session",
+		ProjectID: "test-project",
+	}
+	for _, msg := range []*models.Message{
+		{UUID: "msg1", Type: models.MessageTypeUser, UserType: "external", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), Message: json.RawMessage(`{"role":"user","content":"Why is **Redis** slow?"}`)},
+		{UUID: "msg2", Type: models.MessageTypeAssistant, Timestamp: time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC), Message: json.RawMessage(`{"role":"assistant","content":[` +
+			`{"type":"thinking","thinking":"Check maxmemory."},` +
+			`{"type":"text","text":"Let me look."},` +
+			`{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path": "redis.conf"}},` +
+			`{"type":"text","text":"The limit is too low."}]}`)},
+		{UUID: "msg3", Type: models.MessageTypeUser, Timestamp: time.Date(2024, 1, 1, 10, 0, 6, 0, time.UTC), Message: json.RawMessage(`{"role":"user","content":[{"tool_use_id":"toolu_1","type":"tool_result","content":"maxmemory 1mb"}]}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(session)
+
+	output := NewTextConverter(nil).ConvertProject(project)
+	for _, want := range []string{
+		"Project: project\n",
+		"\nUSER: Why is Redis slow?\n",
+		"\nASSISTANT: Let me look.\n\nThe limit is too low.\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"Check maxmemory", "redis.conf", "maxmemory 1mb", "##", "```"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("Expected %q to be left out, got:\n%s", unwanted, output)
+		}
+	}
+
+	output = NewTextConverter(&TextOptions{ShowThinking: true, ShowToolUse: true}).ConvertSession(session)
+	want := "ASSISTANT: [thinking] Check maxmemory.\n\nLet me look.\n\n[tool: Read] {\"file_path\":\"redis.conf\"}\n\nThe limit is too low.\n"
+	if !strings.Contains(output, want) {
+		t.Errorf("Expected %q in output, got:\n%s", want, output)
+	}
+}
+
+func TestStripMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"emphasis", "Use **bold**, __strong__, *em*, _em_ and ~~gone~~", "Use bold, strong, em, em and gone"},
+		{"not emphasis", "2 * 3 * 4 and snake_case_name", "2 * 3 * 4 and snake_case_name"},
+		{"heading", "## Summary\nDone", "Summary\nDone"},
+		{"link", "See [the docs](https://redis.io \"Redis\") or <https://example.com>", "See the docs (https://redis.io) or https://example.com"},
+		{"bare link", "[https://redis.io](https://redis.io)", "https://redis.io"},
+		{"image", "![diagram](arch.png)", "diagram"},
+		{"inline code", "Set `**max**_memory` to **2mb**", "Set **max**_memory to 2mb"},
+		{"fence", "Run:\n```bash\necho **hi**\n```\nDone", "Run:\necho **hi**\nDone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripMarkdown(tt.input); got != tt.want {
+				t.Errorf("stripMarkdown(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
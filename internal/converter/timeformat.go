@@ -0,0 +1,62 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Named time formats accepted as a TimeFormat option in place of a Go layout
+const (
+	TimeFormatRFC3339 = "rfc3339" // 2006-01-02T15:04:05Z07:00
+	TimeFormatUnix    = "unix"    // Seconds since the epoch
+	TimeFormatKitchen = "kitchen" // 3:04PM
+)
+
+// Default layouts of rendered times when no TimeFormat is set. JSON times
+// keep the offset they were recorded with, written as Z for UTC.
+const (
+	jsonTimeLayout     = time.RFC3339
+	markdownTimeLayout = "2006-01-02 15:04:05"
+)
+
+// timeFormatPresets maps the named time formats to their layouts; unix has
+// no layout
+var timeFormatPresets = map[string]string{
+	TimeFormatRFC3339: time.RFC3339,
+	TimeFormatKitchen: time.Kitchen,
+}
+
+// ValidateTimeFormat checks that a TimeFormat option is empty, a named
+// preset or a Go layout containing at least one element of the reference
+// time, such as 2006-01-02 15:04
+func ValidateTimeFormat(format string) error {
+	if format == "" || strings.EqualFold(format, TimeFormatUnix) {
+		return nil
+	}
+	if _, ok := timeFormatPresets[strings.ToLower(format)]; ok {
+		return nil
+	}
+	// A layout without reference elements formats every time the same way
+	if time.Unix(0, 0).UTC().Format(format) == time.Unix(1e9, 0).UTC().Format(format) {
+		return fmt.Errorf("invalid time format %q (use rfc3339, unix, kitchen or a Go layout like \"2006-01-02 15:04\")", format)
+	}
+	return nil
+}
+
+// formatTime formats t with a TimeFormat option, or with defaultLayout if
+// the option is empty
+func formatTime(t time.Time, format, defaultLayout string) string {
+	switch lower := strings.ToLower(format); {
+	case format == "":
+		return t.Format(defaultLayout)
+	case lower == TimeFormatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	default:
+		if layout, ok := timeFormatPresets[lower]; ok {
+			return t.Format(layout)
+		}
+		return t.Format(format)
+	}
+}
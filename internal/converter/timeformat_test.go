@@ -0,0 +1,79 @@
+package converter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func TestValidateTimeFormat(t *testing.T) {
+	for _, format := range []string{"", "rfc3339", "UNIX", "kitchen", "2006-01-02 15:04", "Jan 2"} {
+		if err := ValidateTimeFormat(format); err != nil {
+			t.Errorf("ValidateTimeFormat(%q) error = %v", format, err)
+		}
+	}
+	for _, format := range []string{"iso", "YYYY-MM-DD"} {
+		if err := ValidateTimeFormat(format); err == nil {
+			t.Errorf("ValidateTimeFormat(%q) should error", format)
+		}
+	}
+}
+
+func TestConvertersTimeFormat(t *testing.T) {
+	session := &models.Session{ID: "s1"}
+	msg := &models.Message{
+		UUID:      "m1",
+		Type:      models.MessageTypeUser,
+		UserType:  "external",
+		Timestamp: time.Date(2024, 3, 1, 15, 4, 5, 0, time.UTC),
+		Message:   json.RawMessage(`{"role":"user","content":"Hello"}`),
+	}
+	msg.ParseContent()
+	session.AddMessage(msg)
+
+	// Each format keeps its own layout by default
+	markdown := NewMarkdownConverter(nil).ConvertSession(session)
+	for _, want := range []string{"**Started:** 2024-03-01T15:04:05Z  \n", "*2024-03-01 15:04:05*  \n"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("ConvertSession() missing %q. Output:\n%s", want, markdown)
+		}
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"unix", "1709305445"},
+		{"kitchen", "3:04PM"},
+		{"rfc3339", "2024-03-01T15:04:05Z"},
+		{"Jan 2 15:04", "Mar 1 15:04"},
+	}
+	for _, tt := range tests {
+		markdown := NewMarkdownConverter(&MarkdownOptions{ShowTimestamps: true, TimeFormat: tt.format}).ConvertSession(session)
+		for _, want := range []string{"**Started:** " + tt.want + "  \n", "*" + tt.want + "*  \n"} {
+			if !strings.Contains(markdown, want) {
+				t.Errorf("%s: Markdown missing %q. Output:\n%s", tt.format, want, markdown)
+			}
+		}
+
+		data, err := NewJSONConverter(&JSONOptions{TimeFormat: tt.format}).ConvertSession(session)
+		if err != nil {
+			t.Fatalf("ConvertSession() error = %v", err)
+		}
+		var exported JSONSession
+		if err := json.Unmarshal(data, &exported); err != nil {
+			t.Fatalf("Failed to unmarshal session: %v", err)
+		}
+		if exported.StartTime != tt.want || exported.Messages[0].Timestamp != tt.want {
+			t.Errorf("%s: JSON times = %q, %q, want %q", tt.format, exported.StartTime, exported.Messages[0].Timestamp, tt.want)
+		}
+
+		text := NewTextConverter(&TextOptions{TimeFormat: tt.format}).ConvertSession(session)
+		if !strings.Contains(text, "Started: "+tt.want+"\n") {
+			t.Errorf("%s: text missing the start time. Output:\n%s", tt.format, text)
+		}
+	}
+}
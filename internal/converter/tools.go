@@ -0,0 +1,67 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// isToolOnly checks if msg holds nothing but tool calls or tool results, so
+// it is left out when tool messages are skipped. An assistant message counts
+// as tool-only when its tool calls are all it shows: its text blocks are blank
+// and its thinking is blank or, unless showThinking is set, hidden.
+func isToolOnly(msg *models.Message, showThinking bool) bool {
+	switch content := msg.Content.(type) {
+	case []models.ToolResult:
+		return len(content) > 0
+	case *models.AssistantMessage:
+		hasToolUse := false
+		for _, block := range content.Content {
+			switch block.Type {
+			case "tool_use":
+				hasToolUse = true
+			case "text":
+				if strings.TrimSpace(block.Text) != "" {
+					return false
+				}
+			case "thinking":
+				if showThinking && strings.TrimSpace(block.Thinking) != "" {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+		return hasToolUse
+	}
+	return false
+}
+
+// withoutToolUse returns a copy of the assistant message without its tool_use
+// blocks, or the message itself if it has none
+func withoutToolUse(assistantMsg *models.AssistantMessage) *models.AssistantMessage {
+	blocks := make([]models.MessageContent, 0, len(assistantMsg.Content))
+	for _, block := range assistantMsg.Content {
+		if block.Type != "tool_use" {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == len(assistantMsg.Content) {
+		return assistantMsg
+	}
+	copied := *assistantMsg
+	copied.Content = blocks
+	return &copied
+}
+
+// withoutToolMessages returns the messages that are not tool-only, counting
+// thinking as shown if showThinking is set
+func withoutToolMessages(messages []*models.Message, showThinking bool) []*models.Message {
+	kept := make([]*models.Message, 0, len(messages))
+	for _, msg := range messages {
+		if !isToolOnly(msg, showThinking) {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// YAMLConverter converts sessions and projects to YAML with the same
+// structure and field names as the JSON output
+type YAMLConverter struct {
+	json      *JSONConverter
+	omitEmpty bool
+}
+
+// NewYAMLConverter creates a new YAML converter. The JSON options apply as
+// for JSON output, except PrettyPrint; with OmitEmpty, null fields are left
+// out as well.
+func NewYAMLConverter(options *JSONOptions) *YAMLConverter {
+	if options == nil {
+		options = &JSONOptions{OmitEmpty: true}
+	}
+	jsonOpts := *options
+	jsonOpts.PrettyPrint = false
+	return &YAMLConverter{
+		json:      NewJSONConverter(&jsonOpts),
+		omitEmpty: options.OmitEmpty,
+	}
+}
+
+// ConvertSession converts a session to YAML format
+func (c *YAMLConverter) ConvertSession(session *models.Session) ([]byte, error) {
+	return c.fromJSON(c.json.ConvertSession(session))
+}
+
+// ConvertProject converts a project to YAML format
+func (c *YAMLConverter) ConvertProject(project *models.Project) ([]byte, error) {
+	return c.fromJSON(c.json.ConvertProject(project))
+}
+
+// ConvertProjects converts multiple projects to YAML format
+func (c *YAMLConverter) ConvertProjects(projects []*models.Project) ([]byte, error) {
+	return c.fromJSON(c.json.ConvertProjects(projects))
+}
+
+// ConvertIndex converts index entries to YAML format
+func (c *YAMLConverter) ConvertIndex(entries []*IndexEntry) ([]byte, error) {
+	return c.fromJSON(c.json.ConvertIndex(entries))
+}
+
+// ConvertPrompts converts prompts to YAML format
+func (c *YAMLConverter) ConvertPrompts(prompts []*Prompt) ([]byte, error) {
+	return c.fromJSON(c.json.ConvertPrompts(prompts))
+}
+
+// ConvertSearchResults converts search results to YAML format
+func (c *YAMLConverter) ConvertSearchResults(results *SearchResults) ([]byte, error) {
+	return c.fromJSON(c.json.ConvertSearchResults(results))
+}
+
+// fromJSON re-encodes JSON output as YAML. Decoding into a node keeps the
+// field order of the JSON output.
+func (c *YAMLConverter) fromJSON(data []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to convert to YAML: %w", err)
+	}
+	c.clean(&doc)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to convert to YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to convert to YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// clean switches nodes decoded from JSON to block style, with strings only
+// quoted where needed, and drops null fields if OmitEmpty is set
+func (c *YAMLConverter) clean(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.MappingNode && c.omitEmpty {
+		content := node.Content[:0]
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if value := node.Content[i+1]; value.Kind != yaml.ScalarNode || value.Tag != "!!null" {
+				content = append(content, node.Content[i], value)
+			}
+		}
+		node.Content = content
+	}
+	for _, child := range node.Content {
+		c.clean(child)
+	}
+}
@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func TestYAMLConverter(t *testing.T) {
+	session := &models.Session{
+		ID:        "test-session",
+		ProjectID: "test-project",
+	}
+	for _, msg := range []*models.Message{
+		{UUID: "msg1", Type: models.MessageTypeUser, UserType: "external", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), Message: json.RawMessage(`{"role":"user","content":"yes: a \"quoted\" reply\nover two lines"}`)},
+		{UUID: "msg2", Type: models.MessageTypeAssistant, Timestamp: time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC), Message: json.RawMessage(`{"role":"assistant","model":"claude-3","content":[{"type":"text","text":"true"}]}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project := models.NewProject("-Users-test-project")
+	project.AddSession(session)
+
+	options := &JSONOptions{OmitEmpty: true}
+	yamlData, err := NewYAMLConverter(options).ConvertProject(project)
+	if err != nil {
+		t.Fatalf("ConvertProject() error = %v", err)
+	}
+	jsonData, err := NewJSONConverter(options).ConvertProject(project)
+	if err != nil {
+		t.Fatalf("JSON ConvertProject() error = %v", err)
+	}
+
+	// The YAML document holds the same data as the JSON one
+	var fromYAML, fromJSON interface{}
+	if err := yaml.Unmarshal(yamlData, &fromYAML); err != nil {
+		t.Fatalf("Output is not valid YAML: %v\n%s", err, yamlData)
+	}
+	if err := json.Unmarshal(jsonData, &fromJSON); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if !reflect.DeepEqual(normalizeYAML(fromYAML), fromJSON) {
+		t.Errorf("YAML and JSON output differ.\nYAML:\n%s\nJSON:\n%s", yamlData, jsonData)
+	}
+
+	output := string(yamlData)
+	if !strings.HasPrefix(output, "id: -Users-test-project\n") {
+		t.Errorf("Expected fields in JSON order, got:\n%s", output)
+	}
+	if strings.Contains(output, "null") {
+		t.Errorf("Expected null fields to be omitted, got:\n%s", output)
+	}
+}
+
+// normalizeYAML converts decoded YAML to the types encoding/json decodes
+// to, so the two can be compared
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalizeYAML(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeYAML(value)
+		}
+		return v
+	case int:
+		return float64(v)
+	default:
+		return v
+	}
+}
@@ -3,7 +3,10 @@ package exporter
 import (
 	"fmt"
 	"io"
+	"path/filepath"
+	"strings"
 
+	"github.com/eternnoir/cc-history-export/internal/converter"
 	"github.com/eternnoir/cc-history-export/internal/models"
 )
 
@@ -14,8 +17,33 @@ const (
 	FormatJSON     Format = "json"
 	FormatMarkdown Format = "markdown"
 	FormatHTML     Format = "html"
+	FormatCSV      Format = "csv"
+	FormatYAML     Format = "yaml"
+	FormatText     Format = "text"
+	FormatJSONL    Format = "jsonl"
+	FormatTemplate Format = "template"
 )
 
+// formatExtensions maps output file extensions to their format
+var formatExtensions = map[string]Format{
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+	".json":     FormatJSON,
+	".jsonl":    FormatJSONL,
+	".html":     FormatHTML,
+	".csv":      FormatCSV,
+	".yaml":     FormatYAML,
+	".yml":      FormatYAML,
+	".txt":      FormatText,
+}
+
+// DetectFormat infers the export format from the extension of an output
+// filename. It reports false for unknown or missing extensions.
+func DetectFormat(filename string) (Format, bool) {
+	format, ok := formatExtensions[strings.ToLower(filepath.Ext(filename))]
+	return format, ok
+}
+
 // ExportType represents what to export
 type ExportType string
 
@@ -23,6 +51,31 @@ const (
 	ExportTypeSession  ExportType = "session"
 	ExportTypeProject  ExportType = "project"
 	ExportTypeProjects ExportType = "projects"
+	ExportTypeIndex    ExportType = "index"
+	ExportTypeSearch   ExportType = "search"
+	ExportTypeDaily    ExportType = "daily"
+	ExportTypePrompts  ExportType = "prompts"
+)
+
+// Granularity represents how batch exports split data into files
+type Granularity string
+
+const (
+	GranularityProject Granularity = "project"
+	GranularitySession Granularity = "session"
+)
+
+// PathStrategy represents where in the output directory batch exports place
+// each file
+type PathStrategy string
+
+const (
+	// FlatStrategy writes every file directly to the output directory
+	FlatStrategy PathStrategy = "flat"
+	// DateStrategy writes each file to a year/month subdirectory (2024/07)
+	// for the start date of its project or session. Files without a
+	// date stay in the output directory.
+	DateStrategy PathStrategy = "date"
 )
 
 // Exporter is the interface for exporting data
@@ -37,6 +90,10 @@ type Exporter interface {
 	GetFormat() Format
 }
 
+// DefaultStreamThreshold is the message count above which a project is
+// streamed when exported as JSON
+const DefaultStreamThreshold = 10000
+
 // ExportOptions provides common export options
 type ExportOptions struct {
 	// Format to export to
@@ -48,6 +105,29 @@ type ExportOptions struct {
 	// Include statistics
 	IncludeStats bool
 	
+	// Include the CLAUDE.md content in Config: as a Project Instructions
+	// section before Markdown exports and as a top-level claude_md field of
+	// multi-project JSON and YAML exports
+	IncludeConfig bool
+	
+	// Config is the CLAUDE.md content included with IncludeConfig
+	Config string
+	
+	// Projects with more messages than this are written to JSON one message
+	// at a time instead of being converted in memory first
+	// (0 = DefaultStreamThreshold)
+	StreamThreshold int
+	
+	// Leave tool calls and tool results out of JSON, YAML, JSONL and
+	// Markdown content, overriding the format options. The data is not
+	// modified, so counts still include them.
+	SkipToolMessages bool
+	
+	// Export at most this many messages of each session to JSON, YAML,
+	// JSONL and Markdown, the earliest by timestamp, noting how many were
+	// left out (0 = no limit). Overrides the format options.
+	MaxMessagesPerSession int
+	
 	// Custom options for specific formats
 	FormatOptions interface{}
 }
@@ -55,11 +135,14 @@ type ExportOptions struct {
 // Validate validates the export options
 func (o *ExportOptions) Validate() error {
 	switch o.Format {
-	case FormatJSON, FormatMarkdown, FormatHTML:
+	case FormatJSON, FormatMarkdown, FormatHTML, FormatCSV, FormatYAML, FormatText, FormatJSONL, FormatTemplate:
 		// Valid formats
 	default:
 		return fmt.Errorf("unsupported format: %s", o.Format)
 	}
+	if o.MaxMessagesPerSession < 0 {
+		return fmt.Errorf("max messages per session must not be negative: %d", o.MaxMessagesPerSession)
+	}
 	return nil
 }
 
@@ -124,6 +207,22 @@ func ValidateData(data interface{}, exportType ExportType) error {
 		if _, ok := data.([]*models.Project); !ok {
 			return fmt.Errorf("expected []*models.Project for export type %s", exportType)
 		}
+	case ExportTypeIndex:
+		if _, ok := data.([]*converter.IndexEntry); !ok {
+			return fmt.Errorf("expected []*converter.IndexEntry for export type %s", exportType)
+		}
+	case ExportTypeSearch:
+		if _, ok := data.(*converter.SearchResults); !ok {
+			return fmt.Errorf("expected *converter.SearchResults for export type %s", exportType)
+		}
+	case ExportTypePrompts:
+		if _, ok := data.([]*converter.Prompt); !ok {
+			return fmt.Errorf("expected []*converter.Prompt for export type %s", exportType)
+		}
+	case ExportTypeDaily:
+		if _, ok := data.([]*models.DailyUsage); !ok {
+			return fmt.Errorf("expected []*models.DailyUsage for export type %s", exportType)
+		}
 	default:
 		return fmt.Errorf("unsupported export type: %s", exportType)
 	}
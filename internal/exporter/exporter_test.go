@@ -1,8 +1,14 @@
 package exporter
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -100,6 +106,98 @@ func TestFileExporterMarkdown(t *testing.T) {
 	}
 }
 
+func TestFileExporterHTML(t *testing.T) {
+	exporter, err := NewFileExporter(&ExportOptions{Format: FormatHTML})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := exporter.Export(&buf, []*models.Project{createTestProject()}, ExportTypeProjects); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	output := buf.String()
+	if !strings.HasPrefix(output, "<!DOCTYPE html>") || !strings.Contains(output, "Test message") {
+		t.Errorf("Unexpected HTML output:\n%s", output)
+	}
+
+	// The index has no HTML form
+	if err := exporter.Export(&buf, []*converter.IndexEntry{}, ExportTypeIndex); err == nil {
+		t.Error("Export() should error for an index in HTML")
+	}
+}
+
+func TestFileExporterIncludeConfig(t *testing.T) {
+	config := "Always run the tests.\n"
+	project := createTestProject()
+	project.Config = "Use tabs in this project.\n"
+
+	exporter, err := NewFileExporter(&ExportOptions{Format: FormatMarkdown, IncludeConfig: true, Config: config})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := exporter.Export(&buf, project, ExportTypeProject); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	output := buf.String()
+	if !strings.HasPrefix(output, "## Project Instructions\n\nAlways run the tests.\n\n---\n\n# Project: project") {
+		t.Errorf("Markdown should start with the instructions. Output:\n%s", output)
+	}
+	if !strings.Contains(output, "## CLAUDE.md\n\nUse tabs in this project.\n") {
+		t.Errorf("Markdown missing the project's CLAUDE.md. Output:\n%s", output)
+	}
+
+	exporter, err = NewFileExporter(&ExportOptions{Format: FormatJSON, IncludeConfig: true, Config: config})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+	buf.Reset()
+	if err := exporter.Export(&buf, []*models.Project{project}, ExportTypeProjects); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	var result struct {
+		ClaudeMD string `json:"claude_md"`
+		Projects []struct {
+			Config string `json:"project_config"`
+		} `json:"projects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if result.ClaudeMD != config || len(result.Projects) != 1 || result.Projects[0].Config != project.Config {
+		t.Errorf("JSON claude_md = %q and projects %+v", result.ClaudeMD, result.Projects)
+	}
+
+	// Without IncludeConfig the content is left out
+	exporter, _ = NewFileExporter(&ExportOptions{Format: FormatMarkdown, Config: config})
+	buf.Reset()
+	if err := exporter.Export(&buf, project, ExportTypeProject); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "Project Instructions") {
+		t.Errorf("Instructions should only be included with IncludeConfig")
+	}
+}
+
+func TestFileExporterYAML(t *testing.T) {
+	exporter, err := NewFileExporter(&ExportOptions{Format: FormatYAML})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := exporter.Export(&buf, []*models.Project{createTestProject()}, ExportTypeProjects); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"projects:\n", "project_count: 1\n", "id: test-session\n", "content: Test message\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("YAML output missing %q. Output:\n%s", want, output)
+		}
+	}
+}
+
 func TestFileExporterToFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	
@@ -166,6 +264,32 @@ func TestFileExporterProjects(t *testing.T) {
 	}
 }
 
+func TestFileExporterStreamThreshold(t *testing.T) {
+	project := createTestProject()
+	project.AddSession(createTestSession())
+
+	export := func(threshold int) string {
+		exporter, err := NewFileExporter(&ExportOptions{
+			Format:          FormatJSON,
+			StreamThreshold: threshold,
+		})
+		if err != nil {
+			t.Fatalf("NewFileExporter() error = %v", err)
+		}
+		var buf bytes.Buffer
+		if err := exporter.Export(&buf, project, ExportTypeProject); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		return buf.String()
+	}
+
+	// The project's two messages exceed a threshold of 1 and are streamed
+	buffered, streamed := export(0), export(1)
+	if streamed != buffered {
+		t.Errorf("Streamed export differs:\n%s\nwant:\n%s", streamed, buffered)
+	}
+}
+
 func TestExportOptionsValidation(t *testing.T) {
 	// Test valid options
 	opts := &ExportOptions{
@@ -182,6 +306,42 @@ func TestExportOptionsValidation(t *testing.T) {
 	if err := opts.Validate(); err == nil {
 		t.Error("Validate() should error for invalid format")
 	}
+
+	// Test negative message limit
+	opts = &ExportOptions{
+		Format:                FormatMarkdown,
+		MaxMessagesPerSession: -1,
+	}
+	if err := opts.Validate(); err == nil {
+		t.Error("Validate() should error for a negative message limit")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     Format
+		ok       bool
+	}{
+		{"report.md", FormatMarkdown, true},
+		{"notes.Markdown", FormatMarkdown, true},
+		{"/tmp/data.json", FormatJSON, true},
+		{"page.html", FormatHTML, true},
+		{"table.csv", FormatCSV, true},
+		{"config.yaml", FormatYAML, true},
+		{"config.YML", FormatYAML, true},
+		{"log.txt", FormatText, true},
+		{"exports/", "", false},
+		{"archive.tar.gz", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := DetectFormat(tt.filename)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("DetectFormat(%q) = (%q, %v), want (%q, %v)", tt.filename, got, ok, tt.want, tt.ok)
+		}
+	}
 }
 
 func TestValidateData(t *testing.T) {
@@ -202,6 +362,10 @@ func TestValidateData(t *testing.T) {
 		t.Errorf("ValidateData() error for valid projects = %v", err)
 	}
 
+	if err := ValidateData(converter.Search(projects, "test", 1), ExportTypeSearch); err != nil {
+		t.Errorf("ValidateData() error for valid search results = %v", err)
+	}
+
 	// Test invalid data
 	if err := ValidateData(session, ExportTypeProject); err == nil {
 		t.Error("ValidateData() should error for mismatched type")
@@ -290,4 +454,800 @@ func TestCountingWriter(t *testing.T) {
 	if cw.BytesWritten() != expectedTotal {
 		t.Errorf("BytesWritten() after second write = %d, want %d", cw.BytesWritten(), expectedTotal)
 	}
-}
\ No newline at end of file
+}
+
+func TestBatchExporterConcurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	batchExporter := NewBatchExporter(fileExporter, tmpDir, "project_%s.json")
+	batchExporter.Concurrency = 4
+	var progress []int
+	batchExporter.Progress = func(current, total int, filename string) {
+		if total != 50 || filename == "" {
+			t.Errorf("Progress(%d, %d, %q), want a file name out of 50", current, total, filename)
+		}
+		progress = append(progress, current)
+	}
+
+	var projects []*models.Project
+	for i := 0; i < 50; i++ {
+		project := models.NewProject(fmt.Sprintf("-Users-test-project%02d", i))
+		session := createTestSession()
+		session.ID = fmt.Sprintf("session-%02d", i)
+		project.AddSession(session)
+		projects = append(projects, project)
+	}
+
+	result, err := batchExporter.ExportProjects(projects)
+	if err != nil {
+		t.Fatalf("ExportProjects() error = %v", err)
+	}
+
+	if result.HasErrors() {
+		t.Fatalf("Unexpected errors in concurrent batch export: %v", result.Errors)
+	}
+
+	if len(result.Files) != len(projects) {
+		t.Fatalf("Files count = %v, want %v", len(result.Files), len(projects))
+	}
+	for i, current := range progress {
+		if current != i+1 {
+			t.Fatalf("Progress counts = %v, want 1 to 50 in order", progress)
+		}
+	}
+	if len(progress) != len(projects) {
+		t.Errorf("Progress called %d times, want %d", len(progress), len(projects))
+	}
+
+	for i, project := range projects {
+		want := filepath.Join(tmpDir, fmt.Sprintf("project_%s.json", project.GetProjectName()))
+		if result.Files[i] != want {
+			t.Errorf("Files[%d] = %v, want %v", i, result.Files[i], want)
+		}
+
+		data, err := os.ReadFile(result.Files[i])
+		if err != nil {
+			t.Fatalf("Failed to read exported file: %v", err)
+		}
+
+		var exported converter.JSONProject
+		if err := json.Unmarshal(data, &exported); err != nil {
+			t.Fatalf("Failed to parse exported JSON: %v", err)
+		}
+
+		if len(exported.Sessions) != 1 || exported.Sessions[0].ID != project.Sessions[0].ID {
+			t.Errorf("File %s does not contain session %s", result.Files[i], project.Sessions[0].ID)
+		}
+	}
+}
+
+
+func TestBatchExporterSameProjectName(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	batchExporter := NewBatchExporter(fileExporter, tmpDir, "project_%s.json")
+	batchExporter.Concurrency = 2
+	batchExporter.WriteIndex = true
+
+	// /a/api and /b/api share a base name but must not share a file
+	var projects []*models.Project
+	for _, encoded := range []string{"-a-api", "-b-api"} {
+		project := models.NewProject(encoded)
+		session := createTestSession()
+		session.ID = "session" + encoded
+		project.AddSession(session)
+		projects = append(projects, project)
+	}
+
+	result, err := batchExporter.ExportProjects(projects)
+	if err != nil {
+		t.Fatalf("ExportProjects() error = %v", err)
+	}
+	want := []string{filepath.Join(tmpDir, "project_api.json"), filepath.Join(tmpDir, "project_api_2.json")}
+	if len(result.Files) != 2 || result.Files[0] != want[0] || result.Files[1] != want[1] {
+		t.Fatalf("Files = %v, want %v", result.Files, want)
+	}
+	for i, file := range want {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read exported file: %v", err)
+		}
+		if !strings.Contains(string(data), projects[i].Sessions[0].ID) {
+			t.Errorf("File %s does not contain session %s", file, projects[i].Sessions[0].ID)
+		}
+	}
+
+	content, err := os.ReadFile(result.IndexFile)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	var index FileIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		t.Fatalf("Failed to parse index: %v", err)
+	}
+	if len(index.Files) != 2 || index.Files[0].File != "project_api.json" || index.Files[1].File != "project_api_2.json" {
+		t.Errorf("Index files = %+v", index.Files)
+	}
+}
+
+func TestBatchExporterDatePrefix(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	batchExporter := NewBatchExporter(fileExporter, tmpDir, "project_%s.md")
+	batchExporter.DatePrefix = true
+
+	project := models.NewProject("-Users-test-recent")
+	session := createTestSession()
+	session.EndTime = time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+	project.AddSession(session)
+
+	emptyProject := models.NewProject("-Users-test-empty")
+
+	result, err := batchExporter.ExportProjects([]*models.Project{project, emptyProject})
+	if err != nil {
+		t.Fatalf("ExportProjects() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(tmpDir, "2024-07-15_project_recent.md"),
+		filepath.Join(tmpDir, "project_empty.md"),
+	}
+	for i, file := range want {
+		if result.Files[i] != file {
+			t.Errorf("Files[%d] = %v, want %v", i, result.Files[i], file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Expected file %s to exist: %v", file, err)
+		}
+	}
+}
+
+func TestBatchExporterWriteIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	batchExporter := NewBatchExporter(fileExporter, tmpDir, "project_%s.md")
+	batchExporter.WriteIndex = true
+
+	project := createTestProject()
+	emptyProject := models.NewProject("-Users-test-empty")
+
+	result, err := batchExporter.ExportProjects([]*models.Project{project, emptyProject})
+	if err != nil {
+		t.Fatalf("ExportProjects() error = %v", err)
+	}
+	if result.IndexFile != filepath.Join(tmpDir, DefaultFileIndexName) {
+		t.Fatalf("IndexFile = %v", result.IndexFile)
+	}
+
+	content, err := os.ReadFile(result.IndexFile)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	var index FileIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		t.Fatalf("Failed to parse index: %v", err)
+	}
+
+	if index.Format != FormatMarkdown || len(index.Files) != 2 {
+		t.Fatalf("Expected 2 markdown files, got %+v", index)
+	}
+	entry := index.Files[0]
+	info, err := os.Stat(filepath.Join(tmpDir, "project_project.md"))
+	if err != nil {
+		t.Fatalf("Expected project file: %v", err)
+	}
+	if entry.File != "project_project.md" || entry.Project != "project" || entry.Path != project.Path ||
+		entry.SessionCount != 1 || entry.MessageCount != 1 || entry.Size != info.Size() {
+		t.Errorf("Unexpected index entry %+v", entry)
+	}
+	if entry.DateRange == nil || entry.DateRange.Start != "2024-01-01" || entry.DateRange.End != "2024-01-01" {
+		t.Errorf("DateRange = %+v, want 2024-01-01 to 2024-01-01", entry.DateRange)
+	}
+	if index.Files[1].DateRange != nil {
+		t.Errorf("Expected no date range for an empty project, got %+v", index.Files[1].DateRange)
+	}
+}
+
+func TestBatchExporterIncremental(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := t.TempDir()
+	manifestPath := filepath.Join(tmpDir, DefaultManifestName)
+
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+	batchExporter := NewBatchExporter(fileExporter, tmpDir, "project_%s.md")
+
+	var projects []*models.Project
+	var sourceFiles []string
+	for _, name := range []string{"one", "two"} {
+		sourceFile := filepath.Join(sourceDir, name+".jsonl")
+		if err := os.WriteFile(sourceFile, []byte("{}"), 0644); err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+		session := createTestSession()
+		session.SourceFile = sourceFile
+		project := models.NewProject("-Users-test-" + name)
+		project.AddSession(session)
+		projects = append(projects, project)
+		sourceFiles = append(sourceFiles, sourceFile)
+	}
+
+	result, err := batchExporter.ExportProjectsIncremental(projects, manifestPath)
+	if err != nil {
+		t.Fatalf("ExportProjectsIncremental() error = %v", err)
+	}
+	if len(result.Files) != 2 || len(result.Skipped) != 0 {
+		t.Fatalf("First export: %d written, %d skipped, want 2 and 0", len(result.Files), len(result.Skipped))
+	}
+	if _, err := os.Stat(manifestPath); err != nil {
+		t.Fatalf("Expected manifest to exist: %v", err)
+	}
+
+	result, err = batchExporter.ExportProjectsIncremental(projects, manifestPath)
+	if err != nil {
+		t.Fatalf("ExportProjectsIncremental() error = %v", err)
+	}
+	if len(result.Files) != 0 || len(result.Skipped) != 2 {
+		t.Fatalf("Unchanged export: %d written, %d skipped, want 0 and 2", len(result.Files), len(result.Skipped))
+	}
+	if !strings.Contains(result.Summary(), "2 unchanged, skipped") {
+		t.Errorf("Summary() = %q, want the skipped count", result.Summary())
+	}
+
+	// The file index lists skipped files too
+	batchExporter.WriteIndex = true
+	result, err = batchExporter.ExportProjectsIncremental(projects, manifestPath)
+	if err != nil {
+		t.Fatalf("ExportProjectsIncremental() error = %v", err)
+	}
+	batchExporter.WriteIndex = false
+	content, err := os.ReadFile(result.IndexFile)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	var index FileIndex
+	if err := json.Unmarshal(content, &index); err != nil || len(index.Files) != 2 {
+		t.Errorf("Expected both skipped files in the index, got %s", content)
+	}
+
+	// Changing a source re-exports only its project
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(sourceFiles[1], later, later); err != nil {
+		t.Fatalf("Failed to touch source file: %v", err)
+	}
+	// Removing an output re-exports it even though its source is unchanged
+	if err := os.Remove(filepath.Join(tmpDir, "project_one.md")); err != nil {
+		t.Fatalf("Failed to remove output: %v", err)
+	}
+
+	result, err = batchExporter.ExportProjectsIncremental(projects, manifestPath)
+	if err != nil {
+		t.Fatalf("ExportProjectsIncremental() error = %v", err)
+	}
+	if len(result.Files) != 2 || len(result.Skipped) != 0 {
+		t.Errorf("Changed export: %d written, %d skipped, want 2 and 0", len(result.Files), len(result.Skipped))
+	}
+
+	// Projects without source files are always exported
+	result, err = batchExporter.ExportProjectsIncremental([]*models.Project{createTestProject()}, manifestPath)
+	if err != nil {
+		t.Fatalf("ExportProjectsIncremental() error = %v", err)
+	}
+	result, err = batchExporter.ExportProjectsIncremental([]*models.Project{createTestProject()}, manifestPath)
+	if err != nil {
+		t.Fatalf("ExportProjectsIncremental() error = %v", err)
+	}
+	if len(result.Files) != 1 || len(result.Skipped) != 0 {
+		t.Errorf("Export without sources: %d written, %d skipped, want 1 and 0", len(result.Files), len(result.Skipped))
+	}
+}
+
+// cancelWriter cancels a context after its first write
+type cancelWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.Buffer.Write(p)
+}
+
+func TestFileExporterExportContextCancel(t *testing.T) {
+	var projects []*models.Project
+	for _, name := range []string{"-Users-test-first", "-Users-test-second", "-Users-test-third"} {
+		project := models.NewProject(name)
+		project.AddSession(createTestSession())
+		projects = append(projects, project)
+	}
+
+	for _, format := range []Format{FormatJSON, FormatMarkdown} {
+		t.Run(string(format), func(t *testing.T) {
+			exporter, err := NewFileExporter(&ExportOptions{Format: format})
+			if err != nil {
+				t.Fatalf("NewFileExporter() error = %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			writer := &cancelWriter{cancel: cancel}
+
+			err = exporter.ExportContext(ctx, writer, projects, ExportTypeProjects)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("ExportContext() error = %v, want context.Canceled", err)
+			}
+
+			output := writer.String()
+			if strings.Contains(output, "second") || strings.Contains(output, "third") {
+				t.Errorf("Export should stop after cancellation, got:\n%s", output)
+			}
+		})
+	}
+
+	exporter, _ := NewFileExporter(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := exporter.ExportContext(ctx, &buf, createTestSession(), ExportTypeSession); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExportContext() with cancelled context error = %v, want context.Canceled", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("ExportContext() with cancelled context wrote %d bytes", buf.Len())
+	}
+}
+
+// lateCancelContext reports itself cancelled from the given call to Err on,
+// so cancellation can be timed to land mid-export
+type lateCancelContext struct {
+	context.Context
+	calls, cancelAt int
+}
+
+func (c *lateCancelContext) Err() error {
+	c.calls++
+	if c.calls >= c.cancelAt {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestFileExporterExportContextCancelSessions(t *testing.T) {
+	project := models.NewProject("-Users-test-project")
+	for _, id := range []string{"first-session", "second-session", "third-session"} {
+		session := createTestSession()
+		session.ID = id
+		project.AddSession(session)
+	}
+
+	formats := []*ExportOptions{
+		{Format: FormatMarkdown},
+		{Format: FormatHTML},
+		{Format: FormatText},
+		{Format: FormatCSV},
+		{Format: FormatJSONL},
+		{Format: FormatTemplate, FormatOptions: &converter.TemplateOptions{Text: "{{range .Sessions}}{{.ID}}\n{{end}}"}},
+	}
+	for _, options := range formats {
+		t.Run(string(options.Format), func(t *testing.T) {
+			exporter, err := NewFileExporter(options)
+			if err != nil {
+				t.Fatalf("NewFileExporter() error = %v", err)
+			}
+
+			// Cancelled while the second session is converted
+			ctx := &lateCancelContext{Context: context.Background(), cancelAt: 3}
+			var buf bytes.Buffer
+			err = exporter.ExportContext(ctx, &buf, project, ExportTypeProject)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("ExportContext() error = %v, want context.Canceled", err)
+			}
+			if strings.Contains(buf.String(), "third-session") {
+				t.Errorf("Export should stop after cancellation, got:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestExportToFileContextCancel(t *testing.T) {
+	tmpDir := t.TempDir()
+	exporter, err := NewFileExporter(&ExportOptions{Format: FormatMarkdown})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+	projects := []*models.Project{createTestProject(), createTestProject()}
+
+	// Cancelled after the first project is written: the partial file is removed
+	filename := filepath.Join(tmpDir, "partial.md")
+	ctx := &lateCancelContext{Context: context.Background(), cancelAt: 4}
+	err = exporter.ExportToFileContext(ctx, filename, projects, ExportTypeProjects)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExportToFileContext() error = %v, want context.Canceled", err)
+	}
+	if !strings.Contains(err.Error(), "removed partial file") {
+		t.Errorf("ExportToFileContext() error = %v, want the partial file reported", err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected partial file %s to be removed, stat error = %v", filename, err)
+	}
+
+	// A batch export cancelled before it starts writes nothing and reports
+	// every project
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	batchExporter := NewBatchExporter(exporter, tmpDir, "project_%s.md")
+	result, err := batchExporter.ExportProjectsContext(cancelled, []*models.Project{createTestProject()})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExportProjectsContext() error = %v, want context.Canceled", err)
+	}
+	if result == nil || result.SuccessCount != 0 || len(result.Errors) != 1 {
+		t.Fatalf("ExportProjectsContext() result = %+v, want 1 error and no files", result)
+	}
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Errorf("Expected no files after cancellation, got %d", len(entries))
+	}
+}
+
+func TestBatchExporterProjectSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fileExporter, err := NewFileExporter(&ExportOptions{Format: FormatMarkdown})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	// Two projects with the same name and a session ID in common collide
+	first := models.NewProject("-Users-a-app")
+	first.AddSession(createTestSession())
+	second := createTestSession()
+	second.ID = "second-session"
+	first.AddSession(second)
+	other := models.NewProject("-Users-b-app")
+	other.AddSession(createTestSession())
+	projects := []*models.Project{first, other}
+
+	batchExporter := NewBatchExporter(fileExporter, tmpDir, "%s.md")
+	batchExporter.Granularity = GranularitySession
+	result, err := batchExporter.ExportProjectSessions(projects)
+	if err != nil {
+		t.Fatalf("ExportProjectSessions() error = %v", err)
+	}
+
+	want := []string{"app__test-session.md", "app__second-session.md", "app__test-session_2.md"}
+	if result.SuccessCount != len(want) || len(result.Files) != len(want) {
+		t.Fatalf("Exported %d files, want %d: %v", len(result.Files), len(want), result.Files)
+	}
+	for i, name := range want {
+		if result.Files[i] != filepath.Join(tmpDir, name) {
+			t.Errorf("Files[%d] = %s, want %s", i, result.Files[i], name)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "app__second-session.md"))
+	if err != nil {
+		t.Fatalf("Failed to read session file: %v", err)
+	}
+	if !strings.Contains(string(content), "# Session: second-session") || strings.Contains(string(content), "# Session: test-session") {
+		t.Errorf("Session file should contain only its session, got:\n%s", content)
+	}
+
+	indexFile, err := batchExporter.ExportIndex(projects, "index.md")
+	if err != nil {
+		t.Fatalf("ExportIndex() error = %v", err)
+	}
+	index, _ := os.ReadFile(indexFile)
+	if !strings.Contains(string(index), "app__test-session_2.md") {
+		t.Errorf("Index should link to session files, got:\n%s", index)
+	}
+}
+
+func TestFileExporterSkipToolMessages(t *testing.T) {
+	session := createTestSession()
+	for _, raw := range []struct {
+		msgType models.MessageType
+		content string
+	}{
+		{models.MessageTypeAssistant, `{"role":"assistant","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"ls"}}]}`},
+		{models.MessageTypeUser, `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"main.go"}]}`},
+	} {
+		msg := &models.Message{UUID: fmt.Sprintf("msg%d", len(session.Messages)+1), Type: raw.msgType, Message: json.RawMessage(raw.content)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	// The option overrides the format options of each format
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatJSONL, FormatMarkdown} {
+		t.Run(string(format), func(t *testing.T) {
+			exporter, err := NewFileExporter(&ExportOptions{Format: format, SkipToolMessages: true})
+			if err != nil {
+				t.Fatalf("NewFileExporter() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := exporter.Export(&buf, session, ExportTypeSession); err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			if !strings.Contains(buf.String(), "Checking.") {
+				t.Errorf("Missing assistant text:\n%s", buf.String())
+			}
+			if strings.Contains(buf.String(), "toolu_1") || strings.Contains(buf.String(), "main.go") {
+				t.Errorf("Tool call or result not skipped:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestBatchExporterDateStrategy(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	batchExporter := NewBatchExporter(fileExporter, tmpDir, "project_%s.md")
+	batchExporter.PathStrategy = DateStrategy
+
+	january := models.NewProject("-Users-test-january")
+	january.AddSession(createTestSession())
+	july := models.NewProject("-Users-test-july")
+	session := createTestSession()
+	session.ID = "july-session"
+	session.StartTime = time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)
+	july.AddSession(session)
+	emptyProject := models.NewProject("-Users-test-empty")
+	projects := []*models.Project{january, july, emptyProject}
+
+	result, err := batchExporter.ExportProjects(projects)
+	if err != nil {
+		t.Fatalf("ExportProjects() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(tmpDir, "2024", "01", "project_january.md"),
+		filepath.Join(tmpDir, "2024", "07", "project_july.md"),
+		filepath.Join(tmpDir, "project_empty.md"),
+	}
+	for i, file := range want {
+		if result.Files[i] != file {
+			t.Errorf("Files[%d] = %v, want %v", i, result.Files[i], file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Expected file %s to exist: %v", file, err)
+		}
+	}
+
+	// Sessions are placed by their own start date
+	result, err = batchExporter.ExportProjectSessions(projects)
+	if err != nil {
+		t.Fatalf("ExportProjectSessions() error = %v", err)
+	}
+	if file := filepath.Join(tmpDir, "2024", "07", "project_july__july-session.md"); result.Files[1] != file {
+		t.Errorf("Files[1] = %v, want %v", result.Files[1], file)
+	}
+
+	// A directory that cannot be created fails only the files in it
+	blockedDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(blockedDir, "2024"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create blocking file: %v", err)
+	}
+	batchExporter = NewBatchExporter(fileExporter, blockedDir, "project_%s.md")
+	batchExporter.PathStrategy = DateStrategy
+	result, err = batchExporter.ExportProjects(projects)
+	if err != nil {
+		t.Fatalf("ExportProjects() error = %v", err)
+	}
+	if result.SuccessCount != 1 || len(result.Errors) != 2 {
+		t.Fatalf("Got %d files and errors %+v, want 1 file and 2 errors", result.SuccessCount, result.Errors)
+	}
+	if result.Errors[0].Item != january.ID || !strings.Contains(result.Errors[0].Error, "failed to create directory") {
+		t.Errorf("Errors[0] = %+v, want a directory error for %s", result.Errors[0], january.ID)
+	}
+}
+
+func TestTarExporter(t *testing.T) {
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	var projects []*models.Project
+	for i := 0; i < 20; i++ {
+		project := models.NewProject(fmt.Sprintf("-Users-test-project%02d", i))
+		session := createTestSession()
+		session.ID = fmt.Sprintf("session-%02d", i)
+		project.AddSession(session)
+		projects = append(projects, project)
+	}
+
+	tarExporter := NewTarExporter(fileExporter, "project_%s.json")
+	tarExporter.Gzip = true
+	tarExporter.Concurrency = 4
+	var progress []int
+	tarExporter.Progress = func(current, total int, filename string) {
+		progress = append(progress, current)
+	}
+
+	var buf bytes.Buffer
+	result, err := tarExporter.ExportProjects(&buf, projects)
+	if err != nil {
+		t.Fatalf("ExportProjects() error = %v", err)
+	}
+	if result.HasErrors() || result.SuccessCount != len(projects) || result.IndexFile != DefaultFileIndexName {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if len(progress) != len(projects) || progress[len(progress)-1] != len(projects) {
+		t.Errorf("Progress counts = %v, want 1 to %d", progress, len(projects))
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Archive is not gzipped: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	entries := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("Failed to read entry %s: %v", header.Name, err)
+		}
+		if header.Name != DefaultFileIndexName && !header.ModTime.Equal(createTestSession().EndTime) {
+			t.Errorf("Entry %s ModTime = %v, want the project's end time", header.Name, header.ModTime)
+		}
+		names = append(names, header.Name)
+		entries[header.Name] = data
+	}
+
+	// Project entries come in project order, with the index last
+	if len(names) != len(projects)+1 || names[len(names)-1] != DefaultFileIndexName {
+		t.Fatalf("Archive entries = %v, want %d projects then %s", names, len(projects), DefaultFileIndexName)
+	}
+	for i, project := range projects {
+		want := fmt.Sprintf("project_%s.json", project.GetProjectName())
+		if names[i] != want {
+			t.Errorf("Entry %d = %v, want %v", i, names[i], want)
+		}
+		var exported converter.JSONProject
+		if err := json.Unmarshal(entries[want], &exported); err != nil {
+			t.Fatalf("Failed to parse entry %s: %v", want, err)
+		}
+		if len(exported.Sessions) != 1 || exported.Sessions[0].ID != project.Sessions[0].ID {
+			t.Errorf("Entry %s does not contain session %s", want, project.Sessions[0].ID)
+		}
+	}
+
+	var index FileIndex
+	if err := json.Unmarshal(entries[DefaultFileIndexName], &index); err != nil {
+		t.Fatalf("Failed to parse index: %v", err)
+	}
+	if index.Format != FormatJSON || len(index.Files) != len(projects) {
+		t.Fatalf("Expected %d JSON files in the index, got %+v", len(projects), index)
+	}
+	if entry := index.Files[0]; entry.File != names[0] || entry.Size != int64(len(entries[names[0]])) || entry.SessionCount != 1 {
+		t.Errorf("Unexpected index entry %+v", entry)
+	}
+}
+
+func TestTarExporterSameProjectName(t *testing.T) {
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	var projects []*models.Project
+	for _, encoded := range []string{"-a-api", "-b-api"} {
+		project := models.NewProject(encoded)
+		project.AddSession(createTestSession())
+		projects = append(projects, project)
+	}
+
+	var buf bytes.Buffer
+	result, err := NewTarExporter(fileExporter, "project_%s.md").ExportProjects(&buf, projects)
+	if err != nil {
+		t.Fatalf("ExportProjects() error = %v", err)
+	}
+	if strings.Join(result.Files, ",") != "project_api.md,project_api_2.md" {
+		t.Errorf("Files = %v, want project_api.md and project_api_2.md", result.Files)
+	}
+
+	tr := tar.NewReader(&buf)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	if strings.Join(names, ",") != "project_api.md,project_api_2.md,"+DefaultFileIndexName {
+		t.Errorf("Archive entries = %v, want unique names for both projects", names)
+	}
+}
+
+func TestTarExporterCancel(t *testing.T) {
+	fileExporter, err := NewFileExporter(&ExportOptions{
+		Format: FormatMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tarExporter := NewTarExporter(fileExporter, "project_%s.md")
+	tarExporter.Concurrency = 2
+	projects := []*models.Project{createTestProject(), createTestProject(), createTestProject()}
+	result, err := tarExporter.ExportProjectsContext(ctx, io.Discard, projects)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExportProjectsContext() error = %v, want context.Canceled", err)
+	}
+	if result.IndexFile != "" {
+		t.Errorf("IndexFile = %q, want no index after cancellation", result.IndexFile)
+	}
+}
+
+func TestTarArchiveType(t *testing.T) {
+	tests := []struct {
+		filename       string
+		isTar, gzipped bool
+	}{
+		{"export.tar", true, false},
+		{"export.tar.gz", true, true},
+		{"EXPORT.TGZ", true, true},
+		{"export.gz", false, false},
+		{"export", false, false},
+	}
+	for _, tt := range tests {
+		isTar, gzipped := TarArchiveType(tt.filename)
+		if isTar != tt.isTar || gzipped != tt.gzipped {
+			t.Errorf("TarArchiveType(%q) = %v, %v, want %v, %v", tt.filename, isTar, gzipped, tt.isTar, tt.gzipped)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package exporter
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/eternnoir/cc-history-export/internal/converter"
 	"github.com/eternnoir/cc-history-export/internal/models"
@@ -15,6 +20,12 @@ type FileExporter struct {
 	*BaseExporter
 	jsonConverter     *converter.JSONConverter
 	markdownConverter *converter.MarkdownConverter
+	htmlConverter     *converter.HTMLConverter
+	csvConverter      *converter.CSVConverter
+	yamlConverter     *converter.YAMLConverter
+	textConverter     *converter.TextConverter
+	jsonlConverter    *converter.JSONLConverter
+	templateConverter *converter.TemplateConverter
 }
 
 // NewFileExporter creates a new file exporter
@@ -45,7 +56,7 @@ func NewFileExporter(options *ExportOptions) (*FileExporter, error) {
 		if opts, ok := options.FormatOptions.(*converter.JSONOptions); ok {
 			jsonOpts = opts
 		}
-		exporter.jsonConverter = converter.NewJSONConverter(jsonOpts)
+		exporter.jsonConverter = converter.NewJSONConverter(withConfig(withMessageOptions(jsonOpts, options), options))
 
 	case FormatMarkdown:
 		mdOpts := &converter.MarkdownOptions{
@@ -55,28 +66,132 @@ func NewFileExporter(options *ExportOptions) (*FileExporter, error) {
 		if opts, ok := options.FormatOptions.(*converter.MarkdownOptions); ok {
 			mdOpts = opts
 		}
+		if options.SkipToolMessages || options.MaxMessagesPerSession > 0 {
+			copied := *mdOpts
+			copied.SkipToolMessages = copied.SkipToolMessages || options.SkipToolMessages
+			if options.MaxMessagesPerSession > 0 {
+				copied.MaxMessages = options.MaxMessagesPerSession
+			}
+			mdOpts = &copied
+		}
 		exporter.markdownConverter = converter.NewMarkdownConverter(mdOpts)
 
 	case FormatHTML:
-		return nil, fmt.Errorf("HTML format not yet implemented")
+		htmlOpts := &converter.HTMLOptions{
+			ShowTimestamps: true,
+			ShowTokenUsage: true,
+		}
+		if opts, ok := options.FormatOptions.(*converter.HTMLOptions); ok {
+			htmlOpts = opts
+		}
+		exporter.htmlConverter = converter.NewHTMLConverter(htmlOpts)
+
+	case FormatCSV:
+		exporter.csvConverter = converter.NewCSVConverter()
+
+	case FormatYAML:
+		// YAML mirrors the JSON output and takes the same options
+		yamlOpts := &converter.JSONOptions{
+			OmitEmpty: true,
+		}
+		if opts, ok := options.FormatOptions.(*converter.JSONOptions); ok {
+			yamlOpts = opts
+		}
+		exporter.yamlConverter = converter.NewYAMLConverter(withConfig(withMessageOptions(yamlOpts, options), options))
+
+	case FormatText:
+		textOpts := &converter.TextOptions{}
+		if opts, ok := options.FormatOptions.(*converter.TextOptions); ok {
+			textOpts = opts
+		}
+		exporter.textConverter = converter.NewTextConverter(textOpts)
+
+	case FormatJSONL:
+		// JSON Lines holds the messages of the JSON output
+		jsonlOpts := &converter.JSONOptions{
+			OmitEmpty: true,
+		}
+		if opts, ok := options.FormatOptions.(*converter.JSONOptions); ok {
+			jsonlOpts = opts
+		}
+		exporter.jsonlConverter = converter.NewJSONLConverter(withMessageOptions(jsonlOpts, options))
+
+	case FormatTemplate:
+		// Templates have no default; the template must be given
+		tmplOpts, _ := options.FormatOptions.(*converter.TemplateOptions)
+		templateConverter, err := converter.NewTemplateConverter(tmplOpts)
+		if err != nil {
+			return nil, err
+		}
+		exporter.templateConverter = templateConverter
 	}
 
 	return exporter, nil
 }
 
+// withConfig returns a copy of the JSON options that includes the CLAUDE.md
+// content of the export options if requested
+func withConfig(jsonOpts *converter.JSONOptions, options *ExportOptions) *converter.JSONOptions {
+	if !options.IncludeConfig {
+		return jsonOpts
+	}
+	copied := *jsonOpts
+	copied.ClaudeMD = options.Config
+	return &copied
+}
+
+// withMessageOptions returns a copy of the JSON options that skips tool
+// messages and limits the messages per session if the export options ask
+// for it
+func withMessageOptions(jsonOpts *converter.JSONOptions, options *ExportOptions) *converter.JSONOptions {
+	if !options.SkipToolMessages && options.MaxMessagesPerSession <= 0 {
+		return jsonOpts
+	}
+	copied := *jsonOpts
+	copied.SkipToolMessages = copied.SkipToolMessages || options.SkipToolMessages
+	if options.MaxMessagesPerSession > 0 {
+		copied.MaxMessages = options.MaxMessagesPerSession
+	}
+	return &copied
+}
+
 // Export writes the exported data to the writer
 func (e *FileExporter) Export(writer io.Writer, data interface{}, exportType ExportType) error {
+	return e.ExportContext(context.Background(), writer, data, exportType)
+}
+
+// ExportContext writes the exported data to the writer, checking ctx before
+// converting each session, or each message of streamed JSON. Documents
+// converted as a whole, such as YAML, are checked before they are written.
+// Once ctx is done it stops writing and returns the context error; output
+// written so far is left incomplete.
+func (e *FileExporter) ExportContext(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
 	if err := ValidateData(data, exportType); err != nil {
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	countingWriter := NewCountingWriter(writer)
 
 	switch e.format {
 	case FormatJSON:
-		return e.exportJSON(countingWriter, data, exportType)
+		return e.exportJSON(ctx, countingWriter, data, exportType)
 	case FormatMarkdown:
-		return e.exportMarkdown(countingWriter, data, exportType)
+		return e.exportMarkdown(ctx, countingWriter, data, exportType)
+	case FormatHTML:
+		return e.exportHTML(ctx, countingWriter, data, exportType)
+	case FormatCSV:
+		return e.exportCSV(ctx, countingWriter, data, exportType)
+	case FormatYAML:
+		return e.exportYAML(ctx, countingWriter, data, exportType)
+	case FormatText:
+		return e.exportText(ctx, countingWriter, data, exportType)
+	case FormatJSONL:
+		return e.exportJSONL(ctx, countingWriter, data, exportType)
+	case FormatTemplate:
+		return e.exportTemplate(ctx, countingWriter, data, exportType)
 	default:
 		return fmt.Errorf("unsupported format: %s", e.format)
 	}
@@ -84,9 +199,19 @@ func (e *FileExporter) Export(writer io.Writer, data interface{}, exportType Exp
 
 // ExportToFile exports data to a file
 func (e *FileExporter) ExportToFile(filename string, data interface{}, exportType ExportType) error {
+	return e.ExportToFileContext(context.Background(), filename, data, exportType)
+}
+
+// ExportToFileContext is like ExportToFile but stops writing once ctx is
+// done, as ExportContext does. A file left incomplete by cancellation is
+// removed.
+func (e *FileExporter) ExportToFileContext(ctx context.Context, filename string, data interface{}, exportType ExportType) error {
 	// If filename is empty or "-", write to stdout
 	if filename == "" || filename == "-" {
-		return e.Export(os.Stdout, data, exportType)
+		return e.ExportContext(ctx, os.Stdout, data, exportType)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
 	// Create directory if it doesn't exist
@@ -103,7 +228,12 @@ func (e *FileExporter) ExportToFile(filename string, data interface{}, exportTyp
 	defer file.Close()
 
 	// Export to file
-	if err := e.Export(file, data, exportType); err != nil {
+	if err := e.ExportContext(ctx, file, data, exportType); err != nil {
+		if ctx.Err() != nil {
+			file.Close()
+			os.Remove(filename)
+			return fmt.Errorf("failed to export, removed partial file %s: %w", filename, err)
+		}
 		return fmt.Errorf("failed to export: %w", err)
 	}
 
@@ -111,7 +241,7 @@ func (e *FileExporter) ExportToFile(filename string, data interface{}, exportTyp
 }
 
 // exportJSON exports data as JSON
-func (e *FileExporter) exportJSON(writer io.Writer, data interface{}, exportType ExportType) error {
+func (e *FileExporter) exportJSON(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
 	var jsonData []byte
 	var err error
 
@@ -122,11 +252,36 @@ func (e *FileExporter) exportJSON(writer io.Writer, data interface{}, exportType
 		
 	case ExportTypeProject:
 		project := data.(*models.Project)
+		threshold := e.options.StreamThreshold
+		if threshold <= 0 {
+			threshold = DefaultStreamThreshold
+		}
+		if project.GetTotalMessages() > threshold {
+			if err := e.jsonConverter.StreamProjectContext(ctx, writer, project); err != nil {
+				return fmt.Errorf("failed to convert to JSON: %w", err)
+			}
+			return nil
+		}
 		jsonData, err = e.jsonConverter.ConvertProject(project)
 		
 	case ExportTypeProjects:
+		// Stream projects one at a time to bound memory on large exports
 		projects := data.([]*models.Project)
-		jsonData, err = e.jsonConverter.ConvertProjects(projects)
+		if err := e.jsonConverter.StreamProjectsContext(ctx, writer, projects); err != nil {
+			return fmt.Errorf("failed to convert to JSON: %w", err)
+		}
+		return nil
+		
+	case ExportTypeIndex:
+		entries := data.([]*converter.IndexEntry)
+		jsonData, err = e.jsonConverter.ConvertIndex(entries)
+		
+	case ExportTypeSearch:
+		results := data.(*converter.SearchResults)
+		jsonData, err = e.jsonConverter.ConvertSearchResults(results)
+		
+	case ExportTypePrompts:
+		jsonData, err = e.jsonConverter.ConvertPrompts(data.([]*converter.Prompt))
 		
 	default:
 		return fmt.Errorf("unsupported export type: %s", exportType)
@@ -135,15 +290,62 @@ func (e *FileExporter) exportJSON(writer io.Writer, data interface{}, exportType
 	if err != nil {
 		return fmt.Errorf("failed to convert to JSON: %w", err)
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	_, err = writer.Write(jsonData)
 	return err
 }
 
+// exportYAML exports data as YAML. The document is converted as a whole, so
+// ctx is checked before writing it.
+func (e *FileExporter) exportYAML(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
+	var yamlData []byte
+	var err error
+
+	switch exportType {
+	case ExportTypeSession:
+		yamlData, err = e.yamlConverter.ConvertSession(data.(*models.Session))
+	case ExportTypeProject:
+		yamlData, err = e.yamlConverter.ConvertProject(data.(*models.Project))
+	case ExportTypeProjects:
+		yamlData, err = e.yamlConverter.ConvertProjects(data.([]*models.Project))
+	case ExportTypeIndex:
+		yamlData, err = e.yamlConverter.ConvertIndex(data.([]*converter.IndexEntry))
+	case ExportTypeSearch:
+		yamlData, err = e.yamlConverter.ConvertSearchResults(data.(*converter.SearchResults))
+	case ExportTypePrompts:
+		yamlData, err = e.yamlConverter.ConvertPrompts(data.([]*converter.Prompt))
+	default:
+		return fmt.Errorf("unsupported export type: %s", exportType)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to convert to YAML: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err = writer.Write(yamlData)
+	return err
+}
+
 // exportMarkdown exports data as Markdown
-func (e *FileExporter) exportMarkdown(writer io.Writer, data interface{}, exportType ExportType) error {
+func (e *FileExporter) exportMarkdown(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
 	var markdown string
 
+	// Conversations start with the instructions they were held under
+	if e.options.IncludeConfig && e.options.Config != "" {
+		switch exportType {
+		case ExportTypeSession, ExportTypeProject, ExportTypeProjects:
+			if _, err := io.WriteString(writer, e.markdownConverter.ConvertInstructions(e.options.Config)); err != nil {
+				return err
+			}
+		}
+	}
+
 	switch exportType {
 	case ExportTypeSession:
 		session := data.(*models.Session)
@@ -151,17 +353,38 @@ func (e *FileExporter) exportMarkdown(writer io.Writer, data interface{}, export
 		
 	case ExportTypeProject:
 		project := data.(*models.Project)
-		markdown = e.markdownConverter.ConvertProject(project)
+		var err error
+		if markdown, err = e.markdownConverter.ConvertProjectContext(ctx, project); err != nil {
+			return err
+		}
 		
 	case ExportTypeProjects:
 		projects := data.([]*models.Project)
-		// Convert each project and combine
+		// Convert and write one project at a time
 		for i, project := range projects {
+			markdown, err := e.markdownConverter.ConvertProjectContext(ctx, project)
+			if err != nil {
+				return err
+			}
 			if i > 0 {
-				markdown += "\n\n---\n\n"
+				markdown = "\n\n---\n\n" + markdown
+			}
+			if _, err := io.WriteString(writer, markdown); err != nil {
+				return err
 			}
-			markdown += e.markdownConverter.ConvertProject(project)
 		}
+		return nil
+		
+	case ExportTypeIndex:
+		entries := data.([]*converter.IndexEntry)
+		markdown = e.markdownConverter.ConvertIndex(entries)
+		
+	case ExportTypeSearch:
+		results := data.(*converter.SearchResults)
+		markdown = e.markdownConverter.ConvertSearchResults(results)
+		
+	case ExportTypePrompts:
+		markdown = e.markdownConverter.ConvertPrompts(data.([]*converter.Prompt))
 		
 	default:
 		return fmt.Errorf("unsupported export type: %s", exportType)
@@ -171,11 +394,144 @@ func (e *FileExporter) exportMarkdown(writer io.Writer, data interface{}, export
 	return err
 }
 
+// exportHTML exports data as a self-contained HTML document
+func (e *FileExporter) exportHTML(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
+	var document string
+	var err error
+
+	switch exportType {
+	case ExportTypeSession:
+		document = e.htmlConverter.ConvertSession(data.(*models.Session))
+	case ExportTypeProject:
+		document, err = e.htmlConverter.ConvertProjectContext(ctx, data.(*models.Project))
+	case ExportTypeProjects:
+		document, err = e.htmlConverter.ConvertProjectsContext(ctx, data.([]*models.Project))
+	default:
+		return fmt.Errorf("unsupported export type for HTML: %s", exportType)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(writer, document)
+	return err
+}
+
+// exportText exports data as plain text
+func (e *FileExporter) exportText(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
+	var text string
+	var err error
+
+	switch exportType {
+	case ExportTypeSession:
+		text = e.textConverter.ConvertSession(data.(*models.Session))
+	case ExportTypeProject:
+		text, err = e.textConverter.ConvertProjectContext(ctx, data.(*models.Project))
+	case ExportTypeProjects:
+		text, err = e.textConverter.ConvertProjectsContext(ctx, data.([]*models.Project))
+	default:
+		return fmt.Errorf("unsupported export type for text: %s", exportType)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(writer, text)
+	return err
+}
+
+// exportJSONL exports data as JSON Lines
+func (e *FileExporter) exportJSONL(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
+	switch exportType {
+	case ExportTypeSession:
+		return e.jsonlConverter.WriteSession(writer, data.(*models.Session))
+	case ExportTypeProject:
+		return e.jsonlConverter.WriteProjectContext(ctx, writer, data.(*models.Project))
+	case ExportTypeProjects:
+		return e.jsonlConverter.WriteProjects(ctx, writer, data.([]*models.Project))
+	default:
+		return fmt.Errorf("unsupported export type for JSONL: %s", exportType)
+	}
+}
+
+// exportTemplate exports data with the user-supplied template
+func (e *FileExporter) exportTemplate(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
+	var output []byte
+	var err error
+
+	switch exportType {
+	case ExportTypeSession:
+		output, err = e.templateConverter.ConvertSessionContext(ctx, data.(*models.Session))
+	case ExportTypeProject:
+		output, err = e.templateConverter.ConvertProjectContext(ctx, data.(*models.Project))
+	case ExportTypeProjects:
+		output, err = e.templateConverter.ConvertProjectsContext(ctx, data.([]*models.Project))
+	default:
+		return fmt.Errorf("unsupported export type for template: %s", exportType)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(output)
+	return err
+}
+
+// exportCSV exports one row of statistics per session, or daily usage rows
+// for ExportTypeDaily
+func (e *FileExporter) exportCSV(ctx context.Context, writer io.Writer, data interface{}, exportType ExportType) error {
+	switch exportType {
+	case ExportTypeSession:
+		session := data.(*models.Session)
+		project := models.NewProject(session.ProjectID)
+		project.Sessions = append(project.Sessions, session)
+		return e.csvConverter.WriteSessionsContext(ctx, writer, []*models.Project{project})
+	case ExportTypeProject:
+		return e.csvConverter.WriteSessionsContext(ctx, writer, []*models.Project{data.(*models.Project)})
+	case ExportTypeProjects:
+		return e.csvConverter.WriteSessionsContext(ctx, writer, data.([]*models.Project))
+	case ExportTypeDaily:
+		return e.csvConverter.WriteDailyUsage(writer, data.([]*models.DailyUsage))
+	default:
+		return fmt.Errorf("unsupported export type for CSV: %s", exportType)
+	}
+}
+
 // BatchExporter exports multiple items to separate files
 type BatchExporter struct {
 	exporter   *FileExporter
 	outputDir  string
 	nameFormat string // e.g., "session_%s.json"
+
+	// Concurrency is the number of files written in parallel (0 or 1 = serial)
+	Concurrency int
+
+	// DatePrefix prefixes project filenames with the project's last
+	// activity date (e.g. 2024-07-15_project_name.md) so they sort by recency
+	DatePrefix bool
+
+	// TitleLength is the maximum length in characters of session titles in
+	// the index (0 = models.DefaultTitleLength)
+	TitleLength int
+
+	// Granularity is the unit ExportIndex links sessions to: the project
+	// file (default) or the per-session file from ExportProjectSessions
+	Granularity Granularity
+
+	// PathStrategy places files in the output directory (default
+	// FlatStrategy). Each subdirectory is created once before writing; files
+	// whose directory cannot be created are reported as errors.
+	PathStrategy PathStrategy
+
+	// WriteIndex makes ExportProjects and ExportProjectsIncremental also
+	// write a FileIndex of the project files to DefaultFileIndexName in the
+	// output directory
+	WriteIndex bool
+
+	// Progress is called after each file is written or fails, with the
+	// number of files done so far, the number to write and the file name.
+	// Calls are never concurrent.
+	Progress func(current, total int, filename string)
 }
 
 // NewBatchExporter creates a new batch exporter
@@ -189,52 +545,378 @@ func NewBatchExporter(exporter *FileExporter, outputDir string, nameFormat strin
 
 // ExportSessions exports multiple sessions to separate files
 func (b *BatchExporter) ExportSessions(sessions []*models.Session) (*BatchExportResult, error) {
+	filenames := make([]string, len(sessions))
+	for i, session := range sessions {
+		filenames[i] = b.placeFile(fmt.Sprintf(b.nameFormat, session.ID), session.StartTime)
+	}
+	return b.exportSessionFiles(context.Background(), sessions, filenames)
+}
+
+// ExportProjectSessions exports every session of every project to its own
+// file named after the project and session (e.g. myapp__<session-id>.md)
+func (b *BatchExporter) ExportProjectSessions(projects []*models.Project) (*BatchExportResult, error) {
+	return b.ExportProjectSessionsContext(context.Background(), projects)
+}
+
+// ExportProjectSessionsContext is like ExportProjectSessions but stops once
+// ctx is done (see ExportProjectsContext)
+func (b *BatchExporter) ExportProjectSessionsContext(ctx context.Context, projects []*models.Project) (*BatchExportResult, error) {
+	var sessions []*models.Session
+	var filenames []string
+	names := b.sessionFilenames(projects)
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			sessions = append(sessions, session)
+			filenames = append(filenames, names[session])
+		}
+	}
+	return b.exportSessionFiles(ctx, sessions, filenames)
+}
+
+// exportSessionFiles exports each session to the file of the same index,
+// relative to the output directory
+func (b *BatchExporter) exportSessionFiles(ctx context.Context, sessions []*models.Session, filenames []string) (*BatchExportResult, error) {
 	result := &BatchExportResult{
 		TotalItems: len(sessions),
 		Format:     b.exporter.GetFormat(),
 	}
 
-	for _, session := range sessions {
-		filename := filepath.Join(b.outputDir, fmt.Sprintf(b.nameFormat, session.ID))
-		
-		if err := b.exporter.ExportToFile(filename, session, ExportTypeSession); err != nil {
+	for i := range filenames {
+		filenames[i] = filepath.Join(b.outputDir, filenames[i])
+	}
+	dirErrs := makeDirs(filenames)
+
+	errs := make([]error, len(sessions))
+	progress := b.progressFunc(len(sessions))
+	b.forEach(len(sessions), func(i int) {
+		errs[i] = dirErrs[filepath.Dir(filenames[i])]
+		if errs[i] == nil {
+			errs[i] = b.exporter.ExportToFileContext(ctx, filenames[i], sessions[i], ExportTypeSession)
+		}
+		progress(filenames[i])
+	})
+
+	for i, session := range sessions {
+		if errs[i] != nil {
 			result.Errors = append(result.Errors, ExportError{
 				Item:  session.ID,
-				Error: err.Error(),
+				Error: errs[i].Error(),
 			})
 		} else {
 			result.SuccessCount++
-			result.Files = append(result.Files, filename)
+			result.Files = append(result.Files, filenames[i])
 		}
 	}
 
-	return result, nil
+	return result, ctx.Err()
+}
+
+// sessionFilenames returns the file name, relative to the output directory,
+// of every session for ExportProjectSessions. Names that would collide get a
+// numeric suffix (myapp__abc_2.md).
+func (b *BatchExporter) sessionFilenames(projects []*models.Project) map[*models.Session]string {
+	names := make(map[*models.Session]string)
+	used := make(map[string]bool)
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			filename := fmt.Sprintf(b.nameFormat, project.GetProjectName()+"__"+session.ID)
+			if b.DatePrefix && !session.EndTime.IsZero() {
+				filename = session.EndTime.Format("2006-01-02") + "_" + filename
+			}
+			names[session] = uniqueFilename(b.placeFile(filename, session.StartTime), used)
+		}
+	}
+	return names
+}
+
+// uniqueFilename returns filename, or filename with a numeric suffix
+// (myapp_2.md) if it is already used, and marks the result as used
+func uniqueFilename(filename string, used map[string]bool) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for n := 2; used[filename]; n++ {
+		filename = fmt.Sprintf("%s_%d%s", base, n, ext)
+	}
+	used[filename] = true
+	return filename
 }
 
 // ExportProjects exports multiple projects to separate files
 func (b *BatchExporter) ExportProjects(projects []*models.Project) (*BatchExportResult, error) {
+	return b.ExportProjectsContext(context.Background(), projects)
+}
+
+// ExportProjectsContext is like ExportProjects but stops once ctx is done:
+// files not yet started are reported as errors, a file being written is
+// removed, and the result of the files written so far is returned along with
+// ctx's error. The file index is not written after cancellation.
+func (b *BatchExporter) ExportProjectsContext(ctx context.Context, projects []*models.Project) (*BatchExportResult, error) {
+	names := b.projectFilenames(projects)
+	result := b.exportProjects(ctx, projects, names)
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if b.WriteIndex {
+		if err := b.addFileIndex(result, projects, names); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// addFileIndex writes the file index of the projects exported without error
+// and records it in the result
+func (b *BatchExporter) addFileIndex(result *BatchExportResult, projects []*models.Project, names map[*models.Project]string) error {
+	failed := make(map[string]bool)
+	for _, e := range result.Errors {
+		failed[e.Item] = true
+	}
+	var exported []*models.Project
+	for _, project := range projects {
+		if !failed[project.ID] {
+			exported = append(exported, project)
+		}
+	}
+
+	indexFile, err := b.writeFileIndex(exported, names)
+	if err != nil {
+		return err
+	}
+	result.IndexFile = indexFile
+	return nil
+}
+
+// exportProjects writes each project to its file in names, stopping once ctx
+// is done
+func (b *BatchExporter) exportProjects(ctx context.Context, projects []*models.Project, names map[*models.Project]string) *BatchExportResult {
 	result := &BatchExportResult{
 		TotalItems: len(projects),
 		Format:     b.exporter.GetFormat(),
 	}
 
-	for _, project := range projects {
-		filename := filepath.Join(b.outputDir, fmt.Sprintf(b.nameFormat, project.GetProjectName()))
-		
-		if err := b.exporter.ExportToFile(filename, project, ExportTypeProject); err != nil {
+	// Each project writes into its own slot so results keep input order
+	// regardless of which worker finishes first
+	filenames := make([]string, len(projects))
+	errs := make([]error, len(projects))
+	for i, project := range projects {
+		filenames[i] = filepath.Join(b.outputDir, names[project])
+	}
+	dirErrs := makeDirs(filenames)
+
+	progress := b.progressFunc(len(projects))
+	b.forEach(len(projects), func(i int) {
+		errs[i] = dirErrs[filepath.Dir(filenames[i])]
+		if errs[i] == nil {
+			errs[i] = b.exporter.ExportToFileContext(ctx, filenames[i], projects[i], ExportTypeProject)
+		}
+		progress(filenames[i])
+	})
+
+	for i, project := range projects {
+		if errs[i] != nil {
 			result.Errors = append(result.Errors, ExportError{
 				Item:  project.ID,
-				Error: err.Error(),
+				Error: errs[i].Error(),
 			})
 		} else {
 			result.SuccessCount++
-			result.Files = append(result.Files, filename)
+			result.Files = append(result.Files, filenames[i])
+		}
+	}
+
+	return result
+}
+
+// ExportProjectsIncremental is like ExportProjects but skips projects whose
+// output file exists and whose source files have the same modification time
+// and size as recorded in the manifest at manifestPath. Skipped files are
+// listed in the result, and the manifest is updated with the files written.
+func (b *BatchExporter) ExportProjectsIncremental(projects []*models.Project, manifestPath string) (*BatchExportResult, error) {
+	return b.ExportProjectsIncrementalContext(context.Background(), projects, manifestPath)
+}
+
+// ExportProjectsIncrementalContext is like ExportProjectsIncremental but
+// stops once ctx is done (see ExportProjectsContext). The manifest still
+// records the files written before cancellation.
+func (b *BatchExporter) ExportProjectsIncrementalContext(ctx context.Context, projects []*models.Project, manifestPath string) (*BatchExportResult, error) {
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []*models.Project
+	var skipped []string
+	sources := make(map[*models.Project]map[string]SourceFingerprint)
+	names := b.projectFilenames(projects)
+	for _, project := range projects {
+		name := names[project]
+		projectSources, ok := projectSources(project)
+		if ok {
+			sources[project] = projectSources
+			_, statErr := os.Stat(filepath.Join(b.outputDir, name))
+			if statErr == nil && manifest.Unchanged(name, projectSources) {
+				skipped = append(skipped, filepath.Join(b.outputDir, name))
+				continue
+			}
 		}
+		changed = append(changed, project)
 	}
 
+	result := b.exportProjects(ctx, changed, names)
+	result.Skipped = skipped
+
+	// Record the sources of every file written; failed or unfingerprinted
+	// projects are exported again next time
+	failed := make(map[string]bool)
+	for _, e := range result.Errors {
+		failed[e.Item] = true
+	}
+	for _, project := range changed {
+		name := names[project]
+		if projectSources, ok := sources[project]; ok && !failed[project.ID] {
+			manifest.Outputs[name] = projectSources
+		} else {
+			delete(manifest.Outputs, name)
+		}
+	}
+	if err := manifest.Save(manifestPath); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	// The index also lists the skipped files, which are still current
+	if b.WriteIndex {
+		if err := b.addFileIndex(result, projects, names); err != nil {
+			return nil, err
+		}
+	}
 	return result, nil
 }
 
+// projectFilenames returns the file name, relative to the output directory,
+// that each project is exported to. Projects with the same name, such as
+// /a/api and /b/api, get a numeric suffix in order (project_api_2.md).
+func (b *BatchExporter) projectFilenames(projects []*models.Project) map[*models.Project]string {
+	names := make(map[*models.Project]string)
+	used := make(map[string]bool)
+	for _, project := range projects {
+		names[project] = uniqueFilename(b.projectFilename(project), used)
+	}
+	return names
+}
+
+// projectFilename returns the file name, relative to the output directory,
+// of a project before names that collide are made unique
+func (b *BatchExporter) projectFilename(project *models.Project) string {
+	filename := fmt.Sprintf(b.nameFormat, project.GetProjectName())
+	start, end := project.GetTimeRange()
+	if b.DatePrefix && !end.IsZero() {
+		filename = end.Format("2006-01-02") + "_" + filename
+	}
+	return b.placeFile(filename, start)
+}
+
+// placeFile returns the path of filename relative to the output directory
+// under the PathStrategy, for an item starting at start. Paths use forward
+// slashes, so they also serve as links in indexes.
+func (b *BatchExporter) placeFile(filename string, start time.Time) string {
+	if b.PathStrategy != DateStrategy || start.IsZero() {
+		return filename
+	}
+	return path.Join(start.Format("2006"), start.Format("01"), filename)
+}
+
+// makeDirs creates the directory of each file once, and returns the error
+// of each directory that could not be created
+func makeDirs(filenames []string) map[string]error {
+	errs := make(map[string]error)
+	created := make(map[string]bool)
+	for _, filename := range filenames {
+		dir := filepath.Dir(filename)
+		if created[dir] || errs[dir] != nil {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			errs[dir] = fmt.Errorf("failed to create directory: %w", err)
+			continue
+		}
+		created[dir] = true
+	}
+	return errs
+}
+
+// progressFunc returns a function reporting each of total files written to
+// the Progress callback, one call at a time
+func (b *BatchExporter) progressFunc(total int) func(filename string) {
+	var mu sync.Mutex
+	current := 0
+	return func(filename string) {
+		if b.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		current++
+		b.Progress(current, total, filename)
+	}
+}
+
+// forEach calls fn for every index in [0, n), using up to Concurrency workers
+func (b *BatchExporter) forEach(n int, fn func(i int)) {
+	workers := b.Concurrency
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// ExportIndex writes an index of all sessions to indexName in the output
+// directory, linking each session to the file its project was exported to
+func (b *BatchExporter) ExportIndex(projects []*models.Project, indexName string) (string, error) {
+	projectNames := b.projectFilenames(projects)
+	link := func(project *models.Project, session *models.Session) string {
+		return projectNames[project]
+	}
+	if b.Granularity == GranularitySession {
+		names := b.sessionFilenames(projects)
+		link = func(project *models.Project, session *models.Session) string {
+			return names[session]
+		}
+	}
+	entries := converter.BuildIndex(projects, link, b.TitleLength)
+
+	filename := filepath.Join(b.outputDir, indexName)
+	if err := b.exporter.ExportToFile(filename, entries, ExportTypeIndex); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
 // BatchExportResult contains results from batch export
 type BatchExportResult struct {
 	TotalItems   int
@@ -242,6 +924,11 @@ type BatchExportResult struct {
 	Files        []string
 	Errors       []ExportError
 	Format       Format
+	// Skipped lists the files left as they were because their sources are
+	// unchanged (see ExportProjectsIncremental)
+	Skipped []string
+	// IndexFile is the path of the file index written with WriteIndex
+	IndexFile string
 }
 
 // ExportError represents an error during batch export
@@ -257,6 +944,10 @@ func (r *BatchExportResult) HasErrors() bool {
 
 // Summary returns a summary of the batch export
 func (r *BatchExportResult) Summary() string {
-	return fmt.Sprintf("Exported %d/%d items successfully in %s format", 
+	summary := fmt.Sprintf("Exported %d/%d items successfully in %s format", 
 		r.SuccessCount, r.TotalItems, r.Format)
+	if len(r.Skipped) > 0 {
+		summary += fmt.Sprintf(" (%d unchanged, skipped)", len(r.Skipped))
+	}
+	return summary
 }
\ No newline at end of file
@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/eternnoir/cc-history-export/internal/converter"
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// DefaultFileIndexName is the file name of the index batch exports write to
+// the output directory with WriteIndex
+const DefaultFileIndexName = "index.json"
+
+// FileIndex lists the files of a batch export, so tools can enumerate them
+// without parsing each file
+type FileIndex struct {
+	Format Format            `json:"format"`
+	Files  []*FileIndexEntry `json:"files"`
+}
+
+// FileIndexEntry describes one exported project file
+type FileIndexEntry struct {
+	File         string               `json:"file"` // Relative to the output directory
+	Project      string               `json:"project"`
+	Path         string               `json:"path"`
+	SessionCount int                  `json:"session_count"`
+	MessageCount int                  `json:"message_count"`
+	DateRange    *converter.DateRange `json:"date_range,omitempty"`
+	Size         int64                `json:"size"`
+}
+
+// writeFileIndex writes the index of the given projects, exported to the
+// files in names, to the output directory and returns its path. Files that
+// cannot be found are left out.
+func (b *BatchExporter) writeFileIndex(projects []*models.Project, names map[*models.Project]string) (string, error) {
+	index := &FileIndex{
+		Format: b.exporter.GetFormat(),
+		Files:  make([]*FileIndexEntry, 0, len(projects)),
+	}
+	for _, project := range projects {
+		name := names[project]
+		info, err := os.Stat(filepath.Join(b.outputDir, name))
+		if err != nil {
+			continue
+		}
+
+		index.Files = append(index.Files, fileIndexEntry(name, project, info.Size()))
+	}
+
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal file index: %w", err)
+	}
+	path := filepath.Join(b.outputDir, DefaultFileIndexName)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write file index: %w", err)
+	}
+	return path, nil
+}
+
+// fileIndexEntry describes a project exported to a file of the given size
+func fileIndexEntry(name string, project *models.Project, size int64) *FileIndexEntry {
+	entry := &FileIndexEntry{
+		File:         name,
+		Project:      project.GetProjectName(),
+		Path:         project.Path,
+		SessionCount: project.GetSessionCount(),
+		MessageCount: project.GetTotalMessages(),
+		Size:         size,
+	}
+	if start, end := project.GetTimeRange(); !start.IsZero() {
+		entry.DateRange = &converter.DateRange{
+			Start: start.Format("2006-01-02"),
+			End:   end.Format("2006-01-02"),
+		}
+	}
+	return entry
+}
@@ -0,0 +1,94 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// DefaultManifestName is the file name of the manifest incremental batch
+// exports keep in the output directory
+const DefaultManifestName = ".cc-export-manifest.json"
+
+// Manifest records the source files each batch output file was exported
+// from, so later incremental exports can skip outputs whose sources are
+// unchanged
+type Manifest struct {
+	// Source fingerprints by output file name, then source file path
+	Outputs map[string]map[string]SourceFingerprint `json:"outputs"`
+}
+
+// SourceFingerprint identifies the state of a source file
+type SourceFingerprint struct {
+	ModTime time.Time `json:"mtime"`
+	Size    int64     `json:"size"`
+}
+
+// LoadManifest reads a manifest, returning an empty one if the file does not
+// exist yet
+func LoadManifest(path string) (*Manifest, error) {
+	manifest := &Manifest{Outputs: make(map[string]map[string]SourceFingerprint)}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if manifest.Outputs == nil {
+		manifest.Outputs = make(map[string]map[string]SourceFingerprint)
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest to path
+func (m *Manifest) Save(path string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Unchanged checks if the output was recorded with exactly these sources
+func (m *Manifest) Unchanged(output string, sources map[string]SourceFingerprint) bool {
+	recorded, ok := m.Outputs[output]
+	if !ok || len(recorded) != len(sources) {
+		return false
+	}
+	for path, fingerprint := range sources {
+		previous, ok := recorded[path]
+		if !ok || previous.Size != fingerprint.Size || !previous.ModTime.Equal(fingerprint.ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// projectSources fingerprints the source files of the project's sessions. It
+// reports false if a session has no source file or it cannot be read, in
+// which case the project must always be exported.
+func projectSources(project *models.Project) (map[string]SourceFingerprint, bool) {
+	sources := make(map[string]SourceFingerprint, len(project.Sessions))
+	for _, session := range project.Sessions {
+		if session.SourceFile == "" {
+			return nil, false
+		}
+		info, err := os.Stat(session.SourceFile)
+		if err != nil {
+			return nil, false
+		}
+		sources[session.SourceFile] = SourceFingerprint{ModTime: info.ModTime(), Size: info.Size()}
+	}
+	return sources, true
+}
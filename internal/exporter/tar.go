@@ -0,0 +1,196 @@
+package exporter
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// TarArchiveType reports whether filename names a tar archive (.tar) and
+// whether it is gzip-compressed (.tar.gz or .tgz)
+func TarArchiveType(filename string) (isTar, compressed bool) {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return true, true
+	case strings.HasSuffix(lower, ".tar"):
+		return true, false
+	}
+	return false, false
+}
+
+// TarExporter writes a batch export of projects to a single tar archive,
+// one entry per project named as by BatchExporter, with projects of the same
+// name made unique, followed by a FileIndex entry named DefaultFileIndexName
+type TarExporter struct {
+	exporter   *FileExporter
+	nameFormat string // e.g., "project_%s.md"
+
+	// Gzip compresses the archive
+	Gzip bool
+
+	// Concurrency is the number of projects converted in parallel (0 or 1 =
+	// serial). Entries are still written in project order, and at most this
+	// many converted projects are held in memory.
+	Concurrency int
+
+	// DatePrefix and PathStrategy name entries as the BatchExporter options
+	// of the same name
+	DatePrefix   bool
+	PathStrategy PathStrategy
+
+	// Progress is called after each entry is written or fails, with the
+	// number of projects done so far, the number to write and the entry name
+	Progress func(current, total int, filename string)
+}
+
+// NewTarExporter creates a new tar exporter
+func NewTarExporter(exporter *FileExporter, nameFormat string) *TarExporter {
+	return &TarExporter{
+		exporter:   exporter,
+		nameFormat: nameFormat,
+	}
+}
+
+// tarEntry is a project converted for a TarExporter
+type tarEntry struct {
+	data   []byte
+	err    error
+	worker bool // Converted by a worker holding a slot
+}
+
+// ExportProjects writes the projects to w as a tar archive
+func (t *TarExporter) ExportProjects(w io.Writer, projects []*models.Project) (*BatchExportResult, error) {
+	return t.ExportProjectsContext(context.Background(), w, projects)
+}
+
+// ExportProjectsContext is like ExportProjects but stops once ctx is done.
+// Projects that fail to convert are reported as errors in the result and
+// left out of the archive; an error writing to w ends the export. After
+// cancellation the archive is left incomplete and ctx's error is returned.
+func (t *TarExporter) ExportProjectsContext(ctx context.Context, w io.Writer, projects []*models.Project) (*BatchExportResult, error) {
+	result := &BatchExportResult{
+		TotalItems: len(projects),
+		Format:     t.exporter.GetFormat(),
+	}
+
+	var gz *gzip.Writer
+	out := w
+	if t.Gzip {
+		gz = gzip.NewWriter(w)
+		out = gz
+	}
+	tw := tar.NewWriter(out)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	entries, slots := t.convert(ctx, projects)
+
+	batch := &BatchExporter{nameFormat: t.nameFormat, DatePrefix: t.DatePrefix, PathStrategy: t.PathStrategy}
+	names := batch.projectFilenames(projects)
+	index := &FileIndex{Format: result.Format, Files: make([]*FileIndexEntry, 0, len(projects))}
+	now := time.Now()
+	for i, project := range projects {
+		entry := <-entries[i]
+		if entry.worker {
+			<-slots
+		}
+		name := names[project]
+		if entry.err != nil {
+			result.Errors = append(result.Errors, ExportError{Item: project.ID, Error: entry.err.Error()})
+		} else {
+			modTime := now
+			if _, end := project.GetTimeRange(); !end.IsZero() {
+				modTime = end
+			}
+			if err := writeTarFile(tw, name, entry.data, modTime); err != nil {
+				return result, err
+			}
+			result.SuccessCount++
+			result.Files = append(result.Files, name)
+			index.Files = append(index.Files, fileIndexEntry(name, project, int64(len(entry.data))))
+		}
+		if t.Progress != nil {
+			t.Progress(i+1, len(projects), name)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal file index: %w", err)
+	}
+	if err := writeTarFile(tw, DefaultFileIndexName, content, now); err != nil {
+		return result, err
+	}
+	result.IndexFile = DefaultFileIndexName
+
+	if err := tw.Close(); err != nil {
+		return result, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return result, fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// convert starts converting the projects with up to Concurrency workers,
+// delivering each on the channel of the same index. The caller frees a
+// worker's slot by receiving from slots after each entry, and the remaining
+// projects fail with ctx's error once ctx is done.
+func (t *TarExporter) convert(ctx context.Context, projects []*models.Project) (entries []chan tarEntry, slots chan struct{}) {
+	entries = make([]chan tarEntry, len(projects))
+	for i := range entries {
+		entries[i] = make(chan tarEntry, 1)
+	}
+	slots = make(chan struct{}, max(t.Concurrency, 1))
+
+	go func() {
+		for i, project := range projects {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				for _, entry := range entries[i:] {
+					entry <- tarEntry{err: ctx.Err()}
+				}
+				return
+			}
+			go func(entry chan tarEntry, project *models.Project) {
+				var buf bytes.Buffer
+				err := t.exporter.ExportContext(ctx, &buf, project, ExportTypeProject)
+				entry <- tarEntry{data: buf.Bytes(), err: err, worker: true}
+			}(entries[i], project)
+		}
+	}()
+	return entries, slots
+}
+
+// writeTarFile writes a regular file entry to the archive
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write archive entry %s: %w", name, err)
+	}
+	return nil
+}
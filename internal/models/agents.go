@@ -0,0 +1,68 @@
+package models
+
+// Subagent holds the work of a subagent spawned by a session, e.g. through
+// the Task tool. Its messages are recorded in the session file as sidechain
+// messages.
+type Subagent struct {
+	ID        string
+	Messages  []*Message
+	TodoLists []*TodoList
+}
+
+// GetSubagents groups the sidechain messages of the session by subagent, in
+// the order each subagent first appears. Messages are grouped by their agent
+// ID; older logs without one are grouped by the root of their sidechain
+// chain. Todo lists of the session whose agent ID matches a subagent are
+// attached to it.
+func (s *Session) GetSubagents(todoLists []*TodoList) []*Subagent {
+	sidechain := make(map[string]*Message)
+	for _, msg := range s.Messages {
+		if msg.Sidechain && msg.UUID != "" {
+			sidechain[msg.UUID] = msg
+		}
+	}
+
+	var subagents []*Subagent
+	byID := make(map[string]*Subagent)
+	for _, msg := range s.Messages {
+		if !msg.Sidechain {
+			continue
+		}
+		id := msg.AgentID
+		if id == "" {
+			id = sidechainRoot(msg, sidechain).UUID
+		}
+		subagent, ok := byID[id]
+		if !ok {
+			subagent = &Subagent{ID: id}
+			byID[id] = subagent
+			subagents = append(subagents, subagent)
+		}
+		subagent.Messages = append(subagent.Messages, msg)
+	}
+
+	for _, todoList := range todoLists {
+		if todoList.SessionID != s.ID {
+			continue
+		}
+		if subagent, ok := byID[todoList.AgentID]; ok {
+			subagent.TodoLists = append(subagent.TodoLists, todoList)
+		}
+	}
+	return subagents
+}
+
+// sidechainRoot follows the parents of a sidechain message to the first
+// message of its chain
+func sidechainRoot(msg *Message, sidechain map[string]*Message) *Message {
+	visited := make(map[*Message]bool)
+	for !visited[msg] {
+		visited[msg] = true
+		parent := parentOf(msg, sidechain)
+		if parent == nil {
+			break
+		}
+		msg = parent
+	}
+	return msg
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSessionGetSubagents(t *testing.T) {
+	parent := func(uuid string) *string { return &uuid }
+	messages := []*Message{
+		{UUID: "m1", Type: MessageTypeUser},
+		{UUID: "m2", ParentUUID: parent("m1"), Type: MessageTypeAssistant},
+		{UUID: "a1", Type: MessageTypeUser, Sidechain: true, AgentID: "agent-a"},
+		{UUID: "b1", Type: MessageTypeUser, Sidechain: true},
+		{UUID: "a2", ParentUUID: parent("a1"), Type: MessageTypeAssistant, Sidechain: true, AgentID: "agent-a"},
+		{UUID: "b2", ParentUUID: parent("b1"), Type: MessageTypeAssistant, Sidechain: true},
+		{UUID: "m3", ParentUUID: parent("m2"), Type: MessageTypeUser},
+	}
+	session := &Session{ID: "session1"}
+	for _, msg := range messages {
+		msg.Message = json.RawMessage(`{}`)
+		session.AddMessage(msg)
+	}
+	todoLists := []*TodoList{
+		{SessionID: "session1", AgentID: "session1"},
+		{SessionID: "session1", AgentID: "agent-a"},
+		{SessionID: "other", AgentID: "agent-a"},
+	}
+
+	subagents := session.GetSubagents(todoLists)
+	if len(subagents) != 2 {
+		t.Fatalf("GetSubagents() returned %d subagents, want 2", len(subagents))
+	}
+
+	want := []struct {
+		id       string
+		messages []string
+		todos    int
+	}{
+		{"agent-a", []string{"a1", "a2"}, 1},
+		{"b1", []string{"b1", "b2"}, 0},
+	}
+	for i, w := range want {
+		subagent := subagents[i]
+		if subagent.ID != w.id {
+			t.Errorf("subagents[%d].ID = %s, want %s", i, subagent.ID, w.id)
+		}
+		var uuids []string
+		for _, msg := range subagent.Messages {
+			uuids = append(uuids, msg.UUID)
+		}
+		if len(uuids) != len(w.messages) || uuids[0] != w.messages[0] || uuids[1] != w.messages[1] {
+			t.Errorf("subagents[%d] messages = %v, want %v", i, uuids, w.messages)
+		}
+		if len(subagent.TodoLists) != w.todos {
+			t.Errorf("subagents[%d] has %d todo lists, want %d", i, len(subagent.TodoLists), w.todos)
+		}
+	}
+
+	// Sidechain messages are not regenerated branches of the main chain
+	if n := session.MarkRegenerated(); n != 0 {
+		t.Errorf("MarkRegenerated() = %d, want 0", n)
+	}
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// AnonymizeRule replaces every occurrence of Match with the placeholder
+// Replace. Occurrences inside a longer word are left alone, so a rule for the
+// user name "me" does not touch "message".
+type AnonymizeRule struct {
+	Match   string
+	Replace string
+}
+
+// Placeholders of the default anonymization rules
+const (
+	HomePlaceholder = "<HOME>"
+	UserPlaceholder = "<USER>"
+)
+
+// DefaultAnonymizeRules returns the rules replacing the home directory with
+// <HOME> and the user name with <USER>. Empty values get no rule.
+func DefaultAnonymizeRules(homeDir, username string) []AnonymizeRule {
+	var rules []AnonymizeRule
+	if homeDir != "" {
+		rules = append(rules, AnonymizeRule{Match: homeDir, Replace: HomePlaceholder})
+	}
+	if username != "" {
+		rules = append(rules, AnonymizeRule{Match: username, Replace: UserPlaceholder})
+	}
+	return rules
+}
+
+// Anonymize applies the rules to the projects in place: their paths, CLAUDE.md
+// and todos, and the working directory, git branch, text, thinking, tool
+// inputs, tool results, summaries and raw JSON of every message. All rules
+// are applied in one pass, longer matches first, so the same text always
+// maps to the same placeholder regardless of the order of the rules, and a
+// placeholder is never rewritten by another rule. Project IDs and encoded
+// paths get the rules with each / replaced with -, as Claude Code encodes
+// them.
+func Anonymize(projects []*Project, rules []AnonymizeRule) {
+	a := newAnonymizer(rules)
+	for _, project := range projects {
+		a.project(project)
+	}
+}
+
+// AnonymizeText applies the rules to s as Anonymize does to message text
+func AnonymizeText(s string, rules []AnonymizeRule) string {
+	return newAnonymizer(rules).replace(s)
+}
+
+// anonymizer applies sorted rules, along with their encoded form for
+// project directory names and their JSON-escaped form for raw JSON
+type anonymizer struct {
+	rules   []AnonymizeRule
+	encoded []AnonymizeRule
+	raw     []AnonymizeRule
+}
+
+// newAnonymizer sorts the rules by descending match length, then match,
+// dropping rules without a match
+func newAnonymizer(rules []AnonymizeRule) *anonymizer {
+	a := &anonymizer{}
+	for _, rule := range rules {
+		if rule.Match == "" {
+			continue
+		}
+		a.rules = append(a.rules, rule)
+		a.raw = append(a.raw, AnonymizeRule{
+			Match:   escapeJSONString(rule.Match),
+			Replace: escapeJSONString(rule.Replace),
+		})
+		if strings.Contains(rule.Match, "/") {
+			a.encoded = append(a.encoded, AnonymizeRule{
+				Match:   strings.ReplaceAll(rule.Match, "/", "-"),
+				Replace: rule.Replace,
+			})
+		}
+	}
+	sortRules(a.rules)
+	sortRules(a.encoded)
+	sortRules(a.raw)
+	return a
+}
+
+// sortRules orders rules by descending match length, then match
+func sortRules(rules []AnonymizeRule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		if len(rules[i].Match) != len(rules[j].Match) {
+			return len(rules[i].Match) > len(rules[j].Match)
+		}
+		return rules[i].Match < rules[j].Match
+	})
+}
+
+func (a *anonymizer) project(project *Project) {
+	project.Path = a.replace(project.Path)
+	project.Config = a.replace(project.Config)
+	project.ID = replaceRules(project.ID, a.encoded)
+	project.EncodedPath = replaceRules(project.EncodedPath, a.encoded)
+	for _, session := range project.Sessions {
+		session.ProjectID = replaceRules(session.ProjectID, a.encoded)
+		for _, msg := range session.Messages {
+			a.message(msg)
+		}
+	}
+	for _, todoList := range project.TodoLists {
+		for _, todo := range todoList.Todos {
+			todo.Content = a.replace(todo.Content)
+		}
+	}
+	for _, snapshot := range project.ShellSnapshots {
+		snapshot.Content = a.replace(snapshot.Content)
+	}
+}
+
+func (a *anonymizer) message(msg *Message) {
+	msg.CWD = a.replace(msg.CWD)
+	msg.GitBranch = a.replace(msg.GitBranch)
+	msg.Summary = a.replace(msg.Summary)
+	msg.Message = a.replaceRaw(msg.Message)
+
+	switch content := msg.Content.(type) {
+	case *UserMessage:
+		content.Content = a.replace(content.Content)
+	case *AssistantMessage:
+		for i := range content.Content {
+			content.Content[i].Text = a.replace(content.Content[i].Text)
+			content.Content[i].Thinking = a.replace(content.Content[i].Thinking)
+			content.Content[i].Input = a.replaceRaw(content.Content[i].Input)
+		}
+	case *SummaryMessage:
+		content.Summary = a.replace(content.Summary)
+	case []ToolResult:
+		for i := range content {
+			content[i].Content = a.replaceRaw(content[i].Content)
+		}
+	}
+}
+
+func (a *anonymizer) replace(s string) string {
+	return replaceRules(s, a.rules)
+}
+
+// replaceRaw applies the rules to raw JSON, matching and replacing text as
+// it is escaped in JSON strings, so a Windows path such as C:\Users\me is
+// found and a placeholder containing quotes keeps the JSON valid
+func (a *anonymizer) replaceRaw(raw json.RawMessage) json.RawMessage {
+	if raw == nil {
+		return nil
+	}
+	return json.RawMessage(replaceRules(string(raw), a.raw))
+}
+
+// escapeJSONString returns s escaped as in a JSON string, without the quotes
+func escapeJSONString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(strings.TrimSuffix(buf.String(), "\n")[1:], `"`)
+}
+
+// replaceRules replaces the occurrences of the rules' matches in s that are
+// not part of a longer word, in one pass: at each position the first
+// matching rule wins, and replaced text is not matched again
+func replaceRules(s string, rules []AnonymizeRule) string {
+	var active []AnonymizeRule
+	for _, rule := range rules {
+		if rule.Match != "" && strings.Contains(s, rule.Match) {
+			active = append(active, rule)
+		}
+	}
+	if len(active) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(s); {
+		matched := false
+		for _, rule := range active {
+			if matchesWordAt(s, i, rule.Match) {
+				b.WriteString(s[last:i])
+				b.WriteString(rule.Replace)
+				i += len(rule.Match)
+				last = i
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			i++
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// matchesWordAt reports whether old occurs in s at start without being part
+// of a longer word: an occurrence starting with a word character must not
+// follow one, and one ending with a word character must not be followed by
+// one
+func matchesWordAt(s string, start int, old string) bool {
+	if !strings.HasPrefix(s[start:], old) {
+		return false
+	}
+	end := start + len(old)
+	startOK := start == 0 || !isWordByte(old[0]) || !isWordByte(s[start-1])
+	endOK := end == len(s) || !isWordByte(old[len(old)-1]) || !isWordByte(s[end])
+	return startOK && endOK
+}
+
+// isWordByte reports whether c is part of a word: a letter, digit, underscore
+// or a byte of a multi-byte UTF-8 character
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
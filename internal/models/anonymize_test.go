@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnonymize(t *testing.T) {
+	project := NewProject("-Users-me-work-app")
+	project.Path = "/Users/me/work/app"
+	project.Config = "Build with /Users/me/bin/make"
+	session := &Session{ID: "session1", ProjectID: project.ID}
+	for _, msg := range []*Message{
+		{UUID: "msg1", Type: MessageTypeUser, UserType: "external", CWD: "/Users/me/work/app", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			Message: json.RawMessage(`{"role":"user","content":"Hi, me here. Read /Users/me/work/app/message.txt"}`)},
+		{UUID: "msg2", Type: MessageTypeAssistant, Timestamp: time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC),
+			Message: json.RawMessage(`{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"/Users/me/work/app/message.txt"}}]}`)},
+		{UUID: "msg3", Type: MessageTypeUser, UserType: "external", Timestamp: time.Date(2024, 1, 1, 10, 0, 6, 0, time.UTC),
+			Message: json.RawMessage(`{"role":"user","content":[{"tool_use_id":"toolu_1","type":"tool_result","content":"owner: me"}]}`)},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project.AddSession(session)
+
+	// Rule order does not matter: the home directory is replaced before the
+	// user name it contains
+	Anonymize([]*Project{project}, append([]AnonymizeRule{{Match: "me", Replace: UserPlaceholder}}, DefaultAnonymizeRules("/Users/me", "")...))
+
+	if project.Path != "<HOME>/work/app" || project.ID != "<HOME>-work-app" || session.ProjectID != project.ID {
+		t.Errorf("Project path = %s, ID = %s, want <HOME>/work/app and <HOME>-work-app", project.Path, project.ID)
+	}
+	if project.Config != "Build with <HOME>/bin/make" {
+		t.Errorf("Config = %q", project.Config)
+	}
+	if project.GetProjectName() != "app" {
+		t.Errorf("Project name = %s, want app", project.GetProjectName())
+	}
+
+	user := session.Messages[0]
+	if user.CWD != "<HOME>/work/app" {
+		t.Errorf("CWD = %s, want <HOME>/work/app", user.CWD)
+	}
+	if got := user.Content.(*UserMessage).Content; got != "Hi, <USER> here. Read <HOME>/work/app/message.txt" {
+		t.Errorf("User content = %q", got)
+	}
+	if strings.Contains(string(user.Message), "/Users/me") {
+		t.Errorf("Raw message not anonymized: %s", user.Message)
+	}
+
+	input := string(session.Messages[1].Content.(*AssistantMessage).Content[0].Input)
+	if input != `{"file_path":"<HOME>/work/app/message.txt"}` {
+		t.Errorf("Tool input = %s", input)
+	}
+	if result := session.Messages[2].Content.([]ToolResult)[0]; string(result.Content) != `"owner: <USER>"` {
+		t.Errorf("Tool result = %s", result.Content)
+	}
+}
+
+func TestAnonymizeRawJSON(t *testing.T) {
+	project := NewProject(`C--Users-me-app`)
+	session := &Session{ID: "session1", ProjectID: project.ID}
+	msg := &Message{UUID: "msg1", Type: MessageTypeAssistant, GitBranch: "me/feature", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Message: json.RawMessage(`{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"C:\\Users\\me\\acme.txt"}}]}`)}
+	msg.ParseContent()
+	session.AddMessage(msg)
+	project.AddSession(session)
+
+	// A later rule matching HOME does not rewrite the <HOME> placeholder, and
+	// a replacement with quotes keeps the raw JSON valid
+	Anonymize([]*Project{project}, []AnonymizeRule{
+		{Match: `C:\Users\me`, Replace: HomePlaceholder},
+		{Match: "HOME", Replace: "<H>"},
+		{Match: "acme", Replace: `"COMPANY"`},
+		{Match: "me", Replace: UserPlaceholder},
+	})
+
+	input := msg.Content.(*AssistantMessage).Content[0].Input
+	if string(input) != `{"file_path":"<HOME>\\\"COMPANY\".txt"}` {
+		t.Errorf("Tool input = %s", input)
+	}
+	var decoded map[string]string
+	if err := json.Unmarshal(input, &decoded); err != nil || decoded["file_path"] != `<HOME>\"COMPANY".txt` {
+		t.Errorf("Tool input decodes to %q, %v", decoded["file_path"], err)
+	}
+	if !json.Valid(msg.Message) || strings.Contains(string(msg.Message), "Users") {
+		t.Errorf("Raw message = %s, want valid anonymized JSON", msg.Message)
+	}
+	if msg.GitBranch != "<USER>/feature" {
+		t.Errorf("GitBranch = %q, want <USER>/feature", msg.GitBranch)
+	}
+}
+
+func TestReplaceRules(t *testing.T) {
+	tests := []struct {
+		s, old, want string
+	}{
+		{"me and memo", "me", "X and memo"},
+		{"/Users/me/x /Users/meg", "/Users/me", "X/x /Users/meg"},
+		{"some_me me_", "me", "some_me me_"},
+		{"no match", "me", "no match"},
+	}
+	for _, tt := range tests {
+		if got := replaceRules(tt.s, []AnonymizeRule{{Match: tt.old, Replace: "X"}}); got != tt.want {
+			t.Errorf("replaceRules(%q, %q) = %q, want %q", tt.s, tt.old, got, tt.want)
+		}
+	}
+}
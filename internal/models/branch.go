@@ -0,0 +1,184 @@
+package models
+
+import "time"
+
+// MarkRegenerated flags messages that belong to superseded conversation
+// branches and returns how many were flagged.
+//
+// When a prompt is edited or a response regenerated, Claude Code keeps the
+// old branch in the file: the new message shares its parentUuid with the old
+// one. The final branch is the chain of parents leading to the last message
+// in the file; any message that forks off that chain is superseded.
+// Subagent (sidechain) messages are never flagged.
+func (s *Session) MarkRegenerated() int {
+	byUUID := make(map[string]*Message, len(s.Messages))
+	var last *Message
+	for _, msg := range s.Messages {
+		if msg.UUID == "" || msg.Sidechain {
+			continue
+		}
+		byUUID[msg.UUID] = msg
+		last = msg
+	}
+	if last == nil {
+		return 0
+	}
+
+	// Walk from the last message up to its root to find the final branch
+	final := make(map[string]bool)
+	for msg := last; msg != nil && !final[msg.UUID]; msg = parentOf(msg, byUUID) {
+		final[msg.UUID] = true
+	}
+
+	// superseded caches the result for each message already resolved
+	superseded := make(map[string]bool)
+	count := 0
+	for _, msg := range s.Messages {
+		if msg.UUID == "" || msg.Sidechain || final[msg.UUID] {
+			continue
+		}
+
+		// Walk up until we reach the final branch, a root, or a known result
+		var chain []*Message
+		visited := make(map[string]bool)
+		result := false
+		for cur := msg; cur != nil; cur = parentOf(cur, byUUID) {
+			if final[cur.UUID] {
+				result = true
+				break
+			}
+			if known, ok := superseded[cur.UUID]; ok {
+				result = known
+				break
+			}
+			if visited[cur.UUID] {
+				break
+			}
+			visited[cur.UUID] = true
+			chain = append(chain, cur)
+		}
+
+		for _, m := range chain {
+			superseded[m.UUID] = result
+		}
+		if result {
+			msg.Regenerated = true
+			count++
+		}
+	}
+
+	return count
+}
+
+// PruneRegenerated removes messages flagged by MarkRegenerated and
+// recomputes the session start and end times
+func (s *Session) PruneRegenerated() {
+	messages := s.Messages
+	s.Messages = make([]*Message, 0, len(messages))
+	s.StartTime = time.Time{}
+	s.EndTime = time.Time{}
+	for _, msg := range messages {
+		if !msg.Regenerated {
+			s.AddMessage(msg)
+		}
+	}
+}
+
+// parentOf returns the parent message, or nil if it has none in the session
+func parentOf(msg *Message, byUUID map[string]*Message) *Message {
+	if msg.ParentUUID == nil {
+		return nil
+	}
+	return byUUID[*msg.ParentUUID]
+}
+
+// MessageNode is a message in the conversation tree built by BuildTree,
+// with the replies to it in file order
+type MessageNode struct {
+	// Message is nil for the synthetic root that holds orphaned messages
+	Message  *Message
+	Children []*MessageNode
+}
+
+// BuildTree links the session's messages into trees by UUID and ParentUUID
+// and returns their roots in file order. Messages whose parent is not in the
+// session, and messages caught in a parent cycle, are attached to a
+// synthetic root with a nil Message, returned last. Every message appears in
+// exactly one tree.
+func (s *Session) BuildTree() []*MessageNode {
+	nodes := make([]*MessageNode, len(s.Messages))
+	byUUID := make(map[string]*MessageNode, len(s.Messages))
+	for i, msg := range s.Messages {
+		nodes[i] = &MessageNode{Message: msg}
+		if _, ok := byUUID[msg.UUID]; msg.UUID != "" && !ok {
+			byUUID[msg.UUID] = nodes[i]
+		}
+	}
+
+	var roots []*MessageNode
+	orphans := &MessageNode{}
+	parents := make(map[*MessageNode]*MessageNode)
+	for _, node := range nodes {
+		msg := node.Message
+		switch parent, ok := byUUID[parentUUID(msg)]; {
+		case msg.ParentUUID == nil:
+			roots = append(roots, node)
+		case !ok || parent == node:
+			orphans.Children = append(orphans.Children, node)
+		default:
+			parent.Children = append(parent.Children, node)
+			parents[node] = parent
+		}
+	}
+
+	// Messages not reachable from a root are in a cycle, or below one.
+	// Detach the first of them from its parent and attach it to the
+	// synthetic root until every message is reachable.
+	reached := make(map[*MessageNode]bool, len(nodes))
+	for _, root := range roots {
+		markReached(root, reached)
+	}
+	markReached(orphans, reached)
+	for _, node := range nodes {
+		if reached[node] {
+			continue
+		}
+		parent := parents[node]
+		for i, child := range parent.Children {
+			if child == node {
+				parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+				break
+			}
+		}
+		orphans.Children = append(orphans.Children, node)
+		markReached(node, reached)
+	}
+
+	if len(orphans.Children) > 0 {
+		roots = append(roots, orphans)
+	}
+	return roots
+}
+
+// markReached marks the node and its descendants, stopping at nodes already
+// marked
+func markReached(node *MessageNode, reached map[*MessageNode]bool) {
+	stack := []*MessageNode{node}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reached[node] {
+			continue
+		}
+		reached[node] = true
+		stack = append(stack, node.Children...)
+	}
+}
+
+// parentUUID returns the message's parent UUID, or "" if it has none
+func parentUUID(msg *Message) string {
+	if msg.ParentUUID == nil {
+		return ""
+	}
+	return *msg.ParentUUID
+}
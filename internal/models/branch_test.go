@@ -0,0 +1,140 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func createBranchedSession() *Session {
+	parent := func(uuid string) *string { return &uuid }
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	session := &Session{ID: "branched"}
+	messages := []*Message{
+		{UUID: "u1", Type: MessageTypeUser, Timestamp: base},
+		{UUID: "a1", ParentUUID: parent("u1"), Type: MessageTypeAssistant, Timestamp: base.Add(1 * time.Minute)},
+		{UUID: "u2", ParentUUID: parent("a1"), Type: MessageTypeUser, Timestamp: base.Add(2 * time.Minute)},
+		{UUID: "a2", ParentUUID: parent("u2"), Type: MessageTypeAssistant, Timestamp: base.Add(3 * time.Minute)},
+		// The user edited u2 and regenerated
+		{UUID: "u2-edit", ParentUUID: parent("a1"), Type: MessageTypeUser, Timestamp: base.Add(4 * time.Minute)},
+		{UUID: "a2-edit", ParentUUID: parent("u2-edit"), Type: MessageTypeAssistant, Timestamp: base.Add(5 * time.Minute)},
+	}
+	for _, msg := range messages {
+		session.AddMessage(msg)
+	}
+	return session
+}
+
+func TestMarkRegenerated(t *testing.T) {
+	session := createBranchedSession()
+
+	if count := session.MarkRegenerated(); count != 2 {
+		t.Fatalf("MarkRegenerated() = %v, want 2", count)
+	}
+
+	for _, msg := range session.Messages {
+		wantRegenerated := msg.UUID == "u2" || msg.UUID == "a2"
+		if msg.Regenerated != wantRegenerated {
+			t.Errorf("Message %s Regenerated = %v, want %v", msg.UUID, msg.Regenerated, wantRegenerated)
+		}
+	}
+
+	session.PruneRegenerated()
+
+	if count := session.GetMessageCount(); count != 4 {
+		t.Errorf("GetMessageCount() after prune = %v, want 4", count)
+	}
+	if session.Messages[2].UUID != "u2-edit" {
+		t.Errorf("Third message = %v, want u2-edit", session.Messages[2].UUID)
+	}
+}
+
+func TestMarkRegeneratedLinear(t *testing.T) {
+	parent := "u1"
+	session := &Session{ID: "linear"}
+	session.AddMessage(&Message{UUID: "u1", Type: MessageTypeUser})
+	session.AddMessage(&Message{UUID: "a1", ParentUUID: &parent, Type: MessageTypeAssistant})
+
+	if count := session.MarkRegenerated(); count != 0 {
+		t.Errorf("MarkRegenerated() for linear session = %v, want 0", count)
+	}
+}
+
+func TestMarkRegeneratedCycle(t *testing.T) {
+	a, b := "a", "b"
+	session := &Session{ID: "cycle"}
+	session.AddMessage(&Message{UUID: "a", ParentUUID: &b})
+	session.AddMessage(&Message{UUID: "b", ParentUUID: &a})
+	session.AddMessage(&Message{UUID: "c"})
+
+	// Must terminate despite the a <-> b cycle
+	session.MarkRegenerated()
+}
+
+func TestBuildTree(t *testing.T) {
+	session := createBranchedSession()
+	missing, sidechainRoot := "missing", "a1"
+	session.AddMessage(&Message{UUID: "orphan", ParentUUID: &missing})
+	session.AddMessage(&Message{UUID: "side", ParentUUID: &sidechainRoot, Sidechain: true})
+
+	roots := session.BuildTree()
+	if len(roots) != 2 {
+		t.Fatalf("BuildTree() returned %d roots, want 2", len(roots))
+	}
+
+	root := roots[0]
+	if root.Message.UUID != "u1" || len(root.Children) != 1 {
+		t.Fatalf("First root = %v with %d children, want u1 with 1", root.Message.UUID, len(root.Children))
+	}
+	a1 := root.Children[0]
+	var children []string
+	for _, child := range a1.Children {
+		children = append(children, child.Message.UUID)
+	}
+	if strings.Join(children, ",") != "u2,u2-edit,side" {
+		t.Errorf("Children of a1 = %v, want [u2 u2-edit side]", children)
+	}
+	if len(a1.Children[0].Children) != 1 || a1.Children[0].Children[0].Message.UUID != "a2" {
+		t.Errorf("Expected a2 to reply to u2")
+	}
+
+	synthetic := roots[1]
+	if synthetic.Message != nil || len(synthetic.Children) != 1 || synthetic.Children[0].Message.UUID != "orphan" {
+		t.Errorf("Expected a synthetic root holding the orphan, got %+v", synthetic)
+	}
+}
+
+func TestBuildTreeCycle(t *testing.T) {
+	a, b, self := "a", "b", "self"
+	session := &Session{ID: "cycle"}
+	session.AddMessage(&Message{UUID: "root"})
+	session.AddMessage(&Message{UUID: "a", ParentUUID: &b})
+	session.AddMessage(&Message{UUID: "b", ParentUUID: &a})
+	session.AddMessage(&Message{UUID: "self", ParentUUID: &self})
+
+	roots := session.BuildTree()
+	if len(roots) != 2 || roots[0].Message.UUID != "root" || roots[1].Message != nil {
+		t.Fatalf("Expected the root and a synthetic root, got %d roots", len(roots))
+	}
+
+	// Every message appears exactly once
+	seen := make(map[string]int)
+	var visit func(node *MessageNode)
+	visit = func(node *MessageNode) {
+		if node.Message != nil {
+			seen[node.Message.UUID]++
+		}
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+	for _, uuid := range []string{"root", "a", "b", "self"} {
+		if seen[uuid] != 1 {
+			t.Errorf("Message %s appears %d times, want 1", uuid, seen[uuid])
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import "encoding/json"
+
+// Models are not safe for concurrent mutation. The following methods modify
+// their receiver and must not run while other goroutines read the same value:
+//
+//   - Session: AddMessage, MarkRegenerated, PruneRegenerated
+//   - Project: AddSession, AddTodoList, AddShellSnapshot, CheckPathExists
+//   - Message: ParseContent
+//
+// All other methods only read. Consumers that need to modify a project or
+// session shared with other goroutines should work on a Clone.
+
+// Clone returns a deep copy of the project, its sessions, todo lists and
+// shell snapshots
+func (p *Project) Clone() *Project {
+	clone := *p
+
+	clone.Sessions = make([]*Session, len(p.Sessions))
+	for i, session := range p.Sessions {
+		clone.Sessions[i] = session.Clone()
+	}
+
+	clone.TodoLists = make([]*TodoList, len(p.TodoLists))
+	for i, todoList := range p.TodoLists {
+		clone.TodoLists[i] = todoList.Clone()
+	}
+
+	if p.ShellSnapshots != nil {
+		clone.ShellSnapshots = make([]*ShellSnapshot, len(p.ShellSnapshots))
+		for i, snapshot := range p.ShellSnapshots {
+			s := *snapshot
+			clone.ShellSnapshots[i] = &s
+		}
+	}
+
+	if p.Exists != nil {
+		exists := *p.Exists
+		clone.Exists = &exists
+	}
+
+	return &clone
+}
+
+// Clone returns a deep copy of the session and its messages
+func (s *Session) Clone() *Session {
+	clone := *s
+	if s.Messages != nil {
+		clone.Messages = make([]*Message, len(s.Messages))
+		for i, msg := range s.Messages {
+			clone.Messages[i] = msg.Clone()
+		}
+	}
+	return &clone
+}
+
+// Clone returns a deep copy of the message, including its parsed content
+func (m *Message) Clone() *Message {
+	clone := *m
+
+	if m.ParentUUID != nil {
+		parentUUID := *m.ParentUUID
+		clone.ParentUUID = &parentUUID
+	}
+	clone.Message = cloneRaw(m.Message)
+
+	switch content := m.Content.(type) {
+	case *UserMessage:
+		userMsg := *content
+		clone.Content = &userMsg
+	case *AssistantMessage:
+		clone.Content = content.Clone()
+	case *SummaryMessage:
+		summary := *content
+		clone.Content = &summary
+	case []ToolResult:
+		results := make([]ToolResult, len(content))
+		for i, result := range content {
+			results[i] = result
+			results[i].Content = cloneRaw(result.Content)
+		}
+		clone.Content = results
+	}
+
+	return &clone
+}
+
+// Clone returns a deep copy of the assistant message
+func (a *AssistantMessage) Clone() *AssistantMessage {
+	clone := *a
+
+	if a.Content != nil {
+		clone.Content = make([]MessageContent, len(a.Content))
+		for i, content := range a.Content {
+			clone.Content[i] = content
+			clone.Content[i].Input = cloneRaw(content.Input)
+		}
+	}
+
+	if a.Usage != nil {
+		usage := *a.Usage
+		clone.Usage = &usage
+	}
+
+	return &clone
+}
+
+// Clone returns a deep copy of the todo list
+func (tl *TodoList) Clone() *TodoList {
+	clone := *tl
+	if tl.Todos != nil {
+		clone.Todos = make([]*Todo, len(tl.Todos))
+		for i, todo := range tl.Todos {
+			t := *todo
+			clone.Todos[i] = &t
+		}
+	}
+	return &clone
+}
+
+// cloneRaw copies raw JSON so the clone does not share its backing array
+func cloneRaw(raw json.RawMessage) json.RawMessage {
+	if raw == nil {
+		return nil
+	}
+	return append(json.RawMessage(nil), raw...)
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func createCloneFixture() *Project {
+	project := NewProject("-Users-test-clone")
+	session := &Session{ID: "session1"}
+
+	parent := "msg1"
+	for _, msg := range []*Message{
+		{
+			UUID:      "msg1",
+			Type:      MessageTypeUser,
+			UserType:  "external",
+			Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			Message:   json.RawMessage(`{"role":"user","content":"Hello"}`),
+		},
+		{
+			UUID:       "msg2",
+			ParentUUID: &parent,
+			Type:       MessageTypeAssistant,
+			Timestamp:  time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC),
+			Message:    json.RawMessage(`{"role":"assistant","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":10,"output_tokens":20}}`),
+		},
+	} {
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	project.AddSession(session)
+	project.AddTodoList(&TodoList{SessionID: "session1", Todos: []*Todo{{ID: "1", Content: "Write tests"}}})
+	project.CheckPathExists()
+
+	return project
+}
+
+func TestProjectClone(t *testing.T) {
+	original := createCloneFixture()
+	clone := original.Clone()
+
+	// Mutate every level of the clone
+	clone.Path = "/changed"
+	*clone.Exists = true
+	clone.TodoLists[0].Todos[0].Content = "Changed"
+	session := clone.Sessions[0]
+	session.ID = "changed"
+	session.Messages[0].Content.(*UserMessage).Content = "Changed"
+	*session.Messages[1].ParentUUID = "changed"
+	session.Messages[1].Message[0] = 'X'
+	assistantMsg := session.Messages[1].Content.(*AssistantMessage)
+	assistantMsg.Content[0].Text = "Changed"
+	assistantMsg.Usage.InputTokens = 999
+	session.AddMessage(&Message{UUID: "msg3"})
+
+	if original.Path != "/Users/test/clone" || *original.Exists {
+		t.Error("Mutating the clone changed the original project")
+	}
+	if original.TodoLists[0].Todos[0].Content != "Write tests" {
+		t.Error("Mutating the clone changed the original todos")
+	}
+
+	origSession := original.Sessions[0]
+	if origSession.ID != "session1" || len(origSession.Messages) != 2 {
+		t.Error("Mutating the clone changed the original session")
+	}
+	if origSession.Messages[0].Content.(*UserMessage).Content != "Hello" {
+		t.Error("Mutating the clone changed the original user message")
+	}
+	if *origSession.Messages[1].ParentUUID != "msg1" || origSession.Messages[1].Message[0] != '{' {
+		t.Error("Mutating the clone changed the original message metadata")
+	}
+	origAssistant := origSession.Messages[1].Content.(*AssistantMessage)
+	if origAssistant.GetText() != "Hi" || origAssistant.Usage.InputTokens != 10 {
+		t.Error("Mutating the clone changed the original assistant message")
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// unknownModel labels usage of assistant messages without a model
+const unknownModel = "unknown"
+
+// DailyUsage holds the token usage and estimated cost of one model on one day
+type DailyUsage struct {
+	Date  string  `json:"date"` // YYYY-MM-DD in UTC
+	Model string  `json:"model"`
+	Usage Usage   `json:"usage"`
+	Cost  float64 `json:"estimated_cost"`
+}
+
+// GetDailyUsage aggregates the token usage of all assistant messages of the
+// projects by UTC day and model, sorted by date and then model. Messages
+// without a timestamp or usage are skipped.
+func GetDailyUsage(projects []*Project) []*DailyUsage {
+	type key struct{ date, model string }
+	byKey := make(map[key]*DailyUsage)
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			for _, msg := range session.Messages {
+				assistantMsg, ok := msg.Content.(*AssistantMessage)
+				if !ok || assistantMsg.Usage == nil || msg.Timestamp.IsZero() {
+					continue
+				}
+				model := assistantMsg.Model
+				if model == "" {
+					model = unknownModel
+				}
+				k := key{msg.Timestamp.UTC().Format("2006-01-02"), model}
+				day, ok := byKey[k]
+				if !ok {
+					day = &DailyUsage{Date: k.date, Model: k.model}
+					byKey[k] = day
+				}
+				day.Usage.Add(assistantMsg.Usage)
+				day.Cost += assistantMsg.Usage.EstimateCost(assistantMsg.Model)
+			}
+		}
+	}
+
+	days := make([]*DailyUsage, 0, len(byKey))
+	for _, day := range byKey {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].Date != days[j].Date {
+			return days[i].Date < days[j].Date
+		}
+		return days[i].Model < days[j].Model
+	})
+	return days
+}
+
+// DayStats holds the number of messages, sessions and tokens of one
+// calendar day
+type DayStats struct {
+	Date     string `json:"date"` // YYYY-MM-DD in local time
+	Messages int    `json:"messages"`
+	Sessions int    `json:"sessions"` // Sessions with a message on the day
+	Tokens   int    `json:"tokens"`
+}
+
+// Add adds the counts of other to these stats
+func (d *DayStats) Add(other DayStats) {
+	d.Messages += other.Messages
+	d.Sessions += other.Sessions
+	d.Tokens += other.Tokens
+}
+
+// GetDailyActivity counts the messages, sessions and tokens of the project per
+// calendar day, keyed by YYYY-MM-DD. Days are in local time, as the dates of
+// the CLI's date filters. Tokens are the total usage of assistant messages;
+// messages without a timestamp are skipped.
+func (p *Project) GetDailyActivity() map[string]DayStats {
+	days := make(map[string]DayStats)
+	for _, session := range p.Sessions {
+		seen := make(map[string]bool)
+		for _, msg := range session.Messages {
+			if msg.Timestamp.IsZero() {
+				continue
+			}
+			date := msg.Timestamp.In(time.Local).Format("2006-01-02")
+			day := days[date]
+			day.Date = date
+			day.Messages++
+			if !seen[date] {
+				seen[date] = true
+				day.Sessions++
+			}
+			if assistantMsg, ok := msg.Content.(*AssistantMessage); ok && assistantMsg.Usage != nil {
+				day.Tokens += assistantMsg.Usage.Total()
+			}
+			days[date] = day
+		}
+	}
+	return days
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetDailyUsage(t *testing.T) {
+	day1 := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	newMessage := func(ts time.Time, model string, input, cacheRead, output int) *Message {
+		msg := &Message{
+			Type:      MessageTypeAssistant,
+			Timestamp: ts,
+			Message: json.RawMessage(fmt.Sprintf(`{"role":"assistant","model":%q,"content":[{"type":"text","text":"Done"}],"usage":{"input_tokens":%d,"cache_read_input_tokens":%d,"output_tokens":%d}}`,
+				model, input, cacheRead, output)),
+		}
+		msg.ParseContent()
+		return msg
+	}
+
+	// Two projects, two days, two models; each day and model is used twice
+	var projects []*Project
+	for _, encodedPath := range []string{"-work-api", "-work-web"} {
+		project := NewProject(encodedPath)
+		session := &Session{ID: encodedPath}
+		for _, ts := range []time.Time{day1, day2} {
+			session.AddMessage(newMessage(ts, "claude-3-opus-20240229", 100, 1000, 10))
+			session.AddMessage(newMessage(ts.Add(time.Hour), "claude-3-5-sonnet-20241022", 200, 0, 20))
+		}
+		project.AddSession(session)
+		projects = append(projects, project)
+	}
+
+	days := GetDailyUsage(projects)
+	if len(days) != 4 {
+		t.Fatalf("GetDailyUsage() returned %d rows, want 4", len(days))
+	}
+
+	want := []struct {
+		date, model              string
+		input, cacheRead, output int
+	}{
+		{"2024-07-01", "claude-3-5-sonnet-20241022", 400, 0, 40},
+		{"2024-07-01", "claude-3-opus-20240229", 200, 2000, 20},
+		{"2024-07-02", "claude-3-5-sonnet-20241022", 400, 0, 40},
+		{"2024-07-02", "claude-3-opus-20240229", 200, 2000, 20},
+	}
+	for i, w := range want {
+		day := days[i]
+		if day.Date != w.date || day.Model != w.model {
+			t.Errorf("days[%d] = %s %s, want %s %s", i, day.Date, day.Model, w.date, w.model)
+		}
+		if day.Usage.InputTokens != w.input || day.Usage.CacheReadInputTokens != w.cacheRead || day.Usage.OutputTokens != w.output {
+			t.Errorf("days[%d] usage = %+v, want input %d, cache read %d, output %d", i, day.Usage, w.input, w.cacheRead, w.output)
+		}
+		usage := Usage{InputTokens: w.input, CacheReadInputTokens: w.cacheRead, OutputTokens: w.output}
+		if wantCost := usage.EstimateCost(w.model); math.Abs(day.Cost-wantCost) > 1e-9 {
+			t.Errorf("days[%d] cost = %v, want %v", i, day.Cost, wantCost)
+		}
+	}
+}
+
+func TestGetDailyActivity(t *testing.T) {
+	oldLocal := time.Local
+	time.Local = time.FixedZone("UTC+9", 9*60*60)
+	defer func() { time.Local = oldLocal }()
+
+	session := &Session{ID: "s1"}
+	for _, raw := range []struct {
+		msgType   MessageType
+		timestamp string
+		message   string
+	}{
+		{MessageTypeUser, "2024-01-01T10:00:00Z", `{"role":"user","content":"Hi"}`},
+		{MessageTypeAssistant, "2024-01-01T10:00:05Z", `{"role":"assistant","content":[],"usage":{"input_tokens":10,"output_tokens":20,"cache_read_input_tokens":5}}`},
+		// 9 PM UTC is the next day in UTC+9
+		{MessageTypeUser, "2024-01-01T21:00:00Z", `{"role":"user","content":"Later"}`},
+	} {
+		ts, _ := time.Parse(time.RFC3339, raw.timestamp)
+		msg := &Message{Type: raw.msgType, Timestamp: ts, Message: json.RawMessage(raw.message)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	session.AddMessage(&Message{Type: MessageTypeSummary})
+	project := NewProject("-work-api")
+	project.AddSession(session)
+
+	days := project.GetDailyActivity()
+	want := map[string]DayStats{
+		"2024-01-01": {Date: "2024-01-01", Messages: 2, Sessions: 1, Tokens: 35},
+		"2024-01-02": {Date: "2024-01-02", Messages: 1, Sessions: 1, Tokens: 0},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("GetDailyActivity() = %v, want %v", days, want)
+	}
+	for date, day := range want {
+		if days[date] != day {
+			t.Errorf("GetDailyActivity()[%s] = %+v, want %+v", date, days[date], day)
+		}
+	}
+}
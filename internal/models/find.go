@@ -0,0 +1,31 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FindSession returns the session with the given ID across all projects. It
+// fails if no project has the session, or if more than one does.
+func FindSession(projects []*Project, id string) (*Session, error) {
+	var found *Session
+	var owners []string
+	for _, project := range projects {
+		for _, session := range project.Sessions {
+			if session.ID != id {
+				continue
+			}
+			found = session
+			owners = append(owners, project.Path)
+		}
+	}
+
+	switch len(owners) {
+	case 0:
+		return nil, fmt.Errorf("session %s not found", id)
+	case 1:
+		return found, nil
+	default:
+		return nil, fmt.Errorf("session %s is ambiguous, found in: %s", id, strings.Join(owners, ", "))
+	}
+}
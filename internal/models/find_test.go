@@ -0,0 +1,31 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindSession(t *testing.T) {
+	api := NewProject("work-api")
+	api.AddSession(&Session{ID: "s1"})
+	api.AddSession(&Session{ID: "shared"})
+	web := NewProject("work-web")
+	web.AddSession(&Session{ID: "s2"})
+	web.AddSession(&Session{ID: "shared"})
+	projects := []*Project{api, web}
+
+	session, err := FindSession(projects, "s2")
+	if err != nil {
+		t.Fatalf("FindSession() error = %v", err)
+	}
+	if session != web.Sessions[0] {
+		t.Errorf("FindSession() = %v, want session s2 of work-web", session)
+	}
+
+	if _, err := FindSession(projects, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("FindSession() error = %v, want not found", err)
+	}
+	if _, err := FindSession(projects, "shared"); err == nil || !strings.Contains(err.Error(), "work/api, work/web") {
+		t.Errorf("FindSession() error = %v, want ambiguity naming both projects", err)
+	}
+}
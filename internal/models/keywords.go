@@ -0,0 +1,110 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minKeywordLength is the minimum length of a word to count as a keyword
+const minKeywordLength = 3
+
+// stopwords are common English words ignored by keyword extraction
+var stopwords = map[string]bool{
+	"about": true, "above": true, "after": true, "again": true, "all": true,
+	"also": true, "and": true, "any": true, "are": true, "because": true,
+	"been": true, "before": true, "being": true, "below": true, "between": true,
+	"both": true, "but": true, "can": true, "could": true, "did": true,
+	"does": true, "doing": true, "don": true, "down": true, "during": true,
+	"each": true, "few": true, "for": true, "from": true, "further": true,
+	"had": true, "has": true, "have": true, "having": true, "her": true,
+	"here": true, "hers": true, "him": true, "his": true, "how": true,
+	"into": true, "its": true, "just": true, "let": true, "like": true,
+	"make": true, "more": true, "most": true, "need": true, "not": true,
+	"now": true, "off": true, "once": true, "only": true, "other": true,
+	"our": true, "ours": true, "out": true, "over": true, "own": true,
+	"please": true, "same": true, "she": true, "should": true, "some": true,
+	"such": true, "than": true, "that": true, "the": true, "their": true,
+	"them": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "those": true, "through": true, "too": true, "under": true,
+	"until": true, "use": true, "very": true, "want": true, "was": true,
+	"way": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "while": true, "who": true, "whom": true, "why": true,
+	"will": true, "with": true, "would": true, "you": true, "your": true,
+	"yours": true, "yes": true, "sure": true, "okay": true, "get": true,
+	"here's": true, "i'll": true, "let's": true, "it's": true, "i'm": true,
+}
+
+// GetTopKeywords returns up to n of the most frequent words in the user and
+// assistant text of the session, ignoring stopwords, short words and numbers.
+// Ties are broken alphabetically so results are stable.
+func (s *Session) GetTopKeywords(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, msg := range s.Messages {
+		for _, word := range tokenize(messageText(msg)) {
+			if isKeyword(word) {
+				counts[word]++
+			}
+		}
+	}
+
+	keywords := make([]string, 0, len(counts))
+	for word := range counts {
+		keywords = append(keywords, word)
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if counts[keywords[i]] != counts[keywords[j]] {
+			return counts[keywords[i]] > counts[keywords[j]]
+		}
+		return keywords[i] < keywords[j]
+	})
+
+	if len(keywords) > n {
+		keywords = keywords[:n]
+	}
+	return keywords
+}
+
+// messageText returns the human-readable text of a message: the user's
+// prompt or the assistant's text blocks. Tool calls and results are skipped.
+func messageText(msg *Message) string {
+	switch content := msg.Content.(type) {
+	case *UserMessage:
+		return content.Content
+	case *AssistantMessage:
+		return content.GetText()
+	}
+	return ""
+}
+
+// tokenize splits text into lowercase words, keeping inner apostrophes
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '_'
+	})
+
+	words := fields[:0]
+	for _, field := range fields {
+		if word := strings.Trim(field, "'_"); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// isKeyword checks if a word is a keyword candidate
+func isKeyword(word string) bool {
+	if len([]rune(word)) < minKeywordLength || stopwords[word] {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
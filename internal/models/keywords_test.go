@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSessionGetTopKeywords(t *testing.T) {
+	session := &Session{ID: "keywords"}
+	for _, m := range []struct {
+		msgType MessageType
+		raw     string
+	}{
+		{MessageTypeUser, `{"role":"user","content":"The parser fails on nested brackets. Can you fix the parser?"}`},
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"text","text":"The parser's bracket handling is wrong. I'll rewrite the parser loop."}]}`},
+		{MessageTypeUser, `{"role":"user","content":"Thanks, now the parser passes 100 tests"}`},
+	} {
+		msg := &Message{Type: m.msgType, UserType: "external", Message: json.RawMessage(m.raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	keywords := session.GetTopKeywords(3)
+
+	if len(keywords) != 3 {
+		t.Fatalf("GetTopKeywords(3) returned %d keywords, want 3", len(keywords))
+	}
+
+	if keywords[0] != "parser" {
+		t.Errorf("GetTopKeywords(3)[0] = %v, want parser", keywords[0])
+	}
+
+	for _, word := range session.GetTopKeywords(100) {
+		if word == "the" || word == "100" || word == "can" {
+			t.Errorf("GetTopKeywords() returned stopword or number %q", word)
+		}
+	}
+
+	if none := session.GetTopKeywords(0); none != nil {
+		t.Errorf("GetTopKeywords(0) = %v, want nil", none)
+	}
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// MergeProjects merges projects that refer to the same directory, e.g. the
+// same project found under several .claude directories. Sessions are unioned
+// by ID; when both copies of a session exist, the one with more messages is
+// kept. Todo lists are unioned by session and agent, and shell snapshots by
+// name. Projects are returned in the order they were first seen, and the
+// first project of each group is modified in place.
+func MergeProjects(projects []*Project) []*Project {
+	var merged []*Project
+	byPath := make(map[string]*Project)
+	for _, project := range projects {
+		path := filepath.Clean(project.ResolvePath())
+		target, ok := byPath[path]
+		if !ok {
+			byPath[path] = project
+			merged = append(merged, project)
+			continue
+		}
+		target.merge(project)
+	}
+	return merged
+}
+
+// merge adds the sessions, todo lists and shell snapshots of other that p
+// does not have
+func (p *Project) merge(other *Project) {
+	sessions := make(map[string]int, len(p.Sessions))
+	for i, session := range p.Sessions {
+		sessions[session.ID] = i
+	}
+	for _, session := range other.Sessions {
+		i, ok := sessions[session.ID]
+		if !ok {
+			sessions[session.ID] = len(p.Sessions)
+			p.AddSession(session)
+			continue
+		}
+		if session.GetMessageCount() > p.Sessions[i].GetMessageCount() {
+			session.ProjectID = p.ID
+			p.Sessions[i] = session
+		}
+	}
+
+	todoLists := make(map[[2]string]bool, len(p.TodoLists))
+	for _, todoList := range p.TodoLists {
+		todoLists[[2]string{todoList.SessionID, todoList.AgentID}] = true
+	}
+	for _, todoList := range other.TodoLists {
+		key := [2]string{todoList.SessionID, todoList.AgentID}
+		if !todoLists[key] {
+			todoLists[key] = true
+			p.AddTodoList(todoList)
+		}
+	}
+
+	snapshots := make(map[string]bool, len(p.ShellSnapshots))
+	for _, snapshot := range p.ShellSnapshots {
+		snapshots[snapshot.Name] = true
+	}
+	for _, snapshot := range other.ShellSnapshots {
+		if !snapshots[snapshot.Name] {
+			snapshots[snapshot.Name] = true
+			p.AddShellSnapshot(snapshot)
+		}
+	}
+}
+
+// MergeSessions merges sessions that share an ID, such as a session resumed
+// into a second file. The messages of each group are combined in timestamp
+// order, keeping one copy of messages present in more than one file (by
+// UUID); messages without a timestamp stay after the message preceding them
+// in their file. Sessions are returned in the order they were first seen, and
+// the first session of each group is modified in place.
+func MergeSessions(sessions []*Session) []*Session {
+	var merged []*Session
+	groups := make(map[string][]*Session)
+	for _, session := range sessions {
+		if session.ID == "" {
+			merged = append(merged, session)
+			continue
+		}
+		if _, ok := groups[session.ID]; !ok {
+			merged = append(merged, session)
+		}
+		groups[session.ID] = append(groups[session.ID], session)
+	}
+
+	for _, group := range groups {
+		if len(group) > 1 {
+			group[0].merge(group[1:])
+		}
+	}
+	return merged
+}
+
+// FlattenSessions returns a new session holding the messages of all sessions
+// in timestamp order, as MergeSessions combines the files of one session. The
+// sessions are not modified.
+func FlattenSessions(sessions []*Session) *Session {
+	flat := &Session{}
+	if len(sessions) > 0 {
+		flat.ProjectID = sessions[0].ProjectID
+		flat.merge(sessions)
+	}
+	return flat
+}
+
+// merge replaces the messages of s with the combined messages of s and
+// others, sorted by timestamp and without duplicates
+func (s *Session) merge(others []*Session) {
+	type entry struct {
+		msg  *Message
+		time time.Time
+	}
+	var entries []entry
+	seen := make(map[string]bool)
+	for _, session := range append([]*Session{s}, others...) {
+		last := session.StartTime
+		for _, msg := range session.Messages {
+			if msg.UUID != "" {
+				if seen[msg.UUID] {
+					continue
+				}
+				seen[msg.UUID] = true
+			}
+			if !msg.Timestamp.IsZero() {
+				last = msg.Timestamp
+			}
+			entries = append(entries, entry{msg, last})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].time.Before(entries[j].time) })
+
+	s.Messages = nil
+	s.StartTime = time.Time{}
+	s.EndTime = time.Time{}
+	for _, e := range entries {
+		s.AddMessage(e.msg)
+	}
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeProjects(t *testing.T) {
+	newSession := func(id string, messages int) *Session {
+		session := &Session{ID: id}
+		for i := 0; i < messages; i++ {
+			msg := &Message{
+				Type:     MessageTypeUser,
+				UserType: "external",
+				CWD:      "/work/app",
+				Message:  json.RawMessage(`{"role":"user","content":"Hello"}`),
+			}
+			msg.ParseContent()
+			session.AddMessage(msg)
+		}
+		return session
+	}
+
+	// The same directory under two encodings, plus an unrelated project
+	first := NewProject("-work-app")
+	first.AddSession(newSession("s1", 1))
+	first.AddSession(newSession("s2", 1))
+	first.AddTodoList(&TodoList{SessionID: "s1", AgentID: "s1"})
+	second := NewProject("-work-app-copy")
+	second.AddSession(newSession("s2", 3))
+	second.AddSession(newSession("s3", 1))
+	second.AddTodoList(&TodoList{SessionID: "s1", AgentID: "s1"})
+	other := NewProject("-work-other")
+
+	merged := MergeProjects([]*Project{first, other, second})
+	if len(merged) != 2 || merged[0] != first || merged[1] != other {
+		t.Fatalf("MergeProjects() = %v, want [first other]", merged)
+	}
+	if len(first.Sessions) != 3 {
+		t.Fatalf("Merged project has %d sessions, want 3", len(first.Sessions))
+	}
+	if first.Sessions[1].ID != "s2" || first.Sessions[1].GetMessageCount() != 3 {
+		t.Errorf("Conflicting session should keep the copy with more messages in place, got %s with %d messages",
+			first.Sessions[1].ID, first.Sessions[1].GetMessageCount())
+	}
+	if first.Sessions[2].ProjectID != first.ID {
+		t.Errorf("Merged session ProjectID = %s, want %s", first.Sessions[2].ProjectID, first.ID)
+	}
+	if len(first.TodoLists) != 1 {
+		t.Errorf("Merged project has %d todo lists, want 1", len(first.TodoLists))
+	}
+}
+
+func TestMergeSessions(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	newSession := func(id string, uuids []string, minutes []int) *Session {
+		session := &Session{ID: id}
+		for i, uuid := range uuids {
+			msg := &Message{UUID: uuid, Type: MessageTypeUser}
+			if minutes[i] >= 0 {
+				msg.Timestamp = base.Add(time.Duration(minutes[i]) * time.Minute)
+			}
+			session.AddMessage(msg)
+		}
+		return session
+	}
+
+	// The resumed file repeats the last message of the first one
+	resumed := newSession("s1", []string{"m3", "m4", "summary", "m5"}, []int{2, 3, -1, 4})
+	first := newSession("s1", []string{"m1", "m2", "m3"}, []int{0, 1, 2})
+	other := newSession("s2", []string{"o1"}, []int{5})
+
+	merged := MergeSessions([]*Session{resumed, other, first})
+	if len(merged) != 2 || merged[0] != resumed || merged[1] != other {
+		t.Fatalf("MergeSessions() = %v, want [resumed other]", merged)
+	}
+
+	var uuids []string
+	for _, msg := range resumed.Messages {
+		uuids = append(uuids, msg.UUID)
+	}
+	want := []string{"m1", "m2", "m3", "m4", "summary", "m5"}
+	if len(uuids) != len(want) {
+		t.Fatalf("Merged messages = %v, want %v", uuids, want)
+	}
+	for i := range want {
+		if uuids[i] != want[i] {
+			t.Fatalf("Merged messages = %v, want %v", uuids, want)
+		}
+	}
+	if !resumed.StartTime.Equal(base) || !resumed.EndTime.Equal(base.Add(4*time.Minute)) {
+		t.Errorf("Merged session spans %v to %v, want 10:00 to 10:04", resumed.StartTime, resumed.EndTime)
+	}
+}
+
+func TestFlattenSessions(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	later := &Session{ID: "later"}
+	earlier := &Session{ID: "earlier"}
+	for i, uuid := range []string{"b1", "b2"} {
+		later.AddMessage(&Message{UUID: uuid, Timestamp: base.Add(time.Duration(2*i+1) * time.Minute)})
+	}
+	for i, uuid := range []string{"a1", "a2"} {
+		earlier.AddMessage(&Message{UUID: uuid, Timestamp: base.Add(time.Duration(2*i) * time.Minute)})
+	}
+
+	flat := FlattenSessions([]*Session{later, earlier})
+	var uuids []string
+	for _, msg := range flat.Messages {
+		uuids = append(uuids, msg.UUID)
+	}
+	if strings.Join(uuids, ",") != "a1,b1,a2,b2" {
+		t.Errorf("Flattened messages = %v, want a1,b1,a2,b2", uuids)
+	}
+	if len(later.Messages) != 2 || later.Messages[0].UUID != "b1" {
+		t.Errorf("FlattenSessions() modified its input: %v", later.Messages)
+	}
+	if len(FlattenSessions(nil).Messages) != 0 {
+		t.Error("FlattenSessions(nil) should have no messages")
+	}
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 )
 
@@ -11,6 +14,7 @@ type MessageType string
 const (
 	MessageTypeUser      MessageType = "user"
 	MessageTypeAssistant MessageType = "assistant"
+	MessageTypeSummary   MessageType = "summary"
 )
 
 // Message represents a single message in a conversation
@@ -24,10 +28,23 @@ type Message struct {
 	RequestID  string          `json:"requestId,omitempty"`
 	Version    string          `json:"version,omitempty"`
 	CWD        string          `json:"cwd,omitempty"`
+	GitBranch  string          `json:"gitBranch,omitempty"`
+	Level      string          `json:"level,omitempty"`
+	LogLevel   string          `json:"logLevel,omitempty"`
+	Sidechain  bool            `json:"isSidechain,omitempty"`
+	AgentID    string          `json:"agentId,omitempty"`
 	Message    json.RawMessage `json:"message"`
 	
+	// Summary entries carry their text and the last message they cover at
+	// the top level instead of in Message
+	Summary  string `json:"summary,omitempty"`
+	LeafUUID string `json:"leafUuid,omitempty"`
+	
 	// Parsed message content
 	Content interface{} `json:"-"`
+	
+	// Regenerated is set when the message belongs to a superseded branch
+	Regenerated bool `json:"-"`
 }
 
 // UserMessage represents a user's message
@@ -65,13 +82,113 @@ type Usage struct {
 	ServiceTier              string `json:"service_tier,omitempty"`
 }
 
+// SummaryMessage is the summary Claude Code writes of a conversation, e.g.
+// when it is compacted or resumed
+type SummaryMessage struct {
+	Summary  string `json:"summary"`
+	LeafUUID string `json:"leafUuid,omitempty"`
+}
+
 // ToolResult represents the result of a tool use
 type ToolResult struct {
 	ToolUseID string          `json:"tool_use_id"`
 	Type      string          `json:"type"`
 	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// GetText returns the text of the result if its content is a string or a
+// list of content blocks, whose text blocks are joined by blank lines and
+// other blocks shown as their type in brackets, e.g. [image]. It returns
+// false for other content, such as a JSON object.
+func (r ToolResult) GetText() (string, bool) {
+	if isEmptyJSON(r.Content) {
+		return "", true
+	}
+
+	var text string
+	if err := json.Unmarshal(r.Content, &text); err == nil {
+		return text, true
+	}
+
+	var blocks []MessageContent
+	if err := json.Unmarshal(r.Content, &blocks); err != nil {
+		return "", false
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type == "" {
+			return "", false
+		}
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		} else {
+			parts = append(parts, "["+block.Type+"]")
+		}
+	}
+	return strings.Join(parts, "\n\n"), true
+}
+
+// GetText returns the text blocks of the message joined by blank lines
+func (a *AssistantMessage) GetText() string {
+	return a.joinBlocks("text", func(c MessageContent) string { return c.Text })
+}
+
+// GetThinking returns the thinking blocks of the message joined by blank lines
+func (a *AssistantMessage) GetThinking() string {
+	return a.joinBlocks("thinking", func(c MessageContent) string { return c.Thinking })
 }
 
+// charsPerToken approximates how many characters of English text make up
+// one token
+const charsPerToken = 4
+
+// EstimateThinkingTokens estimates the output tokens spent on thinking blocks.
+// Usage does not break output tokens down, so this is derived from the
+// length of the thinking text and capped at the reported output tokens.
+func (a *AssistantMessage) EstimateThinkingTokens() int {
+	thinking := a.GetThinking()
+	if thinking == "" {
+		return 0
+	}
+	tokens := (len(thinking) + charsPerToken - 1) / charsPerToken
+	if a.Usage != nil && a.Usage.OutputTokens > 0 && tokens > a.Usage.OutputTokens {
+		tokens = a.Usage.OutputTokens
+	}
+	return tokens
+}
+
+// joinBlocks joins the non-empty values of all content blocks of a type
+func (a *AssistantMessage) joinBlocks(blockType string, value func(MessageContent) string) string {
+	var parts []string
+	for _, content := range a.Content {
+		if content.Type == blockType && value(content) != "" {
+			parts = append(parts, value(content))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// GetLevel returns the log level of a diagnostic entry, or an empty string
+// for conversation messages
+func (m *Message) GetLevel() string {
+	if m.Level != "" {
+		return strings.ToLower(m.Level)
+	}
+	return strings.ToLower(m.LogLevel)
+}
+
+// IsDiagnostic checks if the entry is a diagnostic log line (one carrying a
+// level such as debug) rather than part of the conversation
+func (m *Message) IsDiagnostic() bool {
+	return m.GetLevel() != ""
+}
+
+// ErrEmptyContent is returned by ParseContent when a message has null or
+// missing content. Content is still set to an empty message so the turn
+// stays visible in exports.
+var ErrEmptyContent = errors.New("message content is empty")
+
 // ParseContent parses the raw message content based on message type
 func (m *Message) ParseContent() error {
 	switch m.Type {
@@ -85,6 +202,11 @@ func (m *Message) ParseContent() error {
 				return err
 			}
 			
+			if isEmptyJSON(msg.Content) {
+				m.Content = &UserMessage{Role: msg.Role}
+				return ErrEmptyContent
+			}
+			
 			// Content can be string or array of tool results
 			var content string
 			if err := json.Unmarshal(msg.Content, &content); err == nil {
@@ -106,6 +228,47 @@ func (m *Message) ParseContent() error {
 			return err
 		}
 		m.Content = &msg
+		if len(msg.Content) == 0 {
+			return ErrEmptyContent
+		}
+	case MessageTypeSummary:
+		m.Content = &SummaryMessage{Summary: m.Summary, LeafUUID: m.LeafUUID}
+		if m.Summary == "" {
+			return ErrEmptyContent
+		}
 	}
 	return nil
+}
+
+// isEmptyJSON reports whether a raw JSON value is missing or null
+func isEmptyJSON(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// UnmarshalJSON parses an assistant message whose content is either an array
+// of content blocks or, as in some older and tool-generated logs, a plain
+// string, which becomes a single text block
+func (a *AssistantMessage) UnmarshalJSON(data []byte) error {
+	// The alias has the fields of AssistantMessage without this method
+	type alias AssistantMessage
+	var msg struct {
+		alias
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+
+	*a = AssistantMessage(msg.alias)
+	if isEmptyJSON(msg.Content) {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(msg.Content, &text); err == nil {
+		a.Content = []MessageContent{{Type: "text", Text: text}}
+		return nil
+	}
+	return json.Unmarshal(msg.Content, &a.Content)
 }
\ No newline at end of file
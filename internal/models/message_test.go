@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -151,4 +153,200 @@ func TestAssistantMessageParsing(t *testing.T) {
 	if assistantMsg.Usage.InputTokens != 100 {
 		t.Errorf("InputTokens = %v, want 100", assistantMsg.Usage.InputTokens)
 	}
-}
\ No newline at end of file
+}
+
+func TestAssistantMessageStringContent(t *testing.T) {
+	msg := &Message{
+		Type: MessageTypeAssistant,
+		Message: json.RawMessage(`{
+			"role": "assistant",
+			"model": "claude-2",
+			"content": "Plain string response"
+		}`),
+	}
+
+	if err := msg.ParseContent(); err != nil {
+		t.Fatalf("ParseContent() error = %v", err)
+	}
+
+	assistantMsg, ok := msg.Content.(*AssistantMessage)
+	if !ok {
+		t.Fatal("Content is not *AssistantMessage")
+	}
+
+	if len(assistantMsg.Content) != 1 {
+		t.Fatalf("Content length = %v, want 1", len(assistantMsg.Content))
+	}
+
+	if assistantMsg.Content[0].Type != "text" || assistantMsg.Content[0].Text != "Plain string response" {
+		t.Errorf("Content[0] = %+v, want text block with Plain string response", assistantMsg.Content[0])
+	}
+
+	if assistantMsg.Model != "claude-2" {
+		t.Errorf("Model = %v, want claude-2", assistantMsg.Model)
+	}
+}
+
+func TestAssistantMessageContentForms(t *testing.T) {
+	// Both shapes of content decode to the same text block
+	forms := map[string]string{
+		"array":  `{"role":"assistant","model":"claude-2","content":[{"type":"text","text":"Same answer"}],"usage":{"input_tokens":1,"output_tokens":2}}`,
+		"string": `{"role":"assistant","model":"claude-2","content":"Same answer","usage":{"input_tokens":1,"output_tokens":2}}`,
+	}
+	var decoded []AssistantMessage
+	for name, raw := range forms {
+		var msg AssistantMessage
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", name, err)
+		}
+		if msg.GetText() != "Same answer" || msg.Model != "claude-2" || msg.Usage == nil || msg.Usage.OutputTokens != 2 {
+			t.Errorf("Unmarshal(%s) = %+v, want the text, model and usage", name, msg)
+		}
+		decoded = append(decoded, msg)
+	}
+	first, _ := json.Marshal(decoded[0])
+	second, _ := json.Marshal(decoded[1])
+	if string(first) != string(second) {
+		t.Errorf("Forms decode differently:\n%s\n%s", first, second)
+	}
+
+	// Other content is still rejected
+	var msg AssistantMessage
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":42}`), &msg); err == nil {
+		t.Error("Unmarshal() should error for numeric content")
+	}
+}
+
+func TestMessageEmptyContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *Message
+	}{
+		{
+			name: "user null content",
+			message: &Message{
+				Type:     MessageTypeUser,
+				UserType: "external",
+				Message:  json.RawMessage(`{"role":"user","content":null}`),
+			},
+		},
+		{
+			name: "user missing content",
+			message: &Message{
+				Type:     MessageTypeUser,
+				UserType: "external",
+				Message:  json.RawMessage(`{"role":"user"}`),
+			},
+		},
+		{
+			name: "assistant null content",
+			message: &Message{
+				Type:    MessageTypeAssistant,
+				Message: json.RawMessage(`{"role":"assistant","model":"claude-3","content":null}`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.message.ParseContent()
+			if !errors.Is(err, ErrEmptyContent) {
+				t.Errorf("ParseContent() error = %v, want ErrEmptyContent", err)
+			}
+			if tt.message.Content == nil {
+				t.Error("ParseContent() should set Content for empty messages")
+			}
+		})
+	}
+}
+
+func TestAssistantMessageEstimateThinkingTokens(t *testing.T) {
+	thinking := strings.Repeat("Consider each branch carefully. ", 100) // 3200 characters
+	raw, _ := json.Marshal(map[string]interface{}{
+		"role": "assistant",
+		"content": []map[string]string{
+			{"type": "thinking", "thinking": thinking},
+			{"type": "text", "text": "Use a map."},
+		},
+		"usage": map[string]int{"input_tokens": 10, "output_tokens": 1000},
+	})
+	msg := &Message{Type: MessageTypeAssistant, Message: raw}
+	if err := msg.ParseContent(); err != nil {
+		t.Fatalf("ParseContent() error = %v", err)
+	}
+	assistantMsg := msg.Content.(*AssistantMessage)
+
+	if got := assistantMsg.EstimateThinkingTokens(); got != 800 {
+		t.Errorf("EstimateThinkingTokens() = %d, want 800", got)
+	}
+
+	// The estimate never exceeds the reported output tokens
+	assistantMsg.Usage.OutputTokens = 500
+	if got := assistantMsg.EstimateThinkingTokens(); got != 500 {
+		t.Errorf("EstimateThinkingTokens() = %d, want capped at 500", got)
+	}
+
+	session := &Session{}
+	session.AddMessage(msg)
+	if got := session.GetThinkingTokens(); got != 500 {
+		t.Errorf("GetThinkingTokens() = %d, want 500", got)
+	}
+}
+
+func TestSummaryMessageParsing(t *testing.T) {
+	var msg Message
+	line := `{"type":"summary","summary":"Fix the login <redirect>","leafUuid":"leaf-1"}`
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if err := msg.ParseContent(); err != nil {
+		t.Fatalf("ParseContent() error = %v", err)
+	}
+
+	summary, ok := msg.Content.(*SummaryMessage)
+	if !ok {
+		t.Fatalf("Content is %T, want *SummaryMessage", msg.Content)
+	}
+	if summary.Summary != "Fix the login <redirect>" || summary.LeafUUID != "leaf-1" {
+		t.Errorf("SummaryMessage = %+v", summary)
+	}
+
+	// Summaries are neither user nor assistant messages and have no time
+	session := &Session{}
+	session.AddMessage(&Message{Type: MessageTypeUser, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)})
+	session.AddMessage(&msg)
+	if session.GetUserMessageCount() != 1 || session.GetAssistantMessageCount() != 0 {
+		t.Errorf("Counts = %d user, %d assistant, want 1 and 0",
+			session.GetUserMessageCount(), session.GetAssistantMessageCount())
+	}
+	if session.StartTime.IsZero() {
+		t.Error("A summary should not reset the session start time")
+	}
+
+	empty := &Message{Type: MessageTypeSummary}
+	if err := empty.ParseContent(); !errors.Is(err, ErrEmptyContent) {
+		t.Errorf("ParseContent() for empty summary error = %v, want ErrEmptyContent", err)
+	}
+}
+
+func TestToolResultGetText(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		wantOK  bool
+	}{
+		{`"plain output"`, "plain output", true},
+		{`[{"type":"text","text":"first"},{"type":"image"},{"type":"text","text":"second"}]`, "first\n\n[image]\n\nsecond", true},
+		{`null`, "", true},
+		{`{"result":"success"}`, "", false},
+		{`[{"result":"success"}]`, "", false},
+	}
+
+	for _, tt := range tests {
+		result := ToolResult{ToolUseID: "toolu_1", Type: "tool_result", Content: json.RawMessage(tt.content)}
+		got, ok := result.GetText()
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetText() for %s = %q, %v, want %q, %v", tt.content, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
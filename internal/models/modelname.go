@@ -0,0 +1,62 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// modelDateSuffix matches the release date of a model name, as in
+	// claude-3-5-sonnet-20241022 or Vertex AI's claude-3-5-sonnet@20241022
+	modelDateSuffix = regexp.MustCompile(`[-@]\d{8}$`)
+	// modelRevisionSuffix matches the revision of a Bedrock or Vertex AI
+	// model name, as in claude-3-5-sonnet-20241022-v2:0
+	modelRevisionSuffix = regexp.MustCompile(`-v\d+(:\d+)?$`)
+)
+
+// NormalizeModel returns the family of a model name, without its release
+// date, revision or provider prefix and with its version numbers joined by
+// dots: claude-3-5-sonnet-20241022 and
+// us.anthropic.claude-3-5-sonnet-20241022-v2:0 are both claude-3.5-sonnet,
+// and claude-sonnet-4-5-20250929 is claude-sonnet-4.5. Names that are not
+// versioned this way, such as <synthetic>, are returned as they are.
+func NormalizeModel(raw string) string {
+	model := strings.ToLower(strings.TrimSpace(raw))
+	if i := strings.Index(model, "anthropic."); i >= 0 {
+		model = model[i+len("anthropic."):]
+	}
+	for {
+		trimmed := modelRevisionSuffix.ReplaceAllString(model, "")
+		trimmed = modelDateSuffix.ReplaceAllString(trimmed, "")
+		trimmed = strings.TrimSuffix(trimmed, "-latest")
+		if trimmed == model {
+			break
+		}
+		model = trimmed
+	}
+
+	parts := strings.Split(model, "-")
+	family := parts[:1]
+	for i := 1; i < len(parts); i++ {
+		if isVersionNumber(parts[i]) && isVersionNumber(parts[i-1]) {
+			family[len(family)-1] += "." + parts[i]
+			continue
+		}
+		family = append(family, parts[i])
+	}
+	return strings.Join(family, "-")
+}
+
+// isVersionNumber reports whether a part of a model name is a major or minor
+// version number, as opposed to a date
+func isVersionNumber(part string) bool {
+	if part == "" || len(part) > 2 {
+		return false
+	}
+	for _, r := range part {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
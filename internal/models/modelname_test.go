@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestNormalizeModel(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"claude-3-5-sonnet-20241022", "claude-3.5-sonnet"},
+		{"claude-3-5-sonnet-latest", "claude-3.5-sonnet"},
+		{"claude-3-opus-20240229", "claude-3-opus"},
+		{"claude-sonnet-4-5-20250929", "claude-sonnet-4.5"},
+		{"claude-opus-4-1-20250805", "claude-opus-4.1"},
+		{"claude-sonnet-4-20250514", "claude-sonnet-4"},
+		{"us.anthropic.claude-3-5-sonnet-20241022-v2:0", "claude-3.5-sonnet"},
+		{"claude-3-5-sonnet-v2@20241022", "claude-3.5-sonnet"},
+		{"Claude-3-Haiku-20240307", "claude-3-haiku"},
+		{"<synthetic>", "<synthetic>"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeModel(tt.raw); got != tt.want {
+			t.Errorf("NormalizeModel(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
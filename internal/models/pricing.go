@@ -0,0 +1,99 @@
+package models
+
+import (
+	"strings"
+	"sync"
+)
+
+// ModelPricing holds per-million-token prices in USD for a model
+type ModelPricing struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// DefaultPricing maps model name prefixes to their published prices.
+// Lookups use the longest matching prefix so dated model names such as
+// claude-3-5-sonnet-20241022 resolve to their family. Use AddPricing rather
+// than modifying the map while costs may be estimated concurrently.
+var DefaultPricing = map[string]ModelPricing{
+	"claude-3-opus":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"claude-3-sonnet":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheWrite: 0.3, CacheRead: 0.03},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.1},
+}
+
+// pricingMu guards DefaultPricing, which AddPricing replaces with an updated
+// copy instead of modifying it, so lookups can use the map they loaded
+// after releasing the lock
+var pricingMu sync.RWMutex
+
+// LookupPricing returns the pricing for a model using the longest matching
+// prefix in DefaultPricing
+func LookupPricing(model string) (ModelPricing, bool) {
+	pricingMu.RLock()
+	pricing := DefaultPricing
+	pricingMu.RUnlock()
+
+	var best string
+	for prefix := range pricing {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return ModelPricing{}, false
+	}
+	return pricing[best], true
+}
+
+// AddPricing adds models to DefaultPricing, replacing the prices of models
+// that are already known. It is safe to call while costs are estimated
+// concurrently.
+func AddPricing(pricing map[string]ModelPricing) {
+	pricingMu.Lock()
+	defer pricingMu.Unlock()
+	updated := make(map[string]ModelPricing, len(DefaultPricing)+len(pricing))
+	for model, prices := range DefaultPricing {
+		updated[model] = prices
+	}
+	for model, prices := range pricing {
+		updated[model] = prices
+	}
+	DefaultPricing = updated
+}
+
+// EstimateCost estimates the cost in USD of this usage for the given model.
+// Unknown models cost 0.
+func (u *Usage) EstimateCost(model string) float64 {
+	pricing, ok := LookupPricing(model)
+	if !ok {
+		return 0
+	}
+
+	cost := float64(u.InputTokens)*pricing.Input +
+		float64(u.OutputTokens)*pricing.Output +
+		float64(u.CacheCreationInputTokens)*pricing.CacheWrite +
+		float64(u.CacheReadInputTokens)*pricing.CacheRead
+	return cost / 1_000_000
+}
+
+// Add adds the token counts of other to this usage
+func (u *Usage) Add(other *Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+}
+
+// Total returns the sum of all token counts of the usage
+func (u *Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+}
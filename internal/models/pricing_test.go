@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestLookupPricing(t *testing.T) {
+	tests := []struct {
+		model     string
+		wantInput float64
+		wantFound bool
+	}{
+		{"claude-3-5-sonnet-20241022", 3, true},
+		{"claude-3-opus-20240229", 15, true},
+		{"claude-opus-4-5-20251101", 5, true},
+		{"claude-opus-4-1-20250805", 15, true},
+		{"gpt-4", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			pricing, found := LookupPricing(tt.model)
+			if found != tt.wantFound {
+				t.Fatalf("LookupPricing() found = %v, want %v", found, tt.wantFound)
+			}
+			if pricing.Input != tt.wantInput {
+				t.Errorf("Input price = %v, want %v", pricing.Input, tt.wantInput)
+			}
+		})
+	}
+}
+
+func TestUsageEstimateCost(t *testing.T) {
+	usage := &Usage{
+		InputTokens:              1_000_000,
+		OutputTokens:             1_000_000,
+		CacheCreationInputTokens: 1_000_000,
+		CacheReadInputTokens:     1_000_000,
+	}
+
+	// 3 + 15 + 3.75 + 0.30
+	if cost := usage.EstimateCost("claude-sonnet-4-20250514"); math.Abs(cost-22.05) > 1e-9 {
+		t.Errorf("EstimateCost() = %v, want 22.05", cost)
+	}
+
+	if cost := usage.EstimateCost("unknown-model"); cost != 0 {
+		t.Errorf("EstimateCost() for unknown model = %v, want 0", cost)
+	}
+}
+
+func TestAddPricing(t *testing.T) {
+	defer func(saved map[string]ModelPricing) { DefaultPricing = saved }(DefaultPricing)
+
+	AddPricing(map[string]ModelPricing{
+		"claude-sonnet-4": {Input: 2, Output: 10},
+		"my-model":        {Input: 1, Output: 2},
+	})
+
+	usage := &Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000}
+	if cost := usage.EstimateCost("claude-sonnet-4-20250514"); cost != 12 {
+		t.Errorf("EstimateCost() with overridden price = %v, want 12", cost)
+	}
+	if cost := usage.EstimateCost("my-model-v2"); cost != 3 {
+		t.Errorf("EstimateCost() with added model = %v, want 3", cost)
+	}
+	if cost := usage.EstimateCost("claude-3-opus-20240229"); cost != 90 {
+		t.Errorf("EstimateCost() with built-in price = %v, want 90", cost)
+	}
+}
+
+func TestSessionEstimatedCost(t *testing.T) {
+	session := &Session{ID: "cost-session"}
+	for _, raw := range []string{
+		`{"model":"claude-3-opus-20240229","content":[],"usage":{"input_tokens":1000,"output_tokens":1000}}`,
+		`{"model":"claude-3-haiku-20240307","content":[],"usage":{"input_tokens":1000,"output_tokens":1000,"cache_read_input_tokens":500}}`,
+	} {
+		msg := &Message{Type: MessageTypeAssistant, Message: json.RawMessage(raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	totals := session.GetUsageTotals()
+	if totals.InputTokens != 2000 || totals.OutputTokens != 2000 || totals.CacheReadInputTokens != 500 {
+		t.Errorf("GetUsageTotals() = %+v", totals)
+	}
+
+	// opus: 0.015 + 0.075, haiku: 0.00025 + 0.00125 + 0.000015
+	want := 0.015 + 0.075 + 0.00025 + 0.00125 + 0.000015
+	if cost := session.GetEstimatedCost(); math.Abs(cost-want) > 1e-9 {
+		t.Errorf("GetEstimatedCost() = %v, want %v", cost, want)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -8,21 +9,23 @@ import (
 
 // Project represents a Claude Code project
 type Project struct {
-	ID          string       `json:"id"`
-	Path        string       `json:"path"`        // Original project path
-	EncodedPath string       `json:"encoded_path"` // Path as stored in .claude directory
-	Sessions    []*Session   `json:"sessions"`
-	TodoLists   []*TodoList  `json:"todo_lists,omitempty"`
+	ID             string           `json:"id"`
+	Path           string           `json:"path"`         // Original project path
+	EncodedPath    string           `json:"encoded_path"` // Path as stored in .claude directory
+	Sessions       []*Session       `json:"sessions"`
+	TodoLists      []*TodoList      `json:"todo_lists,omitempty"`
+	ShellSnapshots []*ShellSnapshot `json:"shell_snapshots,omitempty"`
+	Exists         *bool            `json:"exists,omitempty"`         // Set by CheckPathExists
+	Config         string           `json:"project_config,omitempty"` // CLAUDE.md of the project directory, if read
 }
 
-// NewProject creates a new project from an encoded path
+// NewProject creates a new project from an encoded path, decoded by
+// replacing each - with /. Scanners of a Claude directory decode it against
+// the filesystem instead, keeping the dashes of directory names.
 func NewProject(encodedPath string) *Project {
-	// Decode the path by replacing - with /
-	decodedPath := strings.ReplaceAll(encodedPath, "-", "/")
-	
 	return &Project{
 		ID:          encodedPath,
-		Path:        decodedPath,
+		Path:        strings.ReplaceAll(encodedPath, "-", "/"),
 		EncodedPath: encodedPath,
 		Sessions:    make([]*Session, 0),
 		TodoLists:   make([]*TodoList, 0),
@@ -40,6 +43,28 @@ func (p *Project) AddTodoList(todoList *TodoList) {
 	p.TodoLists = append(p.TodoLists, todoList)
 }
 
+// GetAggregateTodoStats counts the todos of all todo lists of the project by
+// status and priority. A project without todos has zero counts and empty
+// maps.
+func (p *Project) GetAggregateTodoStats() TodoStats {
+	stats := TodoStats{
+		Lists:      len(p.TodoLists),
+		ByStatus:   make(map[TodoStatus]int),
+		ByPriority: make(map[TodoPriority]int),
+	}
+	for _, todoList := range p.TodoLists {
+		for _, todo := range todoList.Todos {
+			stats.Total++
+			stats.ByStatus[todo.Status]++
+			stats.ByPriority[todo.Priority]++
+		}
+	}
+	if stats.Total > 0 {
+		stats.CompletionRate = float64(stats.ByStatus[TodoStatusCompleted]) / float64(stats.Total) * 100
+	}
+	return stats
+}
+
 // GetSessionCount returns the total number of sessions
 func (p *Project) GetSessionCount() int {
 	return len(p.Sessions)
@@ -85,4 +110,71 @@ func (p *Project) GetTotalTokenUsage() (input int, output int) {
 		output += sessionOutput
 	}
 	return
-}
\ No newline at end of file
+}
+
+// GetUsageTotals returns the summed token usage across all sessions
+func (p *Project) GetUsageTotals() Usage {
+	var total Usage
+	for _, session := range p.Sessions {
+		sessionTotal := session.GetUsageTotals()
+		total.Add(&sessionTotal)
+	}
+	return total
+}
+
+// GetThinkingTokens returns the estimated thinking tokens across all sessions
+func (p *Project) GetThinkingTokens() int {
+	total := 0
+	for _, session := range p.Sessions {
+		total += session.GetThinkingTokens()
+	}
+	return total
+}
+
+// GetToolUsageStats counts the tool calls across all sessions by tool name
+func (p *Project) GetToolUsageStats() map[string]int {
+	stats := make(map[string]int)
+	for _, session := range p.Sessions {
+		for name, count := range session.GetToolUsageStats() {
+			stats[name] += count
+		}
+	}
+	return stats
+}
+
+// GetEstimatedCost estimates the cost in USD across all sessions
+func (p *Project) GetEstimatedCost() float64 {
+	cost := 0.0
+	for _, session := range p.Sessions {
+		cost += session.GetEstimatedCost()
+	}
+	return cost
+}
+
+// ResolvePath returns the best known filesystem path of the project: the
+// working directory recorded in its messages, falling back to the decoded
+// Path, which is ambiguous for directory names containing dashes
+func (p *Project) ResolvePath() string {
+	for _, session := range p.Sessions {
+		for _, msg := range session.Messages {
+			if msg.CWD != "" {
+				return msg.CWD
+			}
+		}
+	}
+	return p.Path
+}
+
+// CheckPathExists checks whether the project directory still exists on disk,
+// records the result in Exists and returns it
+func (p *Project) CheckPathExists() bool {
+	info, err := os.Stat(p.ResolvePath())
+	exists := err == nil && info.IsDir()
+	p.Exists = &exists
+	return exists
+}
+
+// IsStale returns true if CheckPathExists found the project directory missing
+func (p *Project) IsStale() bool {
+	return p.Exists != nil && !*p.Exists
+}
@@ -121,4 +121,44 @@ func TestProjectWithTodos(t *testing.T) {
 	if len(project.TodoLists) != 2 {
 		t.Errorf("TodoLists length = %v, want 2", len(project.TodoLists))
 	}
-}
\ No newline at end of file
+	
+	stats := project.GetAggregateTodoStats()
+	if stats.Lists != 2 || stats.Total != 2 || stats.CompletionRate != 50 {
+		t.Errorf("GetAggregateTodoStats() = %+v, want 2 lists, 2 todos, 50%% complete", stats)
+	}
+	if stats.ByStatus[TodoStatusPending] != 1 || stats.ByPriority[TodoPriorityHigh] != 1 || stats.ByPriority[TodoPriorityLow] != 0 {
+		t.Errorf("GetAggregateTodoStats() counts = %v, %v", stats.ByStatus, stats.ByPriority)
+	}
+	
+	// No todos: zero counts
+	stats = NewProject("-Users-test-empty").GetAggregateTodoStats()
+	if stats.Lists != 0 || stats.Total != 0 || stats.CompletionRate != 0 || stats.ByStatus == nil || len(stats.ByStatus) != 0 {
+		t.Errorf("GetAggregateTodoStats() for no todos = %+v, want zeros", stats)
+	}
+}
+func TestProjectCheckPathExists(t *testing.T) {
+	project := NewProject("-nonexistent-cc-export-test-project")
+	if project.IsStale() {
+		t.Error("Project should not be stale before checking")
+	}
+
+	if project.CheckPathExists() {
+		t.Error("CheckPathExists() = true for missing path")
+	}
+	if !project.IsStale() || project.Exists == nil || *project.Exists {
+		t.Errorf("Project with missing path should be stale, Exists = %v", project.Exists)
+	}
+
+	// The working directory recorded in messages takes precedence over the
+	// decoded path
+	session := &Session{ID: "session1"}
+	session.AddMessage(&Message{UUID: "msg1", CWD: t.TempDir(), Timestamp: time.Now()})
+	project.AddSession(session)
+
+	if !project.CheckPathExists() {
+		t.Errorf("CheckPathExists() = false for existing cwd %s", project.ResolvePath())
+	}
+	if project.IsStale() {
+		t.Error("Project with existing cwd should not be stale")
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import "fmt"
+
+// CheckUsage looks for token usage that is inconsistent with the session
+// content, which usually means a malformed or truncated log, and returns a
+// description of each problem found
+func (s *Session) CheckUsage() []string {
+	var warnings []string
+	var total Usage
+
+	for _, msg := range s.Messages {
+		assistantMsg, ok := msg.Content.(*AssistantMessage)
+		if !ok || assistantMsg.Usage == nil {
+			continue
+		}
+		usage := assistantMsg.Usage
+		total.Add(usage)
+
+		if usage.InputTokens < 0 || usage.OutputTokens < 0 ||
+			usage.CacheCreationInputTokens < 0 || usage.CacheReadInputTokens < 0 {
+			warnings = append(warnings, fmt.Sprintf("message %s has negative token counts", msg.UUID))
+		}
+		if usage.OutputTokens > 0 && len(assistantMsg.Content) == 0 {
+			warnings = append(warnings, fmt.Sprintf("message %s has %d output tokens but no content", msg.UUID, usage.OutputTokens))
+		}
+	}
+
+	// Cache reads need a prompt; input excludes cached tokens, so only a
+	// session without any input or cache writes at all is suspicious
+	if total.CacheReadInputTokens > 0 && total.InputTokens+total.CacheCreationInputTokens == 0 {
+		warnings = append(warnings, fmt.Sprintf("%d cache read tokens exceed input tokens (0)", total.CacheReadInputTokens))
+	}
+
+	return warnings
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSessionCheckUsage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "consistent",
+			message: `{"role":"assistant","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":5000}}`,
+		},
+		{
+			name:    "output without content",
+			message: `{"role":"assistant","content":[],"usage":{"input_tokens":10,"output_tokens":50}}`,
+			want:    "50 output tokens but no content",
+		},
+		{
+			name:    "cache read without input",
+			message: `{"role":"assistant","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":0,"output_tokens":5,"cache_read_input_tokens":300}}`,
+			want:    "300 cache read tokens exceed input tokens",
+		},
+		{
+			name:    "negative tokens",
+			message: `{"role":"assistant","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":-1,"output_tokens":5}}`,
+			want:    "negative token counts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Message{UUID: "msg1", Type: MessageTypeAssistant, Message: json.RawMessage(tt.message)}
+			msg.ParseContent()
+			session := &Session{ID: "session1"}
+			session.AddMessage(msg)
+
+			warnings := session.CheckUsage()
+			if tt.want == "" {
+				if len(warnings) != 0 {
+					t.Errorf("CheckUsage() = %v, want no warnings", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], tt.want) {
+				t.Errorf("CheckUsage() = %v, want warning containing %q", warnings, tt.want)
+			}
+		})
+	}
+}
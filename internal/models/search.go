@@ -0,0 +1,71 @@
+package models
+
+import "strings"
+
+// MatchesText checks if the user or assistant text of the message contains
+// query, ignoring case. Tool calls and results are not searched.
+func (m *Message) MatchesText(query string) bool {
+	if query == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(messageText(m)), strings.ToLower(query))
+}
+
+// MatchesThinking checks if the thinking of an assistant message contains
+// query, ignoring case
+func (m *Message) MatchesThinking(query string) bool {
+	assistantMsg, ok := m.Content.(*AssistantMessage)
+	if !ok || query == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(assistantMsg.GetThinking()), strings.ToLower(query))
+}
+
+// FindMatches returns the indexes of the messages whose text contains query,
+// ignoring case
+func (s *Session) FindMatches(query string) []int {
+	var matches []int
+	for i, msg := range s.Messages {
+		if msg.MatchesText(query) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// ContainsText checks if the text of any message contains query, ignoring
+// case (see MatchesText)
+func (s *Session) ContainsText(query string) bool {
+	for _, msg := range s.Messages {
+		if msg.MatchesText(query) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsThinking checks if the thinking of any assistant message contains
+// query, ignoring case
+func (s *Session) ContainsThinking(query string) bool {
+	for _, msg := range s.Messages {
+		if msg.MatchesThinking(query) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrimToMatches keeps only the messages whose text, or thinking if
+// includeThinking is set, contains query and returns the number of messages
+// dropped. The start and end time of the session are kept.
+func (s *Session) TrimToMatches(query string, includeThinking bool) int {
+	kept := make([]*Message, 0, len(s.Messages))
+	for _, msg := range s.Messages {
+		if msg.MatchesText(query) || (includeThinking && msg.MatchesThinking(query)) {
+			kept = append(kept, msg)
+		}
+	}
+	dropped := len(s.Messages) - len(kept)
+	s.Messages = kept
+	return dropped
+}
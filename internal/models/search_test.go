@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSessionFindMatches(t *testing.T) {
+	session := &Session{ID: "search"}
+	for _, m := range []struct {
+		msgType MessageType
+		raw     string
+	}{
+		{MessageTypeUser, `{"role":"user","content":"Why is the Redis cache slow?"}`},
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"text","text":"Let me check the config."}]}`},
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"redis-cli info"}}]}`},
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"text","text":"The REDIS maxmemory is too low."}]}`},
+	} {
+		msg := &Message{Type: m.msgType, UserType: "external", Message: json.RawMessage(m.raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	matches := session.FindMatches("redis")
+	if len(matches) != 2 || matches[0] != 0 || matches[1] != 3 {
+		t.Errorf("FindMatches() = %v, want [0 3]", matches)
+	}
+
+	if matches := session.FindMatches(""); len(matches) != 0 {
+		t.Errorf("FindMatches(\"\") = %v, want none", matches)
+	}
+}
+
+func TestSessionContainsText(t *testing.T) {
+	session := &Session{ID: "search"}
+	for _, m := range []struct {
+		msgType MessageType
+		raw     string
+	}{
+		{MessageTypeUser, `{"role":"user","content":"Fix the login page"}`},
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"thinking","thinking":"The session cookie is probably missing."},{"type":"text","text":"Looking at the handler."}]}`},
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"text","text":"The Login handler now sets the cookie."}]}`},
+	} {
+		msg := &Message{Type: m.msgType, UserType: "external", Message: json.RawMessage(m.raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	if !session.ContainsText("LOGIN") {
+		t.Error("ContainsText(LOGIN) = false, want true")
+	}
+	if session.ContainsText("missing") {
+		t.Error("ContainsText(missing) = true, want false: thinking is not text")
+	}
+	if !session.ContainsThinking("missing") {
+		t.Error("ContainsThinking(missing) = false, want true")
+	}
+
+	trimmed := *session
+	if dropped := trimmed.TrimToMatches("cookie", false); dropped != 2 || trimmed.Messages[0] != session.Messages[2] {
+		t.Errorf("TrimToMatches(cookie, false) dropped %d, kept %v", dropped, trimmed.Messages)
+	}
+	trimmed = *session
+	if dropped := trimmed.TrimToMatches("cookie", true); dropped != 1 || len(trimmed.Messages) != 2 {
+		t.Errorf("TrimToMatches(cookie, true) dropped %d, want 1", dropped)
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -11,13 +15,20 @@ type Session struct {
 	StartTime time.Time  `json:"start_time"`
 	EndTime   time.Time  `json:"end_time"`
 	Messages  []*Message `json:"messages"`
+	
+	// SourceFile is the JSONL file the session was read from, if any
+	SourceFile string `json:"-"`
 }
 
-// AddMessage adds a message to the session and updates timestamps
+// AddMessage adds a message to the session and updates timestamps.
+// Messages without a timestamp, such as summaries, leave them unchanged.
 func (s *Session) AddMessage(msg *Message) {
 	s.Messages = append(s.Messages, msg)
 	
 	// Update session timestamps
+	if msg.Timestamp.IsZero() {
+		return
+	}
 	if s.StartTime.IsZero() || msg.Timestamp.Before(s.StartTime) {
 		s.StartTime = msg.Timestamp
 	}
@@ -61,7 +72,9 @@ func (s *Session) GetDuration() time.Duration {
 	return s.EndTime.Sub(s.StartTime)
 }
 
-// GetTokenUsage calculates total token usage for the session
+// GetTokenUsage calculates total token usage for the session. Input includes
+// cache reads; GetUsageTotals reports cache reads and cache creation
+// separately.
 func (s *Session) GetTokenUsage() (input int, output int) {
 	for _, msg := range s.Messages {
 		if msg.Type == MessageTypeAssistant && msg.Content != nil {
@@ -72,4 +85,267 @@ func (s *Session) GetTokenUsage() (input int, output int) {
 		}
 	}
 	return
-}
\ No newline at end of file
+}
+
+// DefaultTitleLength is the maximum length in characters of a generated
+// session title
+const DefaultTitleLength = 80
+
+// GetTitle returns a short human-readable title for the session, taken from
+// the first line of the first user prompt. Falls back to the session ID.
+func (s *Session) GetTitle() string {
+	return s.GetPreview(DefaultTitleLength)
+}
+
+// GetPreview is like GetTitle but truncates the title to maxLength
+// characters (0 = no limit)
+func (s *Session) GetPreview(maxLength int) string {
+	for _, msg := range s.Messages {
+		userMsg, ok := msg.Content.(*UserMessage)
+		if !ok {
+			continue
+		}
+		title := strings.TrimSpace(userMsg.Content)
+		if idx := strings.IndexByte(title, '\n'); idx >= 0 {
+			title = strings.TrimSpace(title[:idx])
+		}
+		if title == "" {
+			continue
+		}
+		return Truncate(title, maxLength)
+	}
+	return s.ID
+}
+
+// GetMessageOffset returns how long after the start of the session a message
+// was sent, or 0 if either time is unknown
+func (s *Session) GetMessageOffset(msg *Message) time.Duration {
+	if s.StartTime.IsZero() || msg.Timestamp.IsZero() {
+		return 0
+	}
+	return msg.Timestamp.Sub(s.StartTime)
+}
+
+// DefaultReadingWPM is the reading speed in words per minute used to
+// estimate reading time
+const DefaultReadingWPM = 200
+
+// GetWordCount returns the number of words in the user prompts and in the
+// assistant text of the session. Tool calls, tool results and thinking are
+// not counted.
+func (s *Session) GetWordCount() (user int, assistant int) {
+	for _, msg := range s.Messages {
+		if msg.IsDiagnostic() {
+			continue
+		}
+		switch msg.Content.(type) {
+		case *UserMessage:
+			user += len(strings.Fields(messageText(msg)))
+		case *AssistantMessage:
+			assistant += len(strings.Fields(messageText(msg)))
+		}
+	}
+	return user, assistant
+}
+
+// GetThinkingWordCount returns the number of words in the thinking of the
+// assistant messages of the session
+func (s *Session) GetThinkingWordCount() int {
+	count := 0
+	for _, msg := range s.Messages {
+		if assistantMsg, ok := msg.Content.(*AssistantMessage); ok && !msg.IsDiagnostic() {
+			count += len(strings.Fields(assistantMsg.GetThinking()))
+		}
+	}
+	return count
+}
+
+// GetEstimatedReadingTime estimates how long the session takes to read at
+// DefaultReadingWPM
+func (s *Session) GetEstimatedReadingTime() time.Duration {
+	return s.GetReadingTime(DefaultReadingWPM)
+}
+
+// GetReadingTime is like GetEstimatedReadingTime but reads at wpm words per
+// minute (0 = DefaultReadingWPM)
+func (s *Session) GetReadingTime(wpm int) time.Duration {
+	if wpm <= 0 {
+		wpm = DefaultReadingWPM
+	}
+	user, assistant := s.GetWordCount()
+	return time.Duration(user+assistant) * time.Minute / time.Duration(wpm)
+}
+
+// GetUsageTotals returns the summed token usage of all assistant messages,
+// keeping cache reads and cache writes separate from fresh input tokens
+func (s *Session) GetUsageTotals() Usage {
+	var total Usage
+	for _, msg := range s.Messages {
+		if assistantMsg, ok := msg.Content.(*AssistantMessage); ok && assistantMsg.Usage != nil {
+			total.Add(assistantMsg.Usage)
+		}
+	}
+	return total
+}
+
+// GetThinkingTokens returns the estimated output tokens spent on thinking
+// across all assistant messages (see EstimateThinkingTokens)
+func (s *Session) GetThinkingTokens() int {
+	total := 0
+	for _, msg := range s.Messages {
+		if assistantMsg, ok := msg.Content.(*AssistantMessage); ok {
+			total += assistantMsg.EstimateThinkingTokens()
+		}
+	}
+	return total
+}
+
+// GetEstimatedCost estimates the cost in USD of the session, pricing each
+// assistant message by its model
+func (s *Session) GetEstimatedCost() float64 {
+	cost := 0.0
+	for _, msg := range s.Messages {
+		if assistantMsg, ok := msg.Content.(*AssistantMessage); ok && assistantMsg.Usage != nil {
+			cost += assistantMsg.Usage.EstimateCost(assistantMsg.Model)
+		}
+	}
+	return cost
+}
+
+// GetToolCallNumbers numbers the tool calls of the session in order, starting
+// at 1, keyed by tool_use ID. Tool results refer to their call by this ID, so
+// a call and its result can be labeled with the same number.
+func (s *Session) GetToolCallNumbers() map[string]int {
+	numbers := make(map[string]int)
+	for _, msg := range s.Messages {
+		assistantMsg, ok := msg.Content.(*AssistantMessage)
+		if !ok {
+			continue
+		}
+		for _, content := range assistantMsg.Content {
+			if content.Type == "tool_use" && content.ID != "" {
+				if _, seen := numbers[content.ID]; !seen {
+					numbers[content.ID] = len(numbers) + 1
+				}
+			}
+		}
+	}
+	return numbers
+}
+
+// GetToolUsageStats counts the tool calls of the session by tool name
+func (s *Session) GetToolUsageStats() map[string]int {
+	stats := make(map[string]int)
+	for _, msg := range s.Messages {
+		assistantMsg, ok := msg.Content.(*AssistantMessage)
+		if !ok {
+			continue
+		}
+		for _, content := range assistantMsg.Content {
+			if content.Type == "tool_use" {
+				stats[content.Name]++
+			}
+		}
+	}
+	return stats
+}
+
+// GetModels returns the distinct models of the session's assistant messages
+// in order of first use
+func (s *Session) GetModels() []string {
+	var models []string
+	seen := make(map[string]bool)
+	for _, msg := range s.Messages {
+		assistantMsg, ok := msg.Content.(*AssistantMessage)
+		if !ok || assistantMsg.Model == "" || seen[assistantMsg.Model] {
+			continue
+		}
+		seen[assistantMsg.Model] = true
+		models = append(models, assistantMsg.Model)
+	}
+	return models
+}
+
+// GetGitBranches returns the distinct git branches the session's messages
+// were sent on, in order of first use
+func (s *Session) GetGitBranches() []string {
+	var branches []string
+	seen := make(map[string]bool)
+	for _, msg := range s.Messages {
+		if msg.GitBranch == "" || seen[msg.GitBranch] {
+			continue
+		}
+		seen[msg.GitBranch] = true
+		branches = append(branches, msg.GitBranch)
+	}
+	return branches
+}
+
+// UsesModel checks if an assistant message of the session used a model whose
+// name or family (see NormalizeModel) contains one of names, ignoring case,
+// so "opus" matches "claude-opus-4-20250514" and "claude-3.5-sonnet" matches
+// "claude-3-5-sonnet-20241022"
+func (s *Session) UsesModel(names []string) bool {
+	for _, model := range s.GetModels() {
+		family := NormalizeModel(model)
+		model = strings.ToLower(model)
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if strings.Contains(model, name) || strings.Contains(family, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contentHashLength is the number of hex characters in a content hash
+const contentHashLength = 16
+
+// GetContentHash returns a short fingerprint of the session derived from its
+// message UUIDs in order. Sessions with the same ID but different messages,
+// e.g. from different machines, get different hashes.
+func (s *Session) GetContentHash() string {
+	h := sha256.New()
+	for _, msg := range s.Messages {
+		h.Write([]byte(msg.UUID))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:contentHashLength]
+}
+
+// FirstMessages returns the n earliest messages by timestamp, in their
+// original order, or all of them if there are no more than n. A message
+// without a timestamp counts as sent with the message before it, and
+// messages sent at the same time are taken in their original order.
+func FirstMessages(messages []*Message, n int) []*Message {
+	if n < 0 || len(messages) <= n {
+		return messages
+	}
+
+	type entry struct {
+		index int
+		time  time.Time
+	}
+	entries := make([]entry, len(messages))
+	var last time.Time
+	for i, msg := range messages {
+		if !msg.Timestamp.IsZero() {
+			last = msg.Timestamp
+		}
+		entries[i] = entry{i, last}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].time.Before(entries[j].time) })
+
+	keep := make([]bool, len(messages))
+	for _, e := range entries[:n] {
+		keep[e.index] = true
+	}
+	first := make([]*Message, 0, n)
+	for i, msg := range messages {
+		if keep[i] {
+			first = append(first, msg)
+		}
+	}
+	return first
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -99,4 +100,194 @@ func TestEmptySession(t *testing.T) {
 	if inputTokens != 0 || outputTokens != 0 {
 		t.Errorf("GetTokenUsage() = (%v, %v), want (0, 0)", inputTokens, outputTokens)
 	}
-}
\ No newline at end of file
+}
+
+func TestSessionGetTitle(t *testing.T) {
+	session := &Session{ID: "title-session"}
+
+	if title := session.GetTitle(); title != "title-session" {
+		t.Errorf("GetTitle() for empty session = %v, want title-session", title)
+	}
+
+	msg := &Message{
+		Type:     MessageTypeUser,
+		UserType: "external",
+		Message:  json.RawMessage(`{"role":"user","content":"Fix the login bug\nIt fails on empty passwords"}`),
+	}
+	msg.ParseContent()
+	session.AddMessage(msg)
+
+	if title := session.GetTitle(); title != "Fix the login bug" {
+		t.Errorf("GetTitle() = %v, want Fix the login bug", title)
+	}
+}
+
+func TestSessionGetToolCallNumbers(t *testing.T) {
+	session := &Session{ID: "tools"}
+	for _, raw := range []string{
+		`{"role":"assistant","content":[{"type":"text","text":"Reading"},{"type":"tool_use","id":"toolu_1","name":"Read"}]}`,
+		`{"role":"assistant","content":[{"type":"tool_use","id":"toolu_2","name":"Edit"},{"type":"tool_use","id":"toolu_3","name":"Bash"}]}`,
+	} {
+		msg := &Message{Type: MessageTypeAssistant, Message: json.RawMessage(raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	numbers := session.GetToolCallNumbers()
+	for id, want := range map[string]int{"toolu_1": 1, "toolu_2": 2, "toolu_3": 3} {
+		if numbers[id] != want {
+			t.Errorf("GetToolCallNumbers()[%s] = %d, want %d", id, numbers[id], want)
+		}
+	}
+}
+
+func TestSessionGetToolUsageStats(t *testing.T) {
+	session := &Session{ID: "tools"}
+	for _, raw := range []string{
+		`{"role":"assistant","content":[{"type":"text","text":"Reading"},{"type":"tool_use","id":"toolu_1","name":"Read"}]}`,
+		`{"role":"assistant","content":[{"type":"tool_use","id":"toolu_2","name":"Bash"},{"type":"tool_use","id":"toolu_3","name":"Bash"}]}`,
+	} {
+		msg := &Message{Type: MessageTypeAssistant, Message: json.RawMessage(raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	stats := session.GetToolUsageStats()
+	if len(stats) != 2 || stats["Bash"] != 2 || stats["Read"] != 1 {
+		t.Errorf("GetToolUsageStats() = %v, want map[Bash:2 Read:1]", stats)
+	}
+
+	project := NewProject("-Users-test-tools")
+	project.AddSession(session)
+	project.AddSession(session)
+	if stats := project.GetToolUsageStats(); stats["Bash"] != 4 || stats["Read"] != 2 {
+		t.Errorf("Project.GetToolUsageStats() = %v, want map[Bash:4 Read:2]", stats)
+	}
+}
+
+func TestSessionUsesModel(t *testing.T) {
+	session := &Session{ID: "models"}
+	for _, raw := range []string{
+		`{"role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"Hi"}]}`,
+		`{"role":"assistant","model":"claude-opus-4-20250514","content":[{"type":"text","text":"Hi"}]}`,
+		`{"role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"Hi"}]}`,
+		`{"role":"assistant","model":"claude-opus-4-1-20250805","content":[{"type":"text","text":"Hi"}]}`,
+	} {
+		msg := &Message{Type: MessageTypeAssistant, Message: json.RawMessage(raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	if models := session.GetModels(); strings.Join(models, ",") != "claude-sonnet-4-20250514,claude-opus-4-20250514,claude-opus-4-1-20250805" {
+		t.Errorf("GetModels() = %v", models)
+	}
+
+	tests := []struct {
+		names []string
+		want  bool
+	}{
+		{[]string{"Opus"}, true},
+		{[]string{"haiku", "claude-sonnet-4-20250514"}, true},
+		{[]string{"haiku"}, false},
+		{[]string{"claude-opus-4.1"}, true},
+		{[]string{"claude-opus-4.5"}, false},
+	}
+	for _, tt := range tests {
+		if got := session.UsesModel(tt.names); got != tt.want {
+			t.Errorf("UsesModel(%v) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestSessionGetReadingTime(t *testing.T) {
+	session := &Session{ID: "reading"}
+	words := strings.TrimSpace(strings.Repeat("word ", 300))
+	for _, raw := range []struct {
+		msgType MessageType
+		message string
+	}{
+		{MessageTypeUser, `{"role":"user","content":"` + words + `"}`},
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"text","text":"` + words + `"},{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file":"a b c"}},{"type":"thinking","thinking":"not counted"}]}`},
+		{MessageTypeUser, `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"not counted either"}]}`},
+	} {
+		msg := &Message{Type: raw.msgType, UserType: "external", Message: json.RawMessage(raw.message)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	if user, assistant := session.GetWordCount(); user != 300 || assistant != 300 {
+		t.Errorf("GetWordCount() = (%d, %d), want (300, 300)", user, assistant)
+	}
+	if count := session.GetThinkingWordCount(); count != 2 {
+		t.Errorf("GetThinkingWordCount() = %d, want 2", count)
+	}
+	if d := session.GetReadingTime(300); d != 2*time.Minute {
+		t.Errorf("GetReadingTime(300) = %v, want 2m", d)
+	}
+	if d := session.GetEstimatedReadingTime(); d != 3*time.Minute {
+		t.Errorf("GetEstimatedReadingTime() = %v, want 3m", d)
+	}
+}
+
+func TestFirstMessages(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	messages := []*Message{
+		{UUID: "b", Timestamp: base.Add(2 * time.Minute)},
+		{UUID: "summary"},
+		{UUID: "a", Timestamp: base},
+		{UUID: "c", Timestamp: base.Add(2 * time.Minute)},
+		{UUID: "d", Timestamp: base.Add(3 * time.Minute)},
+	}
+	uuids := func(messages []*Message) string {
+		var ids []string
+		for _, msg := range messages {
+			ids = append(ids, msg.UUID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, ""},
+		{1, "a"},
+		// The summary counts as sent with b, and b comes before c
+		{2, "b,a"},
+		{3, "b,summary,a"},
+		{4, "b,summary,a,c"},
+		{5, "b,summary,a,c,d"},
+		{10, "b,summary,a,c,d"},
+	}
+	for _, tt := range tests {
+		if got := uuids(FirstMessages(messages, tt.n)); got != tt.want {
+			t.Errorf("FirstMessages(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestSessionGetGitBranches(t *testing.T) {
+	var msgs []*Message
+	for _, line := range []string{
+		`{"uuid":"1","type":"user","cwd":"/app","message":{"role":"user","content":"Hi"}}`,
+		`{"uuid":"2","type":"user","gitBranch":"main","message":{"role":"user","content":"Hi"}}`,
+		`{"uuid":"3","type":"user","gitBranch":"feature/x","message":{"role":"user","content":"Hi"}}`,
+		`{"uuid":"4","type":"user","gitBranch":"main","message":{"role":"user","content":"Hi"}}`,
+	} {
+		var msg Message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("Failed to parse %s: %v", line, err)
+		}
+		msgs = append(msgs, &msg)
+	}
+
+	session := &Session{Messages: msgs}
+	if branches := session.GetGitBranches(); strings.Join(branches, ",") != "main,feature/x" {
+		t.Errorf("GetGitBranches() = %v, want [main feature/x]", branches)
+	}
+
+	session = &Session{Messages: msgs[:1]}
+	if branches := session.GetGitBranches(); branches != nil {
+		t.Errorf("GetGitBranches() = %v, want none without gitBranch", branches)
+	}
+}
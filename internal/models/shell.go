@@ -0,0 +1,56 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ShellSnapshotWindow is how long before a session starts a shell snapshot
+// may be taken and still be associated with it
+const ShellSnapshotWindow = time.Hour
+
+// ShellSnapshot is a snapshot of the user's shell environment (functions,
+// aliases and options) that Claude Code saves when it starts
+type ShellSnapshot struct {
+	Name      string    `json:"name"`            // File name in the shell-snapshots directory
+	Shell     string    `json:"shell,omitempty"` // e.g. bash or zsh
+	CreatedAt time.Time `json:"created_at"`
+	SessionID string    `json:"session_id,omitempty"` // Session the snapshot was taken for
+	Content   string    `json:"content"`
+}
+
+// AddShellSnapshot adds a shell snapshot to the project
+func (p *Project) AddShellSnapshot(snapshot *ShellSnapshot) {
+	p.ShellSnapshots = append(p.ShellSnapshots, snapshot)
+}
+
+// AssignShellSnapshots adds each snapshot, in creation order, to the project
+// of the session it was taken for and sets its SessionID. Since Claude Code
+// takes a snapshot when it starts, that is the first session of any of the
+// projects to start at or after the snapshot, within ShellSnapshotWindow.
+// Snapshots without such a session are dropped. projects must hold every
+// session of the directory, or a snapshot may go to a later session than the
+// one it was taken for.
+func AssignShellSnapshots(projects []*Project, snapshots []*ShellSnapshot) {
+	sorted := append([]*ShellSnapshot(nil), snapshots...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+
+	for _, snapshot := range sorted {
+		var project *Project
+		var session *Session
+		for _, p := range projects {
+			for _, s := range p.Sessions {
+				if s.StartTime.Before(snapshot.CreatedAt) || s.StartTime.Sub(snapshot.CreatedAt) > ShellSnapshotWindow {
+					continue
+				}
+				if session == nil || s.StartTime.Before(session.StartTime) {
+					project, session = p, s
+				}
+			}
+		}
+		if session != nil {
+			snapshot.SessionID = session.ID
+			project.AddShellSnapshot(snapshot)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssignShellSnapshots(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	api := NewProject("-work-api")
+	api.AddSession(&Session{ID: "early", StartTime: base.Add(5 * time.Minute)})
+	api.AddSession(&Session{ID: "late", StartTime: base.Add(3 * time.Hour)})
+	web := NewProject("-work-web")
+	web.AddSession(&Session{ID: "web", StartTime: base.Add(2 * time.Minute)})
+
+	snapshots := []*ShellSnapshot{
+		{Name: "second", CreatedAt: base.Add(150 * time.Minute)},
+		{Name: "first", CreatedAt: base},
+		{Name: "orphan", CreatedAt: base.Add(10 * time.Hour)},
+	}
+	AssignShellSnapshots([]*Project{api, web}, snapshots)
+
+	// Each snapshot goes to the first session starting after it, within
+	// the window
+	if len(web.ShellSnapshots) != 1 || web.ShellSnapshots[0].Name != "first" || web.ShellSnapshots[0].SessionID != "web" {
+		t.Errorf("web snapshots = %+v, want first for session web", web.ShellSnapshots)
+	}
+	if len(api.ShellSnapshots) != 1 || api.ShellSnapshots[0].Name != "second" || api.ShellSnapshots[0].SessionID != "late" {
+		t.Errorf("api snapshots = %+v, want second for session late", api.ShellSnapshots)
+	}
+	if snapshots[2].SessionID != "" {
+		t.Errorf("orphan snapshot assigned to %s", snapshots[2].SessionID)
+	}
+}
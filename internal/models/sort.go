@@ -0,0 +1,96 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// SortKey selects the order of projects and their sessions
+type SortKey string
+
+const (
+	// SortByDate orders by start time, oldest first
+	SortByDate SortKey = "date"
+	// SortByDateDesc orders by start time, newest first
+	SortByDateDesc SortKey = "date-desc"
+	// SortByMessages orders by message count, most first
+	SortByMessages SortKey = "messages"
+	// SortByTokens orders by input and output tokens, most first
+	SortByTokens SortKey = "tokens"
+	// SortByName orders projects by name and sessions by title, alphabetically
+	SortByName SortKey = "name"
+)
+
+// SortProjects sorts projects and the sessions of each project in place by
+// the given key. Ties keep their current order. An empty key sorts by date.
+func SortProjects(projects []*Project, by SortKey) {
+	for _, project := range projects {
+		SortSessions(project.Sessions, by)
+	}
+
+	switch by {
+	case "", SortByDate:
+		sort.SliceStable(projects, func(i, j int) bool {
+			start1, _ := projects[i].GetTimeRange()
+			start2, _ := projects[j].GetTimeRange()
+			return start1.Before(start2)
+		})
+	case SortByDateDesc:
+		sort.SliceStable(projects, func(i, j int) bool {
+			start1, _ := projects[i].GetTimeRange()
+			start2, _ := projects[j].GetTimeRange()
+			return start1.After(start2)
+		})
+	case SortByMessages:
+		sort.SliceStable(projects, func(i, j int) bool {
+			return projects[i].GetTotalMessages() > projects[j].GetTotalMessages()
+		})
+	case SortByTokens:
+		sort.SliceStable(projects, func(i, j int) bool {
+			return projectTokens(projects[i]) > projectTokens(projects[j])
+		})
+	case SortByName:
+		sort.SliceStable(projects, func(i, j int) bool {
+			return strings.ToLower(projects[i].GetProjectName()) < strings.ToLower(projects[j].GetProjectName())
+		})
+	}
+}
+
+// SortSessions sorts sessions in place by the given key, as SortProjects
+// does for the sessions of each project
+func SortSessions(sessions []*Session, by SortKey) {
+	switch by {
+	case "", SortByDate:
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return sessions[i].StartTime.Before(sessions[j].StartTime)
+		})
+	case SortByDateDesc:
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return sessions[i].StartTime.After(sessions[j].StartTime)
+		})
+	case SortByMessages:
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return sessions[i].GetMessageCount() > sessions[j].GetMessageCount()
+		})
+	case SortByTokens:
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return sessionTokens(sessions[i]) > sessionTokens(sessions[j])
+		})
+	case SortByName:
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return strings.ToLower(sessions[i].GetTitle()) < strings.ToLower(sessions[j].GetTitle())
+		})
+	}
+}
+
+// sessionTokens returns the input and output tokens of a session
+func sessionTokens(session *Session) int {
+	input, output := session.GetTokenUsage()
+	return input + output
+}
+
+// projectTokens returns the input and output tokens of a project
+func projectTokens(project *Project) int {
+	input, output := project.GetTotalTokenUsage()
+	return input + output
+}
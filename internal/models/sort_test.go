@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSortProjects(t *testing.T) {
+	newSession := func(id, prompt string, start time.Time, messages, outputTokens int) *Session {
+		session := &Session{ID: id}
+		user := &Message{UUID: id + "-user", Type: MessageTypeUser, UserType: "external", Timestamp: start,
+			Message: json.RawMessage(`{"role":"user","content":"` + prompt + `"}`)}
+		user.ParseContent()
+		session.AddMessage(user)
+		for i := 1; i < messages; i++ {
+			msg := &Message{UUID: fmt.Sprintf("%s-%d", id, i), Type: MessageTypeAssistant, Timestamp: start.Add(time.Duration(i) * time.Minute),
+				Message: json.RawMessage(fmt.Sprintf(`{"role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":0,"output_tokens":%d}}`, outputTokens))}
+			msg.ParseContent()
+			session.AddMessage(msg)
+		}
+		return session
+	}
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 10, 0, 0, 0, time.UTC) }
+
+	alpha := NewProject("-work-alpha")
+	alpha.AddSession(newSession("a2", "Zebra", day(5), 2, 9))
+	alpha.AddSession(newSession("a1", "apple", day(2), 4, 1))
+	beta := NewProject("-work-Beta")
+	beta.AddSession(newSession("b1", "Mango", day(1), 3, 2))
+
+	tests := []struct {
+		by       SortKey
+		projects []string
+		sessions []string // of project alpha
+	}{
+		{"", []string{"-work-Beta", "-work-alpha"}, []string{"a1", "a2"}},
+		{SortByDate, []string{"-work-Beta", "-work-alpha"}, []string{"a1", "a2"}},
+		{SortByDateDesc, []string{"-work-alpha", "-work-Beta"}, []string{"a2", "a1"}},
+		{SortByMessages, []string{"-work-alpha", "-work-Beta"}, []string{"a1", "a2"}},
+		{SortByTokens, []string{"-work-alpha", "-work-Beta"}, []string{"a2", "a1"}},
+		{SortByName, []string{"-work-alpha", "-work-Beta"}, []string{"a1", "a2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.by), func(t *testing.T) {
+			projects := []*Project{alpha, beta}
+			SortProjects(projects, tt.by)
+			for i, id := range tt.projects {
+				if projects[i].ID != id {
+					t.Errorf("Project %d = %s, want %s", i, projects[i].ID, id)
+				}
+			}
+			for i, id := range tt.sessions {
+				if alpha.Sessions[i].ID != id {
+					t.Errorf("Session %d = %s, want %s", i, alpha.Sessions[i].ID, id)
+				}
+			}
+		})
+	}
+}
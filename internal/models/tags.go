@@ -0,0 +1,76 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// UntaggedTag groups projects that match no entry of a tag map
+const UntaggedTag = "untagged"
+
+// TagStats holds aggregate statistics for all projects with a tag
+type TagStats struct {
+	Tag      string  `json:"tag"`
+	Projects int     `json:"projects"`
+	Sessions int     `json:"sessions"`
+	Messages int     `json:"messages"`
+	Usage    Usage   `json:"usage"`
+	Cost     float64 `json:"estimated_cost"`
+}
+
+// GetTags returns the tags of the project from a map of project paths to
+// tags. A path entry also applies to projects below it, so "/work" tags
+// "/work/api". Projects without tags are tagged UntaggedTag.
+func (p *Project) GetTags(tagMap map[string][]string) []string {
+	path := p.ResolvePath()
+	seen := make(map[string]bool)
+	var tags []string
+	for prefix, prefixTags := range tagMap {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		for _, tag := range prefixTags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	if len(tags) == 0 {
+		return []string{UntaggedTag}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// GroupByTag aggregates project statistics by tag, sorted by tag name. A
+// project with several tags counts towards each of them.
+func GroupByTag(projects []*Project, tagMap map[string][]string) []*TagStats {
+	byTag := make(map[string]*TagStats)
+	for _, project := range projects {
+		usage := project.GetUsageTotals()
+		for _, tag := range project.GetTags(tagMap) {
+			stats, ok := byTag[tag]
+			if !ok {
+				stats = &TagStats{Tag: tag}
+				byTag[tag] = stats
+			}
+			stats.Projects++
+			stats.Sessions += project.GetSessionCount()
+			stats.Messages += project.GetTotalMessages()
+			stats.Usage.Add(&usage)
+			stats.Cost += project.GetEstimatedCost()
+		}
+	}
+
+	result := make([]*TagStats, 0, len(byTag))
+	for _, stats := range byTag {
+		result = append(result, stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func createTaggedProject(encodedPath string, inputTokens, outputTokens int) *Project {
+	project := NewProject(encodedPath)
+	session := &Session{ID: encodedPath + "-session"}
+	msg := &Message{
+		UUID:    encodedPath + "-msg",
+		Type:    MessageTypeAssistant,
+		Message: json.RawMessage(fmt.Sprintf(`{"role":"assistant","model":"claude-3-opus","content":[{"type":"text","text":"Done"}],"usage":{"input_tokens":%d,"output_tokens":%d}}`, inputTokens, outputTokens)),
+	}
+	msg.ParseContent()
+	session.AddMessage(msg)
+	project.AddSession(session)
+	return project
+}
+
+func TestGroupByTag(t *testing.T) {
+	projects := []*Project{
+		createTaggedProject("-work-api", 100, 10),
+		createTaggedProject("-work-web", 200, 20),
+		createTaggedProject("-home-blog", 400, 40),
+		createTaggedProject("-tmp-scratch", 800, 80),
+	}
+	tagMap := map[string][]string{
+		"/work":      {"work"},
+		"/work/web":  {"frontend"},
+		"/home/blog": {"personal"},
+	}
+
+	stats := GroupByTag(projects, tagMap)
+
+	want := map[string]struct{ projects, input, output int }{
+		"frontend":  {1, 200, 20},
+		"personal":  {1, 400, 40},
+		UntaggedTag: {1, 800, 80},
+		"work":      {2, 300, 30},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("GroupByTag() returned %d tags, want %d", len(stats), len(want))
+	}
+	for i, s := range stats {
+		if i > 0 && stats[i-1].Tag > s.Tag {
+			t.Errorf("Tags not sorted: %s before %s", stats[i-1].Tag, s.Tag)
+		}
+		w, ok := want[s.Tag]
+		if !ok {
+			t.Errorf("Unexpected tag %s", s.Tag)
+			continue
+		}
+		if s.Projects != w.projects || s.Usage.InputTokens != w.input || s.Usage.OutputTokens != w.output {
+			t.Errorf("Tag %s = (%d projects, %d in, %d out), want (%d, %d, %d)",
+				s.Tag, s.Projects, s.Usage.InputTokens, s.Usage.OutputTokens, w.projects, w.input, w.output)
+		}
+	}
+}
+
+func TestProjectGetTags(t *testing.T) {
+	project := NewProject("-work-apiserver")
+	tags := project.GetTags(map[string][]string{"/work/api": {"api"}})
+	if len(tags) != 1 || tags[0] != UntaggedTag {
+		t.Errorf("GetTags() = %v, prefix should only match whole path components", tags)
+	}
+}
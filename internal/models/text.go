@@ -0,0 +1,68 @@
+package models
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// zeroWidthJoiner joins emoji into a single displayed character
+const zeroWidthJoiner = '\u200d'
+
+// Truncate shortens s to at most maxLength user-perceived characters,
+// appending "..." when anything was cut. It never splits a multibyte rune and
+// keeps combining marks, variation selectors, zero-width-joiner sequences and
+// flag pairs together with the character they belong to.
+func Truncate(s string, maxLength int) string {
+	if maxLength <= 0 {
+		return s
+	}
+
+	count := 0
+	for i := 0; i < len(s); {
+		if count == maxLength {
+			return s[:i] + "..."
+		}
+		i += clusterLength(s[i:])
+		count++
+	}
+	return s
+}
+
+// clusterLength returns the byte length of the character cluster at the
+// start of s. This approximates Unicode grapheme clusters for the cases that
+// matter in prompts: accents, emoji modifiers and sequences, and flags.
+func clusterLength(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+	n := size
+	regional := isRegionalIndicator(r)
+
+	for n < len(s) {
+		next, nextSize := utf8.DecodeRuneInString(s[n:])
+		switch {
+		case isExtender(next):
+			n += nextSize
+		case r == zeroWidthJoiner:
+			n += nextSize
+		case regional && isRegionalIndicator(next):
+			n += nextSize
+			regional = false
+		default:
+			return n
+		}
+		r = next
+	}
+	return n
+}
+
+// isExtender checks if a rune extends the preceding character
+func isExtender(r rune) bool {
+	return r == zeroWidthJoiner ||
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		(r >= 0xFE00 && r <= 0xFE0F) || // variation selectors
+		(r >= 0x1F3FB && r <= 0x1F3FF) // emoji skin tone modifiers
+}
+
+// isRegionalIndicator checks if a rune is half of a flag emoji
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		maxLength int
+		want      string
+	}{
+		{"short", "hello", 10, "hello"},
+		{"exact", "hello", 5, "hello"},
+		{"ascii", "hello world", 5, "hello..."},
+		{"multibyte", "修正登入錯誤並新增測試", 4, "修正登入..."},
+		{"combining accent", "café au lait", 4, "café..."},
+		{"zwj emoji", "👩\u200d💻👩\u200d💻👩\u200d💻", 2, "👩\u200d💻👩\u200d💻..."},
+		{"skin tone", "👍🏽👍🏽", 1, "👍🏽..."},
+		{"flags", "🇯🇵🇹🇼🇺🇸", 2, "🇯🇵🇹🇼..."},
+		{"unlimited", "hello", 0, "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.input, tt.maxLength)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.input, tt.maxLength, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Truncate(%q, %d) produced invalid UTF-8", tt.input, tt.maxLength)
+			}
+		})
+	}
+}
+
+func TestSessionGetPreview(t *testing.T) {
+	prompt := strings.Repeat("日本語", 50)
+	session := &Session{ID: "preview"}
+	session.AddMessage(&Message{Content: &UserMessage{Role: "user", Content: prompt}})
+
+	preview := session.GetPreview(10)
+	if preview != strings.Repeat("日本語", 3)+"日..." {
+		t.Errorf("GetPreview(10) = %q", preview)
+	}
+
+	if title := session.GetTitle(); utf8.RuneCountInString(title) != DefaultTitleLength+3 || !utf8.ValidString(title) {
+		t.Errorf("GetTitle() = %q, want %d valid characters plus ellipsis", title, DefaultTitleLength)
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "sort"
+
 // TodoStatus represents the status of a todo item
 type TodoStatus string
 
@@ -55,6 +57,37 @@ func (tl *TodoList) GetTodosByPriority(priority TodoPriority) []*Todo {
 	return filtered
 }
 
+// todoStatusOrder and todoPriorityOrder rank todos in SortedTodos; other
+// statuses and priorities come last
+var (
+	todoStatusOrder   = map[TodoStatus]int{TodoStatusPending: 0, TodoStatusInProgress: 1, TodoStatusCompleted: 2}
+	todoPriorityOrder = map[TodoPriority]int{TodoPriorityHigh: 0, TodoPriorityMedium: 1, TodoPriorityLow: 2}
+)
+
+// todoRank returns the rank of a value in order, or len(order) if it has none
+func todoRank[K comparable](order map[K]int, value K) int {
+	if rank, ok := order[value]; ok {
+		return rank
+	}
+	return len(order)
+}
+
+// SortedTodos returns the todos ordered by status (pending, in progress,
+// completed) and, within a status, by priority (high, medium, low). Todos of
+// the same status and priority keep their order; the list is not modified.
+func (tl *TodoList) SortedTodos() []*Todo {
+	sorted := make([]*Todo, len(tl.Todos))
+	copy(sorted, tl.Todos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		si, sj := todoRank(todoStatusOrder, sorted[i].Status), todoRank(todoStatusOrder, sorted[j].Status)
+		if si != sj {
+			return si < sj
+		}
+		return todoRank(todoPriorityOrder, sorted[i].Priority) < todoRank(todoPriorityOrder, sorted[j].Priority)
+	})
+	return sorted
+}
+
 // GetCompletionRate returns the percentage of completed todos
 func (tl *TodoList) GetCompletionRate() float64 {
 	if len(tl.Todos) == 0 {
@@ -69,4 +102,13 @@ func (tl *TodoList) GetCompletionRate() float64 {
 	}
 	
 	return float64(completed) / float64(len(tl.Todos)) * 100
-}
\ No newline at end of file
+}
+
+// TodoStats summarizes the todos of several todo lists
+type TodoStats struct {
+	Lists          int
+	Total          int
+	ByStatus       map[TodoStatus]int
+	ByPriority     map[TodoPriority]int
+	CompletionRate float64 // Percentage of all todos that are completed
+}
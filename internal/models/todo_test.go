@@ -61,6 +61,30 @@ func TestTodoListOperations(t *testing.T) {
 	}
 }
 
+func TestTodoListSortedTodos(t *testing.T) {
+	todoList := &TodoList{
+		Todos: []*Todo{
+			{ID: "1", Status: TodoStatusCompleted, Priority: TodoPriorityHigh},
+			{ID: "2", Status: TodoStatusPending, Priority: TodoPriorityLow},
+			{ID: "3", Status: TodoStatusPending, Priority: ""},
+			{ID: "4", Status: TodoStatusInProgress, Priority: TodoPriorityMedium},
+			{ID: "5", Status: TodoStatusPending, Priority: TodoPriorityHigh},
+			{ID: "6", Status: TodoStatusPending, Priority: TodoPriorityLow},
+		},
+	}
+
+	var ids string
+	for _, todo := range todoList.SortedTodos() {
+		ids += todo.ID
+	}
+	if ids != "526341" {
+		t.Errorf("SortedTodos() order = %s, want 526341", ids)
+	}
+	if todoList.Todos[0].ID != "1" {
+		t.Error("SortedTodos() should not reorder the list")
+	}
+}
+
 func TestEmptyTodoList(t *testing.T) {
 	todoList := &TodoList{
 		SessionID: "empty-session",
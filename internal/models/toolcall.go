@@ -0,0 +1,42 @@
+package models
+
+// ToolCall is a tool_use block of an assistant message linked to the
+// tool_result that answered it
+type ToolCall struct {
+	// Use is the tool_use block
+	Use MessageContent
+	// Message is the assistant message making the call
+	Message *Message
+	// Result is the tool result, nil if none was recorded
+	Result *ToolResult
+	// ResultMessage is the user message carrying the result
+	ResultMessage *Message
+}
+
+// LinkToolResults matches the tool_use blocks of the session's assistant
+// messages to the tool results with the same ID, returning the calls keyed
+// by tool_use ID. Calls without an ID are left out, and results are only
+// linked to a call made earlier in the session.
+func (s *Session) LinkToolResults() map[string]*ToolCall {
+	calls := make(map[string]*ToolCall)
+	for _, msg := range s.Messages {
+		switch content := msg.Content.(type) {
+		case *AssistantMessage:
+			for _, block := range content.Content {
+				if block.Type == "tool_use" && block.ID != "" {
+					if _, seen := calls[block.ID]; !seen {
+						calls[block.ID] = &ToolCall{Use: block, Message: msg}
+					}
+				}
+			}
+		case []ToolResult:
+			for i := range content {
+				if call, ok := calls[content[i].ToolUseID]; ok && call.Result == nil {
+					call.Result = &content[i]
+					call.ResultMessage = msg
+				}
+			}
+		}
+	}
+	return calls
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSessionLinkToolResults(t *testing.T) {
+	session := &Session{ID: "tools"}
+	for _, m := range []struct {
+		msgType MessageType
+		raw     string
+	}{
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"main.go"}},{"type":"tool_use","id":"toolu_2","name":"Bash","input":{"command":"ls"}}]}`},
+		{MessageTypeUser, `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"package main"},{"type":"tool_result","tool_use_id":"toolu_9","content":"orphan"}]}`},
+		{MessageTypeUser, `{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":"ls: not found","is_error":true}]}`},
+		{MessageTypeAssistant, `{"role":"assistant","content":[{"type":"tool_use","id":"toolu_3","name":"Grep","input":{}}]}`},
+	} {
+		msg := &Message{Type: m.msgType, UserType: "external", Message: json.RawMessage(m.raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+
+	calls := session.LinkToolResults()
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 tool calls, got %d", len(calls))
+	}
+
+	read := calls["toolu_1"]
+	if read.Use.Name != "Read" || read.Message != session.Messages[0] || read.ResultMessage != session.Messages[1] {
+		t.Errorf("Read call linked wrongly: %+v", read)
+	}
+	if text, _ := read.Result.GetText(); text != "package main" {
+		t.Errorf("Read result = %q, want package main", text)
+	}
+
+	bash := calls["toolu_2"]
+	if bash.Result == nil || !bash.Result.IsError || bash.ResultMessage != session.Messages[2] {
+		t.Errorf("Bash call should be linked to its error result: %+v", bash)
+	}
+
+	if grep := calls["toolu_3"]; grep.Result != nil || grep.ResultMessage != nil {
+		t.Errorf("Grep call has no result, got %+v", grep)
+	}
+	if _, ok := calls["toolu_9"]; ok {
+		t.Error("A result without a call should not be linked")
+	}
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// ToolErrorDetector reports whether a tool result is an error
+type ToolErrorDetector func(result ToolResult) bool
+
+// toolErrorDetectors decide whether a tool result is an error; a result is
+// one if any detector says so. The defaults detect:
+//
+//   - the is_error flag of the tool_result block, set by Claude Code when a
+//     tool fails
+//   - text content wrapped in <tool_use_error>, as Claude Code reports
+//     rejected or invalid tool calls
+//   - a JSON object content with a true "is_error" or "isError" field (as in
+//     MCP tool results) or an "error" field holding a non-empty string or
+//     object
+//
+// The slice is replaced, never modified, so readers may keep using the one
+// they loaded after releasing toolErrorMu.
+var (
+	toolErrorMu        sync.RWMutex
+	toolErrorDetectors = []ToolErrorDetector{
+		isErrorFlagged,
+		isToolUseError,
+		isErrorObject,
+	}
+)
+
+// AddToolErrorDetector adds a detector used by ToolResult.Failed to
+// recognize the errors of other tools. It is safe to call while sessions are
+// scanned or exported concurrently.
+func AddToolErrorDetector(detector ToolErrorDetector) {
+	toolErrorMu.Lock()
+	defer toolErrorMu.Unlock()
+	detectors := make([]ToolErrorDetector, len(toolErrorDetectors), len(toolErrorDetectors)+1)
+	copy(detectors, toolErrorDetectors)
+	toolErrorDetectors = append(detectors, detector)
+}
+
+// Failed reports whether the tool result is an error, as decided by the
+// default detectors and those added with AddToolErrorDetector
+func (r ToolResult) Failed() bool {
+	toolErrorMu.RLock()
+	detectors := toolErrorDetectors
+	toolErrorMu.RUnlock()
+	for _, detect := range detectors {
+		if detect(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasToolErrors checks if any tool result of the session is an error (see
+// ToolResult.Failed)
+func (s *Session) HasToolErrors() bool {
+	for _, msg := range s.Messages {
+		results, ok := msg.Content.([]ToolResult)
+		if !ok {
+			continue
+		}
+		for _, result := range results {
+			if result.Failed() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isErrorFlagged detects results with the is_error flag set
+func isErrorFlagged(result ToolResult) bool {
+	return result.IsError
+}
+
+// isToolUseError detects results whose text is a <tool_use_error>
+func isToolUseError(result ToolResult) bool {
+	text, ok := result.GetText()
+	return ok && strings.HasPrefix(strings.TrimSpace(text), "<tool_use_error>")
+}
+
+// isErrorObject detects results whose content is a JSON object flagged as
+// an error or carrying an error message or object
+func isErrorObject(result ToolResult) bool {
+	var object struct {
+		IsError    bool            `json:"is_error"`
+		IsErrorMCP bool            `json:"isError"`
+		Error      json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(result.Content, &object); err != nil {
+		return false
+	}
+	return object.IsError || object.IsErrorMCP || isErrorValue(object.Error)
+}
+
+// isErrorValue reports whether the value of an "error" field describes an
+// error: a non-empty string or object. Numbers, booleans, null, empty
+// objects and arrays do not.
+func isErrorValue(raw json.RawMessage) bool {
+	var message string
+	if err := json.Unmarshal(raw, &message); err == nil {
+		return strings.TrimSpace(message) != ""
+	}
+	var object map[string]json.RawMessage
+	return json.Unmarshal(raw, &object) == nil && len(object) > 0
+}
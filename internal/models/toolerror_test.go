@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToolResultFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		result ToolResult
+		want   bool
+	}{
+		{"success", ToolResult{Content: json.RawMessage(`"ok"`)}, false},
+		{"is_error flag", ToolResult{Content: json.RawMessage(`"exit status 1"`), IsError: true}, true},
+		{"tool_use_error text", ToolResult{Content: json.RawMessage(`"<tool_use_error>File does not exist.</tool_use_error>"`)}, true},
+		{"tool_use_error block", ToolResult{Content: json.RawMessage(`[{"type":"text","text":"<tool_use_error>Denied</tool_use_error>"}]`)}, true},
+		{"mcp isError", ToolResult{Content: json.RawMessage(`{"isError":true,"content":[]}`)}, true},
+		{"error field", ToolResult{Content: json.RawMessage(`{"error":{"code":404}}`)}, true},
+		{"empty error field", ToolResult{Content: json.RawMessage(`{"error":"","data":1}`)}, false},
+		{"null error field", ToolResult{Content: json.RawMessage(`{"error":null}`)}, false},
+		{"error message", ToolResult{Content: json.RawMessage(`{"error":"not found"}`)}, true},
+		{"zero error field", ToolResult{Content: json.RawMessage(`{"error":0}`)}, false},
+		{"empty error object", ToolResult{Content: json.RawMessage(`{"error":{}}`)}, false},
+		{"empty error array", ToolResult{Content: json.RawMessage(`{"error":[]}`)}, false},
+		{"text mentioning an error", ToolResult{Content: json.RawMessage(`"no error found"`)}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.result.Failed(); got != tt.want {
+			t.Errorf("%s: Failed() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSessionHasToolErrors(t *testing.T) {
+	session := &Session{ID: "s1"}
+	for _, raw := range []string{
+		`{"role":"user","content":"Run the tests"}`,
+		`{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"PASS"}]}`,
+	} {
+		msg := &Message{Type: MessageTypeUser, UserType: "external", Message: json.RawMessage(raw)}
+		msg.ParseContent()
+		session.AddMessage(msg)
+	}
+	if session.HasToolErrors() {
+		t.Error("HasToolErrors() = true for a session without errors")
+	}
+
+	// Detectors can be added for other error shapes
+	defer func(detectors []ToolErrorDetector) { toolErrorDetectors = detectors }(toolErrorDetectors)
+	AddToolErrorDetector(func(result ToolResult) bool {
+		text, _ := result.GetText()
+		return text == "PASS"
+	})
+	if !session.HasToolErrors() {
+		t.Error("HasToolErrors() = false with a detector matching the result")
+	}
+}
@@ -0,0 +1,34 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// LoadAnonymizeRules reads a JSON file mapping text to the placeholder that
+// replaces it, e.g.
+//
+//	{"acme-corp": "<COMPANY>", "/srv/builds": "<BUILDS>"}
+func LoadAnonymizeRules(filePath string) ([]models.AnonymizeRule, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anonymize rules file: %w", err)
+	}
+
+	var replacements map[string]string
+	if err := json.Unmarshal(content, &replacements); err != nil {
+		return nil, fmt.Errorf("failed to parse anonymize rules JSON: %w", err)
+	}
+
+	rules := make([]models.AnonymizeRule, 0, len(replacements))
+	for match, replace := range replacements {
+		if match == "" {
+			return nil, fmt.Errorf("anonymize rule with an empty match in %s", filePath)
+		}
+		rules = append(rules, models.AnonymizeRule{Match: match, Replace: replace})
+	}
+	return rules, nil
+}
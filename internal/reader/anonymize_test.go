@@ -0,0 +1,40 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAnonymizeRules(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	rulesFile := filepath.Join(tmpDir, "rules.json")
+	if err := os.WriteFile(rulesFile, []byte(`{"acme-corp": "<COMPANY>"}`), 0644); err != nil {
+		t.Fatalf("Failed to create rules file: %v", err)
+	}
+	rules, err := LoadAnonymizeRules(rulesFile)
+	if err != nil {
+		t.Fatalf("LoadAnonymizeRules() error = %v", err)
+	}
+	if len(rules) != 1 || rules[0].Match != "acme-corp" || rules[0].Replace != "<COMPANY>" {
+		t.Errorf("LoadAnonymizeRules() = %+v", rules)
+	}
+
+	for name, content := range map[string]string{
+		"list.json":  `["acme-corp"]`,
+		"empty.json": `{"": "<EMPTY>"}`,
+	} {
+		invalidFile := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(invalidFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create rules file: %v", err)
+		}
+		if _, err := LoadAnonymizeRules(invalidFile); err == nil {
+			t.Errorf("LoadAnonymizeRules() should error for %s", content)
+		}
+	}
+
+	if _, err := LoadAnonymizeRules(filepath.Join(tmpDir, "missing.json")); err == nil {
+		t.Error("LoadAnonymizeRules() should error for missing file")
+	}
+}
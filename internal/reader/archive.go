@@ -0,0 +1,242 @@
+package reader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// ProjectScanner scans a source of Claude history for projects
+type ProjectScanner interface {
+	ScanProjects() ([]*models.Project, error)
+	ScanProjectsContext(ctx context.Context) ([]*models.Project, error)
+	ScanProjectsDetailedContext(ctx context.Context) (*ScanResult, error)
+}
+
+// IsArchive reports whether a source path names a gzip-compressed tar archive
+func IsArchive(sourcePath string) bool {
+	lower := strings.ToLower(sourcePath)
+	return strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// NewSourceScanner creates a scanner for a Claude directory, or for an
+// archive of one if the path ends in .tar.gz or .tgz
+func NewSourceScanner(sourcePath string, options *ScanOptions) ProjectScanner {
+	if IsArchive(sourcePath) {
+		return NewArchiveScanner(sourcePath, options)
+	}
+	return NewScanner(sourcePath, options)
+}
+
+// ArchiveScanner scans a Claude directory backed up as a .tar.gz archive
+// without extracting it. The archive may contain the projects and todos
+// directories at its root or inside a single top-level directory such as
+// .claude.
+type ArchiveScanner struct {
+	archivePath string
+	scanner     *Scanner
+}
+
+// archiveSession is a session read from an archive with the name of its file
+type archiveSession struct {
+	name    string
+	session *models.Session
+}
+
+// NewArchiveScanner creates a new scanner for the given archive
+func NewArchiveScanner(archivePath string, options *ScanOptions) *ArchiveScanner {
+	scanner := NewScanner(archivePath, options)
+	scanner.resolvePaths = false
+	return &ArchiveScanner{
+		archivePath: archivePath,
+		scanner:     scanner,
+	}
+}
+
+// ScanProjects scans all projects in the archive. The options apply as for a
+// Scanner.
+func (a *ArchiveScanner) ScanProjects() ([]*models.Project, error) {
+	return a.ScanProjectsContext(context.Background())
+}
+
+// ScanProjectsContext is like ScanProjects but checks ctx before reading each
+// archive entry and returns ctx's error once it is done
+func (a *ArchiveScanner) ScanProjectsContext(ctx context.Context) ([]*models.Project, error) {
+	result, err := a.ScanProjectsDetailedContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return result.Projects, nil
+}
+
+// ScanProjectsDetailed is like ScanProjects but also reports the session
+// files that could not be read, with paths inside the archive
+func (a *ArchiveScanner) ScanProjectsDetailed() (*ScanResult, error) {
+	return a.ScanProjectsDetailedContext(context.Background())
+}
+
+// ScanProjectsDetailedContext is like ScanProjectsDetailed but stops
+// scanning once ctx is done
+func (a *ArchiveScanner) ScanProjectsDetailedContext(ctx context.Context) (*ScanResult, error) {
+	if err := a.scanner.compileProjectRegex(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(a.archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer gz.Close()
+
+	options := a.scanner.options
+	sessionsByProject := make(map[string][]archiveSession)
+	todosBySession := make(map[string][]*models.TodoList)
+	var snapshots []*models.ShellSnapshot
+	foundProjects := false
+	result := &ScanResult{}
+
+	tr := tar.NewReader(gz)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		parts := archiveEntryPath(header.Name)
+		if parts[0] == "projects" {
+			foundProjects = true
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch {
+		case len(parts) == 3 && parts[0] == "projects" && strings.HasSuffix(parts[2], ".jsonl"):
+			if !a.scanner.shouldProcessProject(parts[1]) {
+				continue
+			}
+			session, err := ReadSessionStream(tr, a.scanner.options)
+			if err != nil {
+				if options.Strict && !errors.Is(err, ErrNoMessages) {
+					return nil, fmt.Errorf("failed to read session file %s: %w", header.Name, err)
+				}
+				a.scanner.warnf("failed to read session file %s: %v", header.Name, err)
+				if !errors.Is(err, ErrNoMessages) {
+					result.FileErrors = append(result.FileErrors, FileError{Path: header.Name, Err: err})
+				}
+				continue
+			}
+			session.ProjectID = parts[1]
+			sessionsByProject[parts[1]] = append(sessionsByProject[parts[1]], archiveSession{parts[2], session})
+
+		case len(parts) == 2 && parts[0] == "todos" && strings.HasSuffix(parts[1], ".json") && options.IncludeTodos:
+			// Todo files are named sessionID-agent-agentID.json
+			idParts := strings.Split(parts[1], "-agent-")
+			if len(idParts) != 2 {
+				continue
+			}
+			var todos []*models.Todo
+			if err := json.NewDecoder(tr).Decode(&todos); err != nil {
+				a.scanner.warnf("failed to read todo file %s: %v", header.Name, err)
+				continue
+			}
+			if len(todos) > 0 {
+				todosBySession[idParts[0]] = append(todosBySession[idParts[0]], &models.TodoList{
+					SessionID: idParts[0],
+					AgentID:   strings.TrimSuffix(idParts[1], ".json"),
+					Todos:     todos,
+				})
+			}
+
+		case len(parts) == 2 && parts[0] == "shell-snapshots" && strings.HasSuffix(parts[1], ".sh") && options.IncludeShellSnapshots:
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				a.scanner.warnf("failed to read shell snapshot %s: %v", header.Name, err)
+				continue
+			}
+			snapshots = append(snapshots, newShellSnapshot(parts[1], content, header.ModTime))
+		}
+	}
+
+	if !foundProjects {
+		return nil, fmt.Errorf("projects directory not found in archive: %s", a.archivePath)
+	}
+
+	projectIDs := make([]string, 0, len(sessionsByProject))
+	for projectID := range sessionsByProject {
+		projectIDs = append(projectIDs, projectID)
+	}
+	sort.Strings(projectIDs)
+
+	// Sessions are read while streaming the archive, before the number of
+	// projects is known, so progress is reported as they are assembled
+	sessionCount := 0
+	progress := a.scanner.progressFunc(len(projectIDs))
+	for _, projectID := range projectIDs {
+		progress(projectID)
+		project := a.scanner.NewProject(projectID)
+
+		// Order sessions by file name, as when reading a directory
+		entries := sessionsByProject[projectID]
+		sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+		sessions := make([]*models.Session, len(entries))
+		for i, entry := range entries {
+			sessions[i] = entry.session
+		}
+		if options.MergeSessions {
+			sessions = models.MergeSessions(sessions)
+		}
+
+		limitReached := a.scanner.addSessions(project, sessions, &sessionCount)
+
+		for _, session := range project.Sessions {
+			for _, todoList := range todosBySession[session.ID] {
+				project.AddTodoList(todoList)
+			}
+		}
+
+		if len(project.Sessions) > 0 {
+			result.Projects = append(result.Projects, project)
+			a.scanner.notifyProject(project)
+		}
+		if limitReached {
+			break
+		}
+	}
+
+	result.Projects = a.scanner.finishProjects(result.Projects, snapshots)
+	return result, nil
+}
+
+// archiveEntryPath splits the name of an archive entry into path components
+// relative to the Claude directory, dropping a top-level directory such as
+// .claude if the archive has one
+func archiveEntryPath(name string) []string {
+	parts := strings.Split(strings.Trim(path.Clean(name), "/"), "/")
+	if len(parts) > 1 && parts[0] != "projects" && parts[0] != "todos" && parts[0] != "shell-snapshots" {
+		parts = parts[1:]
+	}
+	return parts
+}
@@ -0,0 +1,190 @@
+package reader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestArchive writes a .tar.gz archive with entries in the given order.
+// Names without content in files become directory entries.
+func writeTestArchive(t *testing.T, archivePath string, files map[string]string, order []string) {
+	t.Helper()
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for _, name := range order {
+		content, isFile := files[name]
+		header := &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}
+		if isFile {
+			header = &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write header for %s: %v", name, err)
+		}
+		if isFile {
+			if _, err := tw.Write([]byte(content)); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar writer: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+}
+
+func TestArchiveScanner(t *testing.T) {
+	sessionContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"uuid":"msg2","parentUuid":"msg1","sessionId":"session1","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"id":"asst1","type":"message","role":"assistant","model":"claude-3","content":[{"type":"text","text":"Hi!"}]}}`
+	otherContent := `{"uuid":"msg3","sessionId":"session2","type":"user","userType":"external","timestamp":"2024-01-02T10:00:00Z","message":{"role":"user","content":"Other"}}`
+	todoContent := `[{"id":"1","content":"Test todo","status":"pending","priority":"high"}]`
+
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"claude directory prefix", ".claude/"},
+		{"dot slash prefix", "./.claude/"},
+		{"no prefix", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.prefix
+			files := map[string]string{
+				p + "projects/-Users-test-project1/session1.jsonl": sessionContent,
+				p + "projects/-Users-test-project2/session2.jsonl": otherContent,
+				p + "todos/session1-agent-agent1.json":             todoContent,
+				p + "CLAUDE.md":                                    "Test configuration",
+			}
+			order := []string{
+				p + "projects/",
+				p + "projects/-Users-test-project2/",
+				p + "projects/-Users-test-project2/session2.jsonl",
+				p + "projects/-Users-test-project1/",
+				p + "projects/-Users-test-project1/session1.jsonl",
+				p + "todos/",
+				p + "todos/session1-agent-agent1.json",
+				p + "CLAUDE.md",
+			}
+			if p != "" {
+				order = append([]string{p}, order...)
+			}
+
+			archivePath := filepath.Join(t.TempDir(), "claude-backup.tar.gz")
+			writeTestArchive(t, archivePath, files, order)
+
+			projects, err := NewArchiveScanner(archivePath, &ScanOptions{IncludeTodos: true}).ScanProjects()
+			if err != nil {
+				t.Fatalf("ScanProjects() error = %v", err)
+			}
+			if len(projects) != 2 {
+				t.Fatalf("Expected 2 projects, got %d", len(projects))
+			}
+
+			project := projects[0]
+			if project.ID != "-Users-test-project1" {
+				t.Errorf("Project ID = %v, want -Users-test-project1", project.ID)
+			}
+			if len(project.Sessions) != 1 {
+				t.Fatalf("Expected 1 session, got %d", len(project.Sessions))
+			}
+			session := project.Sessions[0]
+			if session.ID != "session1" || session.ProjectID != project.ID {
+				t.Errorf("Session = %s in %s, want session1 in %s", session.ID, session.ProjectID, project.ID)
+			}
+			if session.GetMessageCount() != 2 {
+				t.Errorf("Expected 2 messages, got %d", session.GetMessageCount())
+			}
+			if len(project.TodoLists) != 1 || project.TodoLists[0].AgentID != "agent1" {
+				t.Errorf("Expected the todo list of agent1, got %v", project.TodoLists)
+			}
+
+			if len(projects[1].TodoLists) != 0 {
+				t.Errorf("Expected no todo lists for project2, got %d", len(projects[1].TodoLists))
+			}
+		})
+	}
+}
+
+func TestArchiveScannerOptions(t *testing.T) {
+	session := func(id, day string) string {
+		return `{"uuid":"` + id + `","sessionId":"` + id + `","type":"user","userType":"external","timestamp":"2024-01-` + day + `T10:00:00Z","message":{"role":"user","content":"Hello"}}`
+	}
+	files := map[string]string{
+		".claude/projects/-Users-test-app/a.jsonl":     session("a", "01"),
+		".claude/projects/-Users-test-app/b.jsonl":     session("b", "02"),
+		".claude/projects/-Users-test-other/c.jsonl":   session("c", "03"),
+		".claude/projects/-Users-test-app/empty.jsonl": "",
+	}
+	order := []string{
+		".claude/projects/-Users-test-app/b.jsonl",
+		".claude/projects/-Users-test-app/a.jsonl",
+		".claude/projects/-Users-test-app/empty.jsonl",
+		".claude/projects/-Users-test-other/c.jsonl",
+	}
+	archivePath := filepath.Join(t.TempDir(), "backup.tgz")
+	writeTestArchive(t, archivePath, files, order)
+
+	t.Run("project filter", func(t *testing.T) {
+		projects, err := NewArchiveScanner(archivePath, &ScanOptions{ProjectPaths: []string{"app"}}).ScanProjects()
+		if err != nil {
+			t.Fatalf("ScanProjects() error = %v", err)
+		}
+		if len(projects) != 1 || projects[0].ID != "-Users-test-app" {
+			t.Fatalf("Expected only -Users-test-app, got %v", projects)
+		}
+		sessions := projects[0].Sessions
+		if len(sessions) != 2 || sessions[0].ID != "a" || sessions[1].ID != "b" {
+			t.Errorf("Expected sessions a and b in file name order, got %v", sessions)
+		}
+	})
+
+	t.Run("max sessions", func(t *testing.T) {
+		projects, err := NewArchiveScanner(archivePath, &ScanOptions{MaxSessions: 1}).ScanProjects()
+		if err != nil {
+			t.Fatalf("ScanProjects() error = %v", err)
+		}
+		if len(projects) != 1 || len(projects[0].Sessions) != 1 {
+			t.Errorf("Expected 1 project with 1 session, got %v", projects)
+		}
+	})
+
+	t.Run("source scanner", func(t *testing.T) {
+		if _, ok := NewSourceScanner(archivePath, nil).(*ArchiveScanner); !ok {
+			t.Errorf("NewSourceScanner(%s) is not an ArchiveScanner", archivePath)
+		}
+		if _, ok := NewSourceScanner(t.TempDir(), nil).(*Scanner); !ok {
+			t.Errorf("NewSourceScanner(directory) is not a Scanner")
+		}
+	})
+}
+
+func TestArchiveScannerErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	notGzip := filepath.Join(tmpDir, "plain.tar.gz")
+	if err := os.WriteFile(notGzip, []byte("not a gzip file"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if _, err := NewArchiveScanner(notGzip, nil).ScanProjects(); err == nil {
+		t.Error("Expected error for a file that is not gzip-compressed")
+	}
+
+	noProjects := filepath.Join(tmpDir, "empty.tar.gz")
+	writeTestArchive(t, noProjects, map[string]string{".claude/CLAUDE.md": "config"}, []string{".claude/CLAUDE.md"})
+	if _, err := NewArchiveScanner(noProjects, nil).ScanProjects(); err == nil {
+		t.Error("Expected error for an archive without a projects directory")
+	}
+}
@@ -0,0 +1,299 @@
+package reader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// Filter decides whether a session of a project should be included
+type Filter interface {
+	Match(project *models.Project, session *models.Session) bool
+}
+
+// ParseFilter parses a filter expression such as
+//
+//	(project=/work/a OR project=/work/b) AND since=7d
+//
+// Supported terms:
+//   - project=<substring>  project path contains substring, matched like
+//     ScanOptions.ProjectPaths
+//   - since=<duration|date> session ended at or after now minus duration
+//     (e.g. 7d, 12h, 2d3h) or the start of the date (YYYY-MM-DD)
+//   - until=<date>          session ended on or before the end of the date
+//
+// AND binds tighter than OR, so "a OR b AND c" means "a OR (b AND c)".
+// Parentheses group terms. Keywords are case-insensitive and values
+// containing spaces can be double-quoted.
+func ParseFilter(expr string) (Filter, error) {
+	return parseFilterAt(expr, time.Now())
+}
+
+// parseFilterAt parses a filter expression relative to now
+func parseFilterAt(expr string, now time.Time) (Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+
+	p := &filterParser{tokens: tokens, now: now}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter expression", p.tokens[p.pos])
+	}
+	return filter, nil
+}
+
+// tokenizeFilter splits an expression into parentheses and words
+func tokenizeFilter(expr string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+			current.WriteRune(r)
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in filter expression")
+	}
+	flush()
+
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser over filter tokens
+type filterParser struct {
+	tokens []string
+	pos    int
+	now    time.Time
+}
+
+// peekKeyword checks if the next token is the given keyword
+func (p *filterParser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && strings.EqualFold(p.tokens[p.pos], keyword)
+}
+
+// parseOr parses: and (OR and)*
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := orFilter{left}
+	for p.peekKeyword("OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, right)
+	}
+
+	if len(filters) == 1 {
+		return left, nil
+	}
+	return filters, nil
+}
+
+// parseAnd parses: primary (AND primary)*
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := andFilter{left}
+	for p.peekKeyword("AND") {
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, right)
+	}
+
+	if len(filters) == 1 {
+		return left, nil
+	}
+	return filters, nil
+}
+
+// parsePrimary parses: "(" or-expression ")" | term
+func (p *filterParser) parsePrimary() (Filter, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of filter expression")
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+
+	if token == "(" {
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos] != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis in filter expression")
+		}
+		p.pos++
+		return filter, nil
+	}
+
+	return p.parseTerm(token)
+}
+
+// parseTerm parses a key=value term
+func (p *filterParser) parseTerm(token string) (Filter, error) {
+	key, value, ok := strings.Cut(token, "=")
+	if !ok || value == "" {
+		return nil, fmt.Errorf("invalid filter term %q (expected key=value)", token)
+	}
+
+	switch strings.ToLower(key) {
+	case "project":
+		return projectFilter(value), nil
+
+	case "since":
+		if d, err := ParseDuration(value); err == nil {
+			return sinceFilter(p.now.Add(-d)), nil
+		}
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since value %q (use a duration like 7d or a date YYYY-MM-DD)", value)
+		}
+		return sinceFilter(t), nil
+
+	case "until":
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until value %q (use YYYY-MM-DD)", value)
+		}
+		return untilFilter(t.AddDate(0, 0, 1)), nil
+
+	default:
+		return nil, fmt.Errorf("unknown filter key %q", key)
+	}
+}
+
+// ParseDuration parses durations like 7d, 12h, 30m or compound forms like
+// 2d3h. Unlike time.ParseDuration it supports days (d) and weeks (w).
+func ParseDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"w": 7 * 24 * time.Hour,
+		"d": 24 * time.Hour,
+		"h": time.Hour,
+		"m": time.Minute,
+		"s": time.Second,
+	}
+
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(rest) {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		unit, ok := units[string(rest[i])]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration unit %q in %q", rest[i], s)
+		}
+
+		total += time.Duration(n) * unit
+		rest = rest[i+1:]
+	}
+
+	return total, nil
+}
+
+// andFilter matches if all filters match
+type andFilter []Filter
+
+// Match implements Filter
+func (f andFilter) Match(project *models.Project, session *models.Session) bool {
+	for _, filter := range f {
+		if !filter.Match(project, session) {
+			return false
+		}
+	}
+	return true
+}
+
+// orFilter matches if any filter matches
+type orFilter []Filter
+
+// Match implements Filter
+func (f orFilter) Match(project *models.Project, session *models.Session) bool {
+	for _, filter := range f {
+		if filter.Match(project, session) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectFilter matches projects whose path contains the value, comparing
+// encoded paths as ScanOptions.ProjectPaths does
+type projectFilter string
+
+// Match implements Filter
+func (f projectFilter) Match(project *models.Project, session *models.Session) bool {
+	return matchesProjectPath(project.EncodedPath, []string{string(f)})
+}
+
+// sinceFilter matches sessions that ended at or after the time
+type sinceFilter time.Time
+
+// Match implements Filter
+func (f sinceFilter) Match(project *models.Project, session *models.Session) bool {
+	return !session.EndTime.Before(time.Time(f))
+}
+
+// untilFilter matches sessions that ended before the time
+type untilFilter time.Time
+
+// Match implements Filter
+func (f untilFilter) Match(project *models.Project, session *models.Session) bool {
+	return session.EndTime.Before(time.Time(f))
+}
@@ -0,0 +1,113 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+func TestParseFilter(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	workA := models.NewProject("-work-a")
+	workB := models.NewProject("-work-b")
+	home := models.NewProject("-home-c")
+	dashed := models.NewProject("-tmp-my-app")
+
+	recent := &models.Session{EndTime: now.Add(-2 * 24 * time.Hour)}
+	old := &models.Session{EndTime: now.Add(-30 * 24 * time.Hour)}
+
+	tests := []struct {
+		expr    string
+		project *models.Project
+		session *models.Session
+		want    bool
+	}{
+		{"project=/work/a", workA, old, true},
+		{"project=/work/a", workB, old, false},
+		{"(project=/work/a OR project=/work/b) AND since=7d", workB, recent, true},
+		{"(project=/work/a OR project=/work/b) AND since=7d", workB, old, false},
+		{"(project=/work/a OR project=/work/b) AND since=7d", home, recent, false},
+		// AND binds tighter than OR
+		{"project=/home OR project=/work/a AND since=7d", home, old, true},
+		{"project=/home OR project=/work/a AND since=7d", workA, old, false},
+		{"project=/work/a or project=/work/b", workB, old, true},
+		{"since=1d12h", workA, recent, false},
+		{"since=2024-06-01", workA, old, false},
+		{`project="/work/a"`, workA, old, true},
+		// Dashed directory names match whether or not the path decodes
+		{"project=/tmp/my-app", dashed, old, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := parseFilterAt(tt.expr, now)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			if got := filter.Match(tt.project, tt.session); got != tt.want {
+				t.Errorf("Match(%s) = %v, want %v", tt.project.Path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"project",
+		"color=red",
+		"(project=/a",
+		"project=/a project=/b",
+		"project=/a AND",
+		"since=yesterday",
+		`project="/a`,
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) should error", expr)
+		}
+	}
+}
+
+func TestScannerWithFilterExpression(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+
+	sessions := map[string]string{
+		"-work-a": "2024-06-28T10:00:00Z",
+		"-work-b": "2024-05-01T10:00:00Z",
+		"-home-c": "2024-06-29T10:00:00Z",
+	}
+	for proj, timestamp := range sessions {
+		projDir := filepath.Join(projectsDir, proj)
+		if err := os.MkdirAll(projDir, 0755); err != nil {
+			t.Fatalf("Failed to create project dir: %v", err)
+		}
+		content := `{"uuid":"msg1","sessionId":"` + proj + `","type":"user","timestamp":"` + timestamp + `","message":{"role":"user","content":"Hello"}}`
+		if err := os.WriteFile(filepath.Join(projDir, "session.jsonl"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create session file: %v", err)
+		}
+	}
+
+	filter, err := parseFilterAt("(project=/work/a OR project=/work/b) AND since=2024-06-01", time.Now())
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+
+	projects, err := NewScanner(claudeDir, &ScanOptions{Filter: filter}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+
+	if len(projects) != 1 || projects[0].ID != "-work-a" {
+		var ids []string
+		for _, p := range projects {
+			ids = append(ids, p.ID)
+		}
+		t.Errorf("Filtered projects = %v, want [-work-a]", ids)
+	}
+}
@@ -2,7 +2,9 @@ package reader
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -10,9 +12,24 @@ import (
 	"github.com/eternnoir/cc-history-export/internal/models"
 )
 
+// ErrNoMessages is returned by ReadSession for a file without any messages
+var ErrNoMessages = errors.New("no messages found in file")
+
 // JSONLReader reads and parses JSONL conversation files
 type JSONLReader struct {
 	filePath string
+
+	// IncludeDiagnostics keeps diagnostic log entries (lines with a level)
+	// in sessions read by ReadSession; they are skipped by default
+	IncludeDiagnostics bool
+
+	// Strict makes ReadSession fail on the first malformed line or message
+	// content that cannot be parsed instead of warning and skipping it.
+	// Messages with empty content are still only warned about.
+	Strict bool
+
+	// Logger receives the warnings about skipped lines (nil = StderrLogger)
+	Logger Logger
 }
 
 // NewJSONLReader creates a new JSONL reader for the given file
@@ -31,17 +48,26 @@ func (r *JSONLReader) ReadSession() (*models.Session, error) {
 	defer file.Close()
 
 	session := &models.Session{}
-	scanner := bufio.NewScanner(file)
-	
-	// Increase buffer size for large lines
-	const maxCapacity = 1024 * 1024 * 10 // 10MB
-	buf := make([]byte, maxCapacity)
-	scanner.Buffer(buf, maxCapacity)
-
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Bytes()
+	lines := newLineReader(file)
+	logger := loggerOrDefault(r.Logger)
+
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !isLineError(err) {
+				return nil, fmt.Errorf("error reading file: %w", err)
+			}
+			if r.Strict {
+				return nil, err
+			}
+			// Log error but continue processing
+			logger.Warnf("%v", err)
+			continue
+		}
+		lineNum := lines.num
 		
 		// Skip empty lines
 		if len(line) == 0 {
@@ -50,8 +76,17 @@ func (r *JSONLReader) ReadSession() (*models.Session, error) {
 
 		var msg models.Message
 		if err := json.Unmarshal(line, &msg); err != nil {
+			err = lines.parseError(err)
+			if r.Strict {
+				return nil, err
+			}
 			// Log error but continue processing
-			fmt.Fprintf(os.Stderr, "Warning: failed to parse line %d: %v\n", lineNum, err)
+			logger.Warnf("%v", err)
+			continue
+		}
+
+		// Skip debug and other log entries unless requested
+		if msg.IsDiagnostic() && !r.IncludeDiagnostics {
 			continue
 		}
 
@@ -62,18 +97,17 @@ func (r *JSONLReader) ReadSession() (*models.Session, error) {
 
 		// Parse message content
 		if err := msg.ParseContent(); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to parse content for message %s: %v\n", msg.UUID, err)
+			if r.Strict && !errors.Is(err, models.ErrEmptyContent) {
+				return nil, fmt.Errorf("failed to parse content for message %s on line %d: %w", msg.UUID, lineNum, err)
+			}
+			logger.Warnf("failed to parse content for message %s: %v", msg.UUID, err)
 		}
 
 		session.AddMessage(&msg)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
-	}
-
 	if len(session.Messages) == 0 {
-		return nil, fmt.Errorf("no messages found in file")
+		return nil, ErrNoMessages
 	}
 
 	return session, nil
@@ -87,22 +121,72 @@ func (r *JSONLReader) StreamMessages(callback func(*models.Message) error) error
 	}
 	defer file.Close()
 
-	return StreamJSONLMessages(file, callback)
+	return streamJSONL(file, false, loggerOrDefault(r.Logger), callback)
 }
 
-// StreamJSONLMessages streams messages from any io.Reader
+// StreamJSONLMessages streams messages from any io.Reader. Diagnostic log
+// entries are passed to the callback too; check Message.IsDiagnostic.
 func StreamJSONLMessages(reader io.Reader, callback func(*models.Message) error) error {
-	scanner := bufio.NewScanner(reader)
-	
-	// Increase buffer size for large lines
-	const maxCapacity = 1024 * 1024 * 10 // 10MB
-	buf := make([]byte, maxCapacity)
-	scanner.Buffer(buf, maxCapacity)
-
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Bytes()
+	return streamJSONL(reader, false, StderrLogger, callback)
+}
+
+// ReadSessionStream reads a single session's JSONL from reader, skipping
+// diagnostic entries and superseded branches as a Scanner does with options
+// (nil = defaults). Session filters are not applied.
+func ReadSessionStream(reader io.Reader, options *ScanOptions) (*models.Session, error) {
+	if options == nil {
+		options = &ScanOptions{}
+	}
+
+	session := &models.Session{}
+	err := streamJSONL(reader, options.Strict, loggerOrDefault(options.Logger), func(msg *models.Message) error {
+		// Skip debug and other log entries unless requested
+		if msg.IsDiagnostic() && !options.IncludeDiagnostics {
+			return nil
+		}
+		if session.ID == "" && msg.SessionID != "" {
+			session.ID = msg.SessionID
+		}
+		session.AddMessage(msg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(session.Messages) == 0 {
+		return nil, ErrNoMessages
+	}
+
+	// Drop superseded edit/regeneration branches unless requested
+	if session.MarkRegenerated() > 0 && !options.IncludeRegenerated {
+		session.PruneRegenerated()
+	}
+	return session, nil
+}
+
+// streamJSONL streams messages like StreamJSONLMessages. When strict, a
+// malformed line or unparsable content is an error instead of a warning, as
+// in JSONLReader.Strict; warnings go to logger.
+func streamJSONL(reader io.Reader, strict bool, logger Logger, callback func(*models.Message) error) error {
+	lines := newLineReader(reader)
+
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !isLineError(err) {
+				return fmt.Errorf("error reading: %w", err)
+			}
+			if strict {
+				return err
+			}
+			// Log error but continue processing
+			logger.Warnf("%v", err)
+			continue
+		}
+		lineNum := lines.num
 		
 		// Skip empty lines
 		if len(line) == 0 {
@@ -111,14 +195,21 @@ func StreamJSONLMessages(reader io.Reader, callback func(*models.Message) error)
 
 		var msg models.Message
 		if err := json.Unmarshal(line, &msg); err != nil {
+			err = lines.parseError(err)
+			if strict {
+				return err
+			}
 			// Log error but continue processing
-			fmt.Fprintf(os.Stderr, "Warning: failed to parse line %d: %v\n", lineNum, err)
+			logger.Warnf("%v", err)
 			continue
 		}
 
 		// Parse message content
 		if err := msg.ParseContent(); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to parse content for message %s: %v\n", msg.UUID, err)
+			if strict && !errors.Is(err, models.ErrEmptyContent) {
+				return fmt.Errorf("failed to parse content for message %s on line %d: %w", msg.UUID, lineNum, err)
+			}
+			logger.Warnf("failed to parse content for message %s: %v", msg.UUID, err)
 		}
 
 		// Call the callback function
@@ -127,9 +218,89 @@ func StreamJSONLMessages(reader io.Reader, callback func(*models.Message) error)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading: %w", err)
+	return nil
+}
+
+// maxLineSize is the length in bytes of the longest line read from a JSONL
+// file. Longer lines are skipped, so a corrupt file cannot exhaust memory.
+var maxLineSize = 256 * 1024 * 1024
+
+// ErrLineTooLong is returned for a line longer than the reader accepts
+var ErrLineTooLong = errors.New("line too long")
+
+// lineError is a line of a JSONL file that could not be read or parsed
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.line, e.err)
+}
+
+func (e *lineError) Unwrap() error {
+	return e.err
+}
+
+// isLineError reports whether err concerns a single line, which can be
+// skipped, rather than the file
+func isLineError(err error) bool {
+	var lineErr *lineError
+	return errors.As(err, &lineErr)
+}
+
+// lineReader reads the lines of a JSONL stream, however long they are
+type lineReader struct {
+	r *bufio.Reader
+	// num is the number of the line last read, counting from 1
+	num int
+	// complete is set if the line last read ended with a newline
+	complete bool
+}
+
+// newLineReader creates a line reader for r
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// next returns the next line without its line ending, or io.EOF after the
+// last line. A line longer than maxLineSize is read past and reported as a
+// lineError wrapping ErrLineTooLong.
+func (lr *lineReader) next() ([]byte, error) {
+	var line []byte
+	size := 0
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		size += len(chunk)
+		if size <= maxLineSize {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && size == 0 {
+			return nil, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		lr.num++
+		lr.complete = err == nil
+		if size > maxLineSize {
+			return nil, &lineError{lr.num, fmt.Errorf("%w (%d bytes, limit %d)", ErrLineTooLong, size, maxLineSize)}
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		return bytes.TrimSuffix(line, []byte("\r")), nil
 	}
+}
 
-	return nil
+// parseError describes a JSON error in the line last read. A last line
+// without a newline is reported as incomplete, since Claude Code may still
+// be writing it.
+func (lr *lineReader) parseError(err error) error {
+	if !lr.complete {
+		return &lineError{lr.num, fmt.Errorf("incomplete line, the file may still be being written: %w", err)}
+	}
+	return &lineError{lr.num, fmt.Errorf("malformed JSON: %w", err)}
 }
\ No newline at end of file
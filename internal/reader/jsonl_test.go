@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -123,6 +124,36 @@ func TestStreamJSONLMessages(t *testing.T) {
 	}
 }
 
+func TestReadSessionStream(t *testing.T) {
+	testContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"uuid":"log1","sessionId":"session1","type":"system","level":"debug","timestamp":"2024-01-01T10:00:01Z","content":"Loaded 3 MCP servers"}
+{"uuid":"msg2","parentUuid":"msg1","sessionId":"session1","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"Hi there!"}]}}
+`
+
+	session, err := ReadSessionStream(strings.NewReader(testContent), nil)
+	if err != nil {
+		t.Fatalf("ReadSessionStream() error = %v", err)
+	}
+	if session.ID != "session1" || session.GetMessageCount() != 2 {
+		t.Errorf("Session %s has %d messages, want session1 with 2 (debug entries excluded)", session.ID, session.GetMessageCount())
+	}
+
+	session, err = ReadSessionStream(strings.NewReader(testContent), &ScanOptions{IncludeDiagnostics: true})
+	if err != nil {
+		t.Fatalf("ReadSessionStream() error = %v", err)
+	}
+	if session.GetMessageCount() != 3 {
+		t.Errorf("Message count = %v, want 3 with diagnostics included", session.GetMessageCount())
+	}
+
+	if _, err := ReadSessionStream(strings.NewReader("\n"), nil); !errors.Is(err, ErrNoMessages) {
+		t.Errorf("ReadSessionStream() error = %v for empty input, want ErrNoMessages", err)
+	}
+	if _, err := ReadSessionStream(strings.NewReader("not json\n"), &ScanOptions{Strict: true}); err == nil {
+		t.Error("ReadSessionStream() should error for a malformed line in strict mode")
+	}
+}
+
 func TestJSONLReaderErrors(t *testing.T) {
 	// Test non-existent file
 	reader := NewJSONLReader("/non/existent/file.jsonl")
@@ -158,6 +189,13 @@ func TestJSONLReaderErrors(t *testing.T) {
 	if session == nil || len(session.Messages) != 1 {
 		t.Error("Should parse valid lines despite malformed ones")
 	}
+
+	// Strict mode fails on the same file
+	reader = NewJSONLReader(malformedFile)
+	reader.Strict = true
+	if _, err := reader.ReadSession(); err == nil {
+		t.Error("Strict mode should error on malformed lines")
+	}
 }
 
 func TestLargeJSONLFile(t *testing.T) {
@@ -197,4 +235,106 @@ func TestLargeJSONLFile(t *testing.T) {
 	if count != 1000 {
 		t.Errorf("Message count = %v, want 1000", count)
 	}
-}
\ No newline at end of file
+}
+
+func TestJSONLReaderLongLines(t *testing.T) {
+	// A single 20MB message, beyond any fixed line buffer, between two
+	// ordinary ones
+	text := strings.Repeat("x", 20*1024*1024)
+	content := `{"uuid":"msg1","sessionId":"long","type":"user","userType":"external","message":{"role":"user","content":"first"}}` + "\n" +
+		`{"uuid":"msg2","sessionId":"long","type":"user","userType":"external","message":{"role":"user","content":"` + text + `"}}` + "\n" +
+		`{"uuid":"msg3","sessionId":"long","type":"user","userType":"external","message":{"role":"user","content":"last"}}` + "\n"
+	longFile := filepath.Join(t.TempDir(), "long.jsonl")
+	if err := os.WriteFile(longFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create long file: %v", err)
+	}
+
+	session, err := NewJSONLReader(longFile).ReadSession()
+	if err != nil {
+		t.Fatalf("ReadSession() error = %v", err)
+	}
+	if len(session.Messages) != 3 {
+		t.Fatalf("ReadSession() = %d messages, want 3", len(session.Messages))
+	}
+	if userMsg, ok := session.Messages[1].Content.(*models.UserMessage); !ok || len(userMsg.Content) != len(text) {
+		t.Error("The long message should be read in full")
+	}
+
+	count := 0
+	if err := StreamJSONLMessages(strings.NewReader(content), func(*models.Message) error {
+		count++
+		return nil
+	}); err != nil || count != 3 {
+		t.Errorf("StreamJSONLMessages() = %d messages, error %v, want 3", count, err)
+	}
+
+	// Lines over the limit are skipped without losing the rest of the file,
+	// and reported as too long rather than malformed
+	defer func(size int) { maxLineSize = size }(maxLineSize)
+	maxLineSize = 1024 * 1024
+	session, err = NewJSONLReader(longFile).ReadSession()
+	if err != nil {
+		t.Fatalf("ReadSession() error = %v", err)
+	}
+	if len(session.Messages) != 2 || session.Messages[1].UUID != "msg3" {
+		t.Errorf("ReadSession() = %d messages, want msg1 and msg3", len(session.Messages))
+	}
+	reader := NewJSONLReader(longFile)
+	reader.Strict = true
+	if _, err := reader.ReadSession(); !errors.Is(err, ErrLineTooLong) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Strict ReadSession() error = %v, want line 2 too long", err)
+	}
+}
+
+func TestJSONLReaderLineErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"malformed", "not json\n", "line 1: malformed JSON"},
+		{"incomplete", `{"uuid":"msg1"}` + "\n" + `{"uuid":"msg2","mess`, "line 2: incomplete line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := streamJSONL(strings.NewReader(tt.content), true, DiscardLogger, func(*models.Message) error { return nil })
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("streamJSONL() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONLReaderDiagnostics(t *testing.T) {
+	testContent := `{"uuid":"msg1","sessionId":"session1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"uuid":"log1","sessionId":"session1","type":"system","level":"debug","timestamp":"2024-01-01T10:00:01Z","content":"Loaded 3 MCP servers"}
+{"uuid":"log2","sessionId":"session1","type":"system","logLevel":"DEBUG","timestamp":"2024-01-01T10:00:02Z","content":"Hook finished"}
+{"uuid":"msg2","parentUuid":"msg1","sessionId":"session1","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"Hi there!"}]}}
+`
+
+	testFile := filepath.Join(t.TempDir(), "test.jsonl")
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	session, err := NewJSONLReader(testFile).ReadSession()
+	if err != nil {
+		t.Fatalf("ReadSession() error = %v", err)
+	}
+	if session.GetMessageCount() != 2 {
+		t.Errorf("Message count = %v, want 2 (debug entries excluded)", session.GetMessageCount())
+	}
+
+	reader := NewJSONLReader(testFile)
+	reader.IncludeDiagnostics = true
+	session, err = reader.ReadSession()
+	if err != nil {
+		t.Fatalf("ReadSession() error = %v", err)
+	}
+	if session.GetMessageCount() != 4 {
+		t.Errorf("Message count = %v, want 4 with diagnostics included", session.GetMessageCount())
+	}
+	if !session.Messages[1].IsDiagnostic() || session.Messages[2].GetLevel() != "debug" {
+		t.Error("Debug entries should be classified as diagnostic")
+	}
+}
@@ -0,0 +1,52 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Logger receives the warnings of readers and scanners, such as a skipped
+// line or an unreadable session file. Warnf may be called by concurrent
+// scans, so a Logger must be safe for concurrent use.
+type Logger interface {
+	Warnf(format string, args ...any)
+}
+
+// writerLogger writes warnings to an io.Writer, one line each
+type writerLogger struct {
+	mu sync.Mutex
+	w  io.Writer // nil = os.Stderr at the time of the warning
+}
+
+// NewWriterLogger creates a logger that writes each warning to w as a line
+// starting with "Warning: "
+func NewWriterLogger(w io.Writer) Logger {
+	return &writerLogger{w: w}
+}
+
+// Warnf writes a warning line
+func (l *writerLogger) Warnf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w := l.w
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "Warning: "+format+"\n", args...)
+}
+
+// StderrLogger writes warnings to stderr. It is used when no logger is set.
+var StderrLogger Logger = &writerLogger{}
+
+// DiscardLogger drops all warnings
+var DiscardLogger Logger = NewWriterLogger(io.Discard)
+
+// loggerOrDefault returns logger, or StderrLogger if it is nil
+func loggerOrDefault(logger Logger) Logger {
+	if logger == nil {
+		return StderrLogger
+	}
+	return logger
+}
@@ -0,0 +1,58 @@
+package reader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoggerCapturesWarnings(t *testing.T) {
+	claudeDir := t.TempDir()
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	content := "not json\n" + `{"uuid":"msg1","sessionId":"s1","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "empty.jsonl"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	projects, err := NewScanner(claudeDir, &ScanOptions{Logger: NewWriterLogger(&buf)}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(projects) != 1 || projects[0].GetSessionCount() != 1 {
+		t.Fatalf("ScanProjects() = %d projects, want 1 with 1 session", len(projects))
+	}
+
+	warnings := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(warnings) != 2 {
+		t.Fatalf("Logged %d warnings, want 2:\n%s", len(warnings), buf.String())
+	}
+	for _, want := range []string{"Warning: line 1: malformed JSON", "Warning: failed to read session file"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Warnings missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	// The reader's own logger is used outside a scan, and DiscardLogger
+	// silences everything
+	buf.Reset()
+	jsonlReader := NewJSONLReader(filepath.Join(projectDir, "s1.jsonl"))
+	jsonlReader.Logger = NewWriterLogger(&buf)
+	if _, err := jsonlReader.ReadSession(); err != nil {
+		t.Fatalf("ReadSession() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Warning: line 1: malformed JSON") {
+		t.Errorf("ReadSession() logged %q, want a malformed line warning", buf.String())
+	}
+	if _, err := ReadSessionStream(strings.NewReader(content), &ScanOptions{Logger: DiscardLogger}); err != nil {
+		t.Fatalf("ReadSessionStream() error = %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// LoadPricing reads a JSON file mapping model name prefixes to prices in USD
+// per million tokens, e.g.
+//
+//	{"claude-sonnet-4": {"input": 3, "output": 15, "cache_write": 3.75, "cache_read": 0.3}}
+func LoadPricing(filePath string) (map[string]models.ModelPricing, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing file: %w", err)
+	}
+
+	var pricing map[string]models.ModelPricing
+	if err := json.Unmarshal(content, &pricing); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing JSON: %w", err)
+	}
+
+	return pricing, nil
+}
@@ -0,0 +1,37 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPricing(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	pricingFile := filepath.Join(tmpDir, "pricing.json")
+	content := `{"claude-sonnet-4": {"input": 2, "output": 10, "cache_read": 0.2}, "my-model": {"input": 1, "output": 2}}`
+	if err := os.WriteFile(pricingFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create pricing file: %v", err)
+	}
+
+	pricing, err := LoadPricing(pricingFile)
+	if err != nil {
+		t.Fatalf("LoadPricing() error = %v", err)
+	}
+	if len(pricing) != 2 || pricing["claude-sonnet-4"].CacheRead != 0.2 || pricing["my-model"].Output != 2 {
+		t.Errorf("LoadPricing() = %v", pricing)
+	}
+
+	invalidFile := filepath.Join(tmpDir, "invalid.json")
+	if err := os.WriteFile(invalidFile, []byte(`{"my-model": 3}`), 0644); err != nil {
+		t.Fatalf("Failed to create pricing file: %v", err)
+	}
+	if _, err := LoadPricing(invalidFile); err == nil {
+		t.Error("LoadPricing() should error for a price that is not an object")
+	}
+
+	if _, err := LoadPricing(filepath.Join(tmpDir, "missing.json")); err == nil {
+		t.Error("LoadPricing() should error for missing file")
+	}
+}
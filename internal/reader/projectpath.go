@@ -0,0 +1,75 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// NewProject creates a project for an encoded path of the scanned directory,
+// with its path decoded as the scanner decodes it (see ScanProjects)
+func (s *Scanner) NewProject(encodedPath string) *models.Project {
+	project := models.NewProject(encodedPath)
+	project.Path = s.projectPath(encodedPath)
+	return project
+}
+
+// projectPath decodes an encoded project path. Scanners of a Claude directory
+// match it against the filesystem once per path; archive scanners replace
+// each - with /, since their paths come from another machine.
+func (s *Scanner) projectPath(encodedPath string) string {
+	if !s.resolvePaths {
+		return models.NewProject(encodedPath).Path
+	}
+	if path, ok := s.paths.Load(encodedPath); ok {
+		return path.(string)
+	}
+	path := decodeProjectPath(encodedPath)
+	s.paths.Store(encodedPath, path)
+	return path
+}
+
+// decodeProjectPath decodes the directory name Claude Code stores a project
+// under, in which each / of the project path is replaced with -. Since
+// directory names may contain dashes too, the path is matched against the
+// filesystem, preferring the longest existing name at each level; the part
+// of the path that does not exist is decoded with a / for every dash.
+func decodeProjectPath(encodedPath string) string {
+	if !strings.HasPrefix(encodedPath, "-") {
+		return strings.ReplaceAll(encodedPath, "-", "/")
+	}
+
+	segments := strings.Split(encodedPath[1:], "-")
+	dir, consumed := resolveSegments("/", segments)
+	if consumed == len(segments) {
+		return dir
+	}
+	return strings.TrimSuffix(dir, "/") + "/" + strings.Join(segments[consumed:], "/")
+}
+
+// resolveSegments finds the existing path below dir that consumes the most
+// dash-separated segments, returning it and the number of segments used
+func resolveSegments(dir string, segments []string) (string, int) {
+	best, bestConsumed := dir, 0
+	for n := len(segments); n > 0; n-- {
+		name := strings.Join(segments[:n], "-")
+		if name == "" || strings.HasPrefix(name, "-") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil || (n < len(segments) && !info.IsDir()) {
+			continue
+		}
+		resolved, consumed := resolveSegments(path, segments[n:])
+		if consumed += n; consumed > bestConsumed {
+			best, bestConsumed = resolved, consumed
+		}
+		if bestConsumed == len(segments) {
+			break
+		}
+	}
+	return best, bestConsumed
+}
@@ -0,0 +1,58 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeProjectPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	if strings.Contains(tmpDir, "-") {
+		t.Skip("temporary directory contains dashes")
+	}
+	for _, dir := range []string{"my-cool-project", "work/api-server/cmd", "work/api"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	encode := func(path string) string {
+		return strings.ReplaceAll(path, "/", "-")
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantName string
+	}{
+		{"hyphenated directory", filepath.Join(tmpDir, "my-cool-project"), "my-cool-project"},
+		{"hyphenated parent", filepath.Join(tmpDir, "work/api-server/cmd"), "cmd"},
+		{"dash-free sibling", filepath.Join(tmpDir, "work/api"), "api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := NewScanner(t.TempDir(), nil).NewProject(encode(tt.path))
+			if project.Path != tt.path {
+				t.Errorf("Path = %v, want %v", project.Path, tt.path)
+			}
+			if name := project.GetProjectName(); name != tt.wantName {
+				t.Errorf("GetProjectName() = %v, want %v", name, tt.wantName)
+			}
+		})
+	}
+
+	// The part of the path that does not exist falls back to one directory
+	// per dash
+	missing := filepath.Join(tmpDir, "my-cool-project") + "/gone-dir"
+	if got, want := decodeProjectPath(encode(missing)), filepath.Join(tmpDir, "my-cool-project", "gone", "dir"); got != want {
+		t.Errorf("decodeProjectPath() = %v, want %v", got, want)
+	}
+
+	// Archive scanners do not decode against the filesystem
+	encoded := encode(filepath.Join(tmpDir, "my-cool-project"))
+	if got, want := NewArchiveScanner("backup.tar.gz", nil).scanner.NewProject(encoded).Path, filepath.Join(tmpDir, "my", "cool", "project"); got != want {
+		t.Errorf("archive NewProject().Path = %v, want %v", got, want)
+	}
+}
@@ -1,10 +1,16 @@
 package reader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/eternnoir/cc-history-export/internal/models"
@@ -19,20 +25,97 @@ type ScanOptions struct {
 	// Filter by project paths
 	ProjectPaths []string
 	
+	// Skip projects matching any of these paths, even if they match
+	// ProjectPaths
+	ExcludePaths []string
+	
+	// Only include projects whose decoded path or encoded directory name
+	// matches this regular expression (empty = all projects). Dashes in the
+	// encoded name may stand for dashes or separators, so a pattern such as
+	// my-app matches a project in my-app even if decoding splits it.
+	ProjectPathRegex string
+	
 	// Include todo lists
 	IncludeTodos bool
 	
-	// Include shell snapshots
+	// Include shell snapshots, each in the project of the session it was
+	// taken for (see models.AssignShellSnapshots). Snapshots are left out
+	// when a project or session filter is set, since the session a snapshot
+	// was taken for may be filtered out.
 	IncludeShellSnapshots bool
 	
+	// Keep superseded edit/regeneration branches (flagged as regenerated)
+	// instead of keeping only the final branch
+	IncludeRegenerated bool
+	
+	// Keep diagnostic log entries (lines with a level such as debug)
+	IncludeDiagnostics bool
+	
+	// Fail on the first malformed line, unparsable message or unreadable
+	// session file instead of warning and skipping it (empty session files
+	// are still skipped)
+	Strict bool
+	
 	// Maximum number of sessions to process (0 = unlimited)
 	MaxSessions int
+	
+	// Skip sessions with fewer messages than this (0 = no minimum)
+	MinMessages int
+	
+	// Number of projects read in parallel (0 = runtime.NumCPU(), 1 = serial)
+	Concurrency int
+	
+	// Only include sessions with an assistant message from one of these
+	// models, matched as in Session.UsesModel (empty = all models)
+	Models []string
+	
+	// Only include sessions with a tool result that is an error (see
+	// models.Session.HasToolErrors)
+	OnlyToolErrors bool
+	
+	// Filter expression combining criteria with AND/OR (see ParseFilter)
+	Filter Filter
+	
+	// Only include sessions with a message containing this text (ignoring case)
+	Search string
+	
+	// Also search the thinking of assistant messages
+	SearchThinking bool
+	
+	// Drop the messages of matching sessions that do not contain the search
+	// text
+	SearchTrim bool
+	
+	// Merge sessions of a project that share an ID, such as a session
+	// resumed into a second file (see models.MergeSessions)
+	MergeSessions bool
+	
+	// Check whether each project directory still exists on disk
+	CheckPaths bool
+	
+	// Read the CLAUDE.md of each project's working directory, if present
+	IncludeConfig bool
+	
+	// Logger receives the warnings about skipped lines and files (nil =
+	// StderrLogger, DiscardLogger = none)
+	Logger Logger
+	
+	// OnProject is called with each project once its sessions are scanned
+	OnProject func(project *models.Project)
+	
+	// Progress is called after the session files of each project are read,
+	// with the number of projects read so far, the number to read and the
+	// name of the project. Calls are never concurrent.
+	Progress func(current, total int, projectName string)
 }
 
 // Scanner scans the Claude directory structure
 type Scanner struct {
-	basePath string
-	options  *ScanOptions
+	basePath     string
+	options      *ScanOptions
+	projectRegex *regexp.Regexp
+	resolvePaths bool     // Decode project paths against the filesystem
+	paths        sync.Map // Decoded project paths by encoded path
 }
 
 // NewScanner creates a new scanner for the given Claude directory
@@ -41,13 +124,75 @@ func NewScanner(basePath string, options *ScanOptions) *Scanner {
 		options = &ScanOptions{}
 	}
 	return &Scanner{
-		basePath: basePath,
-		options:  options,
+		basePath:     basePath,
+		options:      options,
+		resolvePaths: true,
 	}
 }
 
-// ScanProjects scans all projects in the Claude directory
+// FileError is a session file that could not be read
+type FileError struct {
+	Path string
+	Err  error
+}
+
+// Error returns the path of the file with the reason it could not be read
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the reason the file could not be read
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// SessionFile is a session file as it was before a scan read it
+type SessionFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// ScanResult holds the scanned projects along with the session files that
+// were skipped because they could not be read. Empty session files are not
+// errors. SessionFiles lists every session file the scan read, so a later
+// WatchFrom can tell which ones changed since.
+type ScanResult struct {
+	Projects     []*models.Project
+	FileErrors   []FileError
+	SessionFiles []SessionFile
+}
+
+// ScanProjects scans all projects in the Claude directory. Project paths are
+// decoded against the filesystem, so directory names keep their dashes when
+// the directory exists on this machine.
 func (s *Scanner) ScanProjects() ([]*models.Project, error) {
+	return s.ScanProjectsContext(context.Background())
+}
+
+// ScanProjectsContext is like ScanProjects but checks ctx before reading
+// each session file and returns ctx's error once it is done
+func (s *Scanner) ScanProjectsContext(ctx context.Context) ([]*models.Project, error) {
+	result, err := s.ScanProjectsDetailedContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return result.Projects, nil
+}
+
+// ScanProjectsDetailed is like ScanProjects but also reports the session
+// files that could not be read
+func (s *Scanner) ScanProjectsDetailed() (*ScanResult, error) {
+	return s.ScanProjectsDetailedContext(context.Background())
+}
+
+// ScanProjectsDetailedContext is like ScanProjectsDetailed but stops
+// scanning once ctx is done
+func (s *Scanner) ScanProjectsDetailedContext(ctx context.Context) (*ScanResult, error) {
+	if err := s.compileProjectRegex(); err != nil {
+		return nil, err
+	}
+
 	projectsPath := filepath.Join(s.basePath, "projects")
 	
 	// Check if projects directory exists
@@ -61,48 +206,88 @@ func (s *Scanner) ScanProjects() ([]*models.Project, error) {
 		return nil, fmt.Errorf("failed to read projects directory: %w", err)
 	}
 
-	var projects []*models.Project
-	sessionCount := 0
+	// Track resolved directories so symlinks can't scan a directory twice
+	// or loop back into the projects directory
+	visited := make(map[string]bool)
+	if realPath, err := filepath.EvalSymlinks(projectsPath); err == nil {
+		visited[realPath] = true
+	}
 
+	var projectIDs []string
 	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+		// Check if we should process this project
+		if s.isProjectDir(projectsPath, entry, visited) && s.shouldProcessProject(entry.Name()) {
+			projectIDs = append(projectIDs, entry.Name())
 		}
+	}
+	sort.Strings(projectIDs)
+
+	// Read the session files of several projects at once, then add the
+	// sessions in project order so filters and the session limit apply as
+	// in a serial scan. With a session limit, projects are read one at a
+	// time below instead, so reading stops once the limit is reached.
+	scans := make([]projectScan, len(projectIDs))
+	progress := s.progressFunc(len(projectIDs))
+	read := func(i int) {
+		scans[i] = s.scanProjectSessions(ctx, filepath.Join(projectsPath, projectIDs[i]), projectIDs[i])
+		progress(projectIDs[i])
+	}
+	serial := s.options.MaxSessions > 0
+	if !serial {
+		s.forEach(len(projectIDs), read)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
 
-		// Check if we should process this project
-		if !s.shouldProcessProject(entry.Name()) {
-			continue
+	result := &ScanResult{}
+	sessionCount := 0
+
+	var snapshots []*models.ShellSnapshot
+	if s.options.IncludeShellSnapshots {
+		snapshots, err = s.scanShellSnapshots()
+		if err != nil {
+			s.warnf("failed to scan shell snapshots: %v", err)
 		}
+	}
 
-		project := models.NewProject(entry.Name())
-		projectPath := filepath.Join(projectsPath, entry.Name())
+	for i, projectID := range projectIDs {
+		if serial {
+			read(i)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		project := s.NewProject(projectID)
 
-		// Scan sessions in the project
-		sessions, err := s.scanProjectSessions(projectPath, project.ID)
+		sessions, err := scans[i].sessions, scans[i].err
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to scan sessions for project %s: %v\n", entry.Name(), err)
+			if s.options.Strict {
+				return nil, fmt.Errorf("failed to scan sessions for project %s: %w", projectID, err)
+			}
+			s.warnf("failed to scan sessions for project %s: %v", projectID, err)
+			result.FileErrors = append(result.FileErrors, FileError{Path: filepath.Join(projectsPath, projectID), Err: err})
 			continue
 		}
+		result.FileErrors = append(result.FileErrors, scans[i].fileErrors...)
+		result.SessionFiles = append(result.SessionFiles, scans[i].files...)
+		if s.options.MergeSessions {
+			sessions = models.MergeSessions(sessions)
+		}
 
 		// Apply date filters and session limit
-		for _, session := range sessions {
-			if s.shouldIncludeSession(session) {
-				project.AddSession(session)
-				sessionCount++
-				
-				// Check session limit
-				if s.options.MaxSessions > 0 && sessionCount >= s.options.MaxSessions {
-					projects = append(projects, project)
-					return projects, nil
-				}
-			}
+		if s.addSessions(project, sessions, &sessionCount) {
+			result.Projects = append(result.Projects, project)
+			s.notifyProject(project)
+			result.Projects = s.finishProjects(result.Projects, snapshots)
+			return result, nil
 		}
 
 		// Scan todos if requested
 		if s.options.IncludeTodos {
-			todos, err := s.scanProjectTodos(project.ID)
+			todos, err := s.scanProjectTodos(project)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to scan todos for project %s: %v\n", entry.Name(), err)
+				s.warnf("failed to scan todos for project %s: %v", projectID, err)
 			} else {
 				for _, todo := range todos {
 					project.AddTodoList(todo)
@@ -111,45 +296,262 @@ func (s *Scanner) ScanProjects() ([]*models.Project, error) {
 		}
 
 		if len(project.Sessions) > 0 {
-			projects = append(projects, project)
+			result.Projects = append(result.Projects, project)
+			s.notifyProject(project)
 		}
 	}
 
-	return projects, nil
+	result.Projects = s.finishProjects(result.Projects, snapshots)
+	return result, nil
 }
 
-// scanProjectSessions scans all JSONL files in a project directory
-func (s *Scanner) scanProjectSessions(projectPath, projectID string) ([]*models.Session, error) {
-	entries, err := os.ReadDir(projectPath)
+// ScanRoots scans the projects of several Claude directories or archives
+// (see NewSourceScanner) with the same options and merges projects found in more than one of them (see
+// models.MergeProjects). MaxSessions applies to each directory separately.
+func ScanRoots(basePaths []string, options *ScanOptions) ([]*models.Project, error) {
+	return ScanRootsContext(context.Background(), basePaths, options)
+}
+
+// ScanRootsContext is like ScanRoots but stops scanning once ctx is done
+func ScanRootsContext(ctx context.Context, basePaths []string, options *ScanOptions) ([]*models.Project, error) {
+	result, err := ScanRootsDetailedContext(ctx, basePaths, options)
 	if err != nil {
 		return nil, err
 	}
+	return result.Projects, nil
+}
 
-	var sessions []*models.Session
+// ScanRootsDetailedContext is like ScanRootsContext but also reports the
+// session files of all directories that could not be read
+func ScanRootsDetailedContext(ctx context.Context, basePaths []string, options *ScanOptions) (*ScanResult, error) {
+	result := &ScanResult{}
+	for _, basePath := range basePaths {
+		rootResult, err := NewSourceScanner(basePath, options).ScanProjectsDetailedContext(ctx)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", basePath, err)
+		}
+		result.Projects = append(result.Projects, rootResult.Projects...)
+		result.FileErrors = append(result.FileErrors, rootResult.FileErrors...)
+		result.SessionFiles = append(result.SessionFiles, rootResult.SessionFiles...)
+	}
+	if len(basePaths) >= 2 {
+		result.Projects = models.MergeProjects(result.Projects)
+	}
+	return result, nil
+}
+
+// projectScan holds the sessions read from a project directory
+type projectScan struct {
+	sessions   []*models.Session
+	fileErrors []FileError
+	files      []SessionFile
+	err        error
+}
+
+// forEach calls fn for every index in [0, n), using up to Concurrency workers
+func (s *Scanner) forEach(n int, fn func(i int)) {
+	workers := s.options.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
 
+// addSessions adds the sessions that pass the filters to the project and
+// reports whether the session limit has been reached
+func (s *Scanner) addSessions(project *models.Project, sessions []*models.Session, sessionCount *int) bool {
+	for _, session := range sessions {
+		if !s.shouldIncludeSession(project, session) {
+			continue
+		}
+		if s.options.SearchTrim && s.options.Search != "" {
+			session.TrimToMatches(s.options.Search, s.options.SearchThinking)
+		}
+		project.AddSession(session)
+		*sessionCount++
+		if s.options.MaxSessions > 0 && *sessionCount >= s.options.MaxSessions {
+			return true
+		}
+	}
+	return false
+}
+
+// warnf passes a warning to the Logger of the options
+func (s *Scanner) warnf(format string, args ...any) {
+	loggerOrDefault(s.options.Logger).Warnf(format, args...)
+}
+
+// notifyProject calls the OnProject callback if set
+func (s *Scanner) notifyProject(project *models.Project) {
+	if s.options.OnProject != nil {
+		s.options.OnProject(project)
+	}
+}
+
+// progressFunc returns a function reporting each of total projects read to
+// the Progress callback, one call at a time
+func (s *Scanner) progressFunc(total int) func(projectID string) {
+	var mu sync.Mutex
+	current := 0
+	return func(projectID string) {
+		if s.options.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		current++
+		s.options.Progress(current, total, s.NewProject(projectID).GetProjectName())
+	}
+}
+
+// finishProjects marks projects whose directory no longer exists, reads
+// their CLAUDE.md files if requested and adds the shell snapshots to the
+// projects of their sessions
+func (s *Scanner) finishProjects(projects []*models.Project, snapshots []*models.ShellSnapshot) []*models.Project {
+	if len(snapshots) > 0 && s.filtersSessions() {
+		s.warnf("shell snapshots left out: filters may leave out the sessions they were taken for")
+	} else {
+		models.AssignShellSnapshots(projects, snapshots)
+	}
+	for _, project := range projects {
+		if s.options.CheckPaths {
+			project.CheckPathExists()
+		}
+		if s.options.IncludeConfig {
+			project.Config = s.scanProjectConfig(project.ResolvePath())
+		}
+	}
+	return projects
+}
+
+// filtersSessions checks if any project or session filter is set, so the
+// scanned projects may not hold every session of the directory
+func (s *Scanner) filtersSessions() bool {
+	o := s.options
+	return len(o.ProjectPaths) > 0 || len(o.ExcludePaths) > 0 || o.ProjectPathRegex != "" ||
+		o.StartDate != nil || o.EndDate != nil || o.MinMessages > 0 || o.MaxSessions > 0 ||
+		len(o.Models) > 0 || o.OnlyToolErrors || o.Filter != nil || o.Search != ""
+}
+
+// isProjectDir checks if an entry of the projects directory is a directory
+// that has not been visited yet, following symlinks to directories
+func (s *Scanner) isProjectDir(projectsPath string, entry os.DirEntry, visited map[string]bool) bool {
+	path := filepath.Join(projectsPath, entry.Name())
+
+	if entry.Type()&os.ModeSymlink != 0 {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			return false
+		}
+	} else if !entry.IsDir() {
+		return false
+	}
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		s.warnf("failed to resolve project directory %s: %v", path, err)
+		return false
+	}
+	if visited[realPath] {
+		return false
+	}
+	visited[realPath] = true
+	return true
+}
+
+// scanProjectSessions scans all JSONL files in a project directory, returning
+// the files that could not be read alongside the sessions, and the size and
+// modification time of each file before it was read
+func (s *Scanner) scanProjectSessions(ctx context.Context, projectPath, projectID string) projectScan {
+	if err := ctx.Err(); err != nil {
+		return projectScan{err: err}
+	}
+	entries, err := os.ReadDir(projectPath)
+	if err != nil {
+		return projectScan{err: err}
+	}
+
+	var scan projectScan
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
 			continue
 		}
+		if err := ctx.Err(); err != nil {
+			return projectScan{err: err}
+		}
 
 		filePath := filepath.Join(projectPath, entry.Name())
-		reader := NewJSONLReader(filePath)
-		
-		session, err := reader.ReadSession()
+		if info, err := entry.Info(); err == nil {
+			scan.files = append(scan.files, SessionFile{Path: filePath, Size: info.Size(), ModTime: info.ModTime()})
+		}
+		session, err := s.readSessionFile(filePath, projectID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to read session file %s: %v\n", filePath, err)
+			if s.options.Strict && !errors.Is(err, ErrNoMessages) {
+				return projectScan{err: fmt.Errorf("failed to read session file %s: %w", filePath, err)}
+			}
+			s.warnf("failed to read session file %s: %v", filePath, err)
+			if !errors.Is(err, ErrNoMessages) {
+				scan.fileErrors = append(scan.fileErrors, FileError{Path: filePath, Err: err})
+			}
 			continue
 		}
+		scan.sessions = append(scan.sessions, session)
+	}
+
+	return scan
+}
 
-		session.ProjectID = projectID
-		sessions = append(sessions, session)
+// readSessionFile reads a session file of a project
+func (s *Scanner) readSessionFile(filePath, projectID string) (*models.Session, error) {
+	reader := NewJSONLReader(filePath)
+	reader.IncludeDiagnostics = s.options.IncludeDiagnostics
+	reader.Strict = s.options.Strict
+	reader.Logger = s.options.Logger
+	
+	session, err := reader.ReadSession()
+	if err != nil {
+		return nil, err
 	}
 
-	return sessions, nil
+	// Drop superseded edit/regeneration branches unless requested
+	if session.MarkRegenerated() > 0 && !s.options.IncludeRegenerated {
+		session.PruneRegenerated()
+	}
+
+	session.ProjectID = projectID
+	session.SourceFile = filePath
+	return session, nil
 }
 
-// scanProjectTodos scans all todo JSON files for a project
-func (s *Scanner) scanProjectTodos(projectID string) ([]*models.TodoList, error) {
+// scanProjectTodos reads the todo JSON files of the sessions of a project
+func (s *Scanner) scanProjectTodos(project *models.Project) ([]*models.TodoList, error) {
 	todosPath := filepath.Join(s.basePath, "todos")
 	
 	if _, err := os.Stat(todosPath); os.IsNotExist(err) {
@@ -161,9 +563,14 @@ func (s *Scanner) scanProjectTodos(projectID string) ([]*models.TodoList, error)
 		return nil, err
 	}
 
+	// The todos directory is shared by all projects, so only the files of
+	// the project's own sessions are read
+	sessionIDs := make(map[string]bool, len(project.Sessions))
+	for _, session := range project.Sessions {
+		sessionIDs[session.ID] = true
+	}
+
 	var todoLists []*models.TodoList
-	
-	// Look for files that match the project pattern
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
 			continue
@@ -176,17 +583,16 @@ func (s *Scanner) scanProjectTodos(projectID string) ([]*models.TodoList, error)
 		}
 		
 		sessionID := parts[0]
-		
-		// Check if this todo belongs to one of our project's sessions
-		// This is a simplified check - in a real implementation, we'd need to
-		// verify the session ID belongs to the project
+		if !sessionIDs[sessionID] {
+			continue
+		}
 		
 		filePath := filepath.Join(todosPath, entry.Name())
 		todoReader := NewTodoReader(filePath)
 		
 		todos, err := todoReader.Read()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to read todo file %s: %v\n", filePath, err)
+			s.warnf("failed to read todo file %s: %v", filePath, err)
 			continue
 		}
 
@@ -204,26 +610,51 @@ func (s *Scanner) scanProjectTodos(projectID string) ([]*models.TodoList, error)
 	return todoLists, nil
 }
 
+// compileProjectRegex compiles the ProjectPathRegex option, if set
+func (s *Scanner) compileProjectRegex() error {
+	if s.options.ProjectPathRegex == "" {
+		return nil
+	}
+	re, err := regexp.Compile(s.options.ProjectPathRegex)
+	if err != nil {
+		return fmt.Errorf("invalid project path regex: %w", err)
+	}
+	s.projectRegex = re
+	return nil
+}
+
 // shouldProcessProject checks if a project should be processed based on filters
 func (s *Scanner) shouldProcessProject(encodedPath string) bool {
+	// Exclusion takes precedence over inclusion
+	if matchesProjectPath(encodedPath, s.options.ExcludePaths) {
+		return false
+	}
+	if s.projectRegex != nil && !s.projectRegex.MatchString(s.projectPath(encodedPath)) &&
+		!s.projectRegex.MatchString(encodedPath) {
+		return false
+	}
 	if len(s.options.ProjectPaths) == 0 {
 		return true
 	}
+	return matchesProjectPath(encodedPath, s.options.ProjectPaths)
+}
 
-	// Decode the path
-	decodedPath := strings.ReplaceAll(encodedPath, "-", "/")
-	
-	for _, filterPath := range s.options.ProjectPaths {
-		if strings.Contains(decodedPath, filterPath) {
+// matchesProjectPath checks if an encoded project path contains any of the
+// given paths. Encoded paths are compared, since decoding cannot tell the
+// dashes of directory names from separators.
+func matchesProjectPath(encodedPath string, filterPaths []string) bool {
+	for _, filterPath := range filterPaths {
+		if strings.Contains(encodedPath, strings.ReplaceAll(filterPath, "/", "-")) {
 			return true
 		}
 	}
-
 	return false
 }
 
-// shouldIncludeSession checks if a session should be included based on date filters
-func (s *Scanner) shouldIncludeSession(session *models.Session) bool {
+// shouldIncludeSession checks if a session should be included based on date
+// filters, message count, models, tool errors, the filter expression and
+// the search text
+func (s *Scanner) shouldIncludeSession(project *models.Project, session *models.Session) bool {
 	if s.options.StartDate != nil && session.EndTime.Before(*s.options.StartDate) {
 		return false
 	}
@@ -232,19 +663,57 @@ func (s *Scanner) shouldIncludeSession(session *models.Session) bool {
 		return false
 	}
 	
+	if session.GetMessageCount() < s.options.MinMessages {
+		return false
+	}
+	
+	if len(s.options.Models) > 0 && !session.UsesModel(s.options.Models) {
+		return false
+	}
+	
+	if s.options.OnlyToolErrors && !session.HasToolErrors() {
+		return false
+	}
+	
+	if s.options.Filter != nil && !s.options.Filter.Match(project, session) {
+		return false
+	}
+	
+	if s.options.Search != "" && !session.ContainsText(s.options.Search) &&
+		!(s.options.SearchThinking && session.ContainsThinking(s.options.Search)) {
+		return false
+	}
+	
 	return true
 }
 
 // ScanClaudeConfig reads the CLAUDE.md configuration file
 func (s *Scanner) ScanClaudeConfig() (string, error) {
-	configPath := filepath.Join(s.basePath, "CLAUDE.md")
+	return readClaudeMD(s.basePath)
+}
+
+// scanProjectConfig reads the CLAUDE.md file of a project directory. The
+// directory is taken from the history and may not exist on this machine, so
+// any error leaves the config empty.
+func (s *Scanner) scanProjectConfig(decodedPath string) string {
+	config, err := readClaudeMD(decodedPath)
+	if err != nil {
+		return ""
+	}
+	return config
+}
+
+// readClaudeMD reads the CLAUDE.md file of a directory, returning an empty
+// string if there is none
+func readClaudeMD(dir string) (string, error) {
+	configPath := filepath.Join(dir, "CLAUDE.md")
 	
 	content, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return "", nil // Config file is optional
 		}
-		return "", fmt.Errorf("failed to read CLAUDE.md: %w", err)
+		return "", fmt.Errorf("failed to read %s: %w", configPath, err)
 	}
 	
 	return string(content), nil
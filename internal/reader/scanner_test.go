@@ -1,8 +1,13 @@
 package reader
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -95,6 +100,52 @@ func TestScanner(t *testing.T) {
 	}
 }
 
+func TestScannerTodosPerProject(t *testing.T) {
+	claudeDir := t.TempDir()
+	todosDir := filepath.Join(claudeDir, "todos")
+	if err := os.MkdirAll(todosDir, 0755); err != nil {
+		t.Fatalf("Failed to create todos dir: %v", err)
+	}
+	for _, project := range []struct{ dir, sessionID string }{
+		{"-Users-test-alpha", "alpha-session"},
+		{"-Users-test-beta", "beta-session"},
+	} {
+		projectDir := filepath.Join(claudeDir, "projects", project.dir)
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			t.Fatalf("Failed to create project dir: %v", err)
+		}
+		content := `{"uuid":"msg1","sessionId":"` + project.sessionID + `","type":"user","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}`
+		if err := os.WriteFile(filepath.Join(projectDir, project.sessionID+".jsonl"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create session file: %v", err)
+		}
+		todo := `[{"id":"1","content":"Todo of ` + project.sessionID + `","status":"pending","priority":"high"}]`
+		if err := os.WriteFile(filepath.Join(todosDir, project.sessionID+"-agent-"+project.sessionID+".json"), []byte(todo), 0644); err != nil {
+			t.Fatalf("Failed to create todo file: %v", err)
+		}
+	}
+	// A todo file of a session that belongs to no project
+	if err := os.WriteFile(filepath.Join(todosDir, "orphan-agent-orphan.json"), []byte(`[{"id":"1","content":"Orphan","status":"pending","priority":"low"}]`), 0644); err != nil {
+		t.Fatalf("Failed to create todo file: %v", err)
+	}
+
+	projects, err := NewScanner(claudeDir, &ScanOptions{IncludeTodos: true}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("Expected 2 projects, got %d", len(projects))
+	}
+	for _, project := range projects {
+		if len(project.TodoLists) != 1 {
+			t.Errorf("Project %s has %d todo lists, want 1", project.ID, len(project.TodoLists))
+			continue
+		}
+		if sessionID := project.Sessions[0].ID; project.TodoLists[0].SessionID != sessionID {
+			t.Errorf("Project %s has the todos of %s, want %s", project.ID, project.TodoLists[0].SessionID, sessionID)
+		}
+	}
+}
+
 func TestScannerWithFilters(t *testing.T) {
 	// Create test directory structure
 	tmpDir := t.TempDir()
@@ -165,6 +216,62 @@ func TestScannerWithFilters(t *testing.T) {
 		}
 	}
 	
+	// Filter paths may name directories containing dashes
+	scanner = NewScanner(claudeDir, &ScanOptions{
+		ProjectPaths: []string{"/Users/test-project1"},
+	})
+	filteredProjects, err = scanner.ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(filteredProjects) != 1 || filteredProjects[0].ID != "-Users-test-project1" {
+		t.Errorf("Expected only -Users-test-project1 for a hyphenated filter, got %d projects", len(filteredProjects))
+	}
+	
+	// Regexes match the decoded path
+	scanner = NewScanner(claudeDir, &ScanOptions{ProjectPathRegex: `^/Users/test/project\d$`})
+	filteredProjects, err = scanner.ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(filteredProjects) != 2 {
+		t.Errorf("Expected 2 projects matching the regex, got %d", len(filteredProjects))
+	}
+	
+	// as well as the encoded name, which keeps the dashes of directory names
+	scanner = NewScanner(claudeDir, &ScanOptions{ProjectPathRegex: `test-project1$`})
+	filteredProjects, err = scanner.ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(filteredProjects) != 1 || filteredProjects[0].ID != "-Users-test-project1" {
+		t.Errorf("Expected only -Users-test-project1 for a hyphenated regex, got %d projects", len(filteredProjects))
+	}
+	if _, err := NewScanner(claudeDir, &ScanOptions{ProjectPathRegex: "(test"}).ScanProjects(); err == nil {
+		t.Error("Expected error for an invalid project path regex")
+	}
+	
+	// Exclusion takes precedence over inclusion
+	scanner = NewScanner(claudeDir, &ScanOptions{
+		ProjectPaths: []string{"/Users/test"},
+		ExcludePaths: []string{"/Users/test-project2"},
+	})
+	filteredProjects, err = scanner.ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(filteredProjects) != 1 || filteredProjects[0].ID != "-Users-test-project1" {
+		t.Errorf("Expected only -Users-test-project1 with project2 excluded, got %d projects", len(filteredProjects))
+	}
+	scanner = NewScanner(claudeDir, &ScanOptions{ExcludePaths: []string{"/Users/other"}})
+	filteredProjects, err = scanner.ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(filteredProjects) != 2 {
+		t.Errorf("Expected 2 projects with /Users/other excluded, got %d", len(filteredProjects))
+	}
+	
 	// Test date filter
 	startDate := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
 	endDate := time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
@@ -202,6 +309,18 @@ func TestScannerWithFilters(t *testing.T) {
 	if totalSessions > 2 {
 		t.Errorf("Expected at most 2 sessions, got %d", totalSessions)
 	}
+	
+	// Test minimum message count: every session has 2 messages, and projects
+	// left without sessions are dropped
+	for minMessages, want := range map[int]int{2: 3, 3: 0} {
+		projects, err := NewScanner(claudeDir, &ScanOptions{MinMessages: minMessages}).ScanProjects()
+		if err != nil {
+			t.Fatalf("ScanProjects() with min messages error = %v", err)
+		}
+		if len(projects) != want {
+			t.Errorf("Expected %d projects with at least %d messages, got %d", want, minMessages, len(projects))
+		}
+	}
 }
 
 func TestScannerDateFilterWithEndTime(t *testing.T) {
@@ -292,4 +411,476 @@ func TestScannerErrors(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for missing projects directory")
 	}
-}
\ No newline at end of file
+
+	// Test a malformed session file, skipped unless strict
+	projectDir := filepath.Join(claudeDir, "projects", "-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	malformed := "not json\n{\"uuid\":\"msg1\",\"sessionId\":\"s1\",\"type\":\"user\",\"timestamp\":\"2024-01-01T10:00:00Z\",\"message\":{\"role\":\"user\",\"content\":\"Hi\"}}"
+	if err := os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(malformed), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	if _, err := NewScanner(claudeDir, nil).ScanProjects(); err != nil {
+		t.Errorf("Lenient scan should succeed, got error: %v", err)
+	}
+	if _, err := NewScanner(claudeDir, &ScanOptions{Strict: true}).ScanProjects(); err == nil {
+		t.Error("Strict scan should error on a malformed line")
+	}
+
+	// Test an unreadable session file, reported in the detailed result
+	brokenFile := filepath.Join(projectDir, "s2.jsonl")
+	if err := os.Symlink(filepath.Join(tmpDir, "missing.jsonl"), brokenFile); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "s3.jsonl"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+	result, err := NewScanner(claudeDir, nil).ScanProjectsDetailed()
+	if err != nil {
+		t.Fatalf("ScanProjectsDetailed() error = %v", err)
+	}
+	if len(result.Projects) != 1 || len(result.Projects[0].Sessions) != 1 {
+		t.Errorf("Expected 1 project with the readable session, got %d projects", len(result.Projects))
+	}
+	if len(result.FileErrors) != 1 || result.FileErrors[0].Path != brokenFile || !errors.Is(result.FileErrors[0], os.ErrNotExist) {
+		t.Errorf("FileErrors = %v, want only %s (empty files are not errors)", result.FileErrors, brokenFile)
+	}
+}
+
+func TestScannerRegeneratedBranches(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projDir := filepath.Join(claudeDir, "projects", "-Users-test-branched")
+	if err := os.MkdirAll(projDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+
+	// msg2 was edited into msg2b and regenerated as msg3b
+	sessionContent := `{"uuid":"msg1","parentUuid":null,"sessionId":"branched","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}
+{"uuid":"msg2","parentUuid":"msg1","sessionId":"branched","type":"user","userType":"external","timestamp":"2024-01-01T10:01:00Z","message":{"role":"user","content":"Original prompt"}}
+{"uuid":"msg3","parentUuid":"msg2","sessionId":"branched","type":"assistant","timestamp":"2024-01-01T10:02:00Z","message":{"role":"assistant","content":[{"type":"text","text":"Original answer"}]}}
+{"uuid":"msg2b","parentUuid":"msg1","sessionId":"branched","type":"user","userType":"external","timestamp":"2024-01-01T10:03:00Z","message":{"role":"user","content":"Edited prompt"}}
+{"uuid":"msg3b","parentUuid":"msg2b","sessionId":"branched","type":"assistant","timestamp":"2024-01-01T10:04:00Z","message":{"role":"assistant","content":[{"type":"text","text":"Regenerated answer"}]}}`
+
+	if err := os.WriteFile(filepath.Join(projDir, "branched.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	// Default keeps only the final branch
+	projects, err := NewScanner(claudeDir, nil).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+
+	session := projects[0].Sessions[0]
+	if len(session.Messages) != 3 {
+		t.Fatalf("Expected 3 messages on final branch, got %d", len(session.Messages))
+	}
+	for _, msg := range session.Messages {
+		if msg.UUID == "msg2" || msg.UUID == "msg3" {
+			t.Errorf("Superseded message %s should be dropped by default", msg.UUID)
+		}
+	}
+
+	// IncludeRegenerated keeps and flags the superseded branch
+	projects, err = NewScanner(claudeDir, &ScanOptions{IncludeRegenerated: true}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+
+	session = projects[0].Sessions[0]
+	if len(session.Messages) != 5 {
+		t.Fatalf("Expected 5 messages with regenerated branches, got %d", len(session.Messages))
+	}
+	for _, msg := range session.Messages {
+		wantRegenerated := msg.UUID == "msg2" || msg.UUID == "msg3"
+		if msg.Regenerated != wantRegenerated {
+			t.Errorf("Message %s Regenerated = %v, want %v", msg.UUID, msg.Regenerated, wantRegenerated)
+		}
+	}
+}
+
+
+func TestScannerSymlinkedProject(t *testing.T) {
+	tmpDir := t.TempDir()
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projectsDir := filepath.Join(claudeDir, "projects")
+	if err := os.MkdirAll(projectsDir, 0755); err != nil {
+		t.Fatalf("Failed to create projects dir: %v", err)
+	}
+
+	// The real project directory lives outside the projects directory
+	realDir := filepath.Join(tmpDir, "elsewhere", "linked-project")
+	if err := os.MkdirAll(realDir, 0755); err != nil {
+		t.Fatalf("Failed to create real project dir: %v", err)
+	}
+	sessionContent := `{"uuid":"msg1","sessionId":"linked","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}`
+	if err := os.WriteFile(filepath.Join(realDir, "linked.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	if err := os.Symlink(realDir, filepath.Join(projectsDir, "-Users-test-linked")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	// A second link to the same directory and a link back to projects
+	if err := os.Symlink(realDir, filepath.Join(projectsDir, "-Users-test-linked-again")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink(projectsDir, filepath.Join(projectsDir, "-Users-test-loop")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	projects, err := NewScanner(claudeDir, nil).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+
+	if len(projects) != 1 {
+		t.Fatalf("Expected 1 project, got %d", len(projects))
+	}
+
+	if projects[0].ID != "-Users-test-linked" {
+		t.Errorf("Project ID = %v, want -Users-test-linked", projects[0].ID)
+	}
+
+	if len(projects[0].Sessions) != 1 {
+		t.Errorf("Expected 1 session in symlinked project, got %d", len(projects[0].Sessions))
+	}
+}
+
+func TestScanRootsMergesProjects(t *testing.T) {
+	line := func(uuid, session string) string {
+		return `{"uuid":"` + uuid + `","sessionId":"` + session + `","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}` + "\n"
+	}
+	writeRoot := func(sessions map[string]string) string {
+		root := t.TempDir()
+		projectDir := filepath.Join(root, "projects", "-Users-test-shared")
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			t.Fatalf("Failed to create project dir: %v", err)
+		}
+		for name, content := range sessions {
+			if err := os.WriteFile(filepath.Join(projectDir, name+".jsonl"), []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create session file: %v", err)
+			}
+		}
+		return root
+	}
+
+	// Both roots have s2; the second copy has more messages
+	root1 := writeRoot(map[string]string{
+		"s1": line("a1", "s1"),
+		"s2": line("b1", "s2"),
+	})
+	root2 := writeRoot(map[string]string{
+		"s2": line("b1", "s2") + line("b2", "s2"),
+		"s3": line("c1", "s3"),
+	})
+
+	projects, err := ScanRoots([]string{root1, root2}, nil)
+	if err != nil {
+		t.Fatalf("ScanRoots() error = %v", err)
+	}
+	if len(projects) != 1 {
+		t.Fatalf("Expected 1 merged project, got %d", len(projects))
+	}
+
+	counts := make(map[string]int)
+	for _, session := range projects[0].Sessions {
+		counts[session.ID] = session.GetMessageCount()
+	}
+	want := map[string]int{"s1": 1, "s2": 2, "s3": 1}
+	if len(counts) != len(want) {
+		t.Errorf("Sessions = %v, want %v", counts, want)
+	}
+	for id, n := range want {
+		if counts[id] != n {
+			t.Errorf("Session %s has %d messages, want %d", id, counts[id], n)
+		}
+	}
+
+	if _, err := ScanRoots([]string{root1, filepath.Join(root1, "missing")}, nil); err == nil {
+		t.Error("Expected error for a missing root")
+	}
+}
+
+func TestScannerSearch(t *testing.T) {
+	claudeDir := filepath.Join(t.TempDir(), ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-app")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	
+	sessions := map[string]string{
+		"text.jsonl": `{"uuid":"t1","sessionId":"text","type":"user","userType":"external","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Why is Redis slow?"}}
+{"uuid":"t2","parentUuid":"t1","sessionId":"text","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"Checking the config."}]}}`,
+		"thinking.jsonl": `{"uuid":"k1","sessionId":"thinking","type":"user","userType":"external","timestamp":"2024-01-02T10:00:00Z","message":{"role":"user","content":"Speed up the cache"}}
+{"uuid":"k2","parentUuid":"k1","sessionId":"thinking","type":"assistant","timestamp":"2024-01-02T10:00:05Z","message":{"role":"assistant","model":"claude-opus-4-20250514","content":[{"type":"thinking","thinking":"Maybe redis is misconfigured."},{"type":"text","text":"Done."}]}}`,
+		"failed.jsonl": `{"uuid":"f1","sessionId":"failed","type":"user","userType":"external","timestamp":"2024-01-03T10:00:00Z","message":{"role":"user","content":"Run the tests"}}
+{"uuid":"f2","parentUuid":"f1","sessionId":"failed","type":"user","userType":"external","timestamp":"2024-01-03T10:00:05Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"exit status 1","is_error":true}]}}`,
+	}
+	for name, content := range sessions {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create session file: %v", err)
+		}
+	}
+	
+	tests := []struct {
+		name     string
+		options  ScanOptions
+		want     []string
+		messages int
+	}{
+		{"text only", ScanOptions{Search: "redis"}, []string{"text"}, 2},
+		{"with thinking", ScanOptions{Search: "redis", SearchThinking: true}, []string{"text", "thinking"}, 4},
+		{"trimmed", ScanOptions{Search: "redis", SearchTrim: true}, []string{"text"}, 1},
+		{"trimmed with thinking", ScanOptions{Search: "REDIS", SearchThinking: true, SearchTrim: true}, []string{"text", "thinking"}, 2},
+		{"model", ScanOptions{Models: []string{"opus"}}, []string{"thinking"}, 2},
+		{"models", ScanOptions{Models: []string{"haiku", "sonnet"}}, []string{"text"}, 2},
+		{"tool errors", ScanOptions{OnlyToolErrors: true}, []string{"failed"}, 2},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, err := NewScanner(claudeDir, &tt.options).ScanProjects()
+			if err != nil {
+				t.Fatalf("ScanProjects() error = %v", err)
+			}
+			if len(projects) != 1 {
+				t.Fatalf("Expected 1 project, got %d", len(projects))
+			}
+			var got []string
+			for _, session := range projects[0].Sessions {
+				got = append(got, session.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Sessions = %v, want %v", got, tt.want)
+			}
+			if messages := projects[0].GetTotalMessages(); messages != tt.messages {
+				t.Errorf("Messages = %d, want %d", messages, tt.messages)
+			}
+		})
+	}
+}
+
+func TestScannerConcurrency(t *testing.T) {
+	claudeDir := writeBenchmarkClaudeDir(t, 20, 3, 5)
+	
+	serial, err := NewScanner(claudeDir, &ScanOptions{Concurrency: 1}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() serial error = %v", err)
+	}
+	parallel, err := NewScanner(claudeDir, &ScanOptions{Concurrency: 8}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() parallel error = %v", err)
+	}
+	
+	if len(parallel) != len(serial) || len(serial) != 20 {
+		t.Fatalf("Got %d projects in parallel and %d serially, want 20", len(parallel), len(serial))
+	}
+	for i := range serial {
+		if parallel[i].ID != serial[i].ID || parallel[i].GetSessionCount() != serial[i].GetSessionCount() {
+			t.Errorf("Project %d = %s with %d sessions, want %s with %d", i,
+				parallel[i].ID, parallel[i].GetSessionCount(), serial[i].ID, serial[i].GetSessionCount())
+		}
+	}
+	
+	// The session limit applies in project order
+	limited, err := NewScanner(claudeDir, &ScanOptions{Concurrency: 8, MaxSessions: 4}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() limited error = %v", err)
+	}
+	if len(limited) != 2 || limited[0].ID != serial[0].ID || limited[1].GetSessionCount() != 1 {
+		t.Errorf("Expected the first 4 sessions of the first 2 projects, got %d projects", len(limited))
+	}
+	
+	// Progress is reported once per project, counting up, even in parallel
+	var progress []int
+	options := &ScanOptions{Concurrency: 8, Progress: func(current, total int, projectName string) {
+		if total != 20 || projectName == "" {
+			t.Errorf("Progress(%d, %d, %q), want a project name out of 20", current, total, projectName)
+		}
+		progress = append(progress, current)
+	}}
+	if _, err := NewScanner(claudeDir, options).ScanProjects(); err != nil {
+		t.Fatalf("ScanProjects() with progress error = %v", err)
+	}
+	if len(progress) != 20 || progress[0] != 1 || progress[19] != 20 {
+		t.Errorf("Progress counts = %v, want 1 to 20", progress)
+	}
+
+	// Projects after the session limit are not read, so a malformed file
+	// in the last one is never warned about
+	badFile := filepath.Join(claudeDir, "projects", serial[19].ID, "bad.jsonl")
+	if err := os.WriteFile(badFile, []byte("not json\n{\"uuid\":\"m1\",\"sessionId\":\"bad\",\"type\":\"user\",\"message\":{\"role\":\"user\",\"content\":\"Hi\"}}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+	var warnings bytes.Buffer
+	if _, err := NewScanner(claudeDir, &ScanOptions{Concurrency: 8, MaxSessions: 4, Logger: NewWriterLogger(&warnings)}).ScanProjects(); err != nil {
+		t.Fatalf("ScanProjects() limited error = %v", err)
+	}
+	if warnings.Len() > 0 {
+		t.Errorf("Projects past the session limit were read: %s", warnings.String())
+	}
+}
+
+func TestScannerIncludeConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	workDir := filepath.Join(tmpDir, "work", "my-app")
+	projectDir := filepath.Join(tmpDir, ".claude", "projects", "-work-my-app")
+	for _, dir := range []string{workDir, projectDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "CLAUDE.md"), []byte("Use tabs."), 0644); err != nil {
+		t.Fatalf("Failed to create CLAUDE.md: %v", err)
+	}
+	// The working directory recorded in the session locates the project
+	sessionContent := `{"uuid":"msg1","sessionId":"s1","type":"user","userType":"external","cwd":"` + workDir + `","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(sessionContent), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	projects, err := NewScanner(claudeDir, &ScanOptions{IncludeConfig: true}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(projects) != 1 || projects[0].Config != "Use tabs." {
+		t.Fatalf("Expected the project's CLAUDE.md, got %v", projects)
+	}
+
+	projects, err = NewScanner(claudeDir, nil).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if projects[0].Config != "" {
+		t.Errorf("CLAUDE.md should only be read with IncludeConfig, got %q", projects[0].Config)
+	}
+
+	// Missing or unreadable files are skipped without a warning
+	unreadableDir := filepath.Join(tmpDir, "unreadable")
+	if err := os.MkdirAll(filepath.Join(unreadableDir, "CLAUDE.md"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	scanner := NewScanner(claudeDir, nil)
+	for _, dir := range []string{filepath.Join(tmpDir, "missing"), unreadableDir} {
+		if config := scanner.scanProjectConfig(dir); config != "" {
+			t.Errorf("scanProjectConfig(%s) = %q, want empty", dir, config)
+		}
+	}
+}
+
+func TestScannerContextCancel(t *testing.T) {
+	claudeDir := writeBenchmarkClaudeDir(t, 3, 2, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewScanner(claudeDir, &ScanOptions{Concurrency: 2}).ScanProjectsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ScanProjectsContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := ScanRootsContext(ctx, []string{claudeDir}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("ScanRootsContext() error = %v, want context.Canceled", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "backup.tar.gz")
+	writeTestArchive(t, archivePath, map[string]string{"projects/-Users-test-app/a.jsonl": ""}, []string{"projects/-Users-test-app/a.jsonl"})
+	if _, err := NewArchiveScanner(archivePath, nil).ScanProjectsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ArchiveScanner.ScanProjectsContext() error = %v, want context.Canceled", err)
+	}
+}
+
+// writeBenchmarkClaudeDir creates a Claude directory with the given number of
+// projects, sessions per project and message pairs per session
+func writeBenchmarkClaudeDir(tb testing.TB, projects, sessions, pairs int) string {
+	tb.Helper()
+	claudeDir := filepath.Join(tb.TempDir(), ".claude")
+	
+	for p := 0; p < projects; p++ {
+		projectDir := filepath.Join(claudeDir, "projects", fmt.Sprintf("-Users-test-project%03d", p))
+		if err := os.MkdirAll(projectDir, 0755); err != nil {
+			tb.Fatalf("Failed to create project dir: %v", err)
+		}
+		for s := 0; s < sessions; s++ {
+			sessionID := fmt.Sprintf("session-%03d-%03d", p, s)
+			var sb strings.Builder
+			parent := "null"
+			for m := 0; m < pairs; m++ {
+				userID := fmt.Sprintf("%s-u%d", sessionID, m)
+				assistantID := fmt.Sprintf("%s-a%d", sessionID, m)
+				timestamp := time.Date(2024, 1, 1, 10, m, 0, 0, time.UTC).Format(time.RFC3339)
+				fmt.Fprintf(&sb, `{"uuid":"%s","parentUuid":%s,"sessionId":"%s","type":"user","userType":"external","timestamp":"%s","message":{"role":"user","content":"Please refactor the scanner to read files in parallel"}}`+"\n",
+					userID, parent, sessionID, timestamp)
+				fmt.Fprintf(&sb, `{"uuid":"%s","parentUuid":"%s","sessionId":"%s","type":"assistant","timestamp":"%s","message":{"role":"assistant","model":"claude-3","content":[{"type":"text","text":"Done, the scanner now uses a worker pool."}],"usage":{"input_tokens":100,"output_tokens":20}}}`+"\n",
+					assistantID, userID, sessionID, timestamp)
+				parent = `"` + assistantID + `"`
+			}
+			if err := os.WriteFile(filepath.Join(projectDir, sessionID+".jsonl"), []byte(sb.String()), 0644); err != nil {
+				tb.Fatalf("Failed to create session file: %v", err)
+			}
+		}
+	}
+	return claudeDir
+}
+
+func BenchmarkScanProjects(b *testing.B) {
+	claudeDir := writeBenchmarkClaudeDir(b, 100, 5, 50)
+	
+	for _, bm := range []struct {
+		name        string
+		concurrency int
+	}{
+		{"serial", 1},
+		{"parallel", 0},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := NewScanner(claudeDir, &ScanOptions{Concurrency: bm.concurrency}).ScanProjects(); err != nil {
+					b.Fatalf("ScanProjects() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestScannerMergeSessions(t *testing.T) {
+	claudeDir := filepath.Join(t.TempDir(), ".claude")
+	projectDir := filepath.Join(claudeDir, "projects", "-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	// The resumed file repeats the last message of the first one
+	files := map[string]string{
+		"a.jsonl": `{"uuid":"m1","sessionId":"s1","type":"user","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hi"}}
+{"uuid":"m2","parentUuid":"m1","sessionId":"s1","type":"user","timestamp":"2024-01-01T10:01:00Z","message":{"role":"user","content":"Still there?"}}`,
+		"b.jsonl": `{"uuid":"m2","parentUuid":"m1","sessionId":"s1","type":"user","timestamp":"2024-01-01T10:01:00Z","message":{"role":"user","content":"Still there?"}}
+{"uuid":"m3","parentUuid":"m2","sessionId":"s1","type":"user","timestamp":"2024-01-02T09:00:00Z","message":{"role":"user","content":"Resumed"}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create session file: %v", err)
+		}
+	}
+
+	projects, err := NewScanner(claudeDir, nil).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if projects[0].GetSessionCount() != 2 {
+		t.Errorf("Expected 2 sessions without merging, got %d", projects[0].GetSessionCount())
+	}
+
+	projects, err = NewScanner(claudeDir, &ScanOptions{MergeSessions: true}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if projects[0].GetSessionCount() != 1 {
+		t.Fatalf("Expected 1 merged session, got %d", projects[0].GetSessionCount())
+	}
+	session := projects[0].Sessions[0]
+	if session.GetMessageCount() != 3 || session.EndTime.Format(time.DateOnly) != "2024-01-02" {
+		t.Errorf("Merged session has %d messages ending %v, want 3 ending 2024-01-02", session.GetMessageCount(), session.EndTime)
+	}
+}
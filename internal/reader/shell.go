@@ -0,0 +1,67 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// shellSnapshotName matches shell snapshot file names such as
+// snapshot-zsh-1752622750085-qza877.sh, which hold the shell and the
+// creation time in milliseconds
+var shellSnapshotName = regexp.MustCompile(`^snapshot-([A-Za-z0-9]+)-(\d+)-[^.]*\.sh$`)
+
+// newShellSnapshot creates a snapshot from a file of the shell-snapshots
+// directory, taking the shell and creation time from its name, or the
+// creation time from modTime if the name does not hold them
+func newShellSnapshot(name string, content []byte, modTime time.Time) *models.ShellSnapshot {
+	snapshot := &models.ShellSnapshot{
+		Name:      name,
+		CreatedAt: modTime,
+		Content:   string(content),
+	}
+	if m := shellSnapshotName.FindStringSubmatch(name); m != nil {
+		snapshot.Shell = m[1]
+		if millis, err := strconv.ParseInt(m[2], 10, 64); err == nil {
+			snapshot.CreatedAt = time.UnixMilli(millis)
+		}
+	}
+	return snapshot
+}
+
+// scanShellSnapshots reads the shell snapshots of the Claude directory
+func (s *Scanner) scanShellSnapshots() ([]*models.ShellSnapshot, error) {
+	snapshotsPath := filepath.Join(s.basePath, "shell-snapshots")
+
+	entries, err := os.ReadDir(snapshotsPath)
+	if os.IsNotExist(err) {
+		return nil, nil // Shell snapshots directory might not exist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*models.ShellSnapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sh") {
+			continue
+		}
+		filePath := filepath.Join(snapshotsPath, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since the directory was read
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			s.warnf("failed to read shell snapshot %s: %v", filePath, err)
+			continue
+		}
+		snapshots = append(snapshots, newShellSnapshot(entry.Name(), content, info.ModTime()))
+	}
+	return snapshots, nil
+}
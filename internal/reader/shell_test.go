@@ -0,0 +1,70 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScannerShellSnapshots(t *testing.T) {
+	claudeDir := t.TempDir()
+	projectDir := filepath.Join(claudeDir, "projects", "-Users-test-project")
+	snapshotsDir := filepath.Join(claudeDir, "shell-snapshots")
+	for _, dir := range []string{projectDir, snapshotsDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	session := `{"uuid":"msg1","sessionId":"session1","type":"user","timestamp":"2024-01-01T10:00:30Z","message":{"role":"user","content":"Hello"}}`
+	if err := os.WriteFile(filepath.Join(projectDir, "session1.jsonl"), []byte(session), 0644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	// Taken at 2024-01-01T10:00:00Z, shortly before the session started
+	name := "snapshot-zsh-1704103200000-abc123.sh"
+	if err := os.WriteFile(filepath.Join(snapshotsDir, name), []byte("alias ll='ls -l'\n"), 0644); err != nil {
+		t.Fatalf("Failed to create snapshot file: %v", err)
+	}
+
+	projects, err := NewScanner(claudeDir, &ScanOptions{IncludeShellSnapshots: true}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(projects) != 1 || len(projects[0].ShellSnapshots) != 1 {
+		t.Fatalf("ScanProjects() = %d projects, want 1 project with 1 shell snapshot", len(projects))
+	}
+	snapshot := projects[0].ShellSnapshots[0]
+	if snapshot.Name != name || snapshot.Shell != "zsh" || snapshot.SessionID != "session1" || snapshot.Content != "alias ll='ls -l'\n" {
+		t.Errorf("snapshot = %+v", snapshot)
+	}
+	if want := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC); !snapshot.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", snapshot.CreatedAt, want)
+	}
+
+	// Snapshots are only read when requested
+	projects, err = NewScanner(claudeDir, nil).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(projects[0].ShellSnapshots) != 0 {
+		t.Error("Shell snapshots should not be read by default")
+	}
+
+	// Filters may leave out the session of a snapshot
+	projects, err = NewScanner(claudeDir, &ScanOptions{IncludeShellSnapshots: true, ProjectPaths: []string{"/Users/test"}, Logger: DiscardLogger}).ScanProjects()
+	if err != nil {
+		t.Fatalf("ScanProjects() error = %v", err)
+	}
+	if len(projects) != 1 || len(projects[0].ShellSnapshots) != 0 {
+		t.Error("Shell snapshots should be left out when projects are filtered")
+	}
+
+	// A missing snapshots directory is not an error
+	if err := os.RemoveAll(snapshotsDir); err != nil {
+		t.Fatalf("Failed to remove snapshots dir: %v", err)
+	}
+	if _, err := NewScanner(claudeDir, &ScanOptions{IncludeShellSnapshots: true}).ScanProjects(); err != nil {
+		t.Errorf("ScanProjects() error without a snapshots directory = %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package reader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadTagMap reads a JSON file mapping project paths to tags, e.g.
+//
+//	{"/Users/me/work": ["work"], "/Users/me/blog": ["personal", "writing"]}
+func LoadTagMap(filePath string) (map[string][]string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag file: %w", err)
+	}
+
+	var tagMap map[string][]string
+	if err := json.Unmarshal(content, &tagMap); err != nil {
+		return nil, fmt.Errorf("failed to parse tag JSON: %w", err)
+	}
+
+	return tagMap, nil
+}
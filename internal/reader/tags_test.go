@@ -0,0 +1,37 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTagMap(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tagFile := filepath.Join(tmpDir, "tags.json")
+	content := `{"/Users/me/work": ["work"], "/Users/me/blog": ["personal", "writing"]}`
+	if err := os.WriteFile(tagFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create tag file: %v", err)
+	}
+
+	tagMap, err := LoadTagMap(tagFile)
+	if err != nil {
+		t.Fatalf("LoadTagMap() error = %v", err)
+	}
+	if len(tagMap["/Users/me/blog"]) != 2 || tagMap["/Users/me/work"][0] != "work" {
+		t.Errorf("LoadTagMap() = %v", tagMap)
+	}
+
+	invalidFile := filepath.Join(tmpDir, "invalid.json")
+	if err := os.WriteFile(invalidFile, []byte(`{"/work": "work"}`), 0644); err != nil {
+		t.Fatalf("Failed to create tag file: %v", err)
+	}
+	if _, err := LoadTagMap(invalidFile); err == nil {
+		t.Error("LoadTagMap() should error for non-array tags")
+	}
+
+	if _, err := LoadTagMap(filepath.Join(tmpDir, "missing.json")); err == nil {
+		t.Error("LoadTagMap() should error for missing file")
+	}
+}
@@ -0,0 +1,191 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// Watch re-scans the projects directory every interval until ctx is done and
+// calls callback with each session that was not seen in an earlier pass. The
+// first pass only records the sessions that already exist. Session files are
+// re-read only when their modification time or size changes, and a session
+// that merely grew (same ID, at least as many messages as before) is not
+// reported again. Sessions are filtered as in ScanProjects, except for
+// MaxSessions, and callbacks are never concurrent. Watch returns ctx's error
+// once ctx is done.
+func (s *Scanner) Watch(ctx context.Context, interval time.Duration, callback func(*models.Session)) error {
+	return s.WatchFrom(ctx, interval, nil, callback)
+}
+
+// WatchFrom is like Watch but starts from seen, an earlier scan of the same
+// directory: the session files and sessions it read count as already seen,
+// and the first pass reports the sessions created or changed since, such as
+// while the scanned projects were exported. With a nil seen it is Watch.
+func (s *Scanner) WatchFrom(ctx context.Context, interval time.Duration, seen *ScanResult, callback func(*models.Session)) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch interval must be positive")
+	}
+	if err := s.compileProjectRegex(); err != nil {
+		return err
+	}
+
+	projectsPath := filepath.Join(s.basePath, "projects")
+	if _, err := os.Stat(projectsPath); os.IsNotExist(err) {
+		return fmt.Errorf("projects directory not found: %s", projectsPath)
+	}
+
+	// Without an earlier scan, the first pass only records what exists
+	w := newWatcher(s)
+	first := callback
+	if seen == nil {
+		first = nil
+	}
+	w.seed(seen)
+	w.poll(ctx, projectsPath, first)
+	return w.watch(ctx, projectsPath, interval, callback)
+}
+
+// watch polls the projects directory every interval until ctx is done
+func (w *watcher) watch(ctx context.Context, projectsPath string, interval time.Duration, callback func(*models.Session)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			w.poll(ctx, projectsPath, callback)
+		}
+	}
+}
+
+// watchedFile is the state of a session file when Watch last read it
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+}
+
+// watcher remembers the session files and sessions seen by Watch
+type watcher struct {
+	scanner *Scanner
+	// files maps session file paths to their state when last read
+	files map[string]watchedFile
+	// sessions maps session IDs to their message counts when last read
+	sessions map[string]int
+}
+
+// newWatcher creates a watcher that has seen nothing yet
+func newWatcher(s *Scanner) *watcher {
+	return &watcher{
+		scanner:  s,
+		files:    make(map[string]watchedFile),
+		sessions: make(map[string]int),
+	}
+}
+
+// seed records the session files and sessions of a scan as seen
+func (w *watcher) seed(result *ScanResult) {
+	if result == nil {
+		return
+	}
+	for _, file := range result.SessionFiles {
+		w.files[file.Path] = watchedFile{modTime: file.ModTime, size: file.Size}
+	}
+	for _, project := range result.Projects {
+		for _, session := range project.Sessions {
+			key := session.ID
+			if key == "" {
+				key = session.SourceFile
+			}
+			w.sessions[key] = session.GetMessageCount()
+		}
+	}
+}
+
+// poll reads the new and changed session files of the projects directory,
+// calling callback with sessions not seen before (if callback is not nil)
+func (w *watcher) poll(ctx context.Context, projectsPath string, callback func(*models.Session)) {
+	entries, err := os.ReadDir(projectsPath)
+	if err != nil {
+		w.scanner.warnf("failed to read projects directory: %v", err)
+		return
+	}
+
+	visited := make(map[string]bool)
+	if realPath, err := filepath.EvalSymlinks(projectsPath); err == nil {
+		visited[realPath] = true
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		if w.scanner.isProjectDir(projectsPath, entry, visited) && w.scanner.shouldProcessProject(entry.Name()) {
+			w.pollProject(filepath.Join(projectsPath, entry.Name()), entry.Name(), callback)
+		}
+	}
+}
+
+// pollProject reads the new and changed session files of a project directory
+func (w *watcher) pollProject(projectPath, projectID string, callback func(*models.Session)) {
+	entries, err := os.ReadDir(projectPath)
+	if err != nil {
+		w.scanner.warnf("failed to read project directory %s: %v", projectPath, err)
+		return
+	}
+
+	project := w.scanner.NewProject(projectID)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since the directory was read
+		}
+
+		filePath := filepath.Join(projectPath, entry.Name())
+		state := watchedFile{modTime: info.ModTime(), size: info.Size()}
+		if last, ok := w.files[filePath]; ok && last.size == state.size && last.modTime.Equal(state.modTime) {
+			continue
+		}
+		w.files[filePath] = state
+
+		session, err := w.scanner.readSessionFile(filePath, projectID)
+		if err != nil {
+			// A new file may not have any messages yet
+			if !errors.Is(err, ErrNoMessages) {
+				w.scanner.warnf("failed to read session file %s: %v", filePath, err)
+			}
+			continue
+		}
+		if !w.scanner.shouldIncludeSession(project, session) {
+			continue
+		}
+
+		// A session that only grew has been reported already, while one
+		// that shrank was rewritten and is reported again
+		key := session.ID
+		if key == "" {
+			key = filePath
+		}
+		count := session.GetMessageCount()
+		last, seen := w.sessions[key]
+		w.sessions[key] = count
+		if (seen && count >= last) || callback == nil {
+			continue
+		}
+
+		if w.scanner.options.SearchTrim && w.scanner.options.Search != "" {
+			session.TrimToMatches(w.scanner.options.Search, w.scanner.options.SearchThinking)
+		}
+		callback(session)
+	}
+}
@@ -0,0 +1,128 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+const (
+	watchUserLine      = `{"uuid":"u%d","sessionId":"%s","type":"user","timestamp":"2024-01-01T10:00:00Z","message":{"role":"user","content":"Hello"}}` + "\n"
+	watchAssistantLine = `{"uuid":"a%d","sessionId":"%s","type":"assistant","timestamp":"2024-01-01T10:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"Hi"}]}}` + "\n"
+)
+
+func writeWatchSession(t *testing.T, path, sessionID string, pairs int) {
+	t.Helper()
+	var content string
+	for i := 0; i < pairs; i++ {
+		content += fmt.Sprintf(watchUserLine, i, sessionID) + fmt.Sprintf(watchAssistantLine, i, sessionID)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+}
+
+func TestWatcherReportsNewSessions(t *testing.T) {
+	claudeDir := t.TempDir()
+	projectsDir := filepath.Join(claudeDir, "projects")
+	projectDir := filepath.Join(projectsDir, "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	writeWatchSession(t, filepath.Join(projectDir, "s1.jsonl"), "s1", 1)
+
+	w := newWatcher(NewScanner(claudeDir, &ScanOptions{MinMessages: 4}))
+	var reported []string
+	callback := func(session *models.Session) {
+		reported = append(reported, session.ID)
+	}
+
+	// The first pass only records existing sessions
+	w.poll(context.Background(), projectsDir, nil)
+
+	// A new session is reported, one that grew is not, and one that grew
+	// past the message minimum is reported once it passes the filters
+	writeWatchSession(t, filepath.Join(projectDir, "s2.jsonl"), "s2", 2)
+	writeWatchSession(t, filepath.Join(projectDir, "s1.jsonl"), "s1", 2)
+	w.poll(context.Background(), projectsDir, callback)
+	if len(reported) != 2 || reported[0] != "s1" || reported[1] != "s2" {
+		t.Fatalf("reported = %v, want [s1 s2]", reported)
+	}
+
+	reported = nil
+	writeWatchSession(t, filepath.Join(projectDir, "s2.jsonl"), "s2", 3)
+	if err := os.WriteFile(filepath.Join(projectDir, "empty.jsonl"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+	w.poll(context.Background(), projectsDir, callback)
+	if len(reported) != 0 {
+		t.Errorf("reported = %v, want no sessions for a session that grew", reported)
+	}
+
+	// A session rewritten with fewer messages is reported again
+	writeWatchSession(t, filepath.Join(projectDir, "s2.jsonl"), "s2", 2)
+	w.poll(context.Background(), projectsDir, callback)
+	if len(reported) != 1 || reported[0] != "s2" {
+		t.Errorf("reported = %v, want [s2] after a rewrite", reported)
+	}
+}
+
+func TestWatcherSeed(t *testing.T) {
+	claudeDir := t.TempDir()
+	projectsDir := filepath.Join(claudeDir, "projects")
+	projectDir := filepath.Join(projectsDir, "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	writeWatchSession(t, filepath.Join(projectDir, "s1.jsonl"), "s1", 1)
+	writeWatchSession(t, filepath.Join(projectDir, "s2.jsonl"), "s2", 1)
+
+	scanner := NewScanner(claudeDir, nil)
+	result, err := scanner.ScanProjectsDetailed()
+	if err != nil {
+		t.Fatalf("ScanProjectsDetailed() error = %v", err)
+	}
+	if len(result.SessionFiles) != 2 {
+		t.Fatalf("SessionFiles = %v, want both session files", result.SessionFiles)
+	}
+
+	// A session created after the scan is reported by the first pass, one
+	// that was scanned is not
+	writeWatchSession(t, filepath.Join(projectDir, "s3.jsonl"), "s3", 1)
+	w := newWatcher(scanner)
+	w.seed(result)
+	var reported []string
+	w.poll(context.Background(), projectsDir, func(session *models.Session) {
+		reported = append(reported, session.ID)
+	})
+	if len(reported) != 1 || reported[0] != "s3" {
+		t.Errorf("reported = %v, want [s3]", reported)
+	}
+}
+
+func TestScannerWatch(t *testing.T) {
+	claudeDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(claudeDir, "projects"), 0755); err != nil {
+		t.Fatalf("Failed to create projects dir: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := NewScanner(claudeDir, nil).Watch(ctx, 10*time.Millisecond, func(*models.Session) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Watch() error = %v, want the context's error", err)
+	}
+
+	if err := NewScanner(claudeDir, nil).Watch(ctx, 0, nil); err == nil {
+		t.Error("Watch() accepted a zero interval")
+	}
+	if err := NewScanner(t.TempDir(), nil).Watch(ctx, time.Second, nil); err == nil {
+		t.Error("Watch() accepted a directory without projects")
+	}
+}
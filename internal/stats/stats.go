@@ -0,0 +1,128 @@
+// Package stats aggregates usage statistics across projects without
+// converting their content
+package stats
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// Summary holds aggregate statistics of a set of projects
+type Summary struct {
+	Projects    int              `json:"projects"`
+	Sessions    int              `json:"sessions"`
+	Messages    int              `json:"messages"`
+	Usage       models.Usage     `json:"usage"`
+	TotalTokens int              `json:"total_tokens"`
+	Cost        float64          `json:"estimated_cost_usd"`
+	BusiestDay  *models.DayStats `json:"busiest_day,omitempty"`
+	Models      []*ModelStats    `json:"models"`
+
+	// DailyActivity holds the messages, sessions and tokens of each day with
+	// activity, in local time and sorted by date
+	DailyActivity []models.DayStats `json:"daily_activity"`
+}
+
+// ModelStats holds the token usage and estimated cost of one model family
+// (see models.NormalizeModel)
+type ModelStats struct {
+	Model       string       `json:"model"`
+	Usage       models.Usage `json:"usage"`
+	TotalTokens int          `json:"total_tokens"`
+	Cost        float64      `json:"estimated_cost_usd"`
+
+	// Versions holds the exact model names of the family that were used,
+	// sorted
+	Versions []string `json:"versions"`
+}
+
+// Aggregate computes the summary of the projects. The busiest day and daily
+// activity are in local time; models are grouped by family and sorted by
+// total tokens, most used first.
+func Aggregate(projects []*models.Project) *Summary {
+	summary := &Summary{Projects: len(projects), Models: []*ModelStats{}, DailyActivity: []models.DayStats{}}
+
+	activity := make(map[string]models.DayStats)
+	for _, project := range projects {
+		for date, stats := range project.GetDailyActivity() {
+			day := activity[date]
+			day.Date = date
+			day.Add(stats)
+			activity[date] = day
+		}
+		summary.Sessions += project.GetSessionCount()
+		summary.Messages += project.GetTotalMessages()
+		projectUsage := project.GetUsageTotals()
+		summary.Usage.Add(&projectUsage)
+		summary.Cost += project.GetEstimatedCost()
+	}
+	summary.TotalTokens = summary.Usage.Total()
+
+	for _, day := range activity {
+		summary.DailyActivity = append(summary.DailyActivity, day)
+	}
+	sort.Slice(summary.DailyActivity, func(i, j int) bool {
+		return summary.DailyActivity[i].Date < summary.DailyActivity[j].Date
+	})
+
+	// The days are sorted, so ties go to the earliest day
+	for i, day := range summary.DailyActivity {
+		if summary.BusiestDay == nil || day.Messages > summary.BusiestDay.Messages {
+			summary.BusiestDay = &summary.DailyActivity[i]
+		}
+	}
+
+	byModel := make(map[string]*ModelStats)
+	for _, daily := range models.GetDailyUsage(projects) {
+		family := models.NormalizeModel(daily.Model)
+		model, ok := byModel[family]
+		if !ok {
+			model = &ModelStats{Model: family}
+			byModel[family] = model
+			summary.Models = append(summary.Models, model)
+		}
+		if !slices.Contains(model.Versions, daily.Model) {
+			model.Versions = append(model.Versions, daily.Model)
+		}
+		model.Usage.Add(&daily.Usage)
+		model.Cost += daily.Cost
+	}
+	for _, model := range summary.Models {
+		model.TotalTokens = model.Usage.Total()
+		sort.Strings(model.Versions)
+	}
+	sort.Slice(summary.Models, func(i, j int) bool {
+		if summary.Models[i].TotalTokens != summary.Models[j].TotalTokens {
+			return summary.Models[i].TotalTokens > summary.Models[j].TotalTokens
+		}
+		return summary.Models[i].Model < summary.Models[j].Model
+	})
+
+	return summary
+}
+
+// WriteText writes the summary as plain text
+func (s *Summary) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Projects: %d | Sessions: %d | Messages: %d\n", s.Projects, s.Sessions, s.Messages)
+	fmt.Fprintf(tw, "Total tokens: %d (input: %d, output: %d, cache read: %d, cache write: %d)\n",
+		s.TotalTokens, s.Usage.InputTokens, s.Usage.OutputTokens, s.Usage.CacheReadInputTokens, s.Usage.CacheCreationInputTokens)
+	fmt.Fprintf(tw, "Estimated cost: $%.4f\n", s.Cost)
+	if s.BusiestDay != nil {
+		fmt.Fprintf(tw, "Busiest day: %s (%d messages in %d sessions)\n", s.BusiestDay.Date, s.BusiestDay.Messages, s.BusiestDay.Sessions)
+	}
+
+	if len(s.Models) > 0 {
+		fmt.Fprintf(tw, "\nModel\tInput\tOutput\tCache read\tCache write\tTotal\tEstimated cost\n")
+		for _, m := range s.Models {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t$%.4f\n", m.Model, m.Usage.InputTokens, m.Usage.OutputTokens,
+				m.Usage.CacheReadInputTokens, m.Usage.CacheCreationInputTokens, m.TotalTokens, m.Cost)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,144 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternnoir/cc-history-export/internal/models"
+)
+
+// newMessage creates a parsed message sent at the given time
+func newMessage(t *testing.T, msgType models.MessageType, timestamp, raw string) *models.Message {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		t.Fatalf("Failed to parse timestamp: %v", err)
+	}
+	msg := &models.Message{Type: msgType, UserType: "external", Timestamp: ts, Message: json.RawMessage(raw)}
+	msg.ParseContent()
+	return msg
+}
+
+func TestAggregate(t *testing.T) {
+	// Daily activity is bucketed in local time
+	oldLocal := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = oldLocal }()
+
+	session1 := &models.Session{ID: "session1"}
+	session1.AddMessage(newMessage(t, models.MessageTypeUser, "2024-01-01T10:00:00Z", `{"role":"user","content":"Hello"}`))
+	session1.AddMessage(newMessage(t, models.MessageTypeAssistant, "2024-01-01T10:00:05Z",
+		`{"role":"assistant","model":"claude-3-opus-20240229","content":[],"usage":{"input_tokens":1000,"output_tokens":2000,"cache_read_input_tokens":300}}`))
+
+	session2 := &models.Session{ID: "session2"}
+	session2.AddMessage(newMessage(t, models.MessageTypeUser, "2024-01-02T10:00:00Z", `{"role":"user","content":"Again"}`))
+	session2.AddMessage(newMessage(t, models.MessageTypeAssistant, "2024-01-02T10:00:05Z",
+		`{"role":"assistant","model":"claude-3-haiku-20240307","content":[],"usage":{"input_tokens":100,"output_tokens":100}}`))
+	session2.AddMessage(newMessage(t, models.MessageTypeAssistant, "2024-01-02T10:00:10Z",
+		`{"role":"assistant","model":"claude-3-opus-latest","content":[],"usage":{"input_tokens":10,"output_tokens":10}}`))
+
+	project1 := models.NewProject("-Users-test-app")
+	project1.AddSession(session1)
+	project2 := models.NewProject("-Users-test-api")
+	project2.AddSession(session2)
+
+	summary := Aggregate([]*models.Project{project1, project2})
+
+	if summary.Projects != 2 || summary.Sessions != 2 || summary.Messages != 5 {
+		t.Errorf("Counts = %d projects, %d sessions, %d messages, want 2, 2, 5", summary.Projects, summary.Sessions, summary.Messages)
+	}
+	if summary.TotalTokens != 3520 {
+		t.Errorf("TotalTokens = %d, want 3520", summary.TotalTokens)
+	}
+	if want := project1.GetEstimatedCost() + project2.GetEstimatedCost(); math.Abs(summary.Cost-want) > 1e-9 {
+		t.Errorf("Cost = %v, want %v", summary.Cost, want)
+	}
+	if day := summary.BusiestDay; day == nil || day.Date != "2024-01-02" || day.Messages != 3 || day.Sessions != 1 {
+		t.Errorf("BusiestDay = %+v, want 2024-01-02 with 3 messages in 1 session", day)
+	}
+
+	if len(summary.Models) != 2 {
+		t.Fatalf("Expected 2 models, got %d", len(summary.Models))
+	}
+	// Versions of a model are grouped into its family
+	opus := summary.Models[0]
+	if opus.Model != "claude-3-opus" || opus.TotalTokens != 3320 || opus.Usage.CacheReadInputTokens != 300 {
+		t.Errorf("First model = %+v, want claude-3-opus with 3320 tokens", opus)
+	}
+	if len(opus.Versions) != 2 || opus.Versions[0] != "claude-3-opus-20240229" || opus.Versions[1] != "claude-3-opus-latest" {
+		t.Errorf("First model versions = %v, want both opus versions", opus.Versions)
+	}
+	if summary.Models[1].Model != "claude-3-haiku" {
+		t.Errorf("Second model = %s, want claude-3-haiku", summary.Models[1].Model)
+	}
+
+	wantActivity := []models.DayStats{
+		{Date: "2024-01-01", Messages: 2, Sessions: 1, Tokens: 3300},
+		{Date: "2024-01-02", Messages: 3, Sessions: 1, Tokens: 220},
+	}
+	if len(summary.DailyActivity) != len(wantActivity) {
+		t.Fatalf("DailyActivity = %+v, want %+v", summary.DailyActivity, wantActivity)
+	}
+	for i, want := range wantActivity {
+		if summary.DailyActivity[i] != want {
+			t.Errorf("DailyActivity[%d] = %+v, want %+v", i, summary.DailyActivity[i], want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := summary.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{
+		"Projects: 2 | Sessions: 2 | Messages: 5\n",
+		"Total tokens: 3520 (input: 1110, output: 2110, cache read: 300, cache write: 0)\n",
+		"Busiest day: 2024-01-02 (3 messages in 1 sessions)\n",
+		"claude-3-opus   1010",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteText() missing %q. Output:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestAggregateBusiestDayLocal(t *testing.T) {
+	// Two messages of the evening of January 1 in UTC fall on January 2 in
+	// UTC+9, along with a third one
+	oldLocal := time.Local
+	time.Local = time.FixedZone("UTC+9", 9*60*60)
+	defer func() { time.Local = oldLocal }()
+
+	session := &models.Session{ID: "session1"}
+	session.AddMessage(newMessage(t, models.MessageTypeUser, "2024-01-01T20:00:00Z", `{"role":"user","content":"Hello"}`))
+	session.AddMessage(newMessage(t, models.MessageTypeUser, "2024-01-01T21:00:00Z", `{"role":"user","content":"Again"}`))
+	session.AddMessage(newMessage(t, models.MessageTypeUser, "2024-01-02T10:00:00Z", `{"role":"user","content":"Later"}`))
+	project := models.NewProject("-Users-test-app")
+	project.AddSession(session)
+
+	summary := Aggregate([]*models.Project{project})
+	if day := summary.BusiestDay; day == nil || day.Date != "2024-01-02" || day.Messages != 3 {
+		t.Errorf("BusiestDay = %+v, want 2024-01-02 with 3 messages", day)
+	}
+	if len(summary.DailyActivity) != 1 || summary.DailyActivity[0].Date != "2024-01-02" {
+		t.Errorf("DailyActivity = %+v, want the same single day", summary.DailyActivity)
+	}
+}
+
+func TestAggregateEmpty(t *testing.T) {
+	summary := Aggregate(nil)
+	if summary.Projects != 0 || summary.BusiestDay != nil || len(summary.Models) != 0 {
+		t.Errorf("Aggregate(nil) = %+v, want an empty summary", summary)
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"models":[]`) || !strings.Contains(string(data), `"daily_activity":[]`) {
+		t.Errorf("Empty summary should have empty models and daily activity lists, got %s", data)
+	}
+}